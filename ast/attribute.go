@@ -0,0 +1,208 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// AttributeTarget is kind of declaration which an attribute could be applied to
+type AttributeTarget int
+
+const (
+	TargetVariable AttributeTarget = 1 << iota
+	TargetFunction
+	TargetMemberVariable
+	TargetMemberFunction
+	TargetEnum
+	TargetInterface
+	TargetClass
+	TargetStruct
+
+	TargetAll = TargetVariable | TargetFunction | TargetMemberVariable | TargetMemberFunction | TargetEnum | TargetInterface | TargetClass | TargetStruct
+)
+
+var (
+	attributeHandlers = map[string]*attributeHandler{}
+
+	targetNames = map[AttributeTarget]string{
+		TargetVariable:       "variable",
+		TargetFunction:       "function",
+		TargetMemberVariable: "member variable",
+		TargetMemberFunction: "member function",
+		TargetEnum:           "enum",
+		TargetInterface:      "interface",
+		TargetClass:          "class",
+		TargetStruct:         "struct",
+	}
+)
+
+// AttributeHandler validates arguments of attribute and transforms declaration it is applied to,
+// it is invoked after ir of declarations is generated, errors are reported by p.Error with position of attribute
+// attributes which change layout or declaration of ir (@packed, @section, @inline) are applied when declaration is generated
+type AttributeHandler = func(p *Program, d Declaration, a *Attribute)
+
+type attributeHandler struct {
+	targets AttributeTarget
+	handler AttributeHandler
+}
+
+// RegisterAttributeHandler registers handler of attribute with name, attribute applied to declaration which is not one of targets is reported
+// handler could be nil if attribute is only used as a marker
+// returned function restores previous handler of name, or unregisters it if there was none
+func RegisterAttributeHandler(name string, targets AttributeTarget, handler AttributeHandler) func() {
+	previous, registered := attributeHandlers[name]
+	attributeHandlers[name] = &attributeHandler{
+		targets: targets,
+		handler: handler,
+	}
+	return func() {
+		if registered {
+			attributeHandlers[name] = previous
+		} else {
+			delete(attributeHandlers, name)
+		}
+	}
+}
+
+func IsAttributeRegistered(name string) bool {
+	_, ok := attributeHandlers[name]
+	return ok
+}
+
+// GetText returns unquoted text of attribute, it is empty if attribute has no text
+func (a *Attribute) GetText() string {
+	if a.Text == "" {
+		return ""
+	}
+	text, err := strconv.Unquote(a.Text)
+	if err != nil {
+		return a.Text
+	}
+	return text
+}
+
+// CheckAttributes reports unknown attributes, attributes applied to wrong kind of declaration and duplicated attributes
+func (p *Program) CheckAttributes() {
+	for _, m := range p.SortedModules() {
+		p.Module = m
+		for _, d := range m.declarations() {
+			target := targetOf(d)
+			names := make(map[string]bool)
+			for _, a := range attributesOf(d) {
+				h := attributeHandlers[a.Name]
+				switch {
+				case h == nil:
+					p.Error(a.Position, fmt.Sprintf("unknown attribute @%s", a.Name))
+
+				case h.targets&target == 0:
+					p.Error(a.Position, fmt.Sprintf("attribute @%s cannot be applied to %s", a.Name, targetNames[target]))
+
+				case names[a.Name]:
+					p.Error(a.Position, fmt.Sprintf("duplicated attribute @%s", a.Name))
+				}
+				names[a.Name] = true
+			}
+		}
+	}
+}
+
+// ProcessAttributes invokes handlers of attributes in declaration order, attributes rejected by CheckAttributes are skipped
+func (p *Program) ProcessAttributes() {
+	for _, m := range p.SortedModules() {
+		p.Module = m
+		for _, d := range m.declarations() {
+			target := targetOf(d)
+			for _, a := range attributesOf(d) {
+				if h := attributeHandlers[a.Name]; h != nil && h.handler != nil && h.targets&target != 0 {
+					h.handler(p, d, a)
+				}
+			}
+		}
+	}
+}
+
+// declarations returns declarations of module with members of structs, classes and interfaces, overloads are included
+func (m *Module) declarations() []Declaration {
+	var declarations []Declaration
+	for _, v := range m.Variables {
+		declarations = append(declarations, v)
+	}
+	for _, f := range m.Functions {
+		declarations = append(declarations, f)
+	}
+	for _, e := range m.Enums {
+		declarations = append(declarations, e)
+	}
+	for _, s := range m.Structs {
+		declarations = append(declarations, s)
+		for _, v := range s.Variables {
+			declarations = append(declarations, v)
+		}
+		for _, f := range s.Functions {
+			declarations = append(declarations, f)
+		}
+	}
+	for _, i := range m.Interfaces {
+		declarations = append(declarations, i)
+		for _, f := range i.Functions {
+			declarations = append(declarations, f)
+		}
+	}
+	for _, c := range m.Classes {
+		declarations = append(declarations, c)
+		for _, v := range c.Variables {
+			declarations = append(declarations, v)
+		}
+		for _, f := range c.Functions {
+			declarations = append(declarations, f)
+		}
+	}
+	return declarations
+}
+
+func targetOf(d Declaration) AttributeTarget {
+	switch n := d.(type) {
+	case *Variable:
+		if n.ObjectName != "" {
+			return TargetMemberVariable
+		}
+		return TargetVariable
+
+	case *Function:
+		if n.ObjectName != "" {
+			return TargetMemberFunction
+		}
+		return TargetFunction
+
+	case *Enum:
+		return TargetEnum
+
+	case *Interface:
+		return TargetInterface
+
+	case *Class:
+		return TargetClass
+
+	case *Struct:
+		return TargetStruct
+	}
+	return 0
+}
+
+func attributesOf(d Declaration) []*Attribute {
+	switch n := d.(type) {
+	case *Variable:
+		return n.Attributes
+	case *Function:
+		return n.Attributes
+	case *Enum:
+		return n.Attributes
+	case *Interface:
+		return n.Attributes
+	case *Class:
+		return n.Attributes
+	case *Struct:
+		return n.Attributes
+	}
+	return nil
+}
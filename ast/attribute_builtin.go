@@ -0,0 +1,84 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+func init() {
+	RegisterAttributeHandler(Extern, TargetFunction, externAttribute)
+	RegisterAttributeHandler(Override, TargetMemberFunction, noArguments)
+	RegisterAttributeHandler(Doc, TargetAll, docAttribute)
+	RegisterAttributeHandler(Deprecated, TargetAll, deprecatedAttribute)
+	RegisterAttributeHandler(Inline, TargetFunction|TargetMemberFunction, inlineAttribute)
+	RegisterAttributeHandler(Packed, TargetClass|TargetStruct, packedAttribute)
+	RegisterAttributeHandler(Section, TargetFunction|TargetVariable, sectionAttribute)
+	RegisterAttributeHandler(Flags, TargetEnum, noArguments)
+}
+
+// @extern(variadic = true) declares external c function
+func externAttribute(p *Program, d Declaration, a *Attribute) {
+	if a.Text != "" {
+		p.Error(a.Position, "attribute @extern does not accept text")
+	}
+	var names []string
+	for name := range a.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := a.Values[name]
+		if name != Variadic {
+			p.Error(value.Position, fmt.Sprintf("unknown argument %s of attribute @extern", name))
+		} else if value.Typ != token.BOOL {
+			p.Error(value.Position, fmt.Sprintf("argument %s of attribute @extern must be bool", name))
+		}
+	}
+}
+
+// @doc "text" documents declaration
+func docAttribute(p *Program, d Declaration, a *Attribute) {
+	if a.Text == "" || a.Values != nil {
+		p.Error(a.Position, "attribute @doc requires text only")
+	}
+}
+
+// @deprecated or @deprecated "message", references to declaration are warned by checker
+func deprecatedAttribute(p *Program, d Declaration, a *Attribute) {
+	if a.Values != nil {
+		p.Error(a.Position, "attribute @deprecated accepts text only")
+	}
+}
+
+// @inline forces function to be inlined, it is applied when function is declared
+func inlineAttribute(p *Program, d Declaration, a *Attribute) {
+	noArguments(p, d, a)
+	f := d.(*Function)
+	if f.Body == nil || f.HasAttribute(Extern) {
+		p.Error(a.Position, "attribute @inline requires function body")
+	}
+}
+
+// @packed removes padding between member variables of class or struct, it is applied when type of struct is declared
+func packedAttribute(p *Program, d Declaration, a *Attribute) {
+	noArguments(p, d, a)
+}
+
+// @section "name" places function or global variable in object file section, it is applied when they are declared
+func sectionAttribute(p *Program, d Declaration, a *Attribute) {
+	if a.GetText() == "" || a.Values != nil {
+		p.Error(a.Position, "attribute @section requires section name only")
+		return
+	}
+	if f, ok := d.(*Function); ok && f.Body == nil {
+		p.Error(a.Position, "attribute @section requires function body")
+	}
+}
+
+func noArguments(p *Program, d Declaration, a *Attribute) {
+	if a.Text != "" || a.Values != nil {
+		p.Error(a.Position, fmt.Sprintf("attribute @%s does not accept arguments", a.Name))
+	}
+}
@@ -2,6 +2,7 @@ package ast
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/panda-foundation/go-compiler/ir"
 )
@@ -39,6 +40,24 @@ func PromoteNumberType(t1 ir.Type, t2 ir.Type) (ir.Type, error) {
 	return nil, fmt.Errorf("invalid number")
 }
 
+// ImplicitCast converts value to the given type if the conversion does not lose precision
+func ImplicitCast(c *Context, value ir.Value, t ir.Type) (ir.Value, error) {
+	if value.Type().Equal(t) {
+		return value, nil
+	}
+	if ir.IsNumber(value.Type()) && ir.IsNumber(t) {
+		promoted, err := PromoteNumberType(t, value.Type())
+		if err != nil {
+			return nil, err
+		}
+		if !promoted.Equal(t) {
+			return nil, fmt.Errorf("cannot implicit convert %s to %s [down grade]", value.Type().String(), t.String())
+		}
+		return CastNumber(c, value, t), nil
+	}
+	return nil, fmt.Errorf("cannot implicit convert %s to %s", value.Type().String(), t.String())
+}
+
 func CastToPointer(b *ir.Block, value ir.Value) ir.Value {
 	if ir.IsPointer(value.Type()) {
 		cast := ir.NewBitCast(value, pointerType)
@@ -103,3 +122,374 @@ func CastNumber(c *Context, from ir.Value, to ir.Type) ir.Value {
 	c.Block.AddInstruction(cast)
 	return cast.(ir.Value)
 }
+
+// ExplicitCast converts value to the given type, numbers are truncated or extended, class instance is checked at runtime when down casting
+// if checked is true, it traps when integer overflows or down casting fails, otherwise result of failed down casting is null
+func ExplicitCast(c *Context, value ir.Value, t ir.Type, checked bool) (ir.Value, error) {
+	from := value.Type()
+	var inst ir.Instruction
+	switch {
+	case IsString(t):
+		return ConvertToString(c, value)
+
+	case ir.IsNumber(from) && ir.IsNumber(t):
+		return ConvertNumber(c, value, t, checked), nil
+
+	case ir.IsBool(from) && ir.IsBool(t):
+		return value, nil
+
+	case ir.IsBool(from) && ir.IsNumber(t):
+		inst = ir.NewZExt(value, t)
+		if ir.IsFloat(t) {
+			inst = ir.NewUIToFP(value, t)
+		}
+
+	case ir.IsNumber(from) && ir.IsBool(t):
+		inst = ir.NewICmp(ir.IPredNE, value, ir.NewInt(from.(*ir.IntType), 0))
+		if ir.IsFloat(from) {
+			inst = ir.NewFCmp(ir.FPredUNE, value, ir.NewFloat(from.(*ir.FloatType), 0))
+		}
+
+	case ir.IsPointer(from) && ir.IsInt(t):
+		if isClassPointer(c.Program, from) {
+			return nil, fmt.Errorf("cannot convert class %s to %s", GetTypeUserData(from), t.String())
+		}
+		inst = ir.NewPtrToInt(value, t)
+
+	case ir.IsInt(from) && ir.IsPointer(t):
+		if isClassPointer(c.Program, t) {
+			return nil, fmt.Errorf("cannot convert %s to class %s", from.String(), GetTypeUserData(t))
+		}
+		inst = ir.NewIntToPtr(value, newPointerType(t))
+
+	case ir.IsPointer(from) && ir.IsPointer(t):
+		return CastPointer(c, value, t, checked)
+
+	default:
+		return nil, fmt.Errorf("cannot convert %s to %s", from.String(), t.String())
+	}
+	c.Block.AddInstruction(inst)
+	return inst.(ir.Value), nil
+}
+
+// CheckConversion returns error if type from cannot be converted to t explicitly, it follows the rules of ExplicitCast
+func CheckConversion(p *Program, from ir.Type, t ir.Type) error {
+	switch {
+	case IsString(t):
+		if IsString(from) || ir.IsNumber(from) || ir.IsBool(from) || ir.IsPointer(from) && GetTypeUserData(from) == "" && !IsClosure(from) {
+			return nil
+		}
+		return fmt.Errorf("cannot convert %s to string", MangleType(from))
+
+	case IsString(from):
+		return fmt.Errorf("cannot convert string to %s, use its member functions instead", MangleType(t))
+
+	case (ir.IsNumber(from) || ir.IsBool(from)) && (ir.IsNumber(t) || ir.IsBool(t)):
+		return nil
+
+	case ir.IsPointer(from) && ir.IsInt(t):
+		if isClassPointer(p, from) {
+			return fmt.Errorf("cannot convert class %s to %s", GetTypeUserData(from), t.String())
+		}
+		return nil
+
+	case ir.IsInt(from) && ir.IsPointer(t):
+		if isClassPointer(p, t) {
+			return fmt.Errorf("cannot convert %s to class %s", from.String(), GetTypeUserData(t))
+		}
+		return nil
+
+	case ir.IsPointer(from) && ir.IsPointer(t):
+		fromClass, _ := p.FindQualified(GetTypeUserData(from)).(*Class)
+		toClass, _ := p.FindQualified(GetTypeUserData(t)).(*Class)
+		if fromClass == nil || toClass == nil || fromClass == toClass {
+			return nil
+		}
+		if IsBuiltinClass(GetTypeUserData(from)) || IsBuiltinClass(GetTypeUserData(t)) {
+			return fmt.Errorf("cannot convert builtin class %s to %s", GetTypeUserData(from), GetTypeUserData(t))
+		}
+		if !fromClass.IsSubclassOf(toClass) && !toClass.IsSubclassOf(fromClass) {
+			return fmt.Errorf("cannot convert %s to %s, they are not in the same class hierarchy", GetTypeUserData(from), GetTypeUserData(t))
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot convert %s to %s", from.String(), t.String())
+}
+
+// ExplicitCastExpr converts constant to the given type, class instance cannot be constant
+func ExplicitCastExpr(from ir.Constant, to ir.Type) (ir.Constant, error) {
+	t := from.Type()
+	switch {
+	case ir.IsInt(t) && ir.IsInt(to):
+		i1 := t.(*ir.IntType)
+		i2 := to.(*ir.IntType)
+		if i1.BitSize > i2.BitSize {
+			return ir.NewExprTrunc(from, to), nil
+		} else if i1.BitSize < i2.BitSize {
+			if i1.Unsigned {
+				return ir.NewExprZExt(from, to), nil
+			}
+			return ir.NewExprSExt(from, to), nil
+		}
+		return ir.NewExprBitCast(from, to), nil
+
+	case ir.IsInt(t) && ir.IsFloat(to):
+		if t.(*ir.IntType).Unsigned {
+			return ir.NewExprUIToFP(from, to), nil
+		}
+		return ir.NewExprSIToFP(from, to), nil
+
+	case ir.IsFloat(t) && ir.IsInt(to):
+		if to.(*ir.IntType).Unsigned {
+			return ir.NewExprFPToUI(from, to), nil
+		}
+		return ir.NewExprFPToSI(from, to), nil
+
+	case ir.IsFloat(t) && ir.IsFloat(to):
+		if t.Equal(to) {
+			return from, nil
+		} else if to.(*ir.FloatType).Kind == ir.FloatKindDouble {
+			return ir.NewExprFPExt(from, to), nil
+		}
+		return ir.NewExprFPTrunc(from, to), nil
+
+	case ir.IsBool(t) && ir.IsBool(to):
+		return from, nil
+
+	case ir.IsBool(t) && ir.IsInt(to):
+		return ir.NewExprZExt(from, to), nil
+
+	case ir.IsBool(t) && ir.IsFloat(to):
+		return ir.NewExprUIToFP(from, to), nil
+
+	case ir.IsInt(t) && ir.IsBool(to):
+		return ir.NewExprICmp(ir.IPredNE, from, ir.NewInt(t.(*ir.IntType), 0)), nil
+
+	case ir.IsFloat(t) && ir.IsBool(to):
+		return ir.NewExprFCmp(ir.FPredUNE, from, ir.NewFloat(t.(*ir.FloatType), 0)), nil
+
+	case ir.IsPointer(t) && ir.IsInt(to):
+		return ir.NewExprPtrToInt(from, to), nil
+
+	case ir.IsInt(t) && ir.IsPointer(to):
+		return ir.NewExprIntToPtr(from, newPointerType(to)), nil
+
+	case ir.IsPointer(t) && ir.IsPointer(to):
+		return ir.NewExprBitCast(from, newPointerType(to)), nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to %s", t.String(), to.String())
+}
+
+// ConvertNumber converts between any numbers, integer is truncated if the target is smaller
+// if checked is true, it traps when the value does not fit in target integer type
+func ConvertNumber(c *Context, value ir.Value, to ir.Type, checked bool) ir.Value {
+	var cast ir.Instruction
+	t := value.Type()
+	if ir.IsInt(t) {
+		i1 := t.(*ir.IntType)
+		if ir.IsInt(to) {
+			i2 := to.(*ir.IntType)
+			if i1.BitSize > i2.BitSize {
+				cast = ir.NewTrunc(value, to)
+			} else if i1.BitSize < i2.BitSize {
+				if i1.Unsigned {
+					cast = ir.NewZExt(value, to)
+				} else {
+					cast = ir.NewSExt(value, to)
+				}
+			} else if i1.Unsigned == i2.Unsigned && i1.UserData == i2.UserData {
+				return value
+			} else {
+				// only sign changed
+				cast = ir.NewBitCast(value, to)
+			}
+		} else if i1.Unsigned {
+			cast = ir.NewUIToFP(value, to)
+		} else {
+			cast = ir.NewSIToFP(value, to)
+		}
+	} else {
+		f1 := t.(*ir.FloatType)
+		if ir.IsInt(to) {
+			if to.(*ir.IntType).Unsigned {
+				cast = ir.NewFPToUI(value, to)
+			} else {
+				cast = ir.NewFPToSI(value, to)
+			}
+		} else if f1.Kind == to.(*ir.FloatType).Kind {
+			return value
+		} else if to.(*ir.FloatType).Kind == ir.FloatKindDouble {
+			cast = ir.NewFPExt(value, to)
+		} else {
+			cast = ir.NewFPTrunc(value, to)
+		}
+	}
+	c.Block.AddInstruction(cast)
+	result := cast.(ir.Value)
+
+	if checked && ir.IsInt(to) {
+		if ir.IsInt(t) {
+			checkIntOverflow(c, value, result)
+		} else {
+			checkFloatOverflow(c, value, to.(*ir.IntType))
+		}
+	}
+	return result
+}
+
+// CastPointer converts between raw pointers and class instances
+// class can be converted to its parent class directly, converting to child class is checked by vtable of the instance
+func CastPointer(c *Context, value ir.Value, t ir.Type, checked bool) (ir.Value, error) {
+	from := value.Type()
+	fromClass, _ := c.Program.FindQualified(GetTypeUserData(from)).(*Class)
+	qualified := GetTypeUserData(t)
+	toClass, _ := c.Program.FindQualified(qualified).(*Class)
+
+	if fromClass == nil || toClass == nil {
+		// raw pointer, the instance is not checked
+		inst := ir.NewBitCast(value, newPointerType(t))
+		c.Block.AddInstruction(inst)
+		return inst, nil
+	}
+	if fromClass == toClass {
+		return value, nil
+	}
+	if IsBuiltinClass(GetTypeUserData(from)) || IsBuiltinClass(qualified) {
+		return nil, fmt.Errorf("cannot convert builtin class %s to %s", GetTypeUserData(from), qualified)
+	}
+	if fromClass.IsSubclassOf(toClass) {
+		// up cast
+		inst := ir.NewBitCast(value, CreateClassPointer(qualified))
+		c.Block.AddInstruction(inst)
+		return inst, nil
+	}
+	if !toClass.IsSubclassOf(fromClass) {
+		return nil, fmt.Errorf("cannot convert %s to %s, they are not in the same class hierarchy", GetTypeUserData(from), qualified)
+	}
+
+	// down cast, vtable of instance should be one of target class or its children
+	entry := c.Block
+	checkBlock := c.Function.IRFunction.NewBlock("")
+	failBlock := c.Function.IRFunction.NewBlock("")
+	nextBlock := c.Function.IRFunction.NewBlock("")
+
+	isNull := ir.NewICmp(ir.IPredEQ, value, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, nextBlock, checkBlock))
+
+	c.Block = checkBlock
+	counterClass := c.Program.FindQualified(Counter).(*Class)
+	object, _ := counterClass.GetMember(c, value, "object", false)
+	object = c.AutoLoad(object)
+	vtablePointer := ir.NewBitCast(object, ir.NewPointerType(pointerType))
+	c.Block.AddInstruction(vtablePointer)
+	vtable := ir.NewLoad(pointerType, vtablePointer)
+	c.Block.AddInstruction(vtable)
+
+	var matched ir.Value
+	for _, class := range toClass.Descendants(c.Program) {
+		equal := ir.NewICmp(ir.IPredEQ, vtable, ir.NewExprBitCast(class.VTable(), pointerType))
+		c.Block.AddInstruction(equal)
+		if matched == nil {
+			matched = equal
+		} else {
+			or := ir.NewOr(matched, equal)
+			c.Block.AddInstruction(or)
+			matched = or
+		}
+	}
+	c.Block.AddInstruction(ir.NewCondBr(matched, nextBlock, failBlock))
+
+	var result ir.Value = value
+	if checked {
+		failBlock.AddInstruction(ir.NewCall(c.Program.DeclareIntrinsic(trap)))
+		failBlock.AddInstruction(ir.NewUnreachable())
+	} else {
+		failBlock.AddInstruction(ir.NewBr(nextBlock))
+		phi := ir.NewPhi(ir.NewIncoming(value, entry), ir.NewIncoming(value, c.Block), ir.NewIncoming(ir.NewNull(pointerType), failBlock))
+		nextBlock.AddInstruction(phi)
+		result = phi
+	}
+	c.Block = nextBlock
+
+	inst := ir.NewBitCast(result, CreateClassPointer(qualified))
+	c.Block.AddInstruction(inst)
+	return inst, nil
+}
+
+// checkIntOverflow traps if result cannot be converted back to value
+func checkIntOverflow(c *Context, value ir.Value, result ir.Value) {
+	i1 := value.Type().(*ir.IntType)
+	i2 := result.Type().(*ir.IntType)
+	back := result
+	if i1.BitSize > i2.BitSize {
+		if i2.Unsigned {
+			back = ir.NewZExt(result, i1)
+		} else {
+			back = ir.NewSExt(result, i1)
+		}
+		c.Block.AddInstruction(back.(ir.Instruction))
+	} else if i1.BitSize < i2.BitSize {
+		back = ir.NewTrunc(result, i1)
+		c.Block.AddInstruction(back.(ir.Instruction))
+	}
+	var valid ir.Value = ir.NewICmp(ir.IPredEQ, back, value)
+	c.Block.AddInstruction(valid.(ir.Instruction))
+
+	if i1.Unsigned != i2.Unsigned {
+		// negative value cannot be unsigned, large unsigned value cannot be signed
+		var positive *ir.InstICmp
+		if i1.Unsigned {
+			positive = ir.NewICmp(ir.IPredSGE, result, ir.NewInt(i2, 0))
+		} else {
+			positive = ir.NewICmp(ir.IPredSGE, value, ir.NewInt(i1, 0))
+		}
+		c.Block.AddInstruction(positive)
+		and := ir.NewAnd(valid, positive)
+		c.Block.AddInstruction(and)
+		valid = and
+	}
+	trapUnless(c, valid)
+}
+
+// checkFloatOverflow traps if value is out of range of integer type, or is NaN
+func checkFloatOverflow(c *Context, value ir.Value, to *ir.IntType) {
+	f := value.Type().(*ir.FloatType)
+	var lower *ir.InstFCmp
+	var upper *ir.InstFCmp
+	if to.Unsigned {
+		lower = ir.NewFCmp(ir.FPredOGT, value, ir.NewFloat(f, -1))
+		upper = ir.NewFCmp(ir.FPredOLT, value, ir.NewFloat(f, math.Ldexp(1, int(to.BitSize))))
+	} else {
+		lower = ir.NewFCmp(ir.FPredOGE, value, ir.NewFloat(f, -math.Ldexp(1, int(to.BitSize)-1)))
+		upper = ir.NewFCmp(ir.FPredOLT, value, ir.NewFloat(f, math.Ldexp(1, int(to.BitSize)-1)))
+	}
+	c.Block.AddInstruction(lower)
+	c.Block.AddInstruction(upper)
+	valid := ir.NewAnd(lower, upper)
+	c.Block.AddInstruction(valid)
+	trapUnless(c, valid)
+}
+
+// trapUnless continues in a new block if condition is true, otherwise program is aborted
+func trapUnless(c *Context, condition ir.Value) {
+	failBlock := c.Function.IRFunction.NewBlock("")
+	nextBlock := c.Function.IRFunction.NewBlock("")
+	c.Block.AddInstruction(ir.NewCondBr(condition, nextBlock, failBlock))
+	failBlock.AddInstruction(ir.NewCall(c.Program.DeclareIntrinsic(trap)))
+	failBlock.AddInstruction(ir.NewUnreachable())
+	c.Block = nextBlock
+}
+
+func isClassPointer(p *Program, t ir.Type) bool {
+	_, ok := p.FindQualified(GetTypeUserData(t)).(*Class)
+	return ok
+}
+
+// newPointerType creates a copy of pointer type, so shared types like "pointer" are not modified by user data
+func newPointerType(t ir.Type) *ir.PointerType {
+	p := t.(*ir.PointerType)
+	n := ir.NewPointerType(p.ElemType)
+	n.UserData = p.UserData
+	return n
+}
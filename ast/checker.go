@@ -0,0 +1,1721 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+// Checker resolves type and referenced declaration of every expression in function bodies and global initializers,
+// and reports type errors with their positions. it runs after declarations and class layouts are generated,
+// ir of function bodies is generated only if there is no error, so codegen could assume the tree is well-typed
+type Checker struct {
+	Program *Program
+
+	checkerState
+
+	names   map[Declaration]string
+	modules map[Declaration]*Module
+	// class or struct which member is declared in
+	owners    map[Declaration]Declaration
+	variables map[*Variable]ir.Type
+	// global or static variables whose initial values are being checked
+	initializing map[*Variable]bool
+	// index of module in order of initialization
+	order map[*Module]int
+	// imports which declarations are resolved through
+	imports map[*Import]bool
+}
+
+// checkerState is state of function being checked, it is saved when lambda is checked
+type checkerState struct {
+	// class or struct whose members are accessed by "this", it is class or struct of outer function in lambda
+	class      *Class
+	structure  *Struct
+	hasThis    bool
+	returnType ir.Type
+	scope      *scope
+	loops      int
+	switches   int
+	// fallthrough statement which is the last statement of case being checked
+	fallthroughStatement *Fallthrough
+	// function being checked, it is nil in lambda and initial value of global variable
+	function *Function
+	// type of values yielded by generator function, it is nil if function is not generator
+	yieldType ir.Type
+	// global or static variable whose initial value is being checked, it is nil in lambda which is invoked later
+	variable *Variable
+}
+
+type scope struct {
+	parent  *scope
+	objects map[string]*object
+}
+
+type object struct {
+	typ       ir.Type
+	reference Node
+}
+
+func newScope(parent *scope) *scope {
+	return &scope{
+		parent:  parent,
+		objects: make(map[string]*object),
+	}
+}
+
+func (s *scope) find(name string) *object {
+	for current := s; current != nil; current = current.parent {
+		if o, ok := current.objects[name]; ok {
+			return o
+		}
+	}
+	return nil
+}
+
+func NewChecker(p *Program) *Checker {
+	c := &Checker{
+		Program:   p,
+		names:     make(map[Declaration]string),
+		modules:   make(map[Declaration]*Module),
+		owners:    make(map[Declaration]Declaration),
+		variables: make(map[*Variable]ir.Type),
+		imports:   make(map[*Import]bool),
+
+		initializing: make(map[*Variable]bool),
+		order:        make(map[*Module]int),
+	}
+	for i, m := range p.SortedModules() {
+		c.order[m] = i
+	}
+	for qualified, d := range p.Declarations {
+		if name, ok := c.names[d]; !ok || len(qualified) < len(name) {
+			c.names[d] = qualified
+		}
+	}
+	for _, m := range p.Modules {
+		for _, v := range m.Variables {
+			c.modules[v] = m
+		}
+		for _, f := range m.Functions {
+			c.modules[f] = m
+		}
+		for _, e := range m.Enums {
+			c.modules[e] = m
+		}
+		for _, i := range m.Interfaces {
+			c.modules[i] = m
+		}
+		for _, s := range m.Structs {
+			c.modules[s] = m
+			for _, v := range s.Variables {
+				c.modules[v] = m
+				c.owners[v] = s
+			}
+			for _, f := range s.Functions {
+				c.modules[f] = m
+				c.owners[f] = s
+			}
+		}
+		for _, class := range m.Classes {
+			c.modules[class] = m
+			for _, v := range class.Variables {
+				c.modules[v] = m
+				c.owners[v] = class
+			}
+			for _, f := range class.Functions {
+				c.modules[f] = m
+				c.owners[f] = class
+			}
+		}
+	}
+	return c
+}
+
+// Check checks all modules of program, imports which are not used are reported after all modules are checked
+func (c *Checker) Check() {
+	modules := c.Program.SortedModules()
+	for _, m := range modules {
+		c.inferVariables(m)
+	}
+	for _, m := range modules {
+		c.CheckModule(m)
+	}
+	for _, m := range modules {
+		c.Program.Module = m
+		for _, i := range m.Imports {
+			if !i.invalid && !c.imports[i] {
+				c.error(i.Position, fmt.Sprintf("%s imported and not used", i.Namespace))
+			}
+		}
+	}
+}
+
+// inferVariables infers types of member variables with non-constant values before functions refer to them
+func (c *Checker) inferVariables(m *Module) {
+	c.Program.Module = m
+	for _, class := range m.Classes {
+		for _, v := range class.Variables {
+			if !v.IsStatic() && v.Value != nil && !v.Value.IsConstant(c.Program) {
+				c.variableType(v)
+			}
+		}
+	}
+}
+
+func (c *Checker) CheckModule(m *Module) {
+	c.Program.Module = m
+	c.checkerState = checkerState{}
+	for _, v := range m.Variables {
+		c.typeName(v.Type)
+		c.variableType(v)
+	}
+	for _, f := range m.Functions {
+		c.signature(f)
+		c.CheckFunction(f)
+	}
+	for _, s := range m.Structs {
+		for _, v := range s.Variables {
+			c.typeName(v.Type)
+		}
+		for _, f := range s.Functions {
+			c.structure = s
+			c.signature(f)
+			c.CheckFunction(f)
+		}
+	}
+	c.checkerState = checkerState{}
+	for _, class := range m.Classes {
+		c.class = class
+		for _, parent := range class.Parents {
+			c.typeName(parent)
+		}
+		for _, v := range class.Variables {
+			c.typeName(v.Type)
+			if v.IsStatic() {
+				c.variableType(v)
+			}
+		}
+		for _, f := range class.Functions {
+			c.class = class
+			c.signature(f)
+			c.CheckFunction(f)
+		}
+	}
+	for _, i := range m.Interfaces {
+		for _, parent := range i.Parents {
+			c.typeName(parent)
+		}
+		for _, f := range i.Functions {
+			c.signature(f)
+		}
+	}
+}
+
+// signature checks types of parameters and return type of function
+func (c *Checker) signature(f *Function) {
+	if f.Parameters != nil {
+		var types []ir.Type
+		if f.IRFunction != nil {
+			types = f.ParameterTypes()
+		}
+		for i, param := range f.Parameters.Parameters {
+			c.typeName(param.Type)
+			if types != nil {
+				c.parameter(param, types[i])
+			}
+			if param.Reference && f.Generator {
+				// variable passed by reference could be released before generator is finished
+				c.error(param.Position, fmt.Sprintf("generator function %s cannot take %s by reference", f.Name.Name, param.Name))
+			}
+		}
+	}
+	c.typeName(f.ReturnType)
+}
+
+// parameter reports parameter passed by reference which is not struct
+func (c *Checker) parameter(param *Parameter, t ir.Type) {
+	if param.Reference && t != nil && !IsReference(c.Program, t) {
+		c.error(param.Position, fmt.Sprintf("%s cannot be passed by reference, only struct could be passed by reference", param.Name))
+	}
+}
+
+// CheckFunction checks body of function, parameters are declared in the same scope as body
+func (c *Checker) CheckFunction(f *Function) {
+	if f.Body == nil || f.IRFunction == nil {
+		return
+	}
+	constructor := f.ObjectName != "" && f.Name.Name == Constructor
+	c.checkerState = checkerState{
+		class:      f.Class,
+		structure:  f.Struct,
+		hasThis:    f.Class != nil && !constructor && !f.IsStatic() || f.Struct != nil,
+		returnType: f.IRFunction.Sig.RetType,
+		scope:      newScope(nil),
+		function:   f,
+	}
+	if constructor {
+		// instance is returned by constructor itself
+		c.returnType = ir.Void
+	}
+	if f.Generator {
+		// generator returns without value when it is finished
+		c.returnType = ir.Void
+		c.yieldType = GeneratorElement(f.IRFunction.Sig.RetType)
+	}
+	if f.Parameters != nil {
+		types := f.ParameterTypes()
+		for i, param := range f.Parameters.Parameters {
+			c.declare(param.Name, param.Position, valueType(c.Program, types[i]), param)
+		}
+	}
+	c.block(f.Body)
+}
+
+// variableType returns type of global or member variable, its initial value is checked when it is first used
+func (c *Checker) variableType(v *Variable) ir.Type {
+	if t, ok := c.variables[v]; ok {
+		return t
+	}
+	// initial value which refers to variable itself is reported by initialization
+	c.variables[v] = nil
+	c.initializing[v] = true
+
+	module := c.Program.Module
+	state := c.checkerState
+	if m := c.modules[v]; m != nil {
+		c.Program.Module = m
+	}
+	c.checkerState = checkerState{
+		returnType: ir.Void,
+		scope:      newScope(nil),
+	}
+	class, isMember := c.owners[v].(*Class)
+	if isMember {
+		// initial value of static variable could refer to other static members of class
+		c.class = class
+	}
+	if !isMember || v.IsStatic() {
+		c.variable = v
+	}
+
+	var t ir.Type
+	if v.Type != nil {
+		t = c.typeOf(v.Type)
+	}
+	if v.Value != nil {
+		errors := len(c.Program.Errors)
+		value := c.value(v.Value, t)
+		if v.Type == nil {
+			t = value
+			if t == nil && len(c.Program.Errors) == errors {
+				c.error(v.Name.Position, fmt.Sprintf("cannot infer type of %s", v.Name.Name))
+			}
+		} else {
+			c.assign(v.Value, value, t)
+		}
+		if v.Const && !v.Value.IsConstant(c.Program) {
+			c.error(v.Name.Position, "only constant expression is allowed to initialize const value")
+		}
+	}
+	if isMember && !v.IsStatic() && v.Type == nil && t != nil && !v.Value.IsConstant(c.Program) {
+		// member variable is generated with placeholder type before its value is checked
+		class.setVariableType(c.Program, v, t)
+	}
+
+	c.Program.Module = module
+	c.checkerState = state
+	c.variables[v] = t
+	delete(c.initializing, v)
+	return t
+}
+
+// initialization records global or static variable referred by initial value of variable being checked, initializers of module are ordered by them,
+// variable which refers to itself through initial values and variable initialized by module initialized later are reported
+func (c *Checker) initialization(position int, d *Variable) {
+	v := c.variable
+	if v == nil {
+		return
+	}
+	if c.initializing[d] {
+		if d == v {
+			c.error(position, fmt.Sprintf("initial value of %s refers to itself", d.Name.Name))
+		} else {
+			c.error(position, fmt.Sprintf("initialization cycle of %s", d.Name.Name))
+		}
+		return
+	}
+	if d.Value == nil || d.Value.IsConstant(c.Program) {
+		// initialized by constant
+		return
+	}
+	if c.order[c.modules[d]] > c.order[c.modules[v]] {
+		c.error(position, fmt.Sprintf("%s is used before it is initialized", d.Name.Name))
+		return
+	}
+	v.dependencies = append(v.dependencies, d)
+}
+
+func (c *Checker) error(position int, message string) {
+	c.Program.Error(position, message)
+}
+
+func (c *Checker) declare(name string, position int, t ir.Type, reference Node) {
+	if _, ok := c.scope.objects[name]; ok {
+		c.error(position, fmt.Sprintf("redeclared variable: %s", name))
+		return
+	}
+	c.scope.objects[name] = &object{
+		typ:       t,
+		reference: reference,
+	}
+}
+
+// typeOf resolves declared type, nil is returned if it is invalid
+func (c *Checker) typeOf(t Type) ir.Type {
+	errors := len(c.Program.Errors)
+	c.typeName(t)
+	typ := t.Type(c.Program)
+	if len(c.Program.Errors) > errors {
+		return nil
+	}
+	return typ
+}
+
+// find finds declaration like Program.FindSelector, import of selector is marked as used
+func (c *Checker) find(selector, member string) (string, Declaration) {
+	qualified, d := c.Program.FindSelector(selector, member)
+	if selector != "" && qualified != "" {
+		for _, i := range c.Program.Module.Imports {
+			if i.Alias == selector && !i.invalid {
+				c.imports[i] = true
+			}
+		}
+	}
+	return qualified, d
+}
+
+// typeName reports error if type refers to declaration which is not accessible
+func (c *Checker) typeName(t Type) {
+	switch t := t.(type) {
+	case *TypeName:
+		if _, d := c.find(t.Selector, t.Name); d != nil {
+			c.refer(t.Position, d)
+		}
+		if t.TypeArguments != nil {
+			for _, argument := range t.TypeArguments.Arguments {
+				c.typeName(argument)
+			}
+		}
+
+	case *TypeFunction:
+		for _, param := range t.Parameters {
+			c.typeName(param)
+		}
+		c.typeName(t.ReturnType)
+	}
+}
+
+// refer checks reference to declaration at position
+func (c *Checker) refer(position int, d Declaration) {
+	c.accessible(position, d)
+	c.deprecated(position, d)
+}
+
+// deprecated warns reference to declaration marked as deprecated, with message of attribute if it has one
+func (c *Checker) deprecated(position int, d Declaration) {
+	for _, a := range attributesOf(d) {
+		if a.Name != Deprecated {
+			continue
+		}
+		name := c.names[d]
+		if owner := c.owners[d]; owner != nil {
+			name = c.names[owner] + "." + d.Identifier()
+		}
+		if message := a.GetText(); message != "" {
+			c.Program.Warning(position, fmt.Sprintf("%s is deprecated: %s", name, message))
+		} else {
+			c.Program.Warning(position, fmt.Sprintf("%s is deprecated", name))
+		}
+		return
+	}
+}
+
+// accessible reports error if declaration is not public and it is accessed outside its namespace,
+// member of class which is not public is accessible only inside the class and its subclasses, member of struct only inside the struct
+func (c *Checker) accessible(position int, d Declaration) {
+	m := c.modules[d]
+	if m == nil || d.IsPublic() {
+		return
+	}
+	name := c.names[d]
+	if owner := c.owners[d]; owner != nil {
+		if c.inside(owner) {
+			return
+		}
+		name = c.names[owner] + "." + d.Identifier()
+	} else if m.Namespace == c.Program.Module.Namespace {
+		return
+	}
+	declared := m.File.Position(d.GetPosition())
+	c.error(position, fmt.Sprintf("%s is not public, it is declared at %s", name, declared.String()))
+}
+
+// inside reports whether members of class or struct which are not public are accessible by current function
+func (c *Checker) inside(owner Declaration) bool {
+	if c.structure != nil {
+		return c.structure == owner
+	}
+	class, ok := owner.(*Class)
+	return ok && c.class != nil && (c.class == class || c.class.IsSubclassOf(class))
+}
+
+// statements
+
+func (c *Checker) block(b *Block) {
+	for _, s := range b.Statements {
+		c.statement(s)
+	}
+}
+
+// nested checks statement in a new scope
+func (c *Checker) nested(s Statement) {
+	c.scope = newScope(c.scope)
+	c.statement(s)
+	c.scope = c.scope.parent
+}
+
+func (c *Checker) statement(s Statement) {
+	switch s := s.(type) {
+	case *Block:
+		c.scope = newScope(c.scope)
+		c.block(s)
+		c.scope = c.scope.parent
+
+	case *DeclarationStatement:
+		c.declaration(s)
+
+	case *ExpressionStatement:
+		c.expression(s.Expression, nil)
+
+	case *If:
+		c.scope = newScope(c.scope)
+		if s.Initialization != nil {
+			c.statement(s.Initialization)
+		}
+		c.condition(s.Condition)
+		c.nested(s.Body)
+		if s.Else != nil {
+			c.nested(s.Else)
+		}
+		c.scope = c.scope.parent
+
+	case *For:
+		c.scope = newScope(c.scope)
+		if s.Initialization != nil {
+			c.statement(s.Initialization)
+		}
+		if s.Condition != nil {
+			c.condition(s.Condition)
+		}
+		c.loops++
+		if s.Post != nil {
+			c.nested(s.Post)
+		}
+		c.nested(s.Body)
+		c.loops--
+		c.scope = c.scope.parent
+
+	case *Foreach:
+		c.foreach(s)
+
+	case *Yield:
+		c.yieldStatement(s)
+
+	case *Switch:
+		c.switchStatement(s)
+
+	case *Return:
+		c.returnStatement(s)
+
+	case *Break:
+		if c.loops == 0 && c.switches == 0 {
+			c.error(s.Position, "invalid break")
+		}
+
+	case *Continue:
+		if c.loops == 0 {
+			c.error(s.Position, "invalid continue")
+		}
+
+	case *Fallthrough:
+		if s != c.fallthroughStatement {
+			c.error(s.Position, "fallthrough statement out of place")
+		}
+
+	case *Throw:
+		if s.Expression != nil {
+			c.value(s.Expression, nil)
+		}
+
+	case *Try:
+		c.nested(s.Try)
+		if s.Catch != nil {
+			c.scope = newScope(c.scope)
+			if s.Operand != nil {
+				for _, param := range s.Operand.Parameters {
+					c.declare(param.Name, param.Position, c.typeOf(param.Type), param)
+				}
+			}
+			c.statement(s.Catch)
+			c.scope = c.scope.parent
+		}
+		if s.Finally != nil {
+			c.nested(s.Finally)
+		}
+	}
+}
+
+func (c *Checker) declaration(d *DeclarationStatement) {
+	var t ir.Type
+	if d.Type != nil {
+		t = c.typeOf(d.Type)
+	}
+	if d.Value != nil {
+		value := c.value(d.Value, t)
+		if d.Type == nil {
+			t = value
+		} else {
+			c.assign(d.Value, value, t)
+		}
+	} else if d.Type == nil {
+		c.error(d.Position, "missing type of declaration")
+	}
+	if t != nil && ir.IsVoid(t) {
+		c.error(d.Position, "invalid declaration")
+		t = nil
+	}
+	d.Name.Resolve(t, d)
+	c.declare(d.Name.Name, d.Position, t, d)
+}
+
+func (c *Checker) condition(e Expression) {
+	if t := c.value(e, nil); t != nil && !ir.IsBool(t) {
+		c.error(e.GetPosition(), fmt.Sprintf("condition must be bool, but found %s", MangleType(t)))
+	}
+}
+
+func (c *Checker) switchStatement(s *Switch) {
+	c.scope = newScope(c.scope)
+	if s.Initialization != nil {
+		c.statement(s.Initialization)
+	}
+	t := c.value(s.Operand, nil)
+	if t != nil && !ir.IsInt(t) && !IsString(t) {
+		c.error(s.Operand.GetPosition(), "switch operand must be integer, enum or string")
+		t = nil
+	}
+	c.switches++
+	outer := c.fallthroughStatement
+	cases := make(map[string]bool)
+	for i, cc := range s.Cases {
+		value := c.value(cc.Case, t)
+		if t != nil && IsString(t) {
+			if text, ok := caseText(cc.Case); !ok {
+				c.error(cc.Position, "expect constant string expression")
+			} else if cases[text] {
+				c.error(cc.Position, fmt.Sprintf("duplicate case %s in switch", strconv.Quote(text)))
+			} else {
+				cases[text] = true
+			}
+		} else if value != nil && !cc.Case.IsConstant(c.Program) {
+			c.error(cc.Position, "expect constant int expression")
+		} else {
+			c.assign(cc.Case, value, t)
+			if value, ok := cc.Case.GenerateConstIR(c.Program, t).(*ir.Int); ok && t != nil {
+				if text := value.X.String(); cases[text] {
+					c.error(cc.Position, fmt.Sprintf("duplicate case %s in switch", text))
+				} else {
+					cases[text] = true
+				}
+			}
+		}
+
+		c.fallthroughStatement = lastFallthrough(cc.Body)
+		if c.fallthroughStatement != nil && i == len(s.Cases)-1 && s.Default == nil {
+			c.error(c.fallthroughStatement.Position, "cannot fallthrough final case in switch")
+		}
+		c.nested(cc.Body)
+	}
+	c.fallthroughStatement = nil
+	if e := EnumOf(c.Program, t); e != nil && s.Default == nil && !e.HasAttribute(Flags) {
+		c.exhaustive(s, e)
+	}
+	if s.Default != nil {
+		if f := lastFallthrough(s.Default.Body); f != nil {
+			c.error(f.Position, "cannot fallthrough final case in switch")
+			c.fallthroughStatement = f
+		}
+		c.nested(s.Default.Body)
+	}
+	c.fallthroughStatement = outer
+	c.switches--
+	c.scope = c.scope.parent
+}
+
+// lastFallthrough returns fallthrough statement which is body of case or the last statement of body
+func lastFallthrough(body Statement) *Fallthrough {
+	if b, ok := body.(*Block); ok && len(b.Statements) > 0 {
+		body = b.Statements[len(b.Statements)-1]
+	}
+	f, _ := body.(*Fallthrough)
+	return f
+}
+
+// exhaustive warns switch on enum without default case if members are not handled, members with handled values are skipped
+func (c *Checker) exhaustive(s *Switch, e *Enum) {
+	handled := make(map[int64]bool)
+	for _, cc := range s.Cases {
+		if !cc.Case.IsConstant(c.Program) {
+			return
+		}
+		value, ok := cc.Case.GenerateConstIR(c.Program, e.IRType).(*ir.Int)
+		if !ok {
+			return
+		}
+		handled[value.X.Int64()] = true
+	}
+	var missing []string
+	for i, v := range e.Members {
+		if value := e.IRValues[i].X.Int64(); !handled[value] {
+			handled[value] = true
+			missing = append(missing, v.Name.Name)
+		}
+	}
+	if len(missing) > 0 {
+		c.Program.Warning(s.Position, fmt.Sprintf("switch on %s does not handle %s", MangleType(e.IRType), strings.Join(missing, ", ")))
+	}
+}
+
+// foreach checks iterator which must be generator, key and item are declared by type of iterator
+func (c *Checker) foreach(s *Foreach) {
+	c.scope = newScope(c.scope)
+	t := c.value(s.Iterator, nil)
+	element := GeneratorElement(t)
+	if t != nil && element == nil {
+		c.error(s.Iterator.GetPosition(), fmt.Sprintf("cannot iterate %s, iterator must be generator", MangleType(t)))
+	}
+	if s.Key != nil {
+		if key := c.foreachVariable(s.Key, ir.I32); key != nil && !ir.IsInt(key) {
+			c.error(s.Key.GetPosition(), fmt.Sprintf("key of foreach must be integer type, but found %s", MangleType(key)))
+		}
+	}
+	if item := c.foreachVariable(s.Item, element); item != nil && element != nil && MangleType(item) != MangleType(element) {
+		c.error(s.Item.GetPosition(), fmt.Sprintf("item of foreach is declared as %s, but iterator yields %s", MangleType(item), MangleType(element)))
+	}
+	c.loops++
+	c.nested(s.Body)
+	c.loops--
+	c.scope = c.scope.parent
+}
+
+// foreachVariable declares key or item of foreach, t is its type if type is not declared
+func (c *Checker) foreachVariable(s Statement, t ir.Type) ir.Type {
+	d, ok := s.(*DeclarationStatement)
+	if !ok || d.Value != nil {
+		c.error(s.GetPosition(), "key and item of foreach must be variable declarations without value")
+		return nil
+	}
+	if d.Type != nil {
+		t = c.typeOf(d.Type)
+	}
+	d.Name.Resolve(t, d)
+	c.declare(d.Name.Name, d.Position, t, d)
+	return t
+}
+
+func (c *Checker) yieldStatement(y *Yield) {
+	if c.yieldType == nil {
+		c.expression(y.Expression, nil)
+		switch {
+		case c.function == nil:
+			c.error(y.Position, "lambda cannot yield")
+
+		case c.function.ObjectName != "":
+			c.error(y.Position, fmt.Sprintf("member function %s cannot yield", c.function.Name.Name))
+
+		default:
+			c.error(y.Position, fmt.Sprintf("generator function %s must declare type of yielded values", c.function.Name.Name))
+		}
+		return
+	}
+	t := c.value(y.Expression, c.yieldType)
+	c.assign(y.Expression, t, c.yieldType)
+}
+
+func (c *Checker) returnStatement(r *Return) {
+	if r.Expression != nil && c.yieldType != nil {
+		c.expression(r.Expression, nil)
+		c.error(r.Position, "generator function cannot return value")
+		return
+	}
+	if r.Expression == nil {
+		if !ir.IsVoid(c.returnType) {
+			c.error(r.Position, "missing return value")
+		}
+		return
+	}
+	if ir.IsVoid(c.returnType) {
+		c.expression(r.Expression, nil)
+		c.error(r.Position, "return type mismatch with function define")
+		return
+	}
+	t := c.value(r.Expression, c.returnType)
+	c.assign(r.Expression, t, c.returnType)
+}
+
+// expressions
+
+// expression resolves type of expression, nil is returned if expression is invalid (error is reported) or refers to a type
+func (c *Checker) expression(e Expression, expected ir.Type) ir.Type {
+	var t ir.Type
+	var reference Node
+	switch e := e.(type) {
+	case *Literal:
+		t = c.literal(e, expected)
+
+	case *Identifier, *MemberAccess:
+		var functions []*Function
+		t, reference, functions = c.name(e)
+		if functions != nil {
+			t, reference = c.functionValue(e, functions, expected)
+		} else if m, ok := e.(*MemberAccess); ok && t != nil && IsString(m.Parent.ResolvedType()) {
+			c.error(m.Member.Position, fmt.Sprintf("member function %s of string must be invoked", m.Member.Name))
+			t = nil
+		} else if _, ok := reference.(*Enum); ok && t != nil {
+			c.error(e.GetPosition(), fmt.Sprintf("function %s of enum must be invoked", e.(*MemberAccess).Member.Name))
+			t, reference = nil, nil
+		}
+
+	case *This:
+		if c.structure != nil {
+			// this refers to instance of struct
+			t = c.structure.IRStruct
+			reference = c.structure
+		} else if c.hasThis {
+			t = CreateClassPointer(c.names[c.class])
+			reference = c.class
+		} else {
+			c.error(e.Position, "'this' undefined")
+		}
+
+	case *Base:
+		if c.hasThis && c.class != nil && c.class.Parent != nil {
+			t = CreateClassPointer(c.names[c.class.Parent])
+			reference = c.class.Parent
+		} else {
+			c.error(e.Position, "'base' undefined")
+		}
+
+	case *Parentheses:
+		t = c.expression(e.Expression, expected)
+		reference = e.Expression.Reference()
+
+	case *Invocation:
+		t, reference = c.invocation(e)
+
+	case *New:
+		t, reference = c.new(e)
+
+	case *Unary:
+		t = c.unary(e, expected)
+
+	case *Increment:
+		t = c.increment(e.Expression, "increment")
+
+	case *Decrement:
+		t = c.increment(e.Expression, "decrement")
+
+	case *Binary:
+		t = c.binary(e, expected)
+
+	case *Conversion:
+		from := c.value(e.Expression, nil)
+		t = c.typeOf(e.Typ)
+		if from != nil && t != nil {
+			if err := CheckConversion(c.Program, from, t); err != nil {
+				c.error(e.Position, err.Error())
+			}
+		}
+
+	case *Interpolation:
+		for _, part := range e.Parts {
+			if isText(part) {
+				c.value(part, CreateStringType())
+			} else if from := c.value(part, nil); from != nil && CheckConversion(c.Program, from, CreateStringType()) != nil {
+				c.error(part.GetPosition(), fmt.Sprintf("cannot interpolate %s in string", MangleType(from)))
+			}
+		}
+		t = CreateStringType()
+
+	case *Lambda:
+		t = c.lambda(e)
+
+	case *Subscripting:
+		//TO-DO operator overload
+		parent := c.value(e.Parent, nil)
+		index := c.value(e.Element, ir.I32)
+		if parent != nil && IsString(parent) {
+			// code point of string
+			if index != nil && !ir.IsInt(index) {
+				c.error(e.Element.GetPosition(), "index of string must be integer")
+			}
+			t = CreateCharType()
+		} else if parent != nil {
+			c.error(e.Position, fmt.Sprintf("%s does not support subscripting", MangleType(parent)))
+		}
+	}
+	e.Resolve(t, reference)
+	return t
+}
+
+// value resolves type of expression which is used as value
+func (c *Checker) value(e Expression, expected ir.Type) ir.Type {
+	return c.checkValue(e, c.expression(e, expected))
+}
+
+func (c *Checker) checkValue(e Expression, t ir.Type) ir.Type {
+	if t == nil {
+		switch d := e.Reference().(type) {
+		case *Class, *Enum, *Interface, *Struct:
+			c.error(e.GetPosition(), fmt.Sprintf("%s is not a value", d.(Declaration).Identifier()))
+		}
+		return nil
+	}
+	if ir.IsVoid(t) {
+		c.error(e.GetPosition(), "expression has no value")
+		return nil
+	}
+	return t
+}
+
+func (c *Checker) literal(l *Literal, expected ir.Type) ir.Type {
+	switch l.Typ {
+	case token.STRING, token.NULL:
+		if expected != nil && IsString(expected) {
+			return expected
+		}
+		return pointerType
+
+	case token.CHAR:
+		if expected != nil && ir.IsInt(expected) {
+			return expected
+		}
+		return CreateCharType()
+
+	case token.INT:
+		if expected != nil && ir.IsNumber(expected) {
+			return expected
+		}
+		return ir.I32
+
+	case token.FLOAT:
+		if expected != nil && ir.IsFloat(expected) {
+			return expected
+		}
+		return ir.Float32
+
+	case token.BOOL:
+		return ir.I1
+	}
+	return nil
+}
+
+// name resolves identifier or member access, candidates are returned instead of type if it refers to declared functions
+func (c *Checker) name(e Expression) (ir.Type, Node, []*Function) {
+	switch e := e.(type) {
+	case *Identifier:
+		return c.identifier(e)
+
+	case *MemberAccess:
+		t, reference, functions := c.memberAccess(e)
+		if functions == nil {
+			e.Member.Resolve(t, reference)
+		}
+		return t, reference, functions
+	}
+	return nil, nil, nil
+}
+
+func (c *Checker) identifier(i *Identifier) (ir.Type, Node, []*Function) {
+	if o := c.scope.find(i.Name); o != nil {
+		return o.typ, o.reference, nil
+	}
+	if c.structure != nil {
+		if t, reference, functions, ok := c.structMember(c.structure, i.Name); ok {
+			return t, reference, functions
+		}
+	} else if c.hasThis {
+		if t, reference, functions, ok := c.member(c.class, i.Name); ok {
+			return t, reference, functions
+		}
+	}
+	if c.class != nil {
+		if d := c.class.StaticMember(i.Name); d != nil {
+			return c.declared(i.Name, i.Position, d)
+		}
+	}
+	_, d := c.find("", i.Name)
+	return c.declared(i.Name, i.Position, d)
+}
+
+func (c *Checker) memberAccess(m *MemberAccess) (ir.Type, Node, []*Function) {
+	if ident, ok := m.Parent.(*Identifier); ok && !c.isValue(ident.Name) {
+		// member of imported namespace
+		if qualified, d := c.find(ident.Name, m.Member.Name); qualified != "" {
+			return c.declared(m.Member.Name, m.Member.Position, d)
+		}
+	}
+	var expected ir.Type
+	if l, ok := m.Parent.(*Literal); ok && l.Typ == token.STRING {
+		// literal has member functions of string
+		expected = CreateStringType()
+	}
+	parent := c.expression(m.Parent, expected)
+	if parent == nil {
+		switch d := m.Parent.Reference().(type) {
+		case *Enum:
+			return c.enumMember(d, m)
+
+		case *Class:
+			if static := d.StaticMember(m.Member.Name); static != nil {
+				return c.declared(m.Member.Name, m.Member.Position, static)
+			}
+			c.error(m.Member.Position, fmt.Sprintf("%s undefined", m.Member.Name))
+
+		case *Interface, *Struct:
+			c.error(m.Member.Position, fmt.Sprintf("%s undefined", m.Member.Name))
+		}
+		return nil, nil, nil
+	}
+	if IsString(parent) {
+		if t := StringMemberType(m.Member.Name); t != nil {
+			return t, nil, nil
+		}
+		c.error(m.Member.Position, fmt.Sprintf("%s undefined", m.Member.Name))
+		return nil, nil, nil
+	}
+	switch d := c.Program.FindQualified(GetTypeUserData(parent)).(type) {
+	case *Class:
+		if t, reference, functions, ok := c.member(d, m.Member.Name); ok {
+			if v, ok := reference.(*Variable); ok {
+				c.refer(m.Member.Position, v)
+			}
+			return t, reference, functions
+		}
+		if d.StaticMember(m.Member.Name) != nil {
+			c.error(m.Member.Position, fmt.Sprintf("static member %s must be accessed through class %s", m.Member.Name, d.Name.Name))
+			return nil, nil, nil
+		}
+
+	case *Struct:
+		if t, reference, functions, ok := c.structMember(d, m.Member.Name); ok {
+			if v, ok := reference.(*Variable); ok {
+				c.refer(m.Member.Position, v)
+			}
+			return t, reference, functions
+		}
+
+	case *Enum:
+		// helper function is invoked with value of enum
+		if t := EnumMemberType(d, m.Member.Name, true); t != nil {
+			return t, d, nil
+		}
+	}
+	c.error(m.Member.Position, fmt.Sprintf("%s undefined", m.Member.Name))
+	return nil, nil, nil
+}
+
+// declared resolves declaration of program found by name
+func (c *Checker) declared(name string, position int, d Declaration) (ir.Type, Node, []*Function) {
+	switch d := d.(type) {
+	case *Variable:
+		c.refer(position, d)
+		c.initialization(position, d)
+		return c.variableType(d), d, nil
+
+	case *Function:
+		if d.IRFunction == nil {
+			// compiler function
+			return nil, d, nil
+		}
+		if len(d.Overloads) > 0 {
+			return nil, d, d.Overloads
+		}
+		return nil, d, []*Function{d}
+
+	case *Class, *Enum, *Interface, *Struct:
+		c.refer(position, d)
+		return nil, d, nil
+	}
+	c.error(position, fmt.Sprintf("undefined %s", name))
+	return nil, nil, nil
+}
+
+// member resolves member of class, ok is false if class has no such member
+func (c *Checker) member(class *Class, name string) (t ir.Type, reference Node, functions []*Function, ok bool) {
+	if _, ok := class.VariableIndexes[name]; ok {
+		for current := class; current != nil && reference == nil; current = current.Parent {
+			for _, v := range current.Variables {
+				if v.Name.Name == name {
+					reference = v
+					if v.Type == nil && v.Value != nil && !v.Value.IsConstant(c.Program) {
+						// type of variable is inferred from its value
+						c.variableType(v)
+					}
+				}
+			}
+		}
+		return class.MemberType(name), reference, nil, true
+	}
+	if functions := class.MemberFunctions[name]; len(functions) > 0 {
+		return nil, functions[0], functions, true
+	}
+	return nil, nil, nil, false
+}
+
+// structMember resolves member of struct, ok is false if struct has no such member
+func (c *Checker) structMember(s *Struct, name string) (t ir.Type, reference Node, functions []*Function, ok bool) {
+	if index, ok := s.VariableIndexes[name]; ok {
+		return s.IRStruct.Fields[index], s.Variables[index], nil, true
+	}
+	if functions := s.MemberFunctions[name]; len(functions) > 0 {
+		return nil, functions[0], functions, true
+	}
+	return nil, nil, nil, false
+}
+
+// enumMember resolves member of enum type, helper function of enum is resolved with enum as reference
+func (c *Checker) enumMember(e *Enum, m *MemberAccess) (ir.Type, Node, []*Function) {
+	if index, ok := e.VariableIndexes[m.Member.Name]; ok {
+		return e.IRType, e.Members[index], nil
+	}
+	if t := EnumMemberType(e, m.Member.Name, false); t != nil {
+		return t, e, nil
+	}
+	c.error(m.Member.Position, fmt.Sprintf("%s undefined", m.Member.Name))
+	return nil, nil, nil
+}
+
+// isValue reports whether name refers to local variable, member or global variable, rather than namespace or type
+func (c *Checker) isValue(name string) bool {
+	if c.scope.find(name) != nil {
+		return true
+	}
+	if c.hasMember(name) {
+		return true
+	}
+	_, d := c.Program.FindSelector("", name)
+	_, ok := d.(*Variable)
+	return ok
+}
+
+// hasMember reports whether name is member of class or struct accessed by "this"
+func (c *Checker) hasMember(name string) bool {
+	if c.structure != nil {
+		return c.structure.HasMember(name)
+	}
+	return c.hasThis && c.class.HasMember(name)
+}
+
+// functionValue selects the function used as function value, overload is selected by expected function type
+func (c *Checker) functionValue(e Expression, functions []*Function, expected ir.Type) (ir.Type, Node) {
+	if len(functions) == 1 {
+		return c.resolveFunction(e, functions[0]), functions[0]
+	}
+	if IsClosure(expected) {
+		for _, f := range functions {
+			if MangleType(functionType(f)) == MangleType(expected) {
+				return c.resolveFunction(e, f), f
+			}
+		}
+	}
+	c.error(e.GetPosition(), fmt.Sprintf("ambiguous reference to overloaded function %s", functions[0].Name.Name))
+	return nil, functions[0]
+}
+
+// resolveFunction returns type of selected function, member of member access is also resolved
+func (c *Checker) resolveFunction(e Expression, f *Function) ir.Type {
+	t := functionType(f)
+	if m, ok := e.(*MemberAccess); ok {
+		if _, ok := m.Parent.(*Base); ok && f.IsAbstract() {
+			c.error(m.Member.Position, fmt.Sprintf("abstract function %s of class %s cannot be invoked by base", f.Name.Name, f.Class.Name.Name))
+		}
+		m.Member.Resolve(t, f)
+		c.refer(m.Member.Position, f)
+	} else {
+		c.refer(e.GetPosition(), f)
+	}
+	return t
+}
+
+// functionType returns type of function value, implicit "this" and closure environment are excluded
+func functionType(f *Function) ir.Type {
+	sig := ir.NewFuncType(f.IRFunction.Sig.RetType, f.ParameterTypes()...)
+	sig.Variadic = f.IRFunction.Sig.Variadic
+	return ir.NewPointerType(sig)
+}
+
+func (c *Checker) invocation(i *Invocation) (ir.Type, Node) {
+	if qualified := GetCompilerFunctionName(&Context{Program: c.Program}, i.Function); IsCompilerFunction(qualified) {
+		return c.compilerFunction(i, qualified), nil
+	}
+	var t ir.Type
+	var reference Node
+	switch function := i.Function.(type) {
+	case *Identifier, *MemberAccess:
+		var functions []*Function
+		t, reference, functions = c.name(function)
+		if functions != nil {
+			f := c.overload(i, functions)
+			if f == nil {
+				i.Function.Resolve(nil, functions[0])
+				return nil, nil
+			}
+			i.Function.Resolve(c.resolveFunction(i.Function, f), f)
+			return f.IRFunction.Sig.RetType, f
+		}
+		i.Function.Resolve(t, reference)
+		t = c.checkValue(i.Function, t)
+
+	default:
+		t = c.value(i.Function, nil)
+		reference = i.Function.Reference()
+	}
+	if t == nil {
+		c.arguments(i.Position, i.Arguments, nil, true)
+		return nil, nil
+	}
+	if !IsClosure(t) {
+		c.error(i.Position, "invalid function call")
+		return nil, nil
+	}
+	sig := t.(*ir.PointerType).ElemType.(*ir.FuncType)
+	c.arguments(i.Position, i.Arguments, sig.Params, sig.Variadic)
+	return sig.RetType, reference
+}
+
+// overload selects the function invoked by its arguments, and checks the arguments
+// compilerFunction resolves arguments of compiler function, then they are validated by its registered type
+func (c *Checker) compilerFunction(i *Invocation, qualified string) ir.Type {
+	if m, ok := i.Function.(*MemberAccess); ok {
+		if selector, ok := m.Parent.(*Identifier); ok {
+			c.find(selector.Name, m.Member.Name)
+		}
+	}
+	resolved := true
+	if i.Arguments != nil {
+		for _, arg := range i.Arguments.Arguments {
+			if c.value(arg, nil) == nil {
+				resolved = false
+			}
+		}
+	}
+	typ, ok := compilerFunctionTypes[qualified]
+	if !ok {
+		return ir.Void
+	}
+	if !resolved {
+		return nil
+	}
+	t, err := typ(c.Program, i)
+	if err != nil {
+		c.error(i.Position, err.Error())
+		return nil
+	}
+	return t
+}
+
+func (c *Checker) overload(i *Invocation, functions []*Function) *Function {
+	if len(functions) == 1 {
+		f := functions[0]
+		c.arguments(i.Position, i.Arguments, f.ParameterTypes(), f.IRFunction.Sig.Variadic)
+		return f
+	}
+	var args []Expression
+	if i.Arguments != nil {
+		args = i.Arguments.Arguments
+	}
+	var types []ir.Type
+	for _, arg := range args {
+		types = append(types, c.value(arg, nil))
+	}
+	f, err := resolveOverload(c.Program, functions[0].Name.Name, functions, types)
+	if err != nil {
+		c.error(i.Position, err.Error())
+		return nil
+	}
+	params := f.ParameterTypes()
+	for index, arg := range args {
+		if arg.IsConstant(c.Program) {
+			// type of constant is decided by parameter
+			types[index] = c.expression(arg, params[index])
+		}
+		c.argument(arg, types[index], params[index])
+	}
+	return f
+}
+
+func (c *Checker) arguments(position int, args *Arguments, params []ir.Type, variadic bool) {
+	var list []Expression
+	if args != nil {
+		position = args.Position
+		list = args.Arguments
+	}
+	if len(list) < len(params) {
+		c.error(position, "too few arguments")
+	} else if len(list) > len(params) && !variadic {
+		c.error(position, "too many arguments")
+	}
+	for index, arg := range list {
+		var expected ir.Type
+		if index < len(params) {
+			expected = params[index]
+		}
+		t := c.value(arg, valueType(c.Program, expected))
+		c.argument(arg, t, expected)
+	}
+}
+
+// argument checks argument passed to parameter of type param, argument passed by reference must be variable
+func (c *Checker) argument(arg Expression, t ir.Type, param ir.Type) {
+	if param == nil || !IsReference(c.Program, param) {
+		c.assign(arg, t, param)
+		return
+	}
+	c.assign(arg, t, valueType(c.Program, param))
+	switch e := arg.(type) {
+	case *Parentheses:
+		c.argument(e.Expression, t, param)
+		return
+
+	case *This:
+		return
+
+	case *Identifier, *MemberAccess:
+		switch d := arg.Reference().(type) {
+		case *Variable:
+			if d.Const {
+				c.error(arg.GetPosition(), fmt.Sprintf("cannot pass constant %s by reference", d.Name.Name))
+			}
+			return
+
+		case *Parameter, *DeclarationStatement:
+			return
+		}
+	}
+	if t != nil {
+		c.error(arg.GetPosition(), "only variable could be passed by reference")
+	}
+}
+
+func (c *Checker) new(n *New) (ir.Type, Node) {
+	qualified, d := c.find(n.Typ.Selector, n.Typ.Name)
+	if s, ok := d.(*Struct); ok {
+		// struct has no constructor
+		c.refer(n.Typ.Position, s)
+		c.arguments(n.Position, n.Arguments, nil, false)
+		initialized := make(map[string]bool)
+		for _, i := range n.Initializers {
+			c.initializer(s, i, initialized)
+		}
+		return s.IRStruct, s
+	}
+	class, ok := d.(*Class)
+	if !ok {
+		c.error(n.Position, "invalid type for new operator")
+		c.arguments(n.Position, n.Arguments, nil, true)
+		return nil, nil
+	}
+	c.refer(n.Typ.Position, class)
+	if class.IsAbstract() {
+		c.error(n.Typ.Position, fmt.Sprintf("cannot instantiate abstract class %s", class.Name.Name))
+	}
+	constructor := class.Functions[0]
+	c.refer(n.Position, constructor)
+	c.arguments(n.Position, n.Arguments, constructor.ParameterTypes(), false)
+	initialized := make(map[string]bool)
+	for _, i := range n.Initializers {
+		c.initializer(class, i, initialized)
+	}
+	return CreateClassPointer(qualified), class
+}
+
+// initializer checks value assigned to variable of class or its parents, or variable of struct, by initializer of new
+func (c *Checker) initializer(owner Declaration, i *Initializer, initialized map[string]bool) {
+	var t ir.Type
+	var reference Node
+	kind := "class"
+	switch d := owner.(type) {
+	case *Class:
+		t, reference, _, _ = c.member(d, i.Name.Name)
+
+	case *Struct:
+		t, reference, _, _ = c.structMember(d, i.Name.Name)
+		kind = "struct"
+	}
+	v, ok := reference.(*Variable)
+	if !ok {
+		c.error(i.Name.Position, fmt.Sprintf("%s is not variable of %s %s", i.Name.Name, kind, owner.Identifier()))
+		c.value(i.Value, nil)
+		return
+	}
+	if initialized[i.Name.Name] {
+		c.error(i.Name.Position, fmt.Sprintf("%s initialized more than once", i.Name.Name))
+	}
+	initialized[i.Name.Name] = true
+	c.refer(i.Name.Position, v)
+	if v.Const {
+		c.error(i.Name.Position, fmt.Sprintf("cannot assign to constant %s", i.Name.Name))
+	}
+	i.Name.Resolve(t, v)
+	value := c.value(i.Value, t)
+	c.assign(i.Value, value, t)
+}
+
+func (c *Checker) unary(u *Unary, expected ir.Type) ir.Type {
+	if u.Operator == token.Not {
+		expected = nil
+	}
+	t := c.value(u.Expression, expected)
+	if t == nil {
+		return nil
+	}
+	switch u.Operator {
+	case token.Plus, token.Minus:
+		if ir.IsNumber(t) {
+			return t
+		}
+
+	case token.Not:
+		if ir.IsBool(t) {
+			return ir.I1
+		}
+
+	case token.Complement:
+		if e := EnumOf(c.Program, t); e != nil && !e.HasAttribute(Flags) {
+			c.error(u.Position, fmt.Sprintf("enum %s is not @flags", MangleType(t)))
+			return nil
+		}
+		if ir.IsInt(t) {
+			return t
+		}
+	}
+	c.error(u.Position, "invalid type for unary expression")
+	return nil
+}
+
+func (c *Checker) increment(e Expression, operation string) ir.Type {
+	t := c.value(e, nil)
+	if t == nil {
+		return nil
+	}
+	if !ir.IsNumber(t) {
+		c.error(e.GetPosition(), fmt.Sprintf("invalid type for %s expression", operation))
+		return nil
+	}
+	c.target(e)
+	return t
+}
+
+// target reports error if expression cannot be assigned
+func (c *Checker) target(e Expression) {
+	switch e := e.(type) {
+	case *Parentheses:
+		c.target(e.Expression)
+		return
+
+	case *Identifier, *MemberAccess:
+		if m, ok := e.(*MemberAccess); ok {
+			if _, ok := m.Parent.Reference().(*Enum); ok {
+				c.error(e.GetPosition(), fmt.Sprintf("cannot assign to enum member %s", m.Member.Name))
+				return
+			}
+		}
+		switch d := e.Reference().(type) {
+		case *Variable:
+			if d.Const {
+				c.error(e.GetPosition(), fmt.Sprintf("cannot assign to constant %s", d.Name.Name))
+			}
+			return
+
+		case *Parameter, *DeclarationStatement:
+			return
+		}
+	}
+	if e.ResolvedType() != nil {
+		c.error(e.GetPosition(), "cannot assign to expression")
+	}
+}
+
+func (c *Checker) binary(b *Binary, expected ir.Type) ir.Type {
+	switch b.Operator {
+	case token.Assign:
+		t1 := c.value(b.Left, nil)
+		c.target(b.Left)
+		t2 := c.value(b.Right, t1)
+		c.assign(b.Right, t2, t1)
+		return t1
+
+	case token.MulAssign, token.DivAssign, token.RemAssign, token.PlusAssign, token.MinusAssign,
+		token.LeftShiftAssign, token.RightShiftAssign, token.AndAssign, token.OrAssign, token.XorAssign:
+		t1 := c.value(b.Left, nil)
+		c.target(b.Left)
+		t2 := c.value(b.Right, t1)
+		if t1 == nil || t2 == nil {
+			return t1
+		}
+		if b.Operator == token.PlusAssign && IsString(t1) {
+			c.assign(b.Right, t2, t1)
+			return t1
+		}
+		if b.Operator == token.AndAssign || b.Operator == token.OrAssign || b.Operator == token.XorAssign {
+			if _, ok := c.flags(b.Position, t1, t2); ok {
+				return t1
+			}
+		}
+		integer := b.Operator != token.MulAssign && b.Operator != token.DivAssign && b.Operator != token.PlusAssign && b.Operator != token.MinusAssign
+		if !ir.IsNumber(t1) || !ir.IsNumber(t2) || integer && (!ir.IsInt(t1) || !ir.IsInt(t2)) {
+			c.error(b.Position, "invalid type for binary expression")
+		} else {
+			c.assign(b.Right, t2, t1)
+		}
+		return t1
+	}
+
+	t1, t2 := c.operands(b, expected)
+	if t1 == nil || t2 == nil {
+		return nil
+	}
+	if b.Operator == token.BitAnd || b.Operator == token.BitOr || b.Operator == token.BitXor {
+		if t, ok := c.flags(b.Position, t1, t2); ok {
+			return t
+		}
+	}
+	switch b.Operator {
+	case token.Or, token.And:
+		if ir.IsBool(t1) && ir.IsBool(t2) {
+			return ir.I1
+		}
+
+	case token.Plus, token.Equal, token.NotEqual, token.Less, token.LessEqual, token.Greater, token.GreaterEqual:
+		if IsString(t1) || IsString(t2) {
+			if !IsString(t1) || !IsString(t2) {
+				break
+			}
+			if b.Operator == token.Plus {
+				return t1
+			}
+			return ir.I1
+		}
+		fallthrough
+
+	case token.Minus, token.Mul, token.Div, token.Rem,
+		token.BitAnd, token.BitOr, token.BitXor, token.LeftShift, token.RightShift:
+		if ir.IsNumber(t1) && ir.IsNumber(t2) {
+			t, err := PromoteNumberType(t1, t2)
+			if err != nil {
+				c.error(b.Position, err.Error())
+				return nil
+			}
+			switch b.Operator {
+			case token.Equal, token.NotEqual, token.Less, token.LessEqual, token.Greater, token.GreaterEqual:
+				return ir.I1
+
+			case token.Rem, token.BitAnd, token.BitOr, token.BitXor, token.LeftShift, token.RightShift:
+				if ir.IsInt(t) {
+					return t
+				}
+
+			default:
+				return t
+			}
+		} else if b.Operator == token.Equal || b.Operator == token.NotEqual {
+			if ir.IsPointer(t1) && ir.IsPointer(t2) && (c.assignable(t1, t2) == nil || c.assignable(t2, t1) == nil) {
+				return ir.I1
+			}
+		}
+	}
+	c.error(b.Position, "invalid type for binary expression")
+	return nil
+}
+
+// flags checks operands of bitwise operator if either of them is enum, values of the same @flags enum are combined as the enum
+// ok is false if neither of operands is enum
+func (c *Checker) flags(position int, t1 ir.Type, t2 ir.Type) (t ir.Type, ok bool) {
+	e1, e2 := EnumOf(c.Program, t1), EnumOf(c.Program, t2)
+	switch {
+	case e1 == nil && e2 == nil:
+		return nil, false
+
+	case e1 != e2:
+		c.error(position, fmt.Sprintf("cannot combine %s and %s", MangleType(t1), MangleType(t2)))
+
+	case !e1.HasAttribute(Flags):
+		c.error(position, fmt.Sprintf("enum %s is not @flags", MangleType(t1)))
+
+	default:
+		return t1, true
+	}
+	return nil, true
+}
+
+// operands resolves both sides of binary expression, constant side is typed by the other side like it is generated
+func (c *Checker) operands(b *Binary, expected ir.Type) (ir.Type, ir.Type) {
+	if b.Operator.IsComparison() {
+		// result of comparison is bool, operands are not affected by it
+		expected = nil
+	}
+	c1 := b.Left.IsConstant(c.Program)
+	c2 := b.Right.IsConstant(c.Program)
+	var t1, t2 ir.Type
+	if c1 && !c2 {
+		t2 = c.value(b.Right, expected)
+		if expected == nil {
+			t1 = c.value(b.Left, t2)
+		} else {
+			t1 = c.value(b.Left, expected)
+		}
+	} else if c2 && !c1 {
+		t1 = c.value(b.Left, expected)
+		if expected == nil {
+			t2 = c.value(b.Right, t1)
+		} else {
+			t2 = c.value(b.Right, expected)
+		}
+	} else {
+		t1 = c.value(b.Left, expected)
+		t2 = c.value(b.Right, expected)
+	}
+	return t1, t2
+}
+
+func (c *Checker) lambda(l *Lambda) ir.Type {
+	names := make(map[string]bool)
+	for _, capture := range l.Captures {
+		if names[capture.Name] {
+			c.error(capture.Position, fmt.Sprintf("%s captured more than once", capture.Name))
+		} else if c.scope.find(capture.Name) == nil && !c.hasMember(capture.Name) {
+			c.error(capture.Position, fmt.Sprintf("undefined %s", capture.Name))
+		}
+		if capture.Reference && c.yieldType != nil {
+			// variables of generator are moved when generator is suspended
+			c.error(capture.Position, fmt.Sprintf("cannot capture %s by reference in generator function", capture.Name))
+		} else if capture.Reference {
+			// local variable captured by reference is stored in box, so closure could outlive function
+			if o := c.scope.find(capture.Name); o != nil {
+				if d, ok := o.reference.(*DeclarationStatement); ok {
+					d.Boxed = true
+				} else {
+					c.error(capture.Position, fmt.Sprintf("cannot capture %s by reference", capture.Name))
+				}
+			} else if c.hasMember(capture.Name) {
+				c.error(capture.Position, fmt.Sprintf("cannot capture %s by reference", capture.Name))
+			}
+		}
+		names[capture.Name] = true
+	}
+
+	valid := true
+	var types []ir.Type
+	if l.Parameters != nil {
+		for _, param := range l.Parameters.Parameters {
+			t := c.typeOf(param.Type)
+			if t != nil && param.Reference && StructOf(c.Program, t) != nil {
+				t = ir.NewPointerType(t)
+			}
+			c.parameter(param, t)
+			valid = valid && t != nil
+			types = append(types, t)
+		}
+	}
+	var ret ir.Type = ir.Void
+	if l.ReturnType != nil {
+		ret = c.typeOf(l.ReturnType)
+		valid = valid && ret != nil
+	}
+
+	state := c.checkerState
+	c.scope = newScope(c.scope)
+	c.returnType = ret
+	c.loops = 0
+	c.switches = 0
+	c.function = nil
+	c.yieldType = nil
+	c.variable = nil
+	if l.Parameters != nil {
+		for i, param := range l.Parameters.Parameters {
+			c.declare(param.Name, param.Position, valueType(c.Program, types[i]), param)
+		}
+	}
+	if ret != nil {
+		c.block(l.Body)
+	}
+	c.checkerState = state
+
+	if !valid {
+		return nil
+	}
+	return ir.NewPointerType(ir.NewFuncType(ret, types...))
+}
+
+// assign reports error if value of expression cannot be converted to type "to" implicitly
+func (c *Checker) assign(e Expression, from ir.Type, to ir.Type) {
+	if from == nil || to == nil {
+		return
+	}
+	if err := c.assignable(from, to); err != nil {
+		c.error(e.GetPosition(), err.Error())
+	}
+}
+
+// assignable returns error if type "from" cannot be converted to type "to" implicitly
+// number is converted to wider type, class is converted to its parent classes and interfaces, raw pointer is converted to any class
+// string is not converted from or to raw pointer implicitly, null is typed as string by context
+func (c *Checker) assignable(from ir.Type, to ir.Type) error {
+	if MangleType(from) == MangleType(to) {
+		return nil
+	}
+	if ir.IsNumber(from) && ir.IsNumber(to) {
+		promoted, err := PromoteNumberType(to, from)
+		if err != nil {
+			return err
+		}
+		if !promoted.Equal(to) {
+			return fmt.Errorf("cannot implicit convert %s to %s [down grade]", MangleType(from), MangleType(to))
+		}
+		return nil
+	}
+	if ir.IsPointer(from) && ir.IsPointer(to) && !IsClosure(from) && !IsClosure(to) && IsString(from) == IsString(to) {
+		if GetTypeUserData(from) == "" || GetTypeUserData(to) == "" {
+			return nil
+		}
+		if class, ok := c.Program.FindQualified(GetTypeUserData(from)).(*Class); ok {
+			switch d := c.Program.FindQualified(GetTypeUserData(to)).(type) {
+			case *Class:
+				if class.IsSubclassOf(d) {
+					return nil
+				}
+
+			case *Interface:
+				if class.Implements(d) {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("cannot implicit convert %s to %s", MangleType(from), MangleType(to))
+}
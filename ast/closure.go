@@ -0,0 +1,216 @@
+package ast
+
+import (
+	"github.com/panda-foundation/go-compiler/ir"
+)
+
+// IsClosure reports whether t is type of function value
+func IsClosure(t ir.Type) bool {
+	if p, ok := t.(*ir.PointerType); ok {
+		return ir.IsFunc(p.ElemType)
+	}
+	return false
+}
+
+// FunctionValue returns closure record of function which has no environment, so it is invoked the same as lambda
+func (p *Program) FunctionValue(f *ir.Func) ir.Constant {
+	if v, ok := p.Closures[f.Name()]; ok {
+		return v
+	}
+	record := p.IRModule.NewGlobalDef(f.Name()+".closure", ir.NewStruct(closureType, ir.NewExprBitCast(f, pointerType), ir.NewNull(pointerType)))
+	record.Immutable = true
+	v := ir.NewExprBitCast(record, ir.NewPointerType(f.Sig))
+	p.Closures[f.Name()] = v
+	return v
+}
+
+// CallClosure invokes function value, its environment is passed as first argument if it is not null
+func CallClosure(c *Context, closure ir.Value, args *Arguments) ir.Value {
+	call := ir.NewCall(closure)
+	args.GenerateIR(c, call)
+	return callClosure(c, closure, call.Args)
+}
+
+// callClosure invokes function value with arguments which are already generated
+func callClosure(c *Context, closure ir.Value, args []ir.Value) ir.Value {
+	sig := closure.Type().(*ir.PointerType).ElemType.(*ir.FuncType)
+	record := ir.NewBitCast(closure, ir.NewPointerType(closureType))
+	c.Block.AddInstruction(record)
+	functionPointer := ir.NewGetElementPtr(closureType, record, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 0))
+	c.Block.AddInstruction(functionPointer)
+	function := ir.NewLoad(pointerType, functionPointer)
+	c.Block.AddInstruction(function)
+	envPointer := ir.NewGetElementPtr(closureType, record, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 1))
+	c.Block.AddInstruction(envPointer)
+	env := ir.NewLoad(pointerType, envPointer)
+	c.Block.AddInstruction(env)
+	isNull := ir.NewICmp(ir.IPredEQ, env, ir.NewNull(pointerType))
+	c.Block.AddInstruction(isNull)
+
+	plainBlock := c.Function.IRFunction.NewBlock("")
+	boundBlock := c.Function.IRFunction.NewBlock("")
+	nextBlock := c.Function.IRFunction.NewBlock("")
+	c.Block.AddInstruction(ir.NewCondBr(isNull, plainBlock, boundBlock))
+
+	// function without environment
+	plain := ir.NewBitCast(function, ir.NewPointerType(sig))
+	plainBlock.AddInstruction(plain)
+	plainCall := ir.NewCall(plain, args...)
+	plainBlock.AddInstruction(plainCall)
+	plainBlock.AddInstruction(ir.NewBr(nextBlock))
+
+	// lambda, closure record is the head of its environment
+	boundSig := ir.NewFuncType(sig.RetType, append([]ir.Type{pointerType}, sig.Params...)...)
+	boundSig.Variadic = sig.Variadic
+	bound := ir.NewBitCast(function, ir.NewPointerType(boundSig))
+	boundBlock.AddInstruction(bound)
+	self := ir.NewBitCast(closure, pointerType)
+	boundBlock.AddInstruction(self)
+	boundCall := ir.NewCall(bound, append([]ir.Value{self}, args...)...)
+	boundBlock.AddInstruction(boundCall)
+	boundBlock.AddInstruction(ir.NewBr(nextBlock))
+
+	c.Block = nextBlock
+	if ir.IsVoid(sig.RetType) {
+		return boundCall
+	}
+	phi := ir.NewPhi(ir.NewIncoming(plainCall, plainBlock), ir.NewIncoming(boundCall, boundBlock))
+	c.Block.AddInstruction(phi)
+	return phi
+}
+
+// CreateEnvironment allocates environment of function, environment starts with closure record and it is filled with zero
+// environment is managed by counter which is not shared yet, destructor is called with environment when it is released
+func CreateEnvironment(c *Context, env *ir.StructType, function *ir.Func, destructor *ir.Func) (address ir.Value, counter ir.Value) {
+	ptr := ir.NewGetElementPtr(env, ir.NewNull(ir.NewPointerType(env)), ir.NewInt(ir.I32, 1))
+	c.Block.AddInstruction(ptr)
+	size := ir.NewPtrToInt(ptr, ir.I32)
+	c.Block.AddInstruction(size)
+	call := ir.NewCall(malloc, size)
+	c.Block.AddInstruction(call)
+	c.Block.AddInstruction(ir.NewCall(memset, call, ir.NewInt(ir.I32, 0), size))
+	address = call
+
+	record := ir.NewBitCast(address, ir.NewPointerType(closureType))
+	c.Block.AddInstruction(record)
+	functionPointer := ir.NewGetElementPtr(closureType, record, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 0))
+	c.Block.AddInstruction(functionPointer)
+	c.Block.AddInstruction(ir.NewStore(ir.NewExprBitCast(function, pointerType), functionPointer))
+
+	counterClass := c.Program.FindQualified(Counter).(*Class)
+	counter = counterClass.CreateInstance(c, Counter, nil)
+	object, _ := counterClass.GetMember(c, counter, "object", false)
+	c.Block.AddInstruction(ir.NewStore(address, object))
+	destroy, _ := counterClass.GetMember(c, counter, "destructor", false)
+	c.Block.AddInstruction(ir.NewStore(c.Program.FunctionValue(destructor), destroy))
+	envPointer := ir.NewGetElementPtr(closureType, record, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 1))
+	c.Block.AddInstruction(envPointer)
+	c.Block.AddInstruction(ir.NewStore(counter, envPointer))
+	return address, counter
+}
+
+// RetainClosure increases shared count of closure environment
+func RetainClosure(p *Program, b *ir.Block, closure ir.Value) {
+	cast := ir.NewBitCast(closure, pointerType)
+	b.AddInstruction(cast)
+	b.AddInstruction(ir.NewCall(p.closureFunction(ClosureRetain, retainShared), cast))
+}
+
+// ReleaseClosure decreases shared count of closure environment, environment is destroyed when it is not shared
+func ReleaseClosure(p *Program, b *ir.Block, closure ir.Value) {
+	cast := ir.NewBitCast(closure, pointerType)
+	b.AddInstruction(cast)
+	b.AddInstruction(ir.NewCall(p.closureFunction(ClosureRelease, releaseShared), cast))
+}
+
+// closureFunction generates function which passes environment counter to counterFunction, null closure or environment is skipped
+func (p *Program) closureFunction(name string, counterFunction *ir.Func) *ir.Func {
+	if f, ok := p.Intrinsics[name]; ok {
+		return f
+	}
+	param := ir.NewParam(pointerType)
+	param.LocalName = "closure"
+	f := p.IRModule.NewFunc(name, ir.Void, param)
+	p.Intrinsics[name] = f
+
+	entry := f.NewBlock(FunctionEntry)
+	body := f.NewBlock(FunctionBody)
+	counter := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	isNull := ir.NewICmp(ir.IPredEQ, param, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, exit, body))
+
+	record := ir.NewBitCast(param, ir.NewPointerType(closureType))
+	body.AddInstruction(record)
+	envPointer := ir.NewGetElementPtr(closureType, record, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 1))
+	body.AddInstruction(envPointer)
+	env := ir.NewLoad(pointerType, envPointer)
+	body.AddInstruction(env)
+	isNull = ir.NewICmp(ir.IPredEQ, env, ir.NewNull(pointerType))
+	body.AddInstruction(isNull)
+	body.AddInstruction(ir.NewCondBr(isNull, exit, counter))
+
+	counter.AddInstruction(ir.NewCall(counterFunction, env))
+	counter.AddInstruction(ir.NewBr(exit))
+
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// CreateBox allocates storage of local variable which is captured by reference, closures share the box after function returns
+// box is managed by counter which is not shared yet, value in box is released when box is destroyed
+func CreateBox(c *Context, t ir.Type) (storage ir.Value, counter ir.Value) {
+	ptr := ir.NewGetElementPtr(t, ir.NewNull(ir.NewPointerType(t)), ir.NewInt(ir.I32, 1))
+	c.Block.AddInstruction(ptr)
+	size := ir.NewPtrToInt(ptr, ir.I32)
+	c.Block.AddInstruction(size)
+	address := ir.NewCall(malloc, size)
+	c.Block.AddInstruction(address)
+	c.Block.AddInstruction(ir.NewCall(memset, address, ir.NewInt(ir.I32, 0), size))
+
+	counterClass := c.Program.FindQualified(Counter).(*Class)
+	counter = counterClass.CreateInstance(c, Counter, nil)
+	object, _ := counterClass.GetMember(c, counter, "object", false)
+	c.Block.AddInstruction(ir.NewStore(address, object))
+	destroy, _ := counterClass.GetMember(c, counter, "destructor", false)
+	c.Block.AddInstruction(ir.NewStore(c.Program.FunctionValue(c.Program.boxDestructor(t)), destroy))
+
+	typed := ir.NewBitCast(address, ir.NewPointerType(t))
+	c.Block.AddInstruction(typed)
+	field := ir.NewGetElementPtr(t, typed, ir.NewInt(ir.I32, 0))
+	c.Block.AddInstruction(field)
+	return field, counter
+}
+
+// boxDestructor returns function which releases value in box, it does nothing if value is not managed by counter
+func (p *Program) boxDestructor(t ir.Type) *ir.Func {
+	name := BoxDestroy
+	if isCounted(p, t) {
+		name = BoxRelease
+	} else if IsClosure(t) {
+		name = BoxReleaseClosure
+	}
+	if f, ok := p.Intrinsics[name]; ok {
+		return f
+	}
+	param := newParam("box", pointerType)
+	f := p.IRModule.NewFunc(name, ir.Void, param)
+	p.Intrinsics[name] = f
+
+	b := f.NewBlock(FunctionEntry)
+	if name != BoxDestroy {
+		address := ir.NewBitCast(param, ir.NewPointerType(pointerType))
+		b.AddInstruction(address)
+		value := ir.NewLoad(pointerType, address)
+		b.AddInstruction(value)
+		if name == BoxRelease {
+			b.AddInstruction(ir.NewCall(releaseShared, value))
+		} else {
+			ReleaseClosure(p, b, value)
+		}
+	}
+	b.AddInstruction(ir.NewRet(nil))
+	return f
+}
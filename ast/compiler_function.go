@@ -1,17 +1,40 @@
 package ast
 
-import "github.com/panda-foundation/go-compiler/ir"
+import (
+	"strings"
+
+	"github.com/panda-foundation/go-compiler/ir"
+)
 
 var (
-	compilerFunctions = map[string]CompilerFunction{}
+	compilerFunctions     = map[string]CompilerFunction{}
+	compilerFunctionTypes = map[string]CompilerFunctionType{}
 )
 
 type CompilerFunction = func(c *Context, invocation *Invocation) ir.Value
 
+// CompilerFunctionType validates arguments of compiler function and returns its result type, arguments are resolved by checker before
+type CompilerFunctionType = func(p *Program, invocation *Invocation) (ir.Type, error)
+
 func RegisterComplierFunction(namespace, name string, f CompilerFunction) {
 	compilerFunctions[namespace+"."+name] = f
 }
 
+// RegisterComplierFunctionType registers result type of compiler function, compiler function without type has no value
+func RegisterComplierFunctionType(namespace, name string, t CompilerFunctionType) {
+	compilerFunctionTypes[namespace+"."+name] = t
+}
+
+// IsCompilerNamespace reports whether namespace has compiler functions, it could be imported without any module
+func IsCompilerNamespace(namespace string) bool {
+	for qualified := range compilerFunctions {
+		if strings.HasPrefix(qualified, namespace+".") && !strings.Contains(qualified[len(namespace)+1:], ".") {
+			return true
+		}
+	}
+	return false
+}
+
 func IsCompilerFunction(qualified string) bool {
 	if qualified == "" {
 		return false
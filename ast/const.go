@@ -12,15 +12,59 @@ const (
 	Constructor   = "create"
 	Destructor    = "destroy"
 	Counter       = "global.counter"
+	String        = "global.string"
+	Char          = "char"
+	ClosureEnv    = "closure.env"
+	Anonymous     = "lambda"
 
-	Extern   = "extern"
-	Variadic = "variadic"
+	ClosureRetain  = "global.closure.retain"
+	ClosureRelease = "global.closure.release"
+	InstanceRetain = "global.instance.retain"
+
+	BoxDestroy        = "global.box.destroy"
+	BoxRelease        = "global.box.release"
+	BoxReleaseClosure = "global.box.release_closure"
+
+	Reflect          = "reflect"
+	ReflectType      = "reflect.type"
+	ReflectField     = "reflect.field"
+	ReflectFunction  = "reflect.function"
+	ReflectAttribute = "reflect.attribute"
+	TypeInfo         = "type_info"
+
+	Serialization = "binary"
+	Stream        = "runtime.stream"
+	JSON          = "json"
+	JSONError     = "json.error"
+
+	ModuleInitializer   = "initialize"
+	ModuleFinalizer     = "finalize"
+	InitializerPriority = 101
+
+	Extern     = "extern"
+	Variadic   = "variadic"
+	Override   = "override"
+	Doc        = "doc"
+	Deprecated = "deprecated"
+	Inline     = "inline"
+	Packed     = "packed"
+	Section    = "section"
+	Flags      = "flags"
+
+	// helper functions of enum, name is member function of value, from_name is function of enum type
+	EnumName     = "name"
+	EnumFromName = "from_name"
+
+	Serializable  = "serializable"
+	JSONName      = "name"
+	JSONOmitEmpty = "omit_empty"
+	JSONSkip      = "skip"
 )
 
 var (
 	malloc = ir.NewFunc("malloc", pointerType, ir.NewParam(ir.I32))
 	free   = ir.NewFunc("free", ir.Void, ir.NewParam(pointerType))
-	memcpy = ir.NewFunc("memcpy", pointerType, ir.NewParam(pointerType), ir.NewParam(ir.I32))
+	memcpy = ir.NewFunc("memcpy", ir.Void, ir.NewParam(pointerType), ir.NewParam(pointerType), ir.NewParam(ir.I32))
 	memset = ir.NewFunc("memset", ir.Void, ir.NewParam(ir.I32), ir.NewParam(ir.I32))
 
 	pointerType   = ir.NewPointerType(ir.I8)
@@ -29,4 +73,11 @@ var (
 	releaseShared = ir.NewFunc("global.counter.release_shared", ir.Void, ir.NewParam(pointerType))
 	retainWeak    = ir.NewFunc("global.counter.retain_weak", ir.Void, ir.NewParam(pointerType))
 	releaseWeak   = ir.NewFunc("global.counter.release_weak", ir.Void, ir.NewParam(pointerType))
+
+	trap = ir.NewFunc("llvm.trap", ir.Void)
+
+	// function value points to closure record {function, environment counter}
+	closureType = ir.NewStructType(pointerType, pointerType)
+
+	initializerType = ir.NewStructType(ir.I32, ir.NewPointerType(ir.NewFuncType(ir.Void)), pointerType)
 )
@@ -20,7 +20,9 @@ type Context struct {
 	Block      *ir.Block
 	LeaveBlock *ir.Block
 	LoopBlock  *ir.Block
-	Returned   bool
+	// the next case of switch, it is not inherited by nested contexts of case
+	FallthroughBlock *ir.Block
+	Returned         bool
 
 	parent  *Context
 	objects map[string]ir.Value
@@ -44,8 +46,14 @@ func (c *Context) ObjectType(name string) ir.Type {
 		return c.ContentType(v)
 	} else if c.Function.Class != nil && c.Function.Class.HasMember(name) {
 		return c.Function.Class.MemberType(name)
+	} else if c.Function.Struct != nil && c.Function.Struct.HasMember(name) {
+		return c.Function.Struct.MemberType(name)
 	} else if c.parent != nil {
 		return c.parent.ObjectType(name)
+	} else if c.Function.Lambda != nil {
+		if v := c.Function.Lambda.Capture(name); v != nil {
+			return c.ContentType(v)
+		}
 	}
 	return nil
 }
@@ -67,6 +75,12 @@ func (c *Context) ContentType(value ir.Value) ir.Type {
 	// class member
 	case *ir.InstGetElementPtr:
 		return t.Type().(*ir.PointerType).ElemType
+
+	// struct passed by reference
+	case *ir.Param:
+		if isStructReference(t) {
+			return t.Typ.(*ir.PointerType).ElemType
+		}
 	}
 	return nil
 }
@@ -90,29 +104,44 @@ func (c *Context) FindObject(name string) ir.Value {
 		this := c.FindObject(ClassThis)
 		v, _ := c.Function.Class.GetMember(c, this, name, true)
 		return v
+	} else if c.Function.Struct != nil && c.Function.Struct.HasMember(name) {
+		this := c.FindObject(ClassThis)
+		v, _ := c.Function.Struct.GetMember(c, this, name)
+		return v
 	} else if c.parent != nil {
 		return c.parent.FindObject(name)
+	} else if c.Function.Lambda != nil {
+		// variable of outer function
+		return c.Function.Lambda.Capture(name)
 	}
 	return nil
 }
 
 func (c *Context) FindSelector(selector string, member string) (parent ir.Value, value ir.Value, isMemberFunction bool) {
 	parent = c.FindObject(selector)
+	if parent == nil {
+		// could be a global variable
+		if _, d := c.Program.FindSelector("", selector); d != nil {
+			if v, ok := d.(*Variable); ok && v.IRVariable != nil {
+				parent = v.IRVariable
+			}
+		}
+	}
 	if parent == nil {
 		_, d := c.Program.FindSelector(selector, member)
 		if d == nil {
-			// could be an enum
+			// could be an enum or static member of class
 			_, e := c.Program.FindSelector("", selector)
-			if enum, ok := e.(*Enum); ok {
-				value = enum.GetMember(member)
-			} else {
-				return
+			switch e := e.(type) {
+			case *Enum:
+				value = e.GetMember(member)
+
+			case *Class:
+				value = e.StaticValue(member)
 			}
+			return
 		}
 		switch t := d.(type) {
-		case *Enum:
-			value = t.IRStructData
-
 		case *Variable:
 			value = t.IRVariable
 
@@ -120,6 +149,8 @@ func (c *Context) FindSelector(selector string, member string) (parent ir.Value,
 			value = t.IRFunction
 		}
 
+	} else if s := StructOf(c.Program, parent.Type()); s != nil {
+		value, isMemberFunction = s.GetMember(c, parent, member)
 	} else if p, ok := parent.Type().(*ir.PointerType); ok {
 		// find declaration
 		if d, ok := c.Program.Declarations[p.UserData]; ok {
@@ -162,7 +193,24 @@ func AutoLoad(value ir.Value, b *ir.Block) ir.Value {
 		CopyUserData(t, load)
 		b.AddInstruction(load)
 		return load
+
+	// struct passed by reference
+	case *ir.Param:
+		if isStructReference(t) {
+			load := ir.NewLoad(t.Typ.(*ir.PointerType).ElemType, t)
+			b.AddInstruction(load)
+			return load
+		}
 	}
 
 	return value
 }
+
+// isStructReference reports whether parameter is struct passed by reference, including "this" of struct
+func isStructReference(param *ir.Param) bool {
+	if p, ok := param.Typ.(*ir.PointerType); ok {
+		s, ok := p.ElemType.(*ir.StructType)
+		return ok && s.TypeName != ""
+	}
+	return false
+}
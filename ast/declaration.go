@@ -3,6 +3,7 @@ package ast
 type Declaration interface {
 	Node
 	Identifier() string
+	IsPublic() bool
 	HasAttribute(attribute string) bool
 	Qualified(namespace string) string
 }
@@ -10,10 +11,14 @@ type Declaration interface {
 type Modifier struct {
 	Public bool
 	Weak   bool
+	// static member belongs to class rather than its instances
+	Static bool
+	// abstract class cannot be instantiated, its member functions could have no body
+	Abstract bool
 }
 
 func (m *Modifier) Equal(target *Modifier) bool {
-	return m.Public == target.Public && m.Weak == target.Weak
+	return m.Public == target.Public && m.Weak == target.Weak && m.Static == target.Static && m.Abstract == target.Abstract
 }
 
 type Attribute struct {
@@ -40,6 +45,16 @@ func (b *DeclarationBase) HasAttribute(attribute string) bool {
 	return false
 }
 
+// GetAttribute returns the first attribute with name, it is nil if declaration has no such attribute
+func (b *DeclarationBase) GetAttribute(attribute string) *Attribute {
+	for _, a := range b.Attributes {
+		if a.Name == attribute {
+			return a
+		}
+	}
+	return nil
+}
+
 func (b *DeclarationBase) GetAttributeValue(attribute string, value string) *Literal {
 	for _, a := range b.Attributes {
 		if a.Name == attribute {
@@ -53,6 +68,14 @@ func (b *DeclarationBase) Identifier() string {
 	return b.Name.Name
 }
 
+func (b *DeclarationBase) IsPublic() bool {
+	return b.Modifier != nil && b.Modifier.Public
+}
+
+func (b *DeclarationBase) IsStatic() bool {
+	return b.Modifier != nil && b.Modifier.Static
+}
+
 func (b *DeclarationBase) Qualified(namespace string) string {
 	name := b.Name.Name
 	if b.HasAttribute(Extern) {
@@ -2,6 +2,8 @@ package ast
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/panda-foundation/go-compiler/ir"
 	"github.com/panda-foundation/go-compiler/token"
@@ -14,18 +16,24 @@ type Class struct {
 	Functions      []*Function
 	Variables      []*Variable
 
-	Parent     *Class
-	Interfaces []*Interface
+	Parent     *Class       `json:"-"`
+	Interfaces []*Interface `json:"-"`
 
 	IRStruct        *ir.StructType
 	IRVariables     []ir.Type
 	IRValues        []ir.Value
-	VariableIndexes map[string]int
+	VariableIndexes map[string]int `json:"-"`
 
 	IRVTable        *ir.StructType
 	IRFunctions     []*ir.Func
 	IRVTableData    *ir.Global
-	FunctionIndexes map[string]int
+	FunctionIndexes map[string]int `json:"-"`
+	// type info is stored before vtable in vtable data, so it is found from vtable of instance
+	IRTypeInfo *ir.Global
+	// functions in vtable grouped by name
+	MemberFunctions map[string][]*Function `json:"-"`
+
+	vtable []*Function
 }
 
 func (c *Class) AddVariable(v *Variable) error {
@@ -49,9 +57,22 @@ func (c *Class) AddFunction(f *Function) error {
 			return fmt.Errorf("%s redeclared", f.Name.Name)
 		}
 	}
+	if f.IsStatic() && (f.Name.Name == Constructor || f.Name.Name == Destructor) {
+		return fmt.Errorf("%s cannot be static", f.Name.Name)
+	}
 	for _, function := range c.Functions {
 		if f.Name.Name == function.Name.Name {
-			return fmt.Errorf("%s redeclared", f.Name.Name)
+			if f.Name.Name == Constructor || f.Name.Name == Destructor {
+				return fmt.Errorf("%s redeclared", f.Name.Name)
+			}
+			if f.IsStatic() != function.IsStatic() {
+				// static function and member function are found in different ways, so they cannot be overloads
+				return fmt.Errorf("%s redeclared", f.Name.Name)
+			}
+			if err := function.AddOverload(f); err != nil {
+				return err
+			}
+			break
 		}
 	}
 	c.Functions = append(c.Functions, f)
@@ -60,16 +81,43 @@ func (c *Class) AddFunction(f *Function) error {
 
 func (c *Class) GenerateIRDeclaration(p *Program) {
 	for _, v := range c.Variables {
-		c.IRVariables = append(c.IRVariables, v.Type.Type(p))
+		if v.IsStatic() {
+			// static variable is generated as global, it has no field in struct
+			c.IRValues = append(c.IRValues, nil)
+			c.IRVariables = append(c.IRVariables, nil)
+			continue
+		}
+		var t ir.Type
+		if v.Type != nil {
+			t = v.Type.Type(p)
+		}
 		if v.Value == nil {
 			c.IRValues = append(c.IRValues, nil)
+		} else if !v.Value.IsConstant(p) {
+			// value is assigned by constructor, type which is not declared is inferred by checker
+			c.IRValues = append(c.IRValues, nil)
+			if t == nil {
+				t = pointerType
+			}
 		} else {
-			c.IRValues = append(c.IRValues, v.Value.GenerateConstIR(p, v.Type.Type(p)))
+			value := v.Value.GenerateConstIR(p, t)
+			if t == nil && value != nil {
+				// infer type from value
+				t = value.Type()
+			}
+			c.IRValues = append(c.IRValues, value)
+		}
+		if t == nil {
+			p.Error(v.Position, fmt.Sprintf("cannot infer type of %s", v.Name.Name))
+			t = pointerType
 		}
+		c.IRVariables = append(c.IRVariables, t)
 	}
 	for _, f := range c.Functions {
 		c.IRFunctions = append(c.IRFunctions, f.GenerateIRDeclaration(p))
 	}
+	c.IRTypeInfo = p.IRModule.NewGlobal(c.Qualified(p.Module.Namespace)+"."+TypeInfo, reflectType)
+	c.IRTypeInfo.Immutable = true
 }
 
 func (c *Class) GenerateIRStruct(p *Program) {
@@ -86,6 +134,9 @@ func (c *Class) GenerateIRStruct(p *Program) {
 	for i := len(classes) - 1; i > -1; i-- {
 		current = classes[i]
 		for j, v := range current.Variables {
+			if v.IsStatic() {
+				continue
+			}
 			variables = append(variables, current.IRVariables[j])
 			if _, ok := c.VariableIndexes[v.Name.Name]; ok {
 				p.Error(v.Position, fmt.Sprintf("duplicate class member: %s", v.Name.Name))
@@ -98,65 +149,148 @@ func (c *Class) GenerateIRStruct(p *Program) {
 
 	qualified := c.Qualified(p.Module.Namespace)
 	c.IRStruct = ir.NewStructType(variables...)
+	c.IRStruct.Packed = c.HasAttribute(Packed)
 	p.IRModule.NewTypeDef(qualified, c.IRStruct)
 }
 
 func (c *Class) GenerateIRVTable(p *Program) {
 	c.FunctionIndexes = make(map[string]int)
+	c.MemberFunctions = make(map[string][]*Function)
+	c.vtable = nil
 
-	functions := []*ir.Func{}
 	classes := []*Class{c}
 	current := c
 	for current.Parent != nil {
 		classes = append(classes, current.Parent)
 		current = current.Parent
 	}
-	index := 0
 	for i := len(classes) - 1; i > -1; i-- {
 		current = classes[i]
-		for j, f := range current.Functions {
-			if existing, ok := c.FunctionIndexes[f.Name.Name]; ok {
+		for _, f := range current.Functions {
+			if f.IsStatic() {
+				continue
+			}
+			// functions are matched by name and parameter types, so overloads of parent class could be overridden separately
+			// functions of parent classes are checked when vtables of parent classes are generated
+			if existing, ok := c.FunctionIndexes[f.Mangled()]; ok {
 				// existing function
-				function := functions[existing]
-				if !function.Sig.Equal(current.IRFunctions[j].Sig) {
-					p.Error(f.Position, fmt.Sprintf("member function %s does not match its parent class", f.Name.Name))
-					//TO-DO print more params details here
+				function := c.vtable[existing]
+				if !function.IRFunction.Sig.Equal(f.IRFunction.Sig) {
+					if current == c {
+						p.Error(f.Position, fmt.Sprintf("member function %s does not match its parent class %s: expected %s, but found %s",
+							f.Name.Name, function.Class.Name.Name, MangleType(functionType(function)), MangleType(functionType(f))))
+					}
 				} else {
-					functions[existing] = current.IRFunctions[j]
+					c.vtable[existing] = f
 				}
 			} else {
 				// new function
-				functions = append(functions, current.IRFunctions[j])
-				c.FunctionIndexes[f.Name.Name] = index
-				index++
+				if current == c && f.HasAttribute(Override) {
+					c.checkOverride(p, f)
+				}
+				c.FunctionIndexes[f.Mangled()] = len(c.vtable)
+				c.vtable = append(c.vtable, f)
 			}
 		}
 	}
+	for _, f := range c.vtable {
+		c.MemberFunctions[f.Name.Name] = append(c.MemberFunctions[f.Name.Name], f)
+	}
+	for name, functions := range c.MemberFunctions {
+		if len(functions) == 1 {
+			// function which is not overloaded is also found by its name
+			c.FunctionIndexes[name] = c.FunctionIndexes[functions[0].Mangled()]
+		}
+	}
 
 	var types []ir.Type
 	var constants []ir.Constant
-	for _, f := range functions {
-		types = append(types, ir.NewPointerType(f.Sig))
-		constants = append(constants, f)
+	for _, f := range c.vtable {
+		t := ir.NewPointerType(f.IRFunction.Sig)
+		types = append(types, t)
+		if f.IsAbstract() {
+			// slot of abstract function is implemented by subclasses
+			constants = append(constants, ir.NewNull(t))
+		} else {
+			constants = append(constants, f.IRFunction)
+		}
+	}
+	if !c.IsAbstract() {
+		c.checkAbstract(p)
 	}
 	c.IRVTable = ir.NewStructType(types...)
 	p.IRModule.NewTypeDef(c.Qualified(p.Module.Namespace)+".vtable.type", c.IRVTable)
 
-	data := ir.NewStruct(CreateStruct(c.Qualified(p.Module.Namespace)+".vtable.type"), constants...)
+	vtable := ir.NewStruct(CreateStruct(c.Qualified(p.Module.Namespace)+".vtable.type"), constants...)
+	data := ir.NewStruct(ir.NewStructType(c.IRTypeInfo.Type(), vtable.Type()), c.IRTypeInfo, vtable)
 	c.IRVTableData = p.IRModule.NewGlobalDef(c.Qualified(p.Module.Namespace)+".vtable.data", data)
 }
 
+// checkAbstract reports abstract functions which are not implemented by concrete class
+func (c *Class) checkAbstract(p *Program) {
+	for _, f := range c.vtable {
+		if !f.IsAbstract() {
+			continue
+		}
+		if f.Class == c {
+			p.Error(f.Position, fmt.Sprintf("member function %s has no body, class %s must be abstract", f.Name.Name, c.Name.Name))
+		} else {
+			p.Error(c.Position, fmt.Sprintf("class %s does not implement abstract function %s.%s", c.Name.Name, f.Class.Name.Name, f.Name.Name))
+		}
+	}
+}
+
+// IsAbstract reports whether class is declared as abstract, it cannot be instantiated by new
+func (c *Class) IsAbstract() bool {
+	return c.Modifier != nil && c.Modifier.Abstract
+}
+
+// VTable returns address of vtable in vtable data, instances of class point to it
+func (c *Class) VTable() ir.Constant {
+	return ir.NewExprGetElementPtr(c.IRVTableData.ContentType, c.IRVTableData, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 1))
+}
+
+// checkOverride reports function which is marked as override but does not override any function of parent classes
+func (c *Class) checkOverride(p *Program, f *Function) {
+	var expected []string
+	for _, function := range c.vtable {
+		if function.Name.Name == f.Name.Name {
+			expected = append(expected, MangleType(functionType(function)))
+		}
+	}
+	if len(expected) == 0 {
+		p.Error(f.Position, fmt.Sprintf("member function %s is marked as override, but parent classes have no function %s", f.Name.Name, f.Name.Name))
+	} else {
+		p.Error(f.Position, fmt.Sprintf("member function %s is marked as override, but it does not match any function of parent classes: expected %s, but found %s",
+			f.Name.Name, strings.Join(expected, " or "), MangleType(functionType(f))))
+	}
+}
+
+// GenerateIRStatics generates static variables as globals named by class, they are initialized like global variables
+func (c *Class) GenerateIRStatics(p *Program) {
+	for _, v := range c.Variables {
+		if v.IsStatic() {
+			v.GenerateIR(p)
+		}
+	}
+}
+
 func (c *Class) GenerateIR(p *Program) {
 	for _, v := range c.Functions {
 		v.GenerateIR(p)
 	}
 }
 
-func (c *Class) PreProcess(*Program) {
+func (c *Class) PreProcess(p *Program) {
 	// first is constructor, second is destructor
 	functions := []*Function{nil, nil}
 	for _, f := range c.Functions {
 		f.Class = c
+		if f.Body == nil && (f.IsStatic() || f.Name.Name == Constructor || f.Name.Name == Destructor) {
+			// only member function in vtable could be abstract
+			p.Error(f.Position, fmt.Sprintf("function %s must have body", f.Name.Name))
+			f.Body = &Block{}
+		}
 		if f.Name.Name == Constructor {
 			functions[0] = f
 		} else if f.Name.Name == Destructor {
@@ -179,6 +313,10 @@ func (c *Class) PreProcess(*Program) {
 
 func (c *Class) CreateEmptyFunction(name string) *Function {
 	f := &Function{}
+	// implicit constructor and destructor are accessible as class itself
+	f.Modifier = &Modifier{
+		Public: true,
+	}
 	f.ObjectName = c.Name.Name
 	f.Name = &Identifier{
 		Name: name,
@@ -197,8 +335,8 @@ func (c *Class) ResolveParents(p *Program) {
 			switch t := d.(type) {
 			case *Class:
 				if c.Parent == nil {
+					// inheritance cycle is checked after parents of all classes are resolved
 					c.Parent = t
-					//TO-DO check, cannot self inherit, cycle inherit
 				} else {
 					p.Error(parent.Position, "class can only inherit 1 other class")
 				}
@@ -213,6 +351,62 @@ func (c *Class) ResolveParents(p *Program) {
 	}
 }
 
+// IsSubclassOf reports whether parent is one of ancestors of class
+func (c *Class) IsSubclassOf(parent *Class) bool {
+	for current := c.Parent; current != nil; current = current.Parent {
+		if current == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// Implements reports whether class or one of its ancestors implements the interface, directly or by interface inheritance
+func (c *Class) Implements(i *Interface) bool {
+	visited := make(map[*Interface]bool)
+	var extends func(current *Interface) bool
+	extends = func(current *Interface) bool {
+		if current == i {
+			return true
+		}
+		if visited[current] {
+			return false
+		}
+		visited[current] = true
+		for _, parent := range current.Interfaces {
+			if extends(parent) {
+				return true
+			}
+		}
+		return false
+	}
+	for current := c; current != nil; current = current.Parent {
+		for _, implemented := range current.Interfaces {
+			if extends(implemented) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Descendants returns class itself and all classes inherit from it, ordered by qualified name
+func (c *Class) Descendants(p *Program) []*Class {
+	var names []string
+	for qualified, d := range p.Declarations {
+		if class, ok := d.(*Class); ok && class.IsSubclassOf(c) {
+			names = append(names, qualified)
+		}
+	}
+	sort.Strings(names)
+
+	classes := []*Class{c}
+	for _, qualified := range names {
+		classes = append(classes, p.Declarations[qualified].(*Class))
+	}
+	return classes
+}
+
 func (c *Class) HasMember(member string) bool {
 	_, ok := c.VariableIndexes[member]
 	if !ok {
@@ -221,6 +415,60 @@ func (c *Class) HasMember(member string) bool {
 	return ok
 }
 
+// StaticMember returns static variable or static function of class and its parents, function is also found by its mangled name
+func (c *Class) StaticMember(member string) Declaration {
+	for current := c; current != nil; current = current.Parent {
+		for _, v := range current.Variables {
+			if v.IsStatic() && v.Name.Name == member {
+				return v
+			}
+		}
+		for _, f := range current.Functions {
+			if f != nil && f.IsStatic() && (f.Name.Name == member || f.IRFunction != nil && f.Mangled() == member) {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+// StaticValue returns global of static variable or static function, they are accessed without instance
+func (c *Class) StaticValue(member string) ir.Value {
+	return staticValue(c.StaticMember(member))
+}
+
+// staticValue returns global of static variable or static function
+func staticValue(d Declaration) ir.Value {
+	switch d := d.(type) {
+	case *Variable:
+		if d.IRVariable != nil {
+			return d.IRVariable
+		}
+
+	case *Function:
+		if d.IRFunction != nil {
+			return d.IRFunction
+		}
+	}
+	return nil
+}
+
+// staticMember returns referred declaration if it is static member of class
+func staticMember(reference Node) Declaration {
+	switch d := reference.(type) {
+	case *Variable:
+		if d.IsStatic() {
+			return d
+		}
+
+	case *Function:
+		if d.IsStatic() {
+			return d
+		}
+	}
+	return nil
+}
+
 func (c *Class) MemberType(member string) ir.Type {
 	if index, ok := c.VariableIndexes[member]; ok {
 		return ir.GepInstType(c.IRStruct, []ir.Value{ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index))}).(*ir.PointerType).ElemType
@@ -230,6 +478,14 @@ func (c *Class) MemberType(member string) ir.Type {
 	return nil
 }
 
+// Overloads returns member functions with the given name if there are more than one
+func (c *Class) Overloads(name string) []*Function {
+	if functions := c.MemberFunctions[name]; len(functions) > 1 {
+		return functions
+	}
+	return nil
+}
+
 func (c *Class) GetMember(ctx *Context, this ir.Value, member string, direct bool) (parent ir.Value, isMemberFunction bool) {
 	if index, ok := c.VariableIndexes[member]; ok {
 		classPointer := CastToClass(ctx.Block, this, ir.NewPointerType(c.IRStruct))
@@ -237,8 +493,8 @@ func (c *Class) GetMember(ctx *Context, this ir.Value, member string, direct boo
 		ctx.Block.AddInstruction(v)
 		return v, false
 	} else if index, ok := c.FunctionIndexes[member]; ok {
-		if direct {
-			return c.IRFunctions[c.FunctionIndexes[member]], true
+		if direct && !c.vtable[index].IsAbstract() {
+			return c.vtable[index].IRFunction, true
 		} else {
 			classPointer := CastToClass(ctx.Block, this, ir.NewPointerType(c.IRStruct))
 			vtable := ir.NewGetElementPtr(c.IRStruct, classPointer, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 0))
@@ -261,10 +517,69 @@ func (c *Class) GetMemberFromCounter(ctx *Context, counter ir.Value, member stri
 	return parent, value, isMemberFunction
 }
 
-func (c *Class) CreateInstance(ctx *Context, args *Arguments) ir.Value {
+// Allocate allocates instance of class with vtable and default values, other variables are zero
+func (c *Class) Allocate(p *Program, b *ir.Block) ir.Value {
+	// malloc struct and set 0
+	ptr := ir.NewGetElementPtr(c.IRStruct, ir.NewNull(ir.NewPointerType(c.IRStruct)), ir.NewInt(ir.I32, 1))
+	b.AddInstruction(ptr)
+	size := ir.NewPtrToInt(ptr, ir.I32)
+	b.AddInstruction(size)
+	address := ir.NewCall(malloc, size)
+	b.AddInstruction(address)
+	b.AddInstruction(ir.NewCall(memset, address, ir.NewInt(ir.I32, 0), size))
+
+	// set vtable
+	instance := CastToClass(b, address, ir.NewPointerType(c.IRStruct))
+	vtable := ir.NewGetElementPtr(c.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 0))
+	b.AddInstruction(vtable)
+	b.AddInstruction(ir.NewStore(c.VTable(), vtable))
+
+	// set default values
+	current := c
+	for current != nil {
+		for i, v := range current.Variables {
+			if v.IsStatic() {
+				continue
+			}
+			var value ir.Value
+			if v.Value != nil {
+				value = current.IRValues[i]
+			} else if s := StructOf(p, current.IRVariables[i]); s != nil {
+				value = s.Default(p)
+			}
+			if value != nil {
+				index := c.VariableIndexes[v.Name.Name]
+				offset := ir.NewGetElementPtr(c.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+				b.AddInstruction(offset)
+				b.AddInstruction(ir.NewStore(value, offset))
+			}
+		}
+		current = current.Parent
+	}
+	return address
+}
+
+// CreateShared creates counter of instance, which is shared once
+func (c *Class) CreateShared(ctx *Context, qualified string, instance ir.Value) ir.Value {
+	counterClass := ctx.Program.FindQualified(Counter).(*Class)
+	counter := counterClass.CreateInstance(ctx, qualified, nil)
+	// retain shared
+	call := ir.NewCall(retainShared, counter)
+	ctx.Block.AddInstruction(call)
+	// set object
+	object, _ := counterClass.GetMember(ctx, counter, "object", false)
+	ctx.Block.AddInstruction(ir.NewStore(instance, object))
+	// set destructor
+	destructor, _ := counterClass.GetMember(ctx, counter, "destructor", false)
+	ctx.Block.AddInstruction(ir.NewStore(ctx.Program.FunctionValue(c.IRFunctions[1]), destructor))
+	return counter
+}
+
+// CreateInstance calls constructor of class, the result is typed as pointer of qualified class
+func (c *Class) CreateInstance(ctx *Context, qualified string, args *Arguments) ir.Value {
 	f := c.IRFunctions[0]
 	call := ir.NewCall(f)
-	SetUserData(call, c.Qualified(ctx.Program.Module.Namespace))
+	call.Typ = CreateClassPointer(qualified)
 	if args != nil {
 		args.GenerateIR(ctx, call)
 	}
@@ -272,6 +587,87 @@ func (c *Class) CreateInstance(ctx *Context, args *Arguments) ir.Value {
 	return call
 }
 
+// setVariableType changes type of member variable which is inferred by checker, subclasses which include the variable are changed too
+func (c *Class) setVariableType(p *Program, v *Variable, t ir.Type) {
+	for i, variable := range c.Variables {
+		if variable == v {
+			c.IRVariables[i] = t
+		}
+	}
+	for _, class := range c.Descendants(p) {
+		class.IRStruct.Fields[class.VariableIndexes[v.Name.Name]] = t
+	}
+}
+
+// initializeVariables assigns non-constant values of member variables when instance is constructed, variables of parents are assigned first
+// instances of classes are not retained like other member variables
+func (c *Class) initializeVariables(ctx *Context, address ir.Value) {
+	p := ctx.Program
+	var classes []*Class
+	for current := c; current != nil; current = current.Parent {
+		classes = append([]*Class{current}, classes...)
+	}
+	for _, current := range classes {
+		for i, v := range current.Variables {
+			if v.IsStatic() || v.Value == nil || v.Value.IsConstant(p) {
+				continue
+			}
+			if n, ok := v.Value.(*New); ok {
+				n.HasOwner = true
+			}
+			lambda, isLambda := v.Value.(*Lambda)
+			if isLambda {
+				lambda.HasOwner = true
+			}
+			t := current.IRVariables[i]
+			value := v.Value.GenerateIR(ctx, t)
+			if value == nil {
+				continue
+			}
+			value, err := ImplicitCast(ctx, ctx.AutoLoad(value), t)
+			if err != nil {
+				p.Error(v.Value.GetPosition(), err.Error())
+				continue
+			}
+			instance := CastToClass(ctx.Block, address, ir.NewPointerType(c.IRStruct))
+			member := ir.NewGetElementPtr(c.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(c.VariableIndexes[v.Name.Name])))
+			ctx.Block.AddInstruction(member)
+			if IsString(t) {
+				assignString(p, ctx.Block, value, member)
+				continue
+			}
+			ctx.Block.AddInstruction(ir.NewStore(value, member))
+			if IsClosure(t) && !isLambda {
+				RetainClosure(p, ctx.Block, value)
+			}
+		}
+	}
+}
+
+// releaseMembers releases strings and closures of member variables when instance is destroyed, variables of parent are released by destructor of parent
+// TO-DO instances of classes are not retained when they are assigned to member variables, so they are not released
+func (c *Class) releaseMembers(p *Program, b *ir.Block, object ir.Value) {
+	var instance ir.Value
+	for i, v := range c.Variables {
+		t := c.IRVariables[i]
+		if v.IsStatic() || !IsString(t) && !IsClosure(t) {
+			continue
+		}
+		if instance == nil {
+			instance = CastToClass(b, object, ir.NewPointerType(c.IRStruct))
+		}
+		member := ir.NewGetElementPtr(c.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(c.VariableIndexes[v.Name.Name])))
+		b.AddInstruction(member)
+		value := ir.NewLoad(t, member)
+		b.AddInstruction(value)
+		if IsClosure(t) {
+			ReleaseClosure(p, b, value)
+		} else {
+			b.AddInstruction(ir.NewCall(releaseShared, value))
+		}
+	}
+}
+
 func (c *Class) DestroyInstance(b *ir.Block, instance ir.Value) ir.Value {
 	f := c.IRFunctions[1]
 	call := ir.NewCall(f, instance)
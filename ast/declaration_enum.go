@@ -2,22 +2,32 @@ package ast
 
 import (
 	"fmt"
+	"math/bits"
 	"strconv"
 
 	"github.com/panda-foundation/go-compiler/ir"
 	"github.com/panda-foundation/go-compiler/token"
 )
 
+// Enum is named integer constants, values of members are folded when they are accessed
+// underlying type is i32 if it is not declared, members of @flags enum are bits which could be combined by bitwise operators
 type Enum struct {
 	DeclarationBase
-	Members []*Variable
+	Underlying Type
+	Members    []*Variable
 
-	IRStruct        *ir.StructType
-	IRStructData    *ir.Global
-	VariableIndexes map[string]int
+	IRType          *ir.IntType
+	IRValues        []*ir.Int
+	VariableIndexes map[string]int `json:"-"`
+	IRTypeInfo      *ir.Global
+
+	resolving bool
 }
 
 func (e *Enum) AddVariable(m *Variable) error {
+	if m.Name.Name == EnumFromName {
+		return fmt.Errorf("%s is reserved for function of enum", m.Name.Name)
+	}
 	for _, v := range e.Members {
 		if v.Name.Name == m.Name.Name {
 			return fmt.Errorf("%s redeclared", m.Name.Name)
@@ -27,40 +37,84 @@ func (e *Enum) AddVariable(m *Variable) error {
 	return nil
 }
 
-func (e *Enum) GenerateIR(p *Program) {
-	var index int64 = 0
-	var types []ir.Type
-	var values []ir.Constant
-	for _, v := range e.Members {
-		if v.Value == nil {
-			types = append(types, ir.I32)
-			values = append(values, ir.NewInt(ir.I32, index))
-			index++
-		} else {
-			if literal, ok := v.Value.(*Literal); ok {
-				if literal.Typ == token.INT {
-					if i, _ := strconv.Atoi(literal.Value); int64(i) >= index {
-						index = int64(i)
-						types = append(types, ir.I32)
-						values = append(values, ir.NewInt(ir.I32, index))
-						index++
-					} else {
-						p.Error(v.Position, fmt.Sprintf("enum value here should be greater than %d.", i-1))
-					}
-				} else {
-					p.Error(v.Position, "enum value must be integer.")
-				}
+// UnderlyingType returns integer type which values are stored in, it is i32 if declared type is not integer
+func (e *Enum) UnderlyingType(p *Program) *ir.IntType {
+	if t := e.underlying(p); t != nil {
+		return t
+	}
+	return ir.I32
+}
+
+// underlying returns declared integer type, bool and char are not integers of enum
+func (e *Enum) underlying(p *Program) *ir.IntType {
+	if t, ok := e.Underlying.(*BuitinType); ok {
+		if i, ok := t.Type(p).(*ir.IntType); ok && i.BitSize > 1 && !IsChar(i) {
+			return i
+		}
+	}
+	return nil
+}
+
+// GenerateIRValues evaluates values of members, enums which are referred by values are evaluated first
+// member without value is the previous one plus 1, or the next bit of the previous one if enum is @flags
+func (e *Enum) GenerateIRValues(p *Program) {
+	if e.IRType != nil || e.resolving {
+		return
+	}
+	e.resolving = true
+	defer func() {
+		e.resolving = false
+	}()
+
+	if e.Underlying != nil && e.underlying(p) == nil {
+		p.Error(e.Underlying.GetPosition(), "underlying type of enum must be integer")
+	}
+	underlying := e.UnderlyingType(p)
+	t := CreateEnumType(e.Qualified(p.Module.Namespace), underlying)
+	flags := e.HasAttribute(Flags)
+
+	e.VariableIndexes = make(map[string]int)
+	var next int64
+	if flags {
+		next = 1
+	}
+	for i, v := range e.Members {
+		value := next
+		if v.Value != nil {
+			if folded, err := e.evaluate(p, v.Value); err != nil {
+				p.Error(v.Value.GetPosition(), err.Error())
 			} else {
-				p.Error(v.Position, "enum value must be integer.")
+				value = folded
 			}
 		}
+		if !fitsInt(value, underlying) {
+			p.Error(v.Position, fmt.Sprintf("value %d of %s overflows %s", value, v.Name.Name, MangleType(underlying)))
+		}
+		e.IRValues = append(e.IRValues, ir.NewInt(t, value))
+		e.VariableIndexes[v.Name.Name] = i
+
+		if !flags {
+			next = value + 1
+		} else if value > 0 {
+			next = 1 << bits.Len64(uint64(value))
+		} else {
+			next = 1
+		}
 	}
+	e.IRType = t
+}
 
+// GenerateIR generates type info of enum, members are fields of type info and their offsets are values truncated to i32
+func (e *Enum) GenerateIR(p *Program) {
 	qualified := e.Qualified(p.Module.Namespace)
-	e.IRStruct = ir.NewStructType(types...)
-	p.IRModule.NewTypeDef(qualified, e.IRStruct)
-	data := ir.NewStruct(CreateStruct(qualified), values...)
-	e.IRStructData = p.IRModule.NewGlobalDef(qualified+".data", data)
+	var fields []ir.Constant
+	for i, v := range e.Members {
+		fields = append(fields, ir.NewStruct(reflectField, p.AddString(v.Name.Name), p.AddString(MangleType(e.UnderlyingType(p))),
+			ir.NewInt(ir.I32, int64(int32(e.IRValues[i].X.Int64())))))
+	}
+	size := ir.NewInt(ir.I32, int64(e.IRType.BitSize/8))
+	e.IRTypeInfo = p.IRModule.NewGlobalDef(qualified+"."+TypeInfo, p.typeInfo(qualified, TypeInfoEnum, nil, size, fields, nil, e.Attributes))
+	e.IRTypeInfo.Immutable = true
 }
 
 func (e *Enum) HasMember(member string) bool {
@@ -68,9 +122,243 @@ func (e *Enum) HasMember(member string) bool {
 	return ok
 }
 
+// GetMember returns constant value of member, it is nil if member is not found
 func (e *Enum) GetMember(member string) ir.Constant {
 	if index, ok := e.VariableIndexes[member]; ok {
-		return ir.NewExprGetElementPtr(e.IRStruct, e.IRStructData, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+		return e.IRValues[index]
+	}
+	return nil
+}
+
+// evaluate folds constant integer expression of member value
+// it could refer to previous members, members of other enums and integer constants
+func (e *Enum) evaluate(p *Program, expr Expression) (int64, error) {
+	switch expr := expr.(type) {
+	case *Literal:
+		switch expr.Typ {
+		case token.INT:
+			if value, err := strconv.ParseInt(expr.Value, 0, 64); err == nil {
+				return value, nil
+			}
+			value, err := strconv.ParseUint(expr.Value, 0, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid integer %s", expr.Value)
+			}
+			return int64(value), nil
+
+		case token.CHAR:
+			char, _, _, _ := strconv.UnquoteChar(expr.Value[1:len(expr.Value)-1], '\'')
+			return int64(char), nil
+		}
+
+	case *Parentheses:
+		return e.evaluate(p, expr.Expression)
+
+	case *Identifier:
+		if index, ok := e.VariableIndexes[expr.Name]; ok {
+			return e.IRValues[index].X.Int64(), nil
+		}
+		_, d := p.FindSelector("", expr.Name)
+		if v, ok := d.(*Variable); ok && v.Const && v.Value != nil {
+			return e.evaluate(p, v.Value)
+		}
+		return 0, fmt.Errorf("%s undefined", expr.Name)
+
+	case *MemberAccess:
+		var enum *Enum
+		switch parent := expr.Parent.(type) {
+		case *Identifier:
+			if _, d := p.FindSelector(parent.Name, expr.Member.Name); d != nil {
+				if v, ok := d.(*Variable); ok && v.Const && v.Value != nil {
+					return e.evaluate(p, v.Value)
+				}
+				break
+			}
+			_, d := p.FindSelector("", parent.Name)
+			enum, _ = d.(*Enum)
+
+		case *MemberAccess:
+			if selector, ok := parent.Parent.(*Identifier); ok {
+				_, d := p.FindSelector(selector.Name, parent.Member.Name)
+				enum, _ = d.(*Enum)
+			}
+		}
+		if enum != nil {
+			enum.GenerateIRValues(p)
+			if index, ok := enum.VariableIndexes[expr.Member.Name]; ok {
+				return enum.IRValues[index].X.Int64(), nil
+			}
+			if enum.resolving {
+				return 0, fmt.Errorf("value of %s refers to itself", expr.Member.Name)
+			}
+			return 0, fmt.Errorf("%s undefined", expr.Member.Name)
+		}
+
+	case *Unary:
+		value, err := e.evaluate(p, expr.Expression)
+		if err != nil {
+			return 0, err
+		}
+		switch expr.Operator {
+		case token.Plus:
+			return value, nil
+
+		case token.Minus:
+			return -value, nil
+
+		case token.Complement:
+			return ^value, nil
+		}
+
+	case *Binary:
+		left, err := e.evaluate(p, expr.Left)
+		if err != nil {
+			return 0, err
+		}
+		right, err := e.evaluate(p, expr.Right)
+		if err != nil {
+			return 0, err
+		}
+		switch expr.Operator {
+		case token.Plus:
+			return left + right, nil
+
+		case token.Minus:
+			return left - right, nil
+
+		case token.Mul:
+			return left * right, nil
+
+		case token.Div, token.Rem:
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			if expr.Operator == token.Div {
+				return left / right, nil
+			}
+			return left % right, nil
+
+		case token.LeftShift, token.RightShift:
+			if right < 0 || right > 63 {
+				return 0, fmt.Errorf("invalid shift count %d", right)
+			}
+			if expr.Operator == token.LeftShift {
+				return left << right, nil
+			}
+			return left >> right, nil
+
+		case token.BitAnd:
+			return left & right, nil
+
+		case token.BitOr:
+			return left | right, nil
+
+		case token.BitXor:
+			return left ^ right, nil
+		}
+	}
+	return 0, fmt.Errorf("enum value must be constant integer expression")
+}
+
+// fitsInt reports whether value is in range of integer type, unsigned 64 bits integer takes value as bits
+func fitsInt(value int64, t *ir.IntType) bool {
+	if t.BitSize >= 64 {
+		return true
+	}
+	if t.Unsigned {
+		return value >= 0 && value < int64(1)<<t.BitSize
+	}
+	return value >= -(int64(1)<<(t.BitSize-1)) && value < int64(1)<<(t.BitSize-1)
+}
+
+// EnumOf returns enum of value type, it is nil if type is not enum
+func EnumOf(p *Program, t ir.Type) *Enum {
+	if _, ok := t.(*ir.IntType); ok {
+		if e, ok := p.FindQualified(GetTypeUserData(t)).(*Enum); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// nameFunction generates function which returns name of member by value, value which is not member is formatted as number
+// name of the first member is returned if members have the same value
+func (e *Enum) nameFunction(p *Program) *ir.Func {
+	name := GetTypeUserData(e.IRType) + "." + EnumName
+	if f, ok := p.Intrinsics[name]; ok {
+		return f
+	}
+	value := newParam("value", e.IRType)
+	f := p.IRModule.NewFunc(name, CreateStringType(), value)
+	p.Intrinsics[name] = f
+	entry := f.NewBlock(FunctionEntry)
+	number := f.NewBlock("")
+
+	var cases []*ir.Case
+	found := make(map[int64]bool)
+	for i, v := range e.Members {
+		if found[e.IRValues[i].X.Int64()] {
+			continue
+		}
+		found[e.IRValues[i].X.Int64()] = true
+		b := f.NewBlock("")
+		s := p.StringLiteral(v.Name.Name)
+		retainString(p, b, s)
+		b.AddInstruction(ir.NewRet(s))
+		cases = append(cases, ir.NewCase(e.IRValues[i], b))
+	}
+	entry.AddInstruction(ir.NewSwitch(value, number, cases...))
+
+	var extended ir.Value = value
+	if e.IRType.BitSize < 64 {
+		var inst ir.Instruction = ir.NewSExt(value, ir.I64)
+		if e.IRType.Unsigned {
+			inst = ir.NewZExt(value, ir.I64)
+		}
+		number.AddInstruction(inst)
+		extended = inst.(ir.Value)
+	}
+	s := ir.NewCall(p.stringFromIntFunction(e.IRType.Unsigned), extended)
+	number.AddInstruction(s)
+	number.AddInstruction(ir.NewRet(s))
+	return f
+}
+
+// fromNameFunction generates function which returns value of member by name, fallback is returned if name is not found
+func (e *Enum) fromNameFunction(p *Program) *ir.Func {
+	name := GetTypeUserData(e.IRType) + "." + EnumFromName
+	if f, ok := p.Intrinsics[name]; ok {
+		return f
+	}
+	s := stringParam("name")
+	fallback := newParam("fallback", e.IRType)
+	f := p.IRModule.NewFunc(name, e.IRType, s, fallback)
+	p.Intrinsics[name] = f
+	b := f.NewBlock(FunctionEntry)
+	for i, v := range e.Members {
+		compare := ir.NewCall(p.stringCompareFunction(), s, p.StringLiteral(v.Name.Name))
+		b.AddInstruction(compare)
+		equal := ir.NewICmp(ir.IPredEQ, compare, ir.NewInt(ir.I32, 0))
+		b.AddInstruction(equal)
+		found := f.NewBlock("")
+		found.AddInstruction(ir.NewRet(e.IRValues[i]))
+		next := f.NewBlock("")
+		b.AddInstruction(ir.NewCondBr(equal, found, next))
+		b = next
+	}
+	b.AddInstruction(ir.NewRet(fallback))
+	return f
+}
+
+// EnumMemberType returns type of helper function of enum, nil is returned if it is not found
+// name is invoked by value of enum, from_name is invoked by enum type, value itself is not a parameter
+func EnumMemberType(e *Enum, name string, value bool) ir.Type {
+	switch {
+	case value && name == EnumName:
+		return ir.NewPointerType(ir.NewFuncType(CreateStringType()))
+
+	case !value && name == EnumFromName:
+		return ir.NewPointerType(ir.NewFuncType(e.IRType, CreateStringType(), e.IRType))
 	}
 	return nil
 }
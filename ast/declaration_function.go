@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"fmt"
+
 	"github.com/panda-foundation/go-compiler/ir"
 )
 
@@ -13,7 +15,16 @@ type Function struct {
 	ReturnType     Type
 	Body           *Block
 
-	Class *Class
+	Class  *Class  `json:"-"`
+	Struct *Struct `json:"-"`
+	Lambda *Lambda `json:"-"`
+	// function which yields values returns generator, its body is generated as resume function of generator
+	Generator bool `json:"-"`
+
+	// functions with the same name in the same scope, including function itself
+	Overloads []*Function `json:"-"`
+	// mangled parameter types, it identifies function among overloads
+	Signature string `json:"-"`
 
 	IRParams   []*ir.Param
 	IRFunction *ir.Func
@@ -24,16 +35,88 @@ type Function struct {
 
 	AutoReleasePool    []ir.Value
 	BuiltinReleasePool []ir.Value
+
+	generator *generator
+	// address of box counter by storage of variable which is captured by reference
+	boxes map[ir.Value]ir.Value
+}
+
+// AddOverload adds function with the same name to overloads of f
+func (f *Function) AddOverload(overload *Function) error {
+	if f.HasAttribute(Extern) || overload.HasAttribute(Extern) {
+		return fmt.Errorf("external function %s cannot be overloaded", f.Name.Name)
+	}
+	overloads := f.Overloads
+	if overloads == nil {
+		overloads = []*Function{f}
+	}
+	for _, function := range overloads {
+		if sameParameters(function.Parameters, overload.Parameters) {
+			return fmt.Errorf("%s redeclared with the same parameters", f.Name.Name)
+		}
+	}
+	overloads = append(overloads, overload)
+	for _, function := range overloads {
+		function.Overloads = overloads
+	}
+	return nil
+}
+
+// Qualified returns qualified name of function, signature is appended if function is overloaded
+func (f *Function) Qualified(namespace string) string {
+	name := f.DeclarationBase.Qualified(namespace)
+	if len(f.Overloads) > 0 {
+		name += "$" + f.Signature
+	}
+	return name
+}
+
+// addBox records box of variable, so lambda could share the box when the variable is captured by reference
+func (f *Function) addBox(storage ir.Value, box ir.Value) {
+	if f.boxes == nil {
+		f.boxes = make(map[ir.Value]ir.Value)
+	}
+	f.boxes[storage] = box
+}
+
+// IsAbstract reports whether function is member function of class without body, it is implemented by subclasses
+func (f *Function) IsAbstract() bool {
+	return f.Class != nil && f.Body == nil
+}
+
+// Mangled returns name of function with its signature
+func (f *Function) Mangled() string {
+	return f.Name.Name + "$" + f.Signature
+}
+
+// ParameterTypes returns types of declared parameters, implicit "this" and closure environment are excluded
+func (f *Function) ParameterTypes() []ir.Type {
+	var types []ir.Type
+	if f.Parameters != nil {
+		for _, param := range f.IRParams[len(f.IRParams)-len(f.Parameters.Parameters):] {
+			types = append(types, param.Typ)
+		}
+	}
+	return types
 }
 
 func (f *Function) GenerateIRDeclaration(p *Program) *ir.Func {
 	if IsCompilerFunction(f.Qualified(p.Module.Namespace)) {
 		return nil
 	}
-	if f.ObjectName != "" && f.Name.Name != Constructor {
+	if f.Struct != nil {
+		// instance of struct is passed by reference
+		param := ir.NewParam(ir.NewPointerType(f.Struct.IRStruct))
+		param.LocalName = ClassThis
+		f.IRParams = append(f.IRParams, param)
+	} else if f.ObjectName != "" && f.Name.Name != Constructor && !f.IsStatic() {
 		param := ir.NewParam(pointerType)
 		param.LocalName = ClassThis
 		f.IRParams = append(f.IRParams, param)
+	} else if f.Lambda != nil {
+		param := ir.NewParam(pointerType)
+		param.LocalName = ClosureEnv
+		f.IRParams = append(f.IRParams, param)
 	}
 	if f.Parameters != nil {
 		for _, parameter := range f.Parameters.Parameters {
@@ -43,23 +126,10 @@ func (f *Function) GenerateIRDeclaration(p *Program) *ir.Func {
 				param = ir.NewParam(parameter.Type.Type(p))
 
 			case *TypeName:
-				userData, d := p.FindDeclaration(t)
-				switch d.(type) {
-				case *Class:
-					param = ir.NewParam(pointerType)
-
-				case *Enum:
-					param = ir.NewParam(ir.I32)
-
-				case *Interface:
-					// TO-DO interface
-					//TO-DO need to be some convert
-					param = ir.NewParam(pointerType)
-				}
-				SetUserData(param, userData)
+				// TO-DO interface need to be some convert
+				param = ir.NewParam(parameter.IRType(p))
 
 			case *TypeFunction:
-				// TO-DO testing~
 				param = ir.NewParam(t.Type(p))
 			}
 
@@ -67,11 +137,32 @@ func (f *Function) GenerateIRDeclaration(p *Program) *ir.Func {
 			f.IRParams = append(f.IRParams, param)
 		}
 	}
+	f.Signature = MangleTypes(f.ParameterTypes())
+	for _, overload := range f.Overloads {
+		if overload != f && overload.IRFunction != nil && overload.Signature == f.Signature {
+			p.Error(f.Name.Position, fmt.Sprintf("%s redeclared with the same parameters", f.Name.Name))
+		}
+	}
+	if len(f.Overloads) > 0 && f.ObjectName == "" {
+		// overload is found by its mangled name when invoked
+		p.Declarations[f.Qualified(p.Module.Namespace)] = f
+	}
+
 	var t ir.Type = ir.Void
 	if f.ReturnType != nil {
 		t = f.ReturnType.Type(p)
 	}
-	f.IRFunction = p.IRModule.NewFunc(f.Qualified(p.Module.Namespace), t, f.IRParams...)
+	// member functions and lambdas cannot yield, it is reported by checker
+	f.Generator = f.Body != nil && f.ReturnType != nil && f.ObjectName == "" && f.Lambda == nil && Yields(f.Body)
+	if f.Generator {
+		t = GeneratorType(t)
+	}
+	if f.IsAbstract() {
+		// abstract function has no definition in module, it only describes slot of vtable
+		f.IRFunction = ir.NewFunc(f.Qualified(p.Module.Namespace), t, f.IRParams...)
+	} else {
+		f.IRFunction = p.IRModule.NewFunc(f.Qualified(p.Module.Namespace), t, f.IRParams...)
+	}
 	if f.HasAttribute(Extern) {
 		l := f.GetAttributeValue(Extern, Variadic)
 		if l != nil {
@@ -82,11 +173,22 @@ func (f *Function) GenerateIRDeclaration(p *Program) *ir.Func {
 				}
 			}
 		}
+	} else if f.Body != nil {
+		if f.HasAttribute(Inline) {
+			f.IRFunction.FuncAttrs = append(f.IRFunction.FuncAttrs, ir.FuncAttrAlwaysInline)
+		}
+		if a := f.GetAttribute(Section); a != nil {
+			f.IRFunction.Section = a.GetText()
+		}
 	}
 	return f.IRFunction
 }
 
 func (f *Function) GenerateIR(p *Program) {
+	if f.Generator {
+		f.generateGenerator(p)
+		return
+	}
 	if f.Body != nil {
 		c := NewContext(p)
 		c.Function = f
@@ -98,13 +200,19 @@ func (f *Function) GenerateIR(p *Program) {
 		// prepare params
 		for _, param := range f.IRParams {
 			var v ir.Value
-			if param.Type().Equal(pointerType) {
-				//TO-DO add shared ref //TO-DO string
+			if param.Type().Equal(pointerType) && !IsString(param.Typ) || IsReference(p, param.Typ) {
+				//TO-DO add shared ref
+				// struct passed by reference is stored by caller
 				v = param
 			} else {
 				alloc := ir.NewAlloca(param.Typ)
 				CopyUserData(param, alloc)
 				f.IREntry.AddInstruction(alloc)
+				if IsString(param.Typ) {
+					// string could be assigned in function, it is shared until function exits
+					retainString(p, f.IREntry, param)
+					f.AutoReleasePool = append(f.AutoReleasePool, alloc)
+				}
 				store := ir.NewStore(param, alloc)
 				f.IREntry.AddInstruction(store)
 				v = alloc
@@ -123,39 +231,23 @@ func (f *Function) GenerateIR(p *Program) {
 		}
 
 		// generate constructor
+		var address ir.Value
 		if f.ObjectName != "" && f.Name.Name == Constructor {
-			// malloc struct and set 0
-			ptr := ir.NewGetElementPtr(f.Class.IRStruct, ir.NewNull(ir.NewPointerType(f.Class.IRStruct)), ir.NewInt(ir.I32, 1))
-			f.IREntry.AddInstruction(ptr)
-			size := ir.NewPtrToInt(ptr, ir.I32)
-			f.IREntry.AddInstruction(size)
-			address := ir.NewCall(malloc, size)
-			f.IREntry.AddInstruction(address)
-			f.IREntry.AddInstruction(ir.NewCall(memset, address, ir.NewInt(ir.I32, 0), size))
-
-			// set vtable
-			instance := CastToClass(f.IREntry, address, ir.NewPointerType(f.Class.IRStruct))
-			vtable := ir.NewGetElementPtr(f.Class.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 0))
-			f.IREntry.AddInstruction(vtable)
-			f.IREntry.AddInstruction(ir.NewStore(f.Class.IRVTableData, vtable))
-
-			// set default values
-			current := f.Class
-			for current != nil {
-				for i, v := range current.Variables {
-					if v.Value != nil {
-						index := f.Class.VariableIndexes[v.Name.Name]
-						offset := ir.NewGetElementPtr(f.Class.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
-						f.IREntry.AddInstruction(offset)
-						f.IREntry.AddInstruction(ir.NewStore(current.IRValues[i], offset))
-					}
-				}
-				current = current.Parent
-			}
+			address = f.Class.Allocate(p, f.IREntry)
 			f.IREntry.AddInstruction(ir.NewStore(address, f.IRReturn))
 		}
 
 		f.IREntry.AddInstruction(ir.NewBr(f.IRBody))
+		c.Block = f.IRBody
+
+		if address != nil {
+			// non-constant values of variables are generated without parameters of constructor
+			ctx := NewContext(p)
+			ctx.Function = f
+			ctx.Block = c.Block
+			f.Class.initializeVariables(ctx, address)
+			c.Block = ctx.Block
+		}
 
 		if f.ObjectName != "" && f.Name.Name == Destructor {
 			// call parent destructor
@@ -165,7 +257,6 @@ func (f *Function) GenerateIR(p *Program) {
 				f.IRBody.AddInstruction(call)
 			}
 		}
-		c.Block = f.IRBody
 		f.Body.GenerateIR(c)
 
 		if f.ObjectName != "" && f.Name.Name == Constructor {
@@ -182,30 +273,10 @@ func (f *Function) GenerateIR(p *Program) {
 
 		// generate destructor
 		if f.ObjectName != "" && f.Name.Name == Destructor {
-			// TO-DO clean up members // none-builtin-member
+			f.Class.releaseMembers(p, f.IRExit, f.IRParams[0])
 		}
 
-		// auto release pool
-		for _, obj := range f.BuiltinReleasePool {
-			qualified := ""
-			switch t := obj.(type) {
-			case *ir.InstCall:
-				qualified = GetUserData(t)
-
-			case *ir.InstAlloca:
-				qualified = GetUserData(t)
-				load := ir.NewLoad(t.ElemType, t)
-				f.IRExit.AddInstruction(load)
-				obj = load
-			}
-			class := c.Program.FindQualified(qualified).(*Class)
-			class.DestroyInstance(f.IRExit, obj)
-		}
-		for _, obj := range f.AutoReleasePool {
-			obj = AutoLoad(obj, f.IRExit)
-			call := ir.NewCall(releaseShared, obj)
-			f.IRExit.AddInstruction(call)
-		}
+		f.releasePools(p, f.IRExit)
 
 		// return
 		if f.ReturnType == nil {
@@ -218,16 +289,55 @@ func (f *Function) GenerateIR(p *Program) {
 	}
 }
 
+// releasePools releases values in auto release pools when function exits
+func (f *Function) releasePools(p *Program, b *ir.Block) {
+	for _, obj := range f.BuiltinReleasePool {
+		qualified := ""
+		switch t := obj.(type) {
+		case *ir.InstCall:
+			qualified = GetUserData(t)
+
+		case *ir.InstAlloca:
+			qualified = GetUserData(t)
+			load := ir.NewLoad(t.ElemType, t)
+			b.AddInstruction(load)
+			obj = load
+		}
+		class := p.FindQualified(qualified).(*Class)
+		class.DestroyInstance(b, obj)
+	}
+	for _, obj := range f.AutoReleasePool {
+		obj = AutoLoad(obj, b)
+		if IsClosure(obj.Type()) {
+			ReleaseClosure(p, b, obj)
+		} else {
+			call := ir.NewCall(releaseShared, obj)
+			b.AddInstruction(call)
+		}
+	}
+}
+
 type Parameters struct {
 	NodeBase
 	Parameters []*Parameter
 	Ellipsis   bool
 }
 
+// Parameter with "&" before its name is passed by reference "function move(&p point)", only struct could be passed by reference
 type Parameter struct {
 	NodeBase
-	Name string
-	Type Type
+	Name      string
+	Type      Type
+	Reference bool
+}
+
+// IRType returns type of parameter, struct passed by reference is pointer to struct
+func (param *Parameter) IRType(p *Program) ir.Type {
+	t := param.Type.Type(p)
+	if param.Reference && StructOf(p, t) != nil {
+		return ir.NewPointerType(t)
+	}
+	return t
 }
 
 type Arguments struct {
@@ -250,16 +360,35 @@ func (args *Arguments) GenerateIR(c *Context, call *ir.InstCall) {
 	}
 	for _, arg := range args.Arguments {
 		i := len(call.Args)
-		var v ir.Value
+		var expected ir.Type
 		if i < len(function.Params) {
-			v = arg.GenerateIR(c, function.Params[i])
+			expected = function.Params[i]
+		}
+		var v ir.Value
+		if arg.IsConstant(c.Program) {
+			v = arg.GenerateConstIR(c.Program, expected)
 		} else {
-			v = arg.GenerateIR(c, nil)
+			v = arg.GenerateIR(c, expected)
 		}
 		if v == nil {
 			c.Program.Error(arg.GetPosition(), "invalid expression")
-		} else {
-			call.Args = append(call.Args, c.AutoLoad(v))
+			continue
+		}
+		if expected != nil && IsReference(c.Program, expected) {
+			// address of variable is passed
+			call.Args = append(call.Args, v)
+			continue
+		}
+		v = c.AutoLoad(v)
+		if expected != nil {
+			// argument of overloaded function could be converted implicitly
+			cast, err := ImplicitCast(c, v, expected)
+			if err != nil {
+				c.Program.Error(arg.GetPosition(), err.Error())
+				continue
+			}
+			v = cast
 		}
+		call.Args = append(call.Args, v)
 	}
 }
@@ -8,7 +8,7 @@ type Interface struct {
 	Parents        []*TypeName
 	Functions      []*Function
 
-	Interfaces []*Interface
+	Interfaces []*Interface `json:"-"`
 }
 
 func (i *Interface) AddFunction(f *Function) error {
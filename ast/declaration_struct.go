@@ -0,0 +1,243 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/panda-foundation/go-compiler/ir"
+)
+
+// Struct is value type without vtable, its instance is stored in variable or inline in other struct, and copied when it is assigned
+// member functions are not virtual, "this" is pointer to instance
+type Struct struct {
+	DeclarationBase
+	Variables []*Variable
+	Functions []*Function
+
+	IRStruct        *ir.StructType
+	IRValues        []ir.Constant
+	VariableIndexes map[string]int `json:"-"`
+	// member functions by name and mangled name, overloaded functions are only found by mangled name
+	FunctionIndexes map[string]*Function `json:"-"`
+	// member functions grouped by name
+	MemberFunctions map[string][]*Function `json:"-"`
+}
+
+func (s *Struct) AddVariable(v *Variable) error {
+	for _, variable := range s.Variables {
+		if v.Name.Name == variable.Name.Name {
+			return fmt.Errorf("%s redeclared", v.Name.Name)
+		}
+	}
+	for _, function := range s.Functions {
+		if v.Name.Name == function.Name.Name {
+			return fmt.Errorf("%s redeclared", v.Name.Name)
+		}
+	}
+	s.Variables = append(s.Variables, v)
+	return nil
+}
+
+func (s *Struct) AddFunction(f *Function) error {
+	if f.Name.Name == Constructor || f.Name.Name == Destructor {
+		return fmt.Errorf("struct cannot declare %s function", f.Name.Name)
+	}
+	for _, variable := range s.Variables {
+		if f.Name.Name == variable.Name.Name {
+			return fmt.Errorf("%s redeclared", f.Name.Name)
+		}
+	}
+	for _, function := range s.Functions {
+		if f.Name.Name == function.Name.Name {
+			if err := function.AddOverload(f); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	s.Functions = append(s.Functions, f)
+	return nil
+}
+
+// DeclareIR declares named type of struct, its fields are generated after all structs are declared
+func (s *Struct) DeclareIR(p *Program) {
+	s.IRStruct = ir.NewStructType()
+	s.IRStruct.Packed = s.HasAttribute(Packed)
+	p.IRModule.NewTypeDef(s.Qualified(p.Module.Namespace), s.IRStruct)
+	for _, f := range s.Functions {
+		f.Struct = s
+	}
+}
+
+func (s *Struct) GenerateIRDeclaration(p *Program) {
+	s.VariableIndexes = make(map[string]int)
+	for i, v := range s.Variables {
+		var t ir.Type
+		if v.Type != nil {
+			t = v.Type.Type(p)
+		} else if n, ok := v.Value.(*New); ok {
+			t = n.Typ.Type(p)
+		}
+		var value ir.Constant
+		if v.Value != nil && t == nil {
+			// infer type from value
+			value = v.Value.GenerateConstIR(p, t)
+			if value != nil {
+				t = value.Type()
+			}
+		}
+		if t == nil {
+			p.Error(v.Position, fmt.Sprintf("cannot infer type of %s", v.Name.Name))
+			t = pointerType
+		} else if _, ok := p.FindQualified(GetTypeUserData(t)).(*Class); ok || IsClosure(t) || IsString(t) {
+			// struct has no destructor to release them
+			p.Error(v.Position, fmt.Sprintf("variable %s of struct cannot be class instance, string or function", v.Name.Name))
+		}
+		s.IRStruct.Fields = append(s.IRStruct.Fields, t)
+		s.IRValues = append(s.IRValues, value)
+		s.VariableIndexes[v.Name.Name] = i
+	}
+
+	s.FunctionIndexes = make(map[string]*Function)
+	s.MemberFunctions = make(map[string][]*Function)
+	for _, f := range s.Functions {
+		f.GenerateIRDeclaration(p)
+		s.FunctionIndexes[f.Mangled()] = f
+		s.MemberFunctions[f.Name.Name] = append(s.MemberFunctions[f.Name.Name], f)
+	}
+	for name, functions := range s.MemberFunctions {
+		if len(functions) == 1 {
+			s.FunctionIndexes[name] = functions[0]
+		}
+	}
+}
+
+// GenerateIRValues generates default values of variables, default values of structs which are types of variables should be generated first
+func (s *Struct) GenerateIRValues(p *Program) {
+	for i, v := range s.Variables {
+		if v.Value == nil || s.IRValues[i] != nil {
+			continue
+		}
+		t := s.IRStruct.Fields[i]
+		value := v.Value.GenerateConstIR(p, t)
+		if value == nil {
+			continue
+		}
+		if !value.Type().Equal(t) {
+			p.Error(v.Value.GetPosition(), fmt.Sprintf("cannot use %s as default value of %s", MangleType(value.Type()), MangleType(t)))
+			continue
+		}
+		s.IRValues[i] = value
+	}
+}
+
+// CheckLayout reports struct which contains itself, it should be called after fields of all structs are generated
+func (s *Struct) CheckLayout(p *Program) {
+	var contains func(t *ir.StructType, visited map[*ir.StructType]bool) bool
+	contains = func(t *ir.StructType, visited map[*ir.StructType]bool) bool {
+		if visited[t] {
+			return false
+		}
+		visited[t] = true
+		for _, field := range t.Fields {
+			if field, ok := field.(*ir.StructType); ok && (field == s.IRStruct || contains(field, visited)) {
+				return true
+			}
+		}
+		return false
+	}
+	if contains(s.IRStruct, make(map[*ir.StructType]bool)) {
+		p.Error(s.Name.Position, fmt.Sprintf("struct %s contains itself", s.Name.Name))
+	}
+}
+
+func (s *Struct) GenerateIR(p *Program) {
+	for _, f := range s.Functions {
+		f.GenerateIR(p)
+	}
+}
+
+// Default returns value of new instance, variables without default values are zero
+func (s *Struct) Default(p *Program) ir.Constant {
+	var values []ir.Constant
+	for i, t := range s.IRStruct.Fields {
+		if i < len(s.IRValues) && s.IRValues[i] != nil {
+			values = append(values, s.IRValues[i])
+		} else if nested := StructOf(p, t); nested != nil {
+			values = append(values, nested.Default(p))
+		} else {
+			values = append(values, ir.NewZeroInitializer(t))
+		}
+	}
+	return ir.NewStruct(s.IRStruct, values...)
+}
+
+func (s *Struct) HasMember(member string) bool {
+	_, ok := s.VariableIndexes[member]
+	if !ok {
+		_, ok = s.FunctionIndexes[member]
+	}
+	return ok
+}
+
+func (s *Struct) MemberType(member string) ir.Type {
+	if index, ok := s.VariableIndexes[member]; ok {
+		return s.IRStruct.Fields[index]
+	} else if f, ok := s.FunctionIndexes[member]; ok {
+		return f.IRFunction.Type()
+	}
+	return nil
+}
+
+// Overloads returns member functions with the given name if there are more than one
+func (s *Struct) Overloads(name string) []*Function {
+	if functions := s.MemberFunctions[name]; len(functions) > 1 {
+		return functions
+	}
+	return nil
+}
+
+// GetMember returns address of variable or member function, this is address of instance
+func (s *Struct) GetMember(ctx *Context, this ir.Value, member string) (value ir.Value, isMemberFunction bool) {
+	if index, ok := s.VariableIndexes[member]; ok {
+		v := ir.NewGetElementPtr(s.IRStruct, this, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+		ctx.Block.AddInstruction(v)
+		return v, false
+	} else if f, ok := s.FunctionIndexes[member]; ok {
+		return f.IRFunction, true
+	}
+	return nil, false
+}
+
+// CreateInstance allocates instance in entry block of function, it is initialized with default values
+func (s *Struct) CreateInstance(ctx *Context) *ir.InstAlloca {
+	alloca := ir.NewAlloca(s.IRStruct)
+	ctx.Function.IREntry.InsertAlloca(alloca)
+	ctx.Block.AddInstruction(ir.NewStore(s.Default(ctx.Program), alloca))
+	return alloca
+}
+
+// StructOf returns struct of value type t or type of its reference, nil is returned if t is not struct
+func StructOf(p *Program, t ir.Type) *Struct {
+	if pointer, ok := t.(*ir.PointerType); ok {
+		t = pointer.ElemType
+	}
+	if s, ok := t.(*ir.StructType); ok && s.TypeName != "" {
+		d, _ := p.FindQualified(s.TypeName).(*Struct)
+		return d
+	}
+	return nil
+}
+
+// valueType returns struct if t is type of its reference, other types are returned as they are
+func valueType(p *Program, t ir.Type) ir.Type {
+	if IsReference(p, t) {
+		return t.(*ir.PointerType).ElemType
+	}
+	return t
+}
+
+// IsReference reports whether t is type of struct passed by reference
+func IsReference(p *Program, t ir.Type) bool {
+	_, ok := t.(*ir.PointerType)
+	return ok && StructOf(p, t) != nil
+}
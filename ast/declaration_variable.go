@@ -13,20 +13,51 @@ type Variable struct {
 	Const bool
 
 	IRVariable *ir.Global
+
+	// global or static variables with non-constant values which initial value refers to, they are initialized first
+	dependencies []*Variable
 }
 
 func (v *Variable) GenerateIR(p *Program) {
-	if v.Value != nil {
-		value := v.Value.GenerateConstIR(p, v.Type.Type(p))
+	qualified := v.Qualified(p.Module.Namespace)
+	var t ir.Type
+	if v.Type != nil {
+		t = v.Type.Type(p)
+	}
+
+	if v.Value != nil && v.Value.IsConstant(p) {
+		value := v.Value.GenerateConstIR(p, t)
 		if value == nil {
-			p.Error(v.Name.Position, "only constant expression is allowed to initialize value")
+			p.Error(v.Name.Position, "invalid constant expression")
 		} else {
-			v.IRVariable = p.IRModule.NewGlobalDef(v.Qualified(p.Module.Namespace), value)
+			v.IRVariable = p.IRModule.NewGlobalDef(qualified, value)
+			if t == nil {
+				// infer type from value
+				t = value.Type()
+			} else if t.Equal(value.Type()) {
+				v.IRVariable.ContentType = t
+			}
+		}
+	} else if v.Value != nil {
+		if t == nil {
+			// infer type from value resolved by checker, user data of class instance is kept
+			t = v.Value.ResolvedType()
 		}
-	} else {
-		// zero initalize
-		// TO-DO class type conversion with auto pointer
-		v.IRVariable = p.IRModule.NewGlobalDef(v.Qualified(p.Module.Namespace), ir.NewZeroInitializer(pointerType))
+		// initialized at runtime by module initializer
+		p.Module.Initializers = append(p.Module.Initializers, v)
+	}
+	if v.IRVariable == nil {
+		if t == nil {
+			t = pointerType
+		}
+		var value ir.Constant = ir.NewZeroInitializer(t)
+		if s := StructOf(p, t); s != nil {
+			value = s.Default(p)
+		}
+		v.IRVariable = p.IRModule.NewGlobalDef(qualified, value)
+	}
+	if a := v.GetAttribute(Section); a != nil {
+		v.IRVariable.Section = a.GetText()
 	}
-	SetUserData(v.IRVariable, v.Qualified(p.Module.Namespace))
+	SetUserData(v.IRVariable, GetTypeUserData(t))
 }
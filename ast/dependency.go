@@ -0,0 +1,206 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DependencyGraph is graph of namespaces, edge from a namespace to another means it imports the other one
+type DependencyGraph struct {
+	// namespaces ordered by name
+	Namespaces []string
+	// imported namespaces ordered by name, invalid imports are excluded
+	Imports map[string][]string
+}
+
+// Dependencies returns dependency graph of namespaces of program
+func (p *Program) Dependencies() *DependencyGraph {
+	g := &DependencyGraph{
+		Imports: make(map[string][]string),
+	}
+	known := p.namespaces()
+	for namespace := range known {
+		g.Namespaces = append(g.Namespaces, namespace)
+	}
+	sort.Strings(g.Namespaces)
+
+	for _, m := range p.Modules {
+		for _, i := range m.Imports {
+			if i.invalid || !known[i.Namespace] || i.Namespace == m.Namespace {
+				continue
+			}
+			if !contains(g.Imports[m.Namespace], i.Namespace) {
+				g.Imports[m.Namespace] = append(g.Imports[m.Namespace], i.Namespace)
+			}
+		}
+	}
+	for _, imports := range g.Imports {
+		sort.Strings(imports)
+	}
+	return g
+}
+
+// ValidateImports reports imports of unknown namespaces, imports of namespace itself, duplicated imports,
+// conflicting aliases and import cycles, invalid imports are ignored when declarations are resolved
+func (p *Program) ValidateImports() {
+	known := p.namespaces()
+	for _, m := range p.SortedModules() {
+		p.Module = m
+		namespaces := make(map[string]bool)
+		aliases := make(map[string]string)
+		for _, i := range m.Imports {
+			i.invalid = true
+			switch {
+			case !known[i.Namespace] && !IsCompilerNamespace(i.Namespace):
+				p.Error(i.Position, fmt.Sprintf("unknown namespace %s", i.Namespace))
+
+			case i.Namespace == m.Namespace:
+				p.Error(i.Position, fmt.Sprintf("namespace %s cannot import itself", i.Namespace))
+
+			case namespaces[i.Namespace]:
+				p.Error(i.Position, fmt.Sprintf("%s imported more than once", i.Namespace))
+
+			case aliases[i.Alias] != "":
+				p.Error(i.Position, fmt.Sprintf("alias %s of %s conflicts with import of %s", i.Alias, i.Namespace, aliases[i.Alias]))
+
+			default:
+				i.invalid = false
+				namespaces[i.Namespace] = true
+				aliases[i.Alias] = i.Namespace
+			}
+		}
+	}
+
+	for _, cycle := range p.Dependencies().Cycles() {
+		// cycle is reported at import which closes it
+		from, to := cycle[len(cycle)-2], cycle[len(cycle)-1]
+		for _, m := range p.SortedModules() {
+			if i := m.findImport(to); m.Namespace == from && i != nil {
+				p.Module = m
+				p.Error(i.Position, fmt.Sprintf("import cycle not allowed: %s", strings.Join(cycle, " -> ")))
+				break
+			}
+		}
+	}
+}
+
+// Order returns namespaces ordered by dependencies, imported namespaces come first and global namespace is always the first
+// namespaces in a cycle are ordered by their names
+func (g *DependencyGraph) Order() []string {
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(namespace string)
+	visit = func(namespace string) {
+		if visited[namespace] {
+			return
+		}
+		visited[namespace] = true
+		if namespace != Global && contains(g.Namespaces, Global) {
+			visit(Global)
+		}
+		for _, imported := range g.Imports[namespace] {
+			visit(imported)
+		}
+		order = append(order, namespace)
+	}
+	for _, namespace := range g.Namespaces {
+		visit(namespace)
+	}
+	return order
+}
+
+// Cycles returns import cycles of graph, every cycle starts and ends with the same namespace
+func (g *DependencyGraph) Cycles() [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	var cycles [][]string
+	var stack []string
+	states := make(map[string]int)
+	var visit func(namespace string)
+	visit = func(namespace string) {
+		states[namespace] = visiting
+		stack = append(stack, namespace)
+		for _, imported := range g.Imports[namespace] {
+			switch states[imported] {
+			case unvisited:
+				visit(imported)
+
+			case visiting:
+				for i := len(stack) - 1; i >= 0; i-- {
+					if stack[i] == imported {
+						cycle := append([]string{}, stack[i:]...)
+						cycles = append(cycles, append(cycle, imported))
+						break
+					}
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		states[namespace] = visited
+	}
+	for _, namespace := range g.Namespaces {
+		if states[namespace] == unvisited {
+			visit(namespace)
+		}
+	}
+	return cycles
+}
+
+// WriteDOT writes graph in graphviz DOT language, edges in import cycles are red
+func (g *DependencyGraph) WriteDOT(w io.Writer) error {
+	cyclic := make(map[string]bool)
+	for _, cycle := range g.Cycles() {
+		for i := 0; i < len(cycle)-1; i++ {
+			cyclic[cycle[i]+" "+cycle[i+1]] = true
+		}
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("digraph namespaces {\n")
+	for _, namespace := range g.Namespaces {
+		fmt.Fprintf(b, "\t%q;\n", namespace)
+	}
+	for _, namespace := range g.Namespaces {
+		for _, imported := range g.Imports[namespace] {
+			if cyclic[namespace+" "+imported] {
+				fmt.Fprintf(b, "\t%q -> %q [color=red];\n", namespace, imported)
+			} else {
+				fmt.Fprintf(b, "\t%q -> %q;\n", namespace, imported)
+			}
+		}
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (p *Program) namespaces() map[string]bool {
+	namespaces := make(map[string]bool)
+	for _, m := range p.Modules {
+		namespaces[m.Namespace] = true
+	}
+	return namespaces
+}
+
+func (m *Module) findImport(namespace string) *Import {
+	for _, i := range m.Imports {
+		if !i.invalid && i.Namespace == namespace {
+			return i
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
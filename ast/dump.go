@@ -0,0 +1,336 @@
+package ast
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+var irPackage = reflect.TypeOf(ir.Module{}).PkgPath()
+
+// DumpAST returns modules of program as values which could be encoded as JSON
+// every node has its "kind" and "position", fields generated by compiler are not included
+// expressions have their "resolved_type" if program is checked
+func (p *Program) DumpAST() map[string]interface{} {
+	modules := make(map[string]interface{})
+	for name, m := range p.Modules {
+		d := &dumper{
+			file: m.File,
+		}
+		module := d.node(m)
+		module["file"] = name
+		modules[name] = module
+	}
+	return map[string]interface{}{
+		"modules": modules,
+	}
+}
+
+// DumpSymbols returns declarations with their qualified names, resolved types and class layouts, and errors of program
+// it should be called after ir is generated
+func (p *Program) DumpSymbols() map[string]interface{} {
+	symbols := []interface{}{}
+	for _, m := range p.SortedModules() {
+		for _, v := range m.Variables {
+			symbols = append(symbols, p.variableSymbol(m, v))
+		}
+		for _, f := range m.Functions {
+			symbols = append(symbols, p.functionSymbol(m, f))
+		}
+		for _, e := range m.Enums {
+			symbols = append(symbols, p.enumSymbol(m, e))
+		}
+		for _, s := range m.Structs {
+			symbols = append(symbols, p.structSymbol(m, s))
+		}
+		for _, i := range m.Interfaces {
+			symbol := p.symbol(m, "interface", &i.DeclarationBase, i.Qualified(m.Namespace))
+			var functions []interface{}
+			for _, f := range i.Functions {
+				functions = append(functions, p.symbol(m, "function", &f.DeclarationBase, f.Qualified(m.Namespace)))
+			}
+			symbol["functions"] = functions
+			symbols = append(symbols, symbol)
+		}
+		for _, c := range m.Classes {
+			symbols = append(symbols, p.classSymbol(m, c))
+		}
+	}
+
+	errors := []interface{}{}
+	for _, e := range p.Errors {
+		errors = append(errors, map[string]interface{}{
+			"position": e.Position.String(),
+			"message":  e.Message,
+		})
+	}
+	warnings := []interface{}{}
+	for _, w := range p.Warnings {
+		warnings = append(warnings, map[string]interface{}{
+			"position": w.Position.String(),
+			"message":  w.Message,
+		})
+	}
+	return map[string]interface{}{
+		"symbols":  symbols,
+		"errors":   errors,
+		"warnings": warnings,
+	}
+}
+
+func (p *Program) symbol(m *Module, kind string, d *DeclarationBase, qualified string) map[string]interface{} {
+	symbol := map[string]interface{}{
+		"kind":      kind,
+		"name":      d.Name.Name,
+		"qualified": qualified,
+		"position":  m.File.Position(d.Name.Position).String(),
+	}
+	if d.Modifier != nil {
+		symbol["public"] = d.Modifier.Public
+		if d.Modifier.Static {
+			symbol["static"] = true
+		}
+		if d.Modifier.Abstract {
+			symbol["abstract"] = true
+		}
+	}
+	return symbol
+}
+
+func (p *Program) variableSymbol(m *Module, v *Variable) map[string]interface{} {
+	symbol := p.symbol(m, "variable", &v.DeclarationBase, v.Qualified(m.Namespace))
+	symbol["const"] = v.Const
+	if v.IRVariable != nil {
+		setType(symbol, v.IRVariable.ContentType)
+	}
+	return symbol
+}
+
+func (p *Program) functionSymbol(m *Module, f *Function) map[string]interface{} {
+	symbol := p.symbol(m, "function", &f.DeclarationBase, f.Qualified(m.Namespace))
+	if f.IRFunction != nil {
+		symbol["type"] = MangleType(ir.NewPointerType(ir.NewFuncType(f.IRFunction.Sig.RetType, f.ParameterTypes()...)))
+		symbol["ir_type"] = f.IRFunction.Sig.String()
+	}
+	return symbol
+}
+
+func (p *Program) enumSymbol(m *Module, e *Enum) map[string]interface{} {
+	symbol := p.symbol(m, "enum", &e.DeclarationBase, e.Qualified(m.Namespace))
+	if e.IRType != nil {
+		symbol["underlying"] = MangleType(e.UnderlyingType(p))
+	}
+	var members []interface{}
+	for i, v := range e.Members {
+		member := map[string]interface{}{
+			"name": v.Name.Name,
+		}
+		if i < len(e.IRValues) {
+			member["value"] = e.IRValues[i].X.Int64()
+		}
+		members = append(members, member)
+	}
+	symbol["members"] = members
+	return symbol
+}
+
+func (p *Program) classSymbol(m *Module, c *Class) map[string]interface{} {
+	symbol := p.symbol(m, "class", &c.DeclarationBase, c.Qualified(m.Namespace))
+	if c.Parent != nil && c.Parent.IRStruct != nil {
+		symbol["parent"] = c.Parent.IRStruct.Name()
+	}
+	if c.IRStruct != nil {
+		symbol["ir_type"] = c.IRStruct.LLString()
+
+		// variables ordered by their indexes in struct, index 0 is vtable
+		var names []string
+		for name := range c.VariableIndexes {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			return c.VariableIndexes[names[i]] < c.VariableIndexes[names[j]]
+		})
+		var variables []interface{}
+		for _, name := range names {
+			index := c.VariableIndexes[name]
+			variable := map[string]interface{}{
+				"name":  name,
+				"index": index,
+			}
+			setType(variable, c.IRStruct.Fields[index])
+			variables = append(variables, variable)
+		}
+		symbol["variables"] = variables
+	}
+	if c.FunctionIndexes != nil {
+		symbol["function_indexes"] = c.FunctionIndexes
+		var vtable []interface{}
+		for i, f := range c.vtable {
+			entry := map[string]interface{}{
+				"index": i,
+				"name":  f.Mangled(),
+			}
+			if f.IsAbstract() {
+				entry["abstract"] = true
+			} else if f.IRFunction != nil {
+				entry["function"] = f.IRFunction.Name()
+			}
+			vtable = append(vtable, entry)
+		}
+		symbol["vtable"] = vtable
+	}
+	// static variables are globals, they are not in struct of class
+	var statics []interface{}
+	for _, v := range c.Variables {
+		if v.IsStatic() {
+			statics = append(statics, p.variableSymbol(m, v))
+		}
+	}
+	if statics != nil {
+		symbol["static_variables"] = statics
+	}
+	var functions []interface{}
+	for _, f := range c.Functions {
+		functions = append(functions, p.functionSymbol(m, f))
+	}
+	symbol["functions"] = functions
+	return symbol
+}
+
+func (p *Program) structSymbol(m *Module, s *Struct) map[string]interface{} {
+	symbol := p.symbol(m, "struct", &s.DeclarationBase, s.Qualified(m.Namespace))
+	if s.VariableIndexes != nil {
+		symbol["ir_type"] = s.IRStruct.LLString()
+		var variables []interface{}
+		for i, v := range s.Variables {
+			variable := map[string]interface{}{
+				"name":  v.Name.Name,
+				"index": i,
+			}
+			setType(variable, s.IRStruct.Fields[i])
+			variables = append(variables, variable)
+		}
+		symbol["variables"] = variables
+	}
+	var functions []interface{}
+	for _, f := range s.Functions {
+		functions = append(functions, p.functionSymbol(m, f))
+	}
+	symbol["functions"] = functions
+	return symbol
+}
+
+func setType(symbol map[string]interface{}, t ir.Type) {
+	symbol["type"] = MangleType(t)
+	symbol["ir_type"] = t.String()
+}
+
+type dumper struct {
+	file *token.File
+}
+
+func (d *dumper) node(n Node) map[string]interface{} {
+	v := reflect.ValueOf(n).Elem()
+	result := map[string]interface{}{
+		"kind": v.Type().Name(),
+	}
+	if e, ok := n.(Expression); ok && e.ResolvedType() != nil {
+		// expression is resolved by checker when ir is generated
+		result["resolved_type"] = MangleType(e.ResolvedType())
+	}
+	d.fields(v, result)
+	return result
+}
+
+// fields adds exported fields of struct to result, fields of embedded structs are added as they are declared in struct itself
+func (d *dumper) fields(v reflect.Value, result map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			d.fields(v.Field(i), result)
+			continue
+		}
+		if field.PkgPath != "" || field.Tag.Get("json") == "-" || isIRType(field.Type) {
+			continue
+		}
+		if field.Name == "Position" && field.Type.Kind() == reflect.Int {
+			if d.file != nil {
+				result["position"] = d.file.Position(int(v.Field(i).Int())).String()
+			}
+			continue
+		}
+		if value := d.value(v.Field(i)); value != nil {
+			result[snakeCase(field.Name)] = value
+		}
+	}
+}
+
+func (d *dumper) value(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		if n, ok := v.Interface().(Node); ok {
+			return d.node(n)
+		}
+		return d.value(v.Elem())
+
+	case reflect.Struct:
+		result := make(map[string]interface{})
+		d.fields(v, result)
+		return result
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		list := []interface{}{}
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, d.value(v.Index(i)))
+		}
+		return list
+
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		result := make(map[string]interface{})
+		for _, key := range v.MapKeys() {
+			result[key.String()] = d.value(v.MapIndex(key))
+		}
+		return result
+	}
+	if t, ok := v.Interface().(token.Token); ok {
+		return t.String()
+	}
+	return v.Interface()
+}
+
+// isIRType reports whether t is (or contains) type of ir package, which is generated by compiler
+func isIRType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.PkgPath() == irPackage
+}
+
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -10,8 +10,31 @@ type Expression interface {
 	IsConstant(p *Program) bool
 	GenerateIR(c *Context, expected ir.Type) ir.Value
 	GenerateConstIR(p *Program, expected ir.Type) ir.Constant
+
+	// resolved by checker
+	ResolvedType() ir.Type
+	Reference() Node
+	Resolve(t ir.Type, reference Node)
 }
 
 type ExpressionBase struct {
 	NodeBase
+
+	resolved  ir.Type
+	reference Node
+}
+
+// ResolvedType returns type of expression resolved by checker, it is nil if expression refers to a type or it is invalid
+func (e *ExpressionBase) ResolvedType() ir.Type {
+	return e.resolved
+}
+
+// Reference returns declaration which expression refers to, it could be declaration of program, parameter or local variable
+func (e *ExpressionBase) Reference() Node {
+	return e.reference
+}
+
+func (e *ExpressionBase) Resolve(t ir.Type, reference Node) {
+	e.resolved = t
+	e.reference = reference
 }
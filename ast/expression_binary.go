@@ -45,6 +45,10 @@ type Binary struct {
 
 func (b *Binary) Type(c *Context, expected ir.Type) ir.Type {
 	//TO-DO operator override
+	if b.Operator.IsComparison() {
+		// operands are not affected by type of result
+		expected = nil
+	}
 	t1 := b.Left.Type(c, expected)
 	t2 := b.Right.Type(c, expected)
 
@@ -69,12 +73,15 @@ func (b *Binary) Type(c *Context, expected ir.Type) ir.Type {
 
 	// compare
 	case token.Less, token.LessEqual, token.Greater, token.GreaterEqual, token.Equal, token.NotEqual:
-		if ir.IsNumber(t1) && ir.IsNumber(t2) {
+		if ir.IsNumber(t1) && ir.IsNumber(t2) || IsString(t1) || IsString(t2) {
 			return ir.I1
 		}
 
 	// arithmetic operator
 	case token.Plus, token.Minus, token.Mul, token.Div, token.Rem:
+		if b.Operator == token.Plus && (IsString(t1) || IsString(t2)) {
+			return CreateStringType()
+		}
 		t, err := PromoteNumberType(t1, t2)
 		if err == nil {
 			return t
@@ -87,6 +94,13 @@ func (b *Binary) Type(c *Context, expected ir.Type) ir.Type {
 
 func (b *Binary) GenerateIR(c *Context, expected ir.Type) ir.Value {
 	//TO-DO operator overload
+	lambda, isLambda := b.Right.(*Lambda)
+	if isLambda && b.Operator == token.Assign {
+		lambda.HasOwner = true
+	}
+	if b.Operator.IsComparison() {
+		expected = nil
+	}
 	t1 := b.Left.Type(c, expected)
 	t2 := b.Right.Type(c, expected)
 	c1 := b.Left.IsConstant(c.Program)
@@ -103,6 +117,10 @@ func (b *Binary) GenerateIR(c *Context, expected ir.Type) ir.Value {
 		} else {
 			v1 = b.Left.GenerateConstIR(c.Program, expected)
 		}
+		if v1 != nil {
+			// constant takes type of the other side
+			t1 = v1.Type()
+		}
 	} else {
 		v1 = c.AutoLoad(b.Left.GenerateIR(c, expected))
 	}
@@ -112,10 +130,17 @@ func (b *Binary) GenerateIR(c *Context, expected ir.Type) ir.Value {
 		} else {
 			v2 = b.Right.GenerateConstIR(c.Program, expected)
 		}
+		if v2 != nil {
+			t2 = v2.Type()
+		}
 	} else {
 		v2 = c.AutoLoad(b.Right.GenerateIR(c, expected))
 	}
 
+	if IsString(t1) || IsString(t2) {
+		return b.generateString(c, v1, v2, expected)
+	}
+
 	var inst ir.Instruction
 	switch b.Operator {
 	case token.Assign:
@@ -137,10 +162,24 @@ func (b *Binary) GenerateIR(c *Context, expected ir.Type) ir.Value {
 			if t2 == nil {
 				t2 = v2.Type()
 			}
+			if StructOf(c.Program, t1) != nil && t1.Equal(t2) {
+				// struct is copied
+				c.Block.AddInstruction(ir.NewStore(v2, v1))
+				return v1
+			}
 			if ir.IsPointer(t1) && ir.IsPointer(t2) {
 				userData1 := t1.(*ir.PointerType).UserData
 				userData2 := t2.(*ir.PointerType).UserData
 				if userData1 == userData2 {
+					if IsClosure(t1) {
+						// variable shares the new closure and releases the old one
+						if !isLambda {
+							RetainClosure(c.Program, c.Block, v2)
+						}
+						old := ir.NewLoad(t1, v1)
+						c.Block.AddInstruction(old)
+						ReleaseClosure(c.Program, c.Block, old)
+					}
 					c.Block.AddInstruction(ir.NewStore(v2, v1))
 					return v1
 				} else if userData1 == Counter && userData2 != "" {
@@ -238,6 +277,7 @@ func (b *Binary) GenerateIR(c *Context, expected ir.Type) ir.Value {
 			}
 
 			if inst != nil {
+				c.Block.AddInstruction(inst)
 				v1 = b.Left.GenerateIR(c, expected)
 				c.Block.AddInstruction(ir.NewStore(inst.(ir.Value), v1))
 				return v1
@@ -486,3 +526,24 @@ func (b *Binary) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
 	p.Error(b.Position, "invalid constant expression")
 	return nil
 }
+
+// generateString generates concatenation, comparison and assignment of strings, null string is the same as empty string
+func (b *Binary) generateString(c *Context, v1 ir.Value, v2 ir.Value, expected ir.Type) ir.Value {
+	switch b.Operator {
+	case token.Assign, token.PlusAssign:
+		if b.Operator == token.PlusAssign {
+			v2 = ConcatString(c, v1, v2)
+		}
+		address := b.Left.GenerateIR(c, expected)
+		assignString(c.Program, c.Block, v2, address)
+		return address
+
+	case token.Plus:
+		return ConcatString(c, v1, v2)
+
+	case token.Equal, token.NotEqual, token.Less, token.LessEqual, token.Greater, token.GreaterEqual:
+		return CompareString(c, v1, v2, ICMP[b.Operator])
+	}
+	c.Program.Error(b.Position, "invalid type for binary expression")
+	return nil
+}
@@ -0,0 +1,57 @@
+package ast
+
+import (
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+// Conversion is explicit cast "value as type", "value as! type" traps if value cannot be converted
+type Conversion struct {
+	ExpressionBase
+	Expression Expression
+	Typ        Type
+	Checked    bool
+}
+
+func (v *Conversion) Type(c *Context, expected ir.Type) ir.Type {
+	return v.Typ.Type(c.Program)
+}
+
+func (v *Conversion) GenerateIR(c *Context, expected ir.Type) ir.Value {
+	var value ir.Value
+	if v.Expression.IsConstant(c.Program) {
+		value = v.Expression.GenerateConstIR(c.Program, nil)
+	} else {
+		value = v.Expression.GenerateIR(c, nil)
+	}
+	if value == nil {
+		return nil
+	}
+	value, err := ExplicitCast(c, c.AutoLoad(value), v.Typ.Type(c.Program), v.Checked)
+	if err != nil {
+		c.Program.Error(v.Position, err.Error())
+		return nil
+	}
+	return value
+}
+
+func (v *Conversion) IsConstant(p *Program) bool {
+	// checked conversion and conversion to string are always done at runtime
+	if b, ok := v.Typ.(*BuitinType); ok && !v.Checked && b.Token != token.String {
+		return v.Expression.IsConstant(p)
+	}
+	return false
+}
+
+func (v *Conversion) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
+	value := v.Expression.GenerateConstIR(p, nil)
+	if value == nil {
+		return nil
+	}
+	value, err := ExplicitCastExpr(value, v.Typ.Type(p))
+	if err != nil {
+		p.Error(v.Position, err.Error())
+		return nil
+	}
+	return value
+}
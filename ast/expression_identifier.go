@@ -14,7 +14,7 @@ type Identifier struct {
 func (i *Identifier) Type(c *Context, expected ir.Type) ir.Type {
 	t := c.ObjectType(i.Name)
 	if t == nil {
-		_, d := c.Program.FindSelector("", i.Name)
+		d := i.declaration(c.Program)
 		if d == nil {
 			c.Program.Error(i.Position, fmt.Sprintf("undefined %s", i.Name))
 			return nil
@@ -36,7 +36,7 @@ func (i *Identifier) Type(c *Context, expected ir.Type) ir.Type {
 func (i *Identifier) GenerateIR(c *Context, expected ir.Type) ir.Value {
 	v := c.FindObject(i.Name)
 	if v == nil {
-		_, d := c.Program.FindSelector("", i.Name)
+		d := i.declaration(c.Program)
 		if d == nil {
 			c.Program.Error(i.Position, fmt.Sprintf("undefined %s", i.Name))
 			return nil
@@ -46,7 +46,7 @@ func (i *Identifier) GenerateIR(c *Context, expected ir.Type) ir.Value {
 			return t.IRVariable
 
 		case *Function:
-			if t.Class == nil {
+			if t.Class == nil || t.IsStatic() {
 				return ir.NewCall(t.IRFunction)
 			}
 			return ir.NewCall(t.IRFunction, c.FindObject(ClassThis))
@@ -56,11 +56,28 @@ func (i *Identifier) GenerateIR(c *Context, expected ir.Type) ir.Value {
 			return nil
 		}
 	}
+	if f, ok := v.(*ir.Func); ok {
+		// member function of current class
+		return ir.NewCall(f, c.FindObject(ClassThis))
+	}
+	if f, ok := i.Reference().(*Function); ok && f.IsAbstract() {
+		// abstract function of current class is invoked by vtable of instance
+		return ir.NewCall(c.AutoLoad(v), c.FindObject(ClassThis))
+	}
 	return v
 }
 
-func (i *Identifier) IsConstant(p *Program) bool {
+// declaration returns declaration of program found by name, static member of class is resolved by checker
+func (i *Identifier) declaration(p *Program) Declaration {
+	if d := staticMember(i.Reference()); d != nil {
+		return d
+	}
 	_, d := p.FindSelector("", i.Name)
+	return d
+}
+
+func (i *Identifier) IsConstant(p *Program) bool {
+	d := i.declaration(p)
 	if d == nil {
 		return false
 	}
@@ -74,7 +91,7 @@ func (i *Identifier) IsConstant(p *Program) bool {
 }
 
 func (i *Identifier) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
-	_, d := p.FindSelector("", i.Name)
+	d := i.declaration(p)
 	if d == nil {
 		p.Error(i.Position, fmt.Sprintf("%s undefined", i.Name))
 		return nil
@@ -84,7 +101,11 @@ func (i *Identifier) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
 			return v.Value.GenerateConstIR(p, nil)
 		}
 	} else if f, ok := d.(*Function); ok {
-		return f.IRFunction
+		if f = SelectOverload(f, expected); f == nil {
+			p.Error(i.Position, fmt.Sprintf("ambiguous reference to overloaded function %s", i.Name))
+			return nil
+		}
+		return p.FunctionValue(f.IRFunction)
 	}
 	p.Error(i.Position, "invalid constant declaration")
 	return nil
@@ -0,0 +1,70 @@
+package ast
+
+import (
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+// Interpolation is interpolated string $"text{expression}text", parts are string literals of text and expressions between them
+// every expression is converted to string by its type, then parts are concatenated
+type Interpolation struct {
+	ExpressionBase
+	Parts []Expression
+}
+
+func (*Interpolation) Type(c *Context, expected ir.Type) ir.Type {
+	return CreateStringType()
+}
+
+func (i *Interpolation) GenerateIR(c *Context, expected ir.Type) ir.Value {
+	var result ir.Value
+	for _, part := range i.Parts {
+		var value ir.Value
+		if isText(part) {
+			value = part.GenerateConstIR(c.Program, CreateStringType())
+		} else if part.IsConstant(c.Program) {
+			value = part.GenerateConstIR(c.Program, part.ResolvedType())
+		} else {
+			value = c.AutoLoad(part.GenerateIR(c, part.ResolvedType()))
+		}
+		if value == nil {
+			return nil
+		}
+		value, err := ConvertToString(c, value)
+		if err != nil {
+			c.Program.Error(part.GetPosition(), err.Error())
+			return nil
+		}
+		if result == nil {
+			result = value
+		} else {
+			result = ConcatString(c, result, value)
+		}
+	}
+	if result == nil {
+		return c.Program.StringLiteral("")
+	}
+	return result
+}
+
+// IsConstant returns true if it has no expression
+func (i *Interpolation) IsConstant(p *Program) bool {
+	for _, part := range i.Parts {
+		if !isText(part) {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *Interpolation) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
+	if len(i.Parts) == 0 {
+		return p.StringLiteral("")
+	}
+	return i.Parts[0].GenerateConstIR(p, CreateStringType())
+}
+
+func isText(e Expression) bool {
+	l, ok := e.(*Literal)
+	return ok && l.Typ == token.STRING
+}
@@ -12,7 +12,23 @@ type Invocation struct {
 }
 
 func (i *Invocation) Type(c *Context, expected ir.Type) ir.Type {
-	t := i.Function.Type(c, expected)
+	if IsCompilerFunction(GetCompilerFunctionName(c, i.Function)) {
+		// result type of compiler function is resolved by checker
+		return i.ResolvedType()
+	}
+	function := i.Function
+	if candidates := i.overloads(c); candidates != nil {
+		f, err := ResolveOverload(c, i.name(), candidates, i.Arguments)
+		if err != nil {
+			// error is reported when invocation is generated
+			return nil
+		}
+		function = i.overload(f)
+	}
+	t := function.Type(c, expected)
+	if p, ok := t.(*ir.PointerType); ok {
+		t = p.ElemType
+	}
 	if ir.IsFunc(t) {
 		return t.(*ir.FuncType).RetType
 	}
@@ -24,13 +40,34 @@ func (i *Invocation) GenerateIR(c *Context, expected ir.Type) ir.Value {
 	if IsCompilerFunction(GetCompilerFunctionName(c, i.Function)) {
 		return InvokeCompilerFunction(c, i)
 	}
-	value := i.Function.GenerateIR(c, nil)
+	function := i.Function
+	if candidates := i.overloads(c); candidates != nil {
+		f, err := ResolveOverload(c, i.name(), candidates, i.Arguments)
+		if err != nil {
+			c.Program.Error(i.Position, err.Error())
+			return nil
+		}
+		function = i.overload(f)
+	}
+	value := function.GenerateIR(c, nil)
 	if value != nil {
 		if call, ok := value.(*ir.InstCall); ok {
 			i.Arguments.GenerateIR(c, call)
 			c.Block.AddInstruction(call)
+			if IsString(call.Type()) || IsClosure(call.Type()) {
+				// returned string or closure is owned by caller
+				return c.temporary(call)
+			}
 			return value
 		}
+		value = c.AutoLoad(value)
+		if IsClosure(value.Type()) {
+			result := CallClosure(c, value, i.Arguments)
+			if result != nil && (IsString(result.Type()) || IsClosure(result.Type())) {
+				return c.temporary(result)
+			}
+			return result
+		}
 	}
 	c.Program.Error(i.Position, "invalid function call")
 	return nil
@@ -43,3 +80,60 @@ func (*Invocation) IsConstant(p *Program) bool {
 func (*Invocation) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
 	return nil
 }
+
+// overloads returns candidates if invoked function is overloaded
+func (i *Invocation) overloads(c *Context) []*Function {
+	switch f := i.Function.(type) {
+	case *Identifier:
+		if c.ObjectType(f.Name) != nil {
+			return nil
+		}
+		if c.Function.Class != nil {
+			if functions := c.Function.Class.Overloads(f.Name); functions != nil {
+				return functions
+			}
+		}
+		if c.Function.Struct != nil {
+			if functions := c.Function.Struct.Overloads(f.Name); functions != nil {
+				return functions
+			}
+		}
+		_, d := c.Program.FindSelector("", f.Name)
+		if function, ok := d.(*Function); ok {
+			return function.Overloads
+		}
+
+	case *MemberAccess:
+		return f.Overloads(c)
+	}
+	return nil
+}
+
+// overload returns function expression which refers to the selected overload by its mangled name
+func (i *Invocation) overload(f *Function) Expression {
+	switch function := i.Function.(type) {
+	case *Identifier:
+		identifier := *function
+		identifier.Name = f.Mangled()
+		return &identifier
+
+	case *MemberAccess:
+		member := *function.Member
+		member.Name = f.Mangled()
+		memberAccess := *function
+		memberAccess.Member = &member
+		return &memberAccess
+	}
+	return i.Function
+}
+
+func (i *Invocation) name() string {
+	switch function := i.Function.(type) {
+	case *Identifier:
+		return function.Name
+
+	case *MemberAccess:
+		return function.Member.Name
+	}
+	return ""
+}
@@ -0,0 +1,248 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/panda-foundation/go-compiler/ir"
+)
+
+// Capture is a local variable listed in lambda "function [a, &b]() {}", "&" captures by reference
+type Capture struct {
+	NodeBase
+	Name      string
+	Reference bool
+}
+
+type captured struct {
+	outer     ir.Value
+	inner     ir.Value
+	typ       ir.Type
+	reference bool
+	index     int
+	// index of box counter, variable captured by reference is shared by its box
+	box int
+}
+
+// Lambda is anonymous function, local variables of outer function are captured by value unless listed as reference
+// captured values are stored in environment which is managed by counter, function value points to head of environment
+// variables captured by reference are stored in boxes, environment shares the boxes so lambda could outlive outer function
+type Lambda struct {
+	ExpressionBase
+	Captures   []*Capture
+	Parameters *Parameters
+	ReturnType Type
+	Body       *Block
+	HasOwner   bool `json:"-"`
+
+	Function *Function `json:"-"`
+	IREnv    *ir.StructType
+
+	context  *Context
+	env      ir.Value
+	captured map[string]*captured
+	order    []string
+}
+
+func (l *Lambda) Type(c *Context, expected ir.Type) ir.Type {
+	var types []ir.Type
+	if l.Parameters != nil {
+		for _, parameter := range l.Parameters.Parameters {
+			types = append(types, parameter.IRType(c.Program))
+		}
+	}
+	var t ir.Type = ir.Void
+	if l.ReturnType != nil {
+		t = l.ReturnType.Type(c.Program)
+	}
+	return ir.NewPointerType(ir.NewFuncType(t, types...))
+}
+
+func (l *Lambda) GenerateIR(c *Context, expected ir.Type) ir.Value {
+	p := c.Program
+	l.Function = &Function{}
+	l.Function.Position = l.Position
+	l.Function.Name = &Identifier{
+		Name: Anonymous + "." + strconv.Itoa(p.Lambdas),
+	}
+	p.Lambdas++
+	l.Function.Parameters = l.Parameters
+	l.Function.ReturnType = l.ReturnType
+	l.Function.Body = l.Body
+	l.Function.Lambda = l
+	qualified := l.Function.Qualified(p.Module.Namespace)
+
+	l.context = c
+	l.env = nil
+	l.captured = make(map[string]*captured)
+	l.order = nil
+	// closure record is the head of environment
+	l.IREnv = ir.NewStructType(pointerType, pointerType)
+	p.IRModule.NewTypeDef(qualified+".env", l.IREnv)
+
+	names := make(map[string]bool)
+	for _, capture := range l.Captures {
+		if names[capture.Name] {
+			p.Error(capture.Position, fmt.Sprintf("%s captured more than once", capture.Name))
+		} else if c.FindObject(capture.Name) == nil {
+			p.Error(capture.Position, fmt.Sprintf("undefined %s", capture.Name))
+		}
+		names[capture.Name] = true
+	}
+
+	l.Function.GenerateIRDeclaration(p)
+	l.Function.GenerateIR(p)
+	destructor := l.generateDestructor(p, qualified)
+
+	// create environment
+	address, counter := CreateEnvironment(c, l.IREnv, l.Function.IRFunction, destructor)
+	c.Block.AddInstruction(ir.NewCall(retainShared, counter))
+	env := ir.NewBitCast(address, ir.NewPointerType(l.IREnv))
+	c.Block.AddInstruction(env)
+
+	for _, name := range l.order {
+		v := l.captured[name]
+		var value ir.Value = v.outer
+		if v.reference {
+			box := c.AutoLoad(c.Function.boxes[v.outer])
+			c.Block.AddInstruction(ir.NewCall(retainShared, box))
+			c.Block.AddInstruction(ir.NewStore(box, l.field(c.Block, env, v.box)))
+		} else {
+			value = c.AutoLoad(v.outer)
+			l.retain(c, value)
+		}
+		c.Block.AddInstruction(ir.NewStore(value, l.field(c.Block, env, v.index)))
+	}
+
+	closure := ir.NewBitCast(address, l.Type(c, nil))
+	c.Block.AddInstruction(closure)
+	if !l.HasOwner {
+		c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, closure)
+	}
+	return closure
+}
+
+func (*Lambda) IsConstant(p *Program) bool {
+	return false
+}
+
+func (*Lambda) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
+	return nil
+}
+
+// Capture returns storage of captured variable in lambda, variable is captured when it is first used
+func (l *Lambda) Capture(name string) ir.Value {
+	if v, ok := l.captured[name]; ok {
+		return v.inner
+	}
+	outer := l.context.FindObject(name)
+	if outer == nil {
+		return nil
+	}
+	if _, ok := outer.(*ir.Func); ok {
+		return nil
+	}
+	reference := false
+	for _, capture := range l.Captures {
+		if capture.Name == name {
+			reference = capture.Reference
+		}
+	}
+
+	t := l.context.ContentType(outer)
+	if t == nil {
+		// parameter which has no storage
+		t = outer.Type()
+	}
+	if _, boxed := l.context.Function.boxes[outer]; reference && !boxed {
+		// only local variable is boxed, others are released when outer function exits
+		l.context.Program.Error(l.Position, fmt.Sprintf("cannot capture %s by reference", name))
+		reference = false
+	}
+	fieldType := t
+	if reference {
+		fieldType = outer.Type()
+	}
+	l.IREnv.Fields = append(l.IREnv.Fields, fieldType)
+	v := &captured{
+		outer:     outer,
+		typ:       t,
+		reference: reference,
+		index:     len(l.IREnv.Fields) - 1,
+	}
+	if reference {
+		l.IREnv.Fields = append(l.IREnv.Fields, pointerType)
+		v.box = len(l.IREnv.Fields) - 1
+	}
+
+	// captured variables are loaded in entry block, so they are available in all blocks
+	entry := l.Function.IREntry
+	if l.env == nil {
+		env := ir.NewBitCast(l.Function.IRParams[0], ir.NewPointerType(l.IREnv))
+		entry.InsertInstruction(env)
+		l.env = env
+	}
+	field := ir.NewGetElementPtr(l.IREnv, l.env, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(v.index)))
+	entry.InsertInstruction(field)
+	v.inner = field
+	if reference {
+		address := ir.NewLoad(fieldType, field)
+		entry.InsertInstruction(address)
+		storage := ir.NewGetElementPtr(t, address, ir.NewInt(ir.I32, 0))
+		entry.InsertInstruction(storage)
+		v.inner = storage
+
+		// nested lambda shares the same box
+		box := ir.NewGetElementPtr(l.IREnv, l.env, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(v.box)))
+		entry.InsertInstruction(box)
+		l.Function.addBox(storage, box)
+	}
+	SetUserData(v.inner, GetTypeUserData(t))
+
+	l.captured[name] = v
+	l.order = append(l.order, name)
+	return v.inner
+}
+
+func (l *Lambda) field(b *ir.Block, env ir.Value, index int) ir.Value {
+	field := ir.NewGetElementPtr(l.IREnv, env, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+	b.AddInstruction(field)
+	return field
+}
+
+// retain shares class instance, string or closure captured by value with environment
+func (l *Lambda) retain(c *Context, value ir.Value) {
+	if isCounted(c.Program, value.Type()) {
+		c.Block.AddInstruction(ir.NewCall(retainShared, value))
+	} else if IsClosure(value.Type()) {
+		RetainClosure(c.Program, c.Block, value)
+	}
+}
+
+// generateDestructor generates function which releases values captured by environment
+func (l *Lambda) generateDestructor(p *Program, qualified string) *ir.Func {
+	param := ir.NewParam(pointerType)
+	param.LocalName = ClosureEnv
+	f := p.IRModule.NewFunc(qualified+"."+Destructor, ir.Void, param)
+	b := f.NewBlock(FunctionEntry)
+	env := ir.NewBitCast(param, ir.NewPointerType(l.IREnv))
+	b.AddInstruction(env)
+	for _, name := range l.order {
+		v := l.captured[name]
+		if v.reference {
+			box := ir.NewLoad(pointerType, l.field(b, env, v.box))
+			b.AddInstruction(box)
+			b.AddInstruction(ir.NewCall(releaseShared, box))
+		} else if isCounted(p, v.typ) {
+			value := ir.NewLoad(v.typ, l.field(b, env, v.index))
+			b.AddInstruction(value)
+			b.AddInstruction(ir.NewCall(releaseShared, value))
+		} else if IsClosure(v.typ) {
+			value := ir.NewLoad(v.typ, l.field(b, env, v.index))
+			b.AddInstruction(value)
+			ReleaseClosure(p, b, value)
+		}
+	}
+	b.AddInstruction(ir.NewRet(nil))
+	return f
+}
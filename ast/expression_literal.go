@@ -17,10 +17,16 @@ type Literal struct {
 func (l *Literal) Type(c *Context, expected ir.Type) ir.Type {
 	switch l.Typ {
 	case token.STRING:
+		if expected != nil && IsString(expected) {
+			return expected
+		}
 		return ir.NewArrayType(uint64(len(l.Value)-1), ir.I8)
 
 	case token.CHAR:
-		return ir.I32
+		if expected != nil && ir.IsInt(expected) {
+			return expected
+		}
+		return CreateCharType()
 
 	case token.FLOAT:
 		if expected != nil && ir.IsFloat(expected) {
@@ -29,7 +35,7 @@ func (l *Literal) Type(c *Context, expected ir.Type) ir.Type {
 		return ir.Float32
 
 	case token.INT:
-		if expected != nil && ir.IsInt(expected) {
+		if expected != nil && ir.IsNumber(expected) {
 			return expected
 		}
 		return ir.I32
@@ -38,6 +44,9 @@ func (l *Literal) Type(c *Context, expected ir.Type) ir.Type {
 		return ir.I1
 
 	case token.NULL:
+		if expected != nil && IsString(expected) {
+			return expected
+		}
 		return pointerType
 
 	default:
@@ -47,19 +56,8 @@ func (l *Literal) Type(c *Context, expected ir.Type) ir.Type {
 
 func (l *Literal) GenerateIR(c *Context, expected ir.Type) ir.Value {
 	switch l.Typ {
-	case token.STRING:
-		if l.Value[0] == '"' {
-			// string
-			str, _ := strconv.Unquote(l.Value)
-			return c.Program.AddString(str)
-		} else {
-			// `` raw string
-			return c.Program.AddString(l.Value[1 : len(l.Value)-1])
-		}
-
-	case token.CHAR:
-		//TO-DO convert char to i32
-		return nil
+	case token.STRING, token.CHAR:
+		return l.GenerateConstIR(c.Program, expected)
 
 	case token.FLOAT:
 		if expected != nil {
@@ -91,6 +89,9 @@ func (l *Literal) GenerateIR(c *Context, expected ir.Type) ir.Value {
 		return ir.False
 
 	case token.NULL:
+		if expected != nil && IsString(expected) {
+			return ir.NewNull(expected.(*ir.PointerType))
+		}
 		return ir.NewNull(pointerType)
 
 	default:
@@ -105,11 +106,19 @@ func (*Literal) IsConstant(p *Program) bool {
 func (l *Literal) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
 	switch l.Typ {
 	case token.STRING:
-		return p.AddString(l.Value[1 : len(l.Value)-1])
+		str := l.text()
+		if expected != nil && IsString(expected) {
+			// literal of string is interned
+			return p.StringLiteral(str)
+		}
+		return p.AddString(str)
 
 	case token.CHAR:
-		//TO-DO convert char to i32
-		return nil
+		char, _, _, _ := strconv.UnquoteChar(l.Value[1:len(l.Value)-1], '\'')
+		if expected != nil && ir.IsInt(expected) {
+			return ir.NewInt(expected.(*ir.IntType), int64(char))
+		}
+		return ir.NewInt(CreateCharType(), int64(char))
 
 	case token.FLOAT:
 		if expected == nil {
@@ -131,6 +140,8 @@ func (l *Literal) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
 			t := ir.NewIntFromString(i, l.Value)
 			t.Typ.Unsigned = i.Unsigned
 			return t
+		} else if ir.IsFloat(expected) {
+			return ir.NewFloatFromString(expected.(*ir.FloatType), l.Value)
 		}
 		p.Error(l.Position, "type mismatch")
 		return nil
@@ -153,14 +164,23 @@ func (l *Literal) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
 	}
 }
 
+// text returns unquoted text of string literal, raw string is not unquoted
+func (l *Literal) text() string {
+	if l.Value[0] == '"' {
+		text, _ := strconv.Unquote(l.Value)
+		return text
+	}
+	return l.Value[1 : len(l.Value)-1]
+}
+
 func (l *Literal) GetValue() interface{} {
 	switch l.Typ {
 	case token.STRING:
 		return l.Value[1 : len(l.Value)-1]
 
 	case token.CHAR:
-		//TO-DO convert char to i32
-		return nil
+		char, _, _, _ := strconv.UnquoteChar(l.Value[1:len(l.Value)-1], '\'')
+		return char
 
 	case token.FLOAT:
 		x, _, _ := big.ParseFloat(l.Value, 10, 24, big.ToNearestEven)
@@ -15,14 +15,31 @@ type MemberAccess struct {
 
 //TO-DO subscripting
 func (m *MemberAccess) Type(c *Context, expected ir.Type) ir.Type {
+	if IsString(m.Parent.ResolvedType()) {
+		// member function of string is resolved by checker
+		return m.ResolvedType()
+	}
+	if _, ok := m.Reference().(*Enum); ok {
+		// helper function of enum is resolved by checker
+		return m.ResolvedType()
+	}
+	if e, ok := m.Parent.Reference().(*Enum); ok {
+		return e.IRType
+	}
 	// parent could be: identifier, member_access, new, subscripting, this, base
 	if ident, ok := m.Parent.(*Identifier); ok {
 		_, obj, _ := c.FindSelector(ident.Name, m.Member.Name)
 		if obj != nil {
+			if t := c.ContentType(obj); t != nil {
+				return t
+			}
 			return obj.Type()
 		}
 
 	} else if _, ok := m.Parent.(*This); ok {
+		if c.Function.Struct != nil {
+			return c.Function.Struct.MemberType(m.Member.Name)
+		}
 		return c.Function.Class.MemberType(m.Member.Name)
 
 	} else if _, ok := m.Parent.(*Base); ok {
@@ -32,16 +49,28 @@ func (m *MemberAccess) Type(c *Context, expected ir.Type) ir.Type {
 		_, d := c.Program.FindDeclaration(n.Typ)
 		if class, ok := d.(*Class); ok {
 			return class.MemberType(m.Member.Name)
+		} else if s, ok := d.(*Struct); ok {
+			return s.MemberType(m.Member.Name)
 		}
 
 	} else if memberAccess, ok := m.Parent.(*MemberAccess); ok {
+		if m.static(c.Program) != nil {
+			// static member of class accessed by qualified class name
+			return m.ResolvedType()
+		}
 		parentType := memberAccess.Type(c, nil)
+		qualified := GetTypeUserData(parentType)
 		if s, ok := parentType.(*ir.StructType); ok {
-			if d, ok := c.Program.Declarations[s.TypeName]; ok {
+			qualified = s.TypeName
+		}
+		if qualified != "" {
+			if d, ok := c.Program.Declarations[qualified]; ok {
 				if class, ok := d.(*Class); ok {
 					return class.MemberType(m.Member.Name)
-				} else if _, ok := d.(*Enum); ok {
-					return ir.I32
+				} else if s, ok := d.(*Struct); ok {
+					return s.MemberType(m.Member.Name)
+				} else if e, ok := d.(*Enum); ok {
+					return e.IRType
 				}
 			}
 		}
@@ -51,6 +80,21 @@ func (m *MemberAccess) Type(c *Context, expected ir.Type) ir.Type {
 }
 
 func (m *MemberAccess) GenerateIR(c *Context, expected ir.Type) ir.Value {
+	if IsString(m.Parent.ResolvedType()) {
+		// string is passed as the first argument
+		return StringMember(c, c.AutoLoad(m.Parent.GenerateIR(c, m.Parent.ResolvedType())), m.Member.Name)
+	}
+	if e, ok := m.Parent.Reference().(*Enum); ok {
+		// member of enum is folded, arguments of helper function are added by invocation
+		if m.Member.Name == EnumFromName {
+			return ir.NewCall(e.fromNameFunction(c.Program))
+		}
+		return e.GetMember(m.Member.Name)
+	}
+	if e := EnumOf(c.Program, m.Parent.ResolvedType()); e != nil {
+		// value of enum is passed as the first argument
+		return ir.NewCall(e.nameFunction(c.Program), c.AutoLoad(m.Parent.GenerateIR(c, m.Parent.ResolvedType())))
+	}
 	var v ir.Value
 	var p ir.Value
 	var isMemberFunction bool
@@ -59,7 +103,11 @@ func (m *MemberAccess) GenerateIR(c *Context, expected ir.Type) ir.Value {
 
 	} else if _, ok := m.Parent.(*This); ok {
 		p = c.FindObject(ClassThis)
-		v, isMemberFunction = c.Function.Class.GetMember(c, p, m.Member.Name, true)
+		if c.Function.Struct != nil {
+			v, isMemberFunction = c.Function.Struct.GetMember(c, p, m.Member.Name)
+		} else {
+			v, isMemberFunction = c.Function.Class.GetMember(c, p, m.Member.Name, true)
+		}
 
 	} else if _, ok := m.Parent.(*Base); ok {
 		p = c.FindObject(ClassThis)
@@ -74,19 +122,34 @@ func (m *MemberAccess) GenerateIR(c *Context, expected ir.Type) ir.Value {
 			} else {
 				p, v, isMemberFunction = class.GetMemberFromCounter(c, p, m.Member.Name)
 			}
+		} else if s, ok := d.(*Struct); ok {
+			p = m.Parent.GenerateIR(c, nil)
+			v, isMemberFunction = s.GetMember(c, p, m.Member.Name)
 		}
 
 	} else if memberAccess, ok := m.Parent.(*MemberAccess); ok {
 		parentType := memberAccess.Type(c, nil)
+		qualified := GetTypeUserData(parentType)
 		if s, ok := parentType.(*ir.StructType); ok {
-			if d, ok := c.Program.Declarations[s.TypeName]; ok {
+			qualified = s.TypeName
+		}
+		if d := m.static(c.Program); d != nil {
+			// static member of class accessed by qualified class name
+			v = staticValue(d)
+		} else if qualified != "" {
+			if d, ok := c.Program.Declarations[qualified]; ok {
 				if class, ok := d.(*Class); ok {
 					p = m.Parent.GenerateIR(c, nil)
-					if IsBuiltinClass(s.TypeName) {
+					if IsBuiltinClass(qualified) {
+						p = c.AutoLoad(p)
 						v, isMemberFunction = class.GetMember(c, p, m.Member.Name, false)
 					} else {
 						p, v, isMemberFunction = class.GetMemberFromCounter(c, p, m.Member.Name)
 					}
+				} else if s, ok := d.(*Struct); ok {
+					// variable of struct is stored inline
+					p = m.Parent.GenerateIR(c, nil)
+					v, isMemberFunction = s.GetMember(c, p, m.Member.Name)
 				} else if enum, ok := d.(*Enum); ok {
 					v = enum.GetMember(m.Member.Name)
 				}
@@ -94,11 +157,14 @@ func (m *MemberAccess) GenerateIR(c *Context, expected ir.Type) ir.Value {
 		}
 	}
 	if v != nil && m.IsFunction(v) {
-		v = c.AutoLoad(v)
-		v = ir.NewCall(v)
-		if p != nil && isMemberFunction {
-			call := v.(*ir.InstCall)
-			call.Args = append(call.Args, p)
+		// function value is invoked as closure
+		if _, ok := v.(*ir.Func); ok || isMemberFunction {
+			v = c.AutoLoad(v)
+			v = ir.NewCall(v)
+			if p != nil && isMemberFunction {
+				call := v.(*ir.InstCall)
+				call.Args = append(call.Args, p)
+			}
 		}
 	}
 	if v == nil {
@@ -107,6 +173,69 @@ func (m *MemberAccess) GenerateIR(c *Context, expected ir.Type) ir.Value {
 	return v
 }
 
+// Overloads returns candidates if member is overloaded function
+func (m *MemberAccess) Overloads(c *Context) []*Function {
+	if IsString(m.Parent.ResolvedType()) || EnumOf(c.Program, m.Parent.ResolvedType()) != nil {
+		return nil
+	}
+	var class *Class
+	switch parent := m.Parent.(type) {
+	case *Identifier:
+		if t := c.ObjectType(parent.Name); t != nil {
+			if s := StructOf(c.Program, t); s != nil {
+				return s.Overloads(m.Member.Name)
+			}
+			class, _ = c.Program.FindQualified(GetTypeUserData(t)).(*Class)
+		} else if _, d := c.Program.FindSelector("", parent.Name); d != nil {
+			// could be a global variable
+			if v, ok := d.(*Variable); ok && v.IRVariable != nil {
+				if s := StructOf(c.Program, v.IRVariable.ContentType); s != nil {
+					return s.Overloads(m.Member.Name)
+				}
+				class, _ = c.Program.FindQualified(GetUserData(v.IRVariable)).(*Class)
+			} else if f, ok := m.static(c.Program).(*Function); ok {
+				return f.Overloads
+			}
+		} else if _, d := c.Program.FindSelector(parent.Name, m.Member.Name); d != nil {
+			if f, ok := d.(*Function); ok {
+				return f.Overloads
+			}
+		}
+
+	case *This:
+		if c.Function.Struct != nil {
+			return c.Function.Struct.Overloads(m.Member.Name)
+		}
+		class = c.Function.Class
+
+	case *Base:
+		if c.Function.Class != nil {
+			class = c.Function.Class.Parent
+		}
+
+	case *New:
+		_, d := c.Program.FindDeclaration(parent.Typ)
+		if s, ok := d.(*Struct); ok {
+			return s.Overloads(m.Member.Name)
+		}
+		class, _ = d.(*Class)
+
+	case *MemberAccess:
+		if f, ok := m.static(c.Program).(*Function); ok {
+			return f.Overloads
+		}
+		t := parent.Type(c, nil)
+		if s := StructOf(c.Program, t); s != nil {
+			return s.Overloads(m.Member.Name)
+		}
+		class, _ = c.Program.FindQualified(GetTypeUserData(t)).(*Class)
+	}
+	if class != nil {
+		return class.Overloads(m.Member.Name)
+	}
+	return nil
+}
+
 func (m *MemberAccess) IsFunction(v ir.Value) bool {
 	if t, ok := v.Type().(*ir.PointerType); ok {
 		if _, ok = t.ElemType.(*ir.FuncType); ok {
@@ -121,6 +250,16 @@ func (m *MemberAccess) IsFunction(v ir.Value) bool {
 }
 
 func (m *MemberAccess) IsConstant(p *Program) bool {
+	if IsString(m.Parent.ResolvedType()) {
+		return false
+	}
+	switch d := m.static(p).(type) {
+	case *Variable:
+		return d.Const && d.Value != nil && d.Value.IsConstant(p)
+
+	case *Function:
+		return true
+	}
 	if ident, ok := m.Parent.(*Identifier); ok {
 		_, d := p.FindSelector(ident.Name, m.Member.Name)
 		if d == nil {
@@ -150,6 +289,19 @@ func (m *MemberAccess) IsConstant(p *Program) bool {
 }
 
 func (m *MemberAccess) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
+	switch d := m.static(p).(type) {
+	case *Variable:
+		if d.Const && d.Value != nil {
+			return d.Value.GenerateConstIR(p, expected)
+		}
+
+	case *Function:
+		if d = SelectOverload(d, expected); d == nil {
+			p.Error(m.Position, fmt.Sprintf("ambiguous reference to overloaded function %s", m.Member.Name))
+			return nil
+		}
+		return p.FunctionValue(d.IRFunction)
+	}
 	if ident, ok := m.Parent.(*Identifier); ok {
 		_, d := p.FindSelector(ident.Name, m.Member.Name)
 		if d == nil {
@@ -163,8 +315,11 @@ func (m *MemberAccess) GenerateConstIR(p *Program, expected ir.Type) ir.Constant
 			return v.Value.GenerateConstIR(p, expected)
 		}
 		if f, ok := d.(*Function); ok {
-			//TO-DO use function as pointer
-			return f.IRFunction
+			if f = SelectOverload(f, expected); f == nil {
+				p.Error(m.Position, fmt.Sprintf("ambiguous reference to overloaded function %s", m.Member.Name))
+				return nil
+			}
+			return p.FunctionValue(f.IRFunction)
 		}
 	} else if memberAccess, ok := m.Parent.(*MemberAccess); ok {
 		if identifier, ok := memberAccess.Parent.(*Identifier); ok {
@@ -177,3 +332,21 @@ func (m *MemberAccess) GenerateConstIR(p *Program, expected ir.Type) ir.Constant
 	p.Error(m.Position, "invalid constant declaration")
 	return nil
 }
+
+// static returns static member of class if parent is class name, it could be qualified by namespace
+func (m *MemberAccess) static(p *Program) Declaration {
+	var d Declaration
+	switch parent := m.Parent.(type) {
+	case *Identifier:
+		_, d = p.FindSelector("", parent.Name)
+
+	case *MemberAccess:
+		if selector, ok := parent.Parent.(*Identifier); ok {
+			_, d = p.FindSelector(selector.Name, parent.Member.Name)
+		}
+	}
+	if class, ok := d.(*Class); ok {
+		return class.StaticMember(m.Member.Name)
+	}
+	return nil
+}
@@ -1,44 +1,61 @@
 package ast
 
 import (
+	"fmt"
+
 	"github.com/panda-foundation/go-compiler/ir"
 )
 
+// Initializer assigns value to variable of instance created by new "new object(){a = 1, b = 2}"
+type Initializer struct {
+	NodeBase
+	Name  *Identifier
+	Value Expression
+}
+
 type New struct {
 	ExpressionBase
-	Typ       *TypeName
-	Arguments *Arguments
-	HasOwner  bool
+	Typ          *TypeName
+	Arguments    *Arguments
+	Initializers []*Initializer
+	HasOwner     bool `json:"-"`
 }
 
 func (n *New) Type(c *Context, expected ir.Type) ir.Type {
-	return pointerType
+	qualified, d := c.Program.FindDeclaration(n.Typ)
+	if s, ok := d.(*Struct); ok {
+		return s.IRStruct
+	}
+	return CreateClassPointer(qualified)
 }
 
 func (n *New) GenerateIR(ctx *Context, expected ir.Type) ir.Value {
 	qualified, d := ctx.Program.FindDeclaration(n.Typ)
+	if s, ok := d.(*Struct); ok {
+		// instance of struct is a value on stack, it is copied when it is assigned
+		instance := s.CreateInstance(ctx)
+		n.initialize(ctx, s.MemberType, func(name string) ir.Value {
+			v, _ := s.GetMember(ctx, instance, name)
+			return v
+		})
+		return instance
+	}
 	if c, ok := d.(*Class); ok {
-		instance := c.CreateInstance(ctx, n.Arguments)
+		instance := c.CreateInstance(ctx, qualified, n.Arguments)
+		n.initialize(ctx, c.MemberType, func(name string) ir.Value {
+			v, _ := c.GetMember(ctx, instance, name, false)
+			return v
+		})
 		if IsBuiltinClass(qualified) {
 			if !n.HasOwner {
 				ctx.Function.BuiltinReleasePool = append(ctx.Function.BuiltinReleasePool, instance)
 			}
 			return instance
 		} else {
-			counterClass := ctx.Program.FindQualified(Counter).(*Class)
-			counter := counterClass.CreateInstance(ctx, nil)
+			counter := c.CreateShared(ctx, qualified, instance)
 			if !n.HasOwner {
 				ctx.Function.AutoReleasePool = append(ctx.Function.AutoReleasePool, counter)
 			}
-			// retain shared
-			call := ir.NewCall(retainShared, counter)
-			ctx.Block.AddInstruction(call)
-			// set object
-			object, _ := counterClass.GetMember(ctx, counter, "object", false)
-			ctx.Block.AddInstruction(ir.NewStore(instance, object))
-			// set destructor
-			destructor, _ := counterClass.GetMember(ctx, counter, "destructor", false)
-			ctx.Block.AddInstruction(ir.NewStore(c.IRFunctions[1], destructor))
 			return counter
 		}
 	}
@@ -46,10 +63,84 @@ func (n *New) GenerateIR(ctx *Context, expected ir.Type) ir.Value {
 	return nil
 }
 
-func (*New) IsConstant(p *Program) bool {
-	return false
+// initialize assigns values of initializers to variables of instance after constructor is called
+// memberType and member return type and address of variable of instance
+func (n *New) initialize(ctx *Context, memberType func(string) ir.Type, member func(string) ir.Value) {
+	for _, i := range n.Initializers {
+		if n, ok := i.Value.(*New); ok {
+			// instance is owned by variable
+			n.HasOwner = true
+		}
+		lambda, isLambda := i.Value.(*Lambda)
+		if isLambda {
+			lambda.HasOwner = true
+		}
+		t := memberType(i.Name.Name)
+		var value ir.Value
+		if i.Value.IsConstant(ctx.Program) {
+			value = i.Value.GenerateConstIR(ctx.Program, t)
+		} else {
+			value = i.Value.GenerateIR(ctx, t)
+		}
+		if value == nil {
+			ctx.Program.Error(i.Value.GetPosition(), "invalid expression")
+			continue
+		}
+		value, err := ImplicitCast(ctx, ctx.AutoLoad(value), t)
+		if err != nil {
+			ctx.Program.Error(i.Value.GetPosition(), err.Error())
+			continue
+		}
+		if IsClosure(t) && !isLambda {
+			// variable shares the closure
+			RetainClosure(ctx.Program, ctx.Block, value)
+		}
+		ctx.Block.AddInstruction(ir.NewStore(value, member(i.Name.Name)))
+	}
+}
+
+// IsConstant reports whether new creates a struct whose initializers are all constant
+func (n *New) IsConstant(p *Program) bool {
+	if _, ok := n.structOf(p); !ok {
+		return false
+	}
+	for _, i := range n.Initializers {
+		if !i.Value.IsConstant(p) {
+			return false
+		}
+	}
+	return true
 }
 
-func (*New) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
-	return nil
+func (n *New) GenerateConstIR(p *Program, expected ir.Type) ir.Constant {
+	s, ok := n.structOf(p)
+	if !ok || s.IRStruct == nil {
+		return nil
+	}
+	value := s.Default(p).(*ir.Struct)
+	fields := append([]ir.Constant{}, value.Fields...)
+	for _, i := range n.Initializers {
+		index, ok := s.VariableIndexes[i.Name.Name]
+		if !ok {
+			p.Error(i.Name.Position, fmt.Sprintf("%s is not variable of struct %s", i.Name.Name, s.Name.Name))
+			continue
+		}
+		t := s.IRStruct.Fields[index]
+		v := i.Value.GenerateConstIR(p, t)
+		if v == nil {
+			continue
+		}
+		if !v.Type().Equal(t) {
+			p.Error(i.Value.GetPosition(), fmt.Sprintf("cannot use %s as %s", MangleType(v.Type()), MangleType(t)))
+			continue
+		}
+		fields[index] = v
+	}
+	return ir.NewStruct(s.IRStruct, fields...)
+}
+
+func (n *New) structOf(p *Program) (*Struct, bool) {
+	_, d := p.FindDeclaration(n.Typ)
+	s, ok := d.(*Struct)
+	return s, ok
 }
@@ -12,11 +12,27 @@ type Subscripting struct {
 
 //TO-DO operator overload
 func (e *Subscripting) Type(c *Context, expected ir.Type) ir.Type {
-	//TO-DO
-	return nil
+	// string is subscripted by code point, others are rejected by checker until operator overload is supported
+	return e.ResolvedType()
 }
 
 func (e *Subscripting) GenerateIR(c *Context, expected ir.Type) ir.Value {
+	if IsString(e.Parent.ResolvedType()) {
+		// code point of string
+		s := c.AutoLoad(e.Parent.GenerateIR(c, nil))
+		var index ir.Value
+		if e.Element.IsConstant(c.Program) {
+			index = e.Element.GenerateConstIR(c.Program, ir.I32)
+		} else {
+			index = c.AutoLoad(e.Element.GenerateIR(c, ir.I32))
+			if !index.Type().Equal(ir.I32) {
+				index = ConvertNumber(c, index, ir.I32, false)
+			}
+		}
+		call := ir.NewCall(c.Program.stringCharAtFunction(), s, index)
+		c.Block.AddInstruction(call)
+		return call
+	}
 	//TO-DO
 	return nil
 }
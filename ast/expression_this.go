@@ -9,11 +9,14 @@ type This struct {
 }
 
 func (t *This) Type(c *Context, expected ir.Type) ir.Type {
+	if c.Function.Struct != nil {
+		return c.Function.Struct.IRStruct
+	}
 	return pointerType
 }
 
 func (t *This) GenerateIR(c *Context, expected ir.Type) ir.Value {
-	if c.Function.Class != nil {
+	if c.Function.Class != nil || c.Function.Struct != nil {
 		return c.FindObject(ClassThis)
 	}
 
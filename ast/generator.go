@@ -0,0 +1,263 @@
+package ast
+
+import (
+	"github.com/panda-foundation/go-compiler/ir"
+)
+
+// fields of generator frame, frame starts with closure record like environment of lambda
+const (
+	generatorState  = 2
+	generatorLocals = 3
+)
+
+// generatorFinished is state of generator which has returned, other states are index of yield where generator is resumed
+const generatorFinished = -1
+
+// generator is state of generator function when its resume function is generated
+// local variables of resume function are saved to frame when generator is suspended, and restored when it is resumed
+type generator struct {
+	frame   *ir.StructType
+	env     ir.Value
+	out     ir.Value
+	element ir.Type
+	suspend *ir.Block
+	resumes []*ir.Block
+}
+
+// GeneratorType returns type of generator which yields values of type t
+// generator is function value, it stores the next value to its argument, and returns false when it is finished
+func GeneratorType(t ir.Type) ir.Type {
+	return ir.NewPointerType(ir.NewFuncType(ir.I1, ir.NewPointerType(t)))
+}
+
+// GeneratorElement returns type of values yielded by generator, nil is returned if t is not type of generator
+func GeneratorElement(t ir.Type) ir.Type {
+	if !IsClosure(t) {
+		return nil
+	}
+	f := t.(*ir.PointerType).ElemType.(*ir.FuncType)
+	if f.Variadic || len(f.Params) != 1 || !ir.IsBool(f.RetType) {
+		return nil
+	}
+	if p, ok := f.Params[0].(*ir.PointerType); ok {
+		return p.ElemType
+	}
+	return nil
+}
+
+// Yields reports whether block contains yield statement, yield in lambda belongs to lambda itself
+func Yields(b *Block) bool {
+	yields := false
+	Inspect(b, func(n Node) bool {
+		switch n.(type) {
+		case *Yield:
+			yields = true
+
+		case *Lambda:
+			return false
+		}
+		return !yields
+	}, nil)
+	return yields
+}
+
+// generateGenerator generates function which creates generator, and resume function which runs body until next yield
+// parameters are saved in frame of generator, class instances and closures in parameters are shared by generator
+func (f *Function) generateGenerator(p *Program) {
+	qualified := f.IRFunction.Name()
+	g := &generator{
+		frame:   ir.NewStructType(pointerType, pointerType, ir.I32),
+		element: f.ReturnType.Type(p),
+	}
+	p.IRModule.NewTypeDef(qualified+".frame", g.frame)
+
+	env := ir.NewParam(pointerType)
+	env.LocalName = ClosureEnv
+	out := ir.NewParam(ir.NewPointerType(g.element))
+	out.LocalName = "value"
+	resume := &Function{}
+	resume.Position = f.Position
+	resume.Name = f.Name
+	resume.Body = f.Body
+	resume.generator = g
+	resume.IRParams = []*ir.Param{env, out}
+	resume.IRFunction = p.IRModule.NewFunc(qualified+".resume", ir.I1, env, out)
+	resume.IREntry = resume.IRFunction.NewBlock(FunctionEntry)
+	resume.IRBody = resume.IRFunction.NewBlock(FunctionBody)
+	resume.IRExit = resume.IRFunction.NewBlock(FunctionExit)
+	g.suspend = resume.IRFunction.NewBlock("")
+	g.out = out
+
+	frame := ir.NewBitCast(env, ir.NewPointerType(g.frame))
+	resume.IREntry.AddInstruction(frame)
+	g.env = frame
+
+	c := NewContext(p)
+	c.Function = resume
+	for _, param := range f.IRParams {
+		alloca := ir.NewAlloca(param.Typ)
+		CopyUserData(param, alloca)
+		resume.IREntry.AddInstruction(alloca)
+		if err := c.AddObject(param.LocalName, alloca); err != nil {
+			p.Error(f.Position, err.Error())
+		}
+	}
+	c.Block = resume.IRBody
+	f.Body.GenerateIR(c)
+	if !c.Block.Terminated {
+		c.Block.AddInstruction(ir.NewBr(resume.IRExit))
+	}
+
+	// all local variables are kept in frame
+	resume.spill()
+	var locals []*ir.InstAlloca
+	indexes := make(map[ir.Value]int)
+	for _, inst := range resume.IREntry.Insts {
+		if alloca, ok := inst.(*ir.InstAlloca); ok {
+			indexes[alloca] = generatorLocals + len(locals)
+			locals = append(locals, alloca)
+			g.frame.Fields = append(g.frame.Fields, alloca.ElemType)
+		}
+	}
+
+	// restore local variables, then continue from where generator is suspended
+	for _, alloca := range locals {
+		load := ir.NewLoad(alloca.ElemType, g.field(resume.IREntry, frame, indexes[alloca]))
+		resume.IREntry.AddInstruction(load)
+		resume.IREntry.AddInstruction(ir.NewStore(load, alloca))
+	}
+	state := ir.NewLoad(ir.I32, g.field(resume.IREntry, frame, generatorState))
+	resume.IREntry.AddInstruction(state)
+	finished := resume.IRFunction.NewBlock("")
+	finished.AddInstruction(ir.NewRet(ir.False))
+	cases := []*ir.Case{ir.NewCase(ir.NewInt(ir.I32, 0), resume.IRBody)}
+	for i, block := range g.resumes {
+		cases = append(cases, ir.NewCase(ir.NewInt(ir.I32, int64(i+1)), block))
+	}
+	resume.IREntry.AddInstruction(ir.NewSwitch(state, finished, cases...))
+
+	// save local variables
+	for _, alloca := range locals {
+		load := ir.NewLoad(alloca.ElemType, alloca)
+		g.suspend.AddInstruction(load)
+		g.suspend.AddInstruction(ir.NewStore(load, g.field(g.suspend, frame, indexes[alloca])))
+	}
+	g.suspend.AddInstruction(ir.NewRet(ir.True))
+
+	resume.releasePools(p, resume.IRExit)
+	resume.IRExit.AddInstruction(ir.NewStore(ir.NewInt(ir.I32, generatorFinished), g.field(resume.IRExit, frame, generatorState)))
+	resume.IRExit.AddInstruction(ir.NewRet(ir.False))
+
+	var pooled []int
+	for _, obj := range resume.AutoReleasePool {
+		if index, ok := indexes[obj]; ok {
+			pooled = append(pooled, index)
+		}
+	}
+	destructor := g.generateDestructor(p, qualified, pooled, len(f.IRParams))
+
+	// generator is owned by caller like closure returned by function
+	entry := f.IRFunction.NewBlock(FunctionEntry)
+	ctx := NewContext(p)
+	ctx.Function = f
+	ctx.Block = entry
+	address, counter := CreateEnvironment(ctx, g.frame, resume.IRFunction, destructor)
+	entry.AddInstruction(ir.NewCall(retainShared, counter))
+	created := ir.NewBitCast(address, ir.NewPointerType(g.frame))
+	entry.AddInstruction(created)
+	for i, param := range f.IRParams {
+		retainValue(p, entry, param)
+		entry.AddInstruction(ir.NewStore(param, g.field(entry, created, generatorLocals+i)))
+	}
+	closure := ir.NewBitCast(address, f.IRFunction.Sig.RetType)
+	entry.AddInstruction(closure)
+	entry.AddInstruction(ir.NewRet(closure))
+}
+
+// spill stores values in release pools to local variables, so they are kept in frame of generator
+func (f *Function) spill() {
+	f.AutoReleasePool = f.spillValues(f.AutoReleasePool)
+	f.BuiltinReleasePool = f.spillValues(f.BuiltinReleasePool)
+}
+
+func (f *Function) spillValues(values []ir.Value) []ir.Value {
+	var result []ir.Value
+	for _, v := range values {
+		inst, ok := v.(ir.Instruction)
+		if _, isAlloca := v.(*ir.InstAlloca); isAlloca || !ok {
+			result = append(result, v)
+			continue
+		}
+		alloca := ir.NewAlloca(v.Type())
+		CopyUserData(v, alloca)
+		f.IREntry.InsertAlloca(alloca)
+		for _, b := range f.IRFunction.Blocks {
+			for i, existing := range b.Insts {
+				if existing == inst {
+					b.Insts = append(b.Insts[:i+1], append([]ir.Instruction{ir.NewStore(v, alloca)}, b.Insts[i+1:]...)...)
+					break
+				}
+			}
+		}
+		result = append(result, alloca)
+	}
+	return result
+}
+
+func (g *generator) field(b *ir.Block, frame ir.Value, index int) ir.Value {
+	field := ir.NewGetElementPtr(g.frame, frame, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+	b.AddInstruction(field)
+	return field
+}
+
+// generateDestructor generates function which releases parameters saved in frame
+// local variables are released by generator when it is finished, they are released by destructor if generator is not finished
+func (g *generator) generateDestructor(p *Program, qualified string, pooled []int, params int) *ir.Func {
+	param := ir.NewParam(pointerType)
+	param.LocalName = ClosureEnv
+	f := p.IRModule.NewFunc(qualified+"."+Destructor, ir.Void, param)
+	entry := f.NewBlock(FunctionEntry)
+	release := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	frame := ir.NewBitCast(param, ir.NewPointerType(g.frame))
+	entry.AddInstruction(frame)
+	state := ir.NewLoad(ir.I32, g.field(entry, frame, generatorState))
+	entry.AddInstruction(state)
+	finished := ir.NewICmp(ir.IPredEQ, state, ir.NewInt(ir.I32, generatorFinished))
+	entry.AddInstruction(finished)
+	entry.AddInstruction(ir.NewCondBr(finished, exit, release))
+
+	for _, index := range pooled {
+		load := ir.NewLoad(g.frame.Fields[index], g.field(release, frame, index))
+		release.AddInstruction(load)
+		releaseValue(p, release, load)
+	}
+	release.AddInstruction(ir.NewBr(exit))
+
+	for i := 0; i < params; i++ {
+		load := ir.NewLoad(g.frame.Fields[generatorLocals+i], g.field(exit, frame, generatorLocals+i))
+		exit.AddInstruction(load)
+		releaseValue(p, exit, load)
+	}
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// retainValue shares class instance, string or closure, other values are ignored
+func retainValue(p *Program, b *ir.Block, value ir.Value) {
+	if isCounted(p, value.Type()) {
+		b.AddInstruction(ir.NewCall(retainShared, value))
+	} else if IsClosure(value.Type()) {
+		RetainClosure(p, b, value)
+	}
+}
+
+// releaseValue releases class instance, string or closure, other values are ignored
+func releaseValue(p *Program, b *ir.Block, value ir.Value) {
+	if isCounted(p, value.Type()) {
+		b.AddInstruction(ir.NewCall(releaseShared, value))
+	} else if IsClosure(value.Type()) {
+		ReleaseClosure(p, b, value)
+	}
+}
@@ -0,0 +1,93 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResolveInheritance reports inheritance cycles of classes and interfaces with the full inheritance chain
+// cycle is broken at the parent which closes it, so classes and interfaces in the cycle could still be generated
+func (p *Program) ResolveInheritance() {
+	names := make(map[Declaration]string)
+	for qualified, d := range p.Declarations {
+		if name, ok := names[d]; !ok || len(qualified) < len(name) {
+			names[d] = qualified
+		}
+	}
+
+	modules := make(map[*Interface]*Module)
+	for _, m := range p.SortedModules() {
+		p.Module = m
+		for _, i := range m.Interfaces {
+			modules[i] = m
+		}
+		for _, c := range m.Classes {
+			chain := []string{names[c]}
+			visited := map[*Class]bool{c: true}
+			for current := c.Parent; current != nil; current = current.Parent {
+				chain = append(chain, names[current])
+				if current == c {
+					p.Error(p.parentPosition(c.Parents, c.Parent), fmt.Sprintf("inheritance cycle: %s", strings.Join(chain, " -> ")))
+					c.Parent = nil
+					break
+				}
+				if visited[current] {
+					// cycle which does not include class is reported by class in it
+					break
+				}
+				visited[current] = true
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make(map[*Interface]int)
+	var stack []*Interface
+	var visit func(i *Interface)
+	visit = func(i *Interface) {
+		states[i] = visiting
+		stack = append(stack, i)
+		var parents []*Interface
+		for _, parent := range i.Interfaces {
+			switch states[parent] {
+			case unvisited:
+				visit(parent)
+
+			case visiting:
+				chain := []string{names[parent]}
+				for j := len(stack) - 1; stack[j] != parent; j-- {
+					chain = append([]string{names[stack[j]]}, chain...)
+				}
+				chain = append([]string{names[parent]}, chain...)
+				p.Module = modules[i]
+				p.Error(p.parentPosition(i.Parents, parent), fmt.Sprintf("inheritance cycle: %s", strings.Join(chain, " -> ")))
+				continue
+			}
+			parents = append(parents, parent)
+		}
+		i.Interfaces = parents
+		stack = stack[:len(stack)-1]
+		states[i] = visited
+	}
+	for _, m := range p.SortedModules() {
+		for _, i := range m.Interfaces {
+			if states[i] == unvisited {
+				visit(i)
+			}
+		}
+	}
+}
+
+// parentPosition returns position of parent type which refers to declaration, current module must be where parents are declared
+func (p *Program) parentPosition(parents []*TypeName, d Declaration) int {
+	for _, parent := range parents {
+		if _, found := p.FindDeclaration(parent); found == d {
+			return parent.Position
+		}
+	}
+	return 0
+}
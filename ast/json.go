@@ -0,0 +1,572 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+// jsonField is member variable of json class which is encoded as key of object
+type jsonField struct {
+	variable  *Variable
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+func init() {
+	RegisterAttributeHandler(JSON, TargetClass|TargetMemberVariable, jsonAttribute)
+
+	RegisterComplierFunction(JSON, "encode", jsonEncode)
+	RegisterComplierFunctionType(JSON, "encode", func(p *Program, i *Invocation) (ir.Type, error) {
+		if err := intrinsicArguments(JSON, i, 1); err != nil {
+			return nil, err
+		}
+		t := i.Arguments.Arguments[0].ResolvedType()
+		if attributedInstance(p, t, JSON) == nil {
+			return nil, fmt.Errorf("argument 1 of %s.encode must be instance of json class, but found %s", JSON, MangleType(t))
+		}
+		return pointerType, nil
+	})
+
+	RegisterComplierFunction(JSON, "decode", jsonDecode)
+	RegisterComplierFunctionType(JSON, "decode", func(p *Program, i *Invocation) (ir.Type, error) {
+		if err := intrinsicArguments(JSON, i, 2); err != nil {
+			return nil, err
+		}
+		if err := reflectPointer(p, i.Arguments.Arguments[0]); err != nil {
+			return nil, fmt.Errorf("argument 1 of %s.decode %s", JSON, err.Error())
+		}
+		class := attributedClass(p, i.Arguments.Arguments[1], JSON)
+		if class == nil {
+			return nil, fmt.Errorf("argument 2 of %s.decode must be name of json class", JSON)
+		}
+		return CreateClassPointer(class.IRStruct.TypeName), nil
+	})
+
+	RegisterComplierFunction(JSON, "error", jsonLastError)
+	RegisterComplierFunctionType(JSON, "error", func(p *Program, i *Invocation) (ir.Type, error) {
+		if err := intrinsicArguments(JSON, i, 0); err != nil {
+			return nil, err
+		}
+		return pointerType, nil
+	})
+}
+
+// @json encodes class to json object, member variables must be numbers, bools, enums, strings or instances of json classes
+// @json(name = "key", omit_empty = true, skip = true) changes how member variable is encoded
+func jsonAttribute(p *Program, d Declaration, a *Attribute) {
+	switch n := d.(type) {
+	case *Class:
+		noArguments(p, d, a)
+		names := make(map[string]bool)
+		for _, field := range jsonFields(n) {
+			t := n.IRStruct.Fields[field.index]
+			if !isJSONType(p, t) {
+				p.Error(field.variable.Position, fmt.Sprintf("variable %s of json class %s cannot be encoded, its type is %s", field.variable.Name.Name, n.Name.Name, MangleType(t)))
+			}
+			if names[field.name] {
+				p.Error(field.variable.Position, fmt.Sprintf("duplicated json name %s of class %s", field.name, n.Name.Name))
+			}
+			names[field.name] = true
+		}
+
+	case *Variable:
+		if a.Text != "" {
+			p.Error(a.Position, "attribute @json does not accept text")
+		}
+		var names []string
+		for name := range a.Values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := a.Values[name]
+			switch name {
+			case JSONName:
+				if value.Typ != token.STRING || value.Value == `""` {
+					p.Error(value.Position, fmt.Sprintf("argument %s of attribute @json must be string", name))
+				}
+
+			case JSONOmitEmpty, JSONSkip:
+				if value.Typ != token.BOOL {
+					p.Error(value.Position, fmt.Sprintf("argument %s of attribute @json must be bool", name))
+				}
+
+			default:
+				p.Error(value.Position, fmt.Sprintf("unknown argument %s of attribute @json", name))
+			}
+		}
+	}
+}
+
+// jsonFields returns member variables of class and its parents in struct order, skipped variables are excluded
+func jsonFields(c *Class) []*jsonField {
+	var fields []*jsonField
+	for current := c; current != nil; current = current.Parent {
+		for _, v := range current.Variables {
+			if v.IsStatic() || jsonFlag(v, JSONSkip) {
+				continue
+			}
+			field := &jsonField{
+				variable:  v,
+				index:     c.VariableIndexes[v.Name.Name],
+				name:      v.Name.Name,
+				omitEmpty: jsonFlag(v, JSONOmitEmpty),
+			}
+			if l := v.GetAttributeValue(JSON, JSONName); l != nil && l.Typ == token.STRING {
+				if name, err := strconv.Unquote(l.Value); err == nil && name != "" {
+					field.name = name
+				}
+			}
+			fields = append(fields, field)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].index < fields[j].index
+	})
+	return fields
+}
+
+func jsonFlag(v *Variable, name string) bool {
+	l := v.GetAttributeValue(JSON, name)
+	return l != nil && l.Typ == token.BOOL && l.Value == "true"
+}
+
+func isJSONType(p *Program, t ir.Type) bool {
+	return binaryFieldSize(t) > 0 || IsString(t) || attributedInstance(p, t, JSON) != nil
+}
+
+// resizeInt truncates or extends integer to type t, enum values are i32 in type info
+func resizeInt(b *ir.Block, value ir.Value, t *ir.IntType) ir.Value {
+	from := value.Type().(*ir.IntType)
+	var inst ir.Instruction
+	switch {
+	case from.BitSize > t.BitSize:
+		inst = ir.NewTrunc(value, t)
+
+	case from.BitSize < t.BitSize && from.Unsigned:
+		inst = ir.NewZExt(value, t)
+
+	case from.BitSize < t.BitSize:
+		inst = ir.NewSExt(value, t)
+
+	default:
+		return value
+	}
+	b.AddInstruction(inst)
+	return inst.(ir.Value)
+}
+
+// jsonEncode returns new json text of instance which should be freed, it is null if instance cannot be encoded
+func jsonEncode(c *Context, i *Invocation) ir.Value {
+	instance := reflectArgument(c, i, 0)
+	class := attributedInstance(c.Program, instance.Type(), JSON)
+	call := ir.NewCall(c.Program.jsonEncodeFunction(class), instance)
+	c.Block.AddInstruction(call)
+	return call
+}
+
+// jsonDecode returns instance of class from json text, it is null if text is invalid
+func jsonDecode(c *Context, i *Invocation) ir.Value {
+	text := reflectArgument(c, i, 0)
+	class := attributedClass(c.Program, i.Arguments.Arguments[1], JSON)
+	call := ir.NewCall(c.Program.jsonDecodeFunction(class), text)
+	call.Typ = CreateClassPointer(class.IRStruct.TypeName)
+	c.Block.AddInstruction(call)
+	return call
+}
+
+// jsonLastError returns error message of the last encoding or decoding, it is null if there is no error
+func jsonLastError(c *Context, i *Invocation) ir.Value {
+	load := ir.NewLoad(pointerType, c.Program.jsonError())
+	c.Block.AddInstruction(load)
+	return load
+}
+
+// jsonEncodeFunction generates function which encodes instance to a new null terminated text
+func (p *Program) jsonEncodeFunction(c *Class) *ir.Func {
+	instance := newParam("instance", pointerType)
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".json.encode", pointerType, instance)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	succeeded := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	stream := newStream(entry)
+	p.clearJSONError(entry)
+	entry.AddInstruction(ir.NewCall(p.jsonWriteObjectFunction(c), stream, instance))
+	p.jsonWriteChar(entry, stream, 0)
+	isFailed := loadStreamMember(entry, stream, streamFailed)
+	entry.AddInstruction(ir.NewCondBr(isFailed, failed, succeeded))
+
+	succeeded.AddInstruction(ir.NewRet(loadStreamMember(succeeded, stream, streamData)))
+
+	failed.AddInstruction(ir.NewCall(p.jsonPrependFunction(), stream, p.AddString("$")))
+	failed.AddInstruction(ir.NewCall(free, loadStreamMember(failed, stream, streamData)))
+	failed.AddInstruction(ir.NewRet(ir.NewNull(pointerType)))
+	return f
+}
+
+// jsonDecodeFunction generates function which decodes instance from null terminated text, text must only contain one value
+func (p *Program) jsonDecodeFunction(c *Class) *ir.Func {
+	text := newParam("text", pointerType)
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".json.decode", pointerType, text)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	null := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+	rest := f.NewBlock("")
+	trailing := f.NewBlock("")
+	succeeded := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	stream := newStream(entry)
+	p.clearJSONError(entry)
+	isNull := ir.NewICmp(ir.IPredEQ, text, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, null, body))
+
+	p.jsonFail(null, stream, ": text is null")
+	null.AddInstruction(ir.NewCall(p.jsonPrependFunction(), stream, p.AddString("$")))
+	null.AddInstruction(ir.NewRet(ir.NewNull(pointerType)))
+
+	length := ir.NewCall(p.jsonLengthFunction(), text)
+	body.AddInstruction(length)
+	body.AddInstruction(ir.NewStore(text, streamMember(body, stream, streamData)))
+	body.AddInstruction(ir.NewStore(length, streamMember(body, stream, streamSize)))
+	instance := ir.NewCall(p.jsonReadObjectFunction(c), stream)
+	body.AddInstruction(instance)
+	body.AddInstruction(ir.NewCondBr(loadStreamMember(body, stream, streamFailed), failed, rest))
+
+	rest.AddInstruction(ir.NewCall(p.jsonSpaceFunction(), stream))
+	position := loadStreamMember(rest, stream, streamPosition)
+	end := ir.NewICmp(ir.IPredEQ, position, length)
+	rest.AddInstruction(end)
+	rest.AddInstruction(ir.NewCondBr(end, succeeded, trailing))
+
+	p.jsonFail(trailing, stream, ": unexpected text after value")
+	trailing.AddInstruction(ir.NewBr(failed))
+
+	succeeded.AddInstruction(ir.NewRet(instance))
+
+	failed.AddInstruction(ir.NewCall(p.jsonPrependFunction(), stream, p.AddString("$")))
+	failed.AddInstruction(ir.NewCall(releaseShared, instance))
+	failed.AddInstruction(ir.NewRet(ir.NewNull(pointerType)))
+	return f
+}
+
+// jsonWriteObjectFunction generates function which writes instance as object, member variables of class instances are written by their declared classes
+func (p *Program) jsonWriteObjectFunction(c *Class) *ir.Func {
+	stream := streamParam()
+	instance := newParam("instance", pointerType)
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".json.write", ir.Void, stream, instance)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	null := f.NewBlock("")
+	enter := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+	exit := f.NewBlock(FunctionExit)
+
+	first := ir.NewAlloca(ir.I1)
+	entry.AddInstruction(first)
+	entry.AddInstruction(ir.NewStore(ir.True, first))
+	isNull := ir.NewICmp(ir.IPredEQ, instance, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, null, enter))
+
+	p.jsonWriteText(null, stream, "null")
+	null.AddInstruction(ir.NewRet(nil))
+
+	entered := ir.NewCall(p.jsonEnterFunction(), stream)
+	enter.AddInstruction(entered)
+	enter.AddInstruction(ir.NewCondBr(entered, body, exit))
+
+	object := ir.NewBitCast(loadCounterObject(p, body, instance), ir.NewPointerType(c.IRStruct))
+	body.AddInstruction(object)
+	p.jsonWriteChar(body, stream, '{')
+	current := body
+	for _, field := range jsonFields(c) {
+		t := c.IRStruct.Fields[field.index]
+		write := f.NewBlock("")
+		failed := f.NewBlock("")
+		next := f.NewBlock("")
+
+		pointer := ir.NewGetElementPtr(c.IRStruct, object, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(field.index)))
+		current.AddInstruction(pointer)
+		value := ir.NewLoad(t, pointer)
+		current.AddInstruction(value)
+		if field.omitEmpty {
+			current.AddInstruction(ir.NewCondBr(p.jsonEmpty(current, value, t), next, write))
+		} else {
+			current.AddInstruction(ir.NewBr(write))
+		}
+
+		key, _ := json.Marshal(field.name)
+		write.AddInstruction(ir.NewCall(p.jsonWriteKeyFunction(), stream, first, p.AddString(string(key)+":"), ir.NewInt(ir.I32, int64(len(key)+1))))
+		p.jsonWriteValue(write, stream, value, t)
+		write.AddInstruction(ir.NewCondBr(loadStreamMember(write, stream, streamFailed), failed, next))
+
+		failed.AddInstruction(ir.NewCall(p.jsonPrependFunction(), stream, p.AddString("."+field.name)))
+		failed.AddInstruction(ir.NewRet(nil))
+		current = next
+	}
+	p.jsonWriteChar(current, stream, '}')
+	current.AddInstruction(ir.NewCall(p.jsonLeaveFunction(), stream))
+	current.AddInstruction(ir.NewRet(nil))
+
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonEmpty returns true if value is 0, false, null or empty string
+func (p *Program) jsonEmpty(b *ir.Block, value ir.Value, t ir.Type) ir.Value {
+	if IsString(t) {
+		size := ir.NewCall(p.stringSizeFunction(), value)
+		b.AddInstruction(size)
+		value, t = size, ir.I32
+	}
+	var empty ir.Instruction
+	switch t := t.(type) {
+	case *ir.IntType:
+		empty = ir.NewICmp(ir.IPredEQ, value, ir.NewInt(t, 0))
+	case *ir.FloatType:
+		empty = ir.NewFCmp(ir.FPredOEQ, value, ir.NewFloat(t, 0))
+	case *ir.PointerType:
+		empty = ir.NewICmp(ir.IPredEQ, value, ir.NewNull(t))
+	}
+	b.AddInstruction(empty)
+	return empty.(ir.Value)
+}
+
+func (p *Program) jsonWriteValue(b *ir.Block, stream ir.Value, value ir.Value, t ir.Type) {
+	if e := EnumOf(p, t); e != nil {
+		b.AddInstruction(ir.NewCall(p.jsonWriteEnumFunction(), stream, e.IRTypeInfo, resizeInt(b, value, ir.I32)))
+		return
+	}
+	if class := attributedInstance(p, t, JSON); class != nil {
+		b.AddInstruction(ir.NewCall(p.jsonWriteObjectFunction(class), stream, value))
+		return
+	}
+	switch t := t.(type) {
+	case *ir.IntType:
+		if t.BitSize == 1 {
+			text := ir.NewSelect(value, p.AddString("true"), p.AddString("false"))
+			b.AddInstruction(text)
+			length := ir.NewSelect(value, ir.NewInt(ir.I32, 4), ir.NewInt(ir.I32, 5))
+			b.AddInstruction(length)
+			b.AddInstruction(ir.NewCall(p.jsonWriteFunction(), stream, text, length))
+			return
+		}
+		if t.BitSize < 64 {
+			var extended ir.Instruction = ir.NewSExt(value, ir.I64)
+			if t.Unsigned {
+				extended = ir.NewZExt(value, ir.I64)
+			}
+			b.AddInstruction(extended)
+			value = extended.(ir.Value)
+		}
+		b.AddInstruction(ir.NewCall(p.jsonWriteIntFunction(), stream, value, ir.NewBool(t.Unsigned)))
+
+	case *ir.FloatType:
+		// digits are enough to restore the same number
+		format := "%.17g"
+		if t.Kind == ir.FloatKindFloat {
+			extended := ir.NewFPExt(value, ir.Float64)
+			b.AddInstruction(extended)
+			value = extended
+			format = "%.9g"
+		}
+		b.AddInstruction(ir.NewCall(p.jsonWriteDoubleFunction(), stream, value, p.AddString(format)))
+
+	case *ir.PointerType:
+		// null string is written as empty string
+		data := ir.NewCall(p.stringDataFunction(), value)
+		b.AddInstruction(data)
+		b.AddInstruction(ir.NewCall(p.jsonWriteStringFunction(), stream, data))
+	}
+}
+
+// jsonReadObjectFunction generates function which reads object or null to new instance, unknown keys are skipped and missing keys keep default values
+func (p *Program) jsonReadObjectFunction(c *Class) *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".json.read", pointerType, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	object := f.NewBlock("")
+	enter := f.NewBlock("")
+	create := f.NewBlock("")
+	key := f.NewBlock("")
+	colon := f.NewBlock("")
+	match := f.NewBlock("")
+	nextKey := f.NewBlock("")
+	comma := f.NewBlock("")
+	closed := f.NewBlock("")
+	separatorFailed := f.NewBlock("")
+	keyFailed := f.NewBlock("")
+	failed := f.NewBlock("")
+	null := f.NewBlock("")
+
+	entry.AddInstruction(ir.NewCondBr(p.jsonMatch(entry, stream, "null"), null, object))
+
+	object.AddInstruction(ir.NewCondBr(p.jsonExpect(object, stream, '{', ": expected object"), enter, null))
+
+	entered := ir.NewCall(p.jsonEnterFunction(), stream)
+	enter.AddInstruction(entered)
+	enter.AddInstruction(ir.NewCondBr(entered, create, null))
+
+	ctx := NewContext(p)
+	ctx.Block = create
+	instance := c.CreateShared(ctx, c.IRStruct.TypeName, c.Allocate(p, create))
+	char := ir.NewCall(p.jsonSpaceFunction(), stream)
+	create.AddInstruction(char)
+	empty := ir.NewICmp(ir.IPredEQ, char, ir.NewInt(ir.I8, '}'))
+	create.AddInstruction(empty)
+	create.AddInstruction(ir.NewCondBr(empty, closed, key))
+
+	name := ir.NewCall(p.jsonReadStringFunction(), stream)
+	key.AddInstruction(name)
+	isNull := ir.NewICmp(ir.IPredEQ, name, ir.NewNull(pointerType))
+	key.AddInstruction(isNull)
+	key.AddInstruction(ir.NewCondBr(isNull, failed, colon))
+
+	colon.AddInstruction(ir.NewCondBr(p.jsonExpect(colon, stream, ':', ": expected :"), match, keyFailed))
+
+	current := match
+	objectPointer := ir.NewBitCast(loadCounterObject(p, current, instance), ir.NewPointerType(c.IRStruct))
+	current.AddInstruction(objectPointer)
+	for _, field := range jsonFields(c) {
+		read := f.NewBlock("")
+		fieldFailed := f.NewBlock("")
+		next := f.NewBlock("")
+
+		equal := ir.NewCall(p.stringEqualFunction(), name, p.AddString(field.name))
+		current.AddInstruction(equal)
+		current.AddInstruction(ir.NewCondBr(equal, read, next))
+
+		pointer := ir.NewGetElementPtr(c.IRStruct, objectPointer, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(field.index)))
+		read.AddInstruction(pointer)
+		read = p.jsonReadValue(f, read, stream, pointer, c.IRStruct.Fields[field.index])
+		read.AddInstruction(ir.NewCondBr(loadStreamMember(read, stream, streamFailed), fieldFailed, nextKey))
+
+		fieldFailed.AddInstruction(ir.NewCall(p.jsonPrependFunction(), stream, p.AddString("."+field.name)))
+		fieldFailed.AddInstruction(ir.NewBr(keyFailed))
+		current = next
+	}
+	unknownFailed := f.NewBlock("")
+	current.AddInstruction(ir.NewCall(p.jsonSkipFunction(), stream))
+	current.AddInstruction(ir.NewCondBr(loadStreamMember(current, stream, streamFailed), unknownFailed, nextKey))
+
+	unknownFailed.AddInstruction(ir.NewCall(p.jsonPrependFunction(), stream, name))
+	unknownFailed.AddInstruction(ir.NewCall(p.jsonPrependFunction(), stream, p.AddString(".")))
+	unknownFailed.AddInstruction(ir.NewBr(keyFailed))
+
+	nextKey.AddInstruction(ir.NewCall(free, name))
+	separator := ir.NewCall(p.jsonSpaceFunction(), stream)
+	nextKey.AddInstruction(separator)
+	nextKey.AddInstruction(ir.NewSwitch(separator, separatorFailed,
+		ir.NewCase(ir.NewInt(ir.I8, ','), comma),
+		ir.NewCase(ir.NewInt(ir.I8, '}'), closed)))
+
+	skipChar(comma, stream)
+	comma.AddInstruction(ir.NewBr(key))
+
+	skipChar(closed, stream)
+	closed.AddInstruction(ir.NewCall(p.jsonLeaveFunction(), stream))
+	closed.AddInstruction(ir.NewRet(instance))
+
+	p.jsonFail(separatorFailed, stream, ": expected , or }")
+	separatorFailed.AddInstruction(ir.NewBr(failed))
+
+	keyFailed.AddInstruction(ir.NewCall(free, name))
+	keyFailed.AddInstruction(ir.NewBr(failed))
+
+	failed.AddInstruction(ir.NewCall(releaseShared, instance))
+	failed.AddInstruction(ir.NewRet(ir.NewNull(pointerType)))
+
+	null.AddInstruction(ir.NewRet(ir.NewNull(pointerType)))
+	return f
+}
+
+// jsonReadValue reads value of member variable and stores it, it returns the block after value is stored
+func (p *Program) jsonReadValue(f *ir.Func, b *ir.Block, stream ir.Value, pointer ir.Value, t ir.Type) *ir.Block {
+	var value ir.Value
+	if e := EnumOf(p, t); e != nil {
+		call := ir.NewCall(p.jsonReadEnumFunction(), stream, e.IRTypeInfo)
+		b.AddInstruction(call)
+		value = resizeInt(b, call, e.IRType)
+	} else if class := attributedInstance(p, t, JSON); class != nil {
+		call := ir.NewCall(p.jsonReadObjectFunction(class), stream)
+		b.AddInstruction(call)
+		old := ir.NewLoad(t, pointer)
+		b.AddInstruction(old)
+		b.AddInstruction(ir.NewCall(releaseShared, old))
+		value = call
+	} else {
+		switch t := t.(type) {
+		case *ir.IntType:
+			if t.BitSize == 1 {
+				call := ir.NewCall(p.jsonReadBoolFunction(), stream)
+				b.AddInstruction(call)
+				value = call
+				break
+			}
+			min, max := int64(0), int64(-1)
+			if t.Unsigned && t.BitSize < 64 {
+				max = int64(1)<<t.BitSize - 1
+			} else if !t.Unsigned {
+				min = -(int64(1) << (t.BitSize - 1))
+				max = -(min + 1)
+			}
+			call := ir.NewCall(p.jsonReadIntFunction(), stream, ir.NewBool(t.Unsigned), ir.NewInt(ir.I64, min), ir.NewInt(ir.I64, max))
+			b.AddInstruction(call)
+			value = call
+			if t.BitSize < 64 {
+				trunc := ir.NewTrunc(call, t)
+				b.AddInstruction(trunc)
+				value = trunc
+			}
+
+		case *ir.FloatType:
+			call := ir.NewCall(p.jsonReadDoubleFunction(), stream)
+			b.AddInstruction(call)
+			value = call
+			if t.Kind == ir.FloatKindFloat {
+				trunc := ir.NewFPTrunc(call, t)
+				b.AddInstruction(trunc)
+				value = trunc
+			}
+
+		case *ir.PointerType:
+			// string is owned by instance, the previous one is released
+			text := f.NewBlock("")
+			stored := f.NewBlock("")
+			isNull := p.jsonMatch(b, stream, "null")
+			b.AddInstruction(ir.NewCondBr(isNull, stored, text))
+			call := ir.NewCall(p.jsonReadTextFunction(), stream)
+			text.AddInstruction(call)
+			text.AddInstruction(ir.NewBr(stored))
+			phi := ir.NewPhi(ir.NewIncoming(ir.NewNull(t), b), ir.NewIncoming(call, text))
+			stored.AddInstruction(phi)
+			old := ir.NewLoad(t, pointer)
+			stored.AddInstruction(old)
+			stored.AddInstruction(ir.NewStore(phi, pointer))
+			stored.AddInstruction(ir.NewCall(releaseShared, old))
+			return stored
+		}
+	}
+	b.AddInstruction(ir.NewStore(value, pointer))
+	return b
+}
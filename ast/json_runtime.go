@@ -0,0 +1,1431 @@
+package ast
+
+import (
+	"math"
+
+	"github.com/panda-foundation/go-compiler/ir"
+)
+
+// json runtime is generated into program when json intrinsics are used, it writes text to and reads text from runtime.stream
+// nesting depth is stored in count of stream, message of the first error is stored in json.error and path is prepended when it returns
+const (
+	JSONMaxDepth = 256
+)
+
+var (
+	jsonEscapes = []struct {
+		char    byte
+		escaped string
+	}{
+		{'"', `\"`}, {'\\', `\\`}, {'\n', `\n`}, {'\r', `\r`}, {'\t', `\t`}, {'\b', `\b`}, {'\f', `\f`},
+	}
+
+	jsonUnescapes = []struct {
+		escaped byte
+		char    byte
+	}{
+		{'"', '"'}, {'\\', '\\'}, {'/', '/'}, {'b', '\b'}, {'f', '\f'}, {'n', '\n'}, {'r', '\r'}, {'t', '\t'},
+	}
+)
+
+// externFunction returns c function declared by program or declares it, declared function is casted if its signature is different
+func (p *Program) externFunction(name string, sig *ir.FuncType) ir.Value {
+	for _, f := range p.IRModule.Funcs {
+		if f.Name() == name {
+			if f.Sig.Equal(sig) {
+				return f
+			}
+			return ir.NewExprBitCast(f, ir.NewPointerType(sig))
+		}
+	}
+	var params []*ir.Param
+	for _, t := range sig.Params {
+		params = append(params, ir.NewParam(t))
+	}
+	f := p.IRModule.NewFunc(name, sig.RetType, params...)
+	f.Sig.Variadic = sig.Variadic
+	return f
+}
+
+func (p *Program) snprintf() ir.Value {
+	sig := ir.NewFuncType(ir.I32, pointerType, ir.I64, pointerType)
+	sig.Variadic = true
+	return p.externFunction("snprintf", sig)
+}
+
+func (p *Program) strtod() ir.Value {
+	return p.externFunction("strtod", ir.NewFuncType(ir.Float64, pointerType, ir.NewPointerType(pointerType)))
+}
+
+// jsonError returns global variable of the last error message, it is null if there is no error
+func (p *Program) jsonError() *ir.Global {
+	for _, g := range p.IRModule.Globals {
+		if g.Name() == JSONError {
+			return g
+		}
+	}
+	return p.IRModule.NewGlobalDef(JSONError, ir.NewNull(pointerType))
+}
+
+func (p *Program) clearJSONError(b *ir.Block) {
+	old := ir.NewLoad(pointerType, p.jsonError())
+	b.AddInstruction(old)
+	b.AddInstruction(ir.NewCall(free, old))
+	b.AddInstruction(ir.NewStore(ir.NewNull(pointerType), p.jsonError()))
+}
+
+func charAt(b *ir.Block, data ir.Value, index ir.Value) ir.Value {
+	pointer := ir.NewGetElementPtr(ir.I8, data, index)
+	b.AddInstruction(pointer)
+	char := ir.NewLoad(ir.I8, pointer)
+	b.AddInstruction(char)
+	return char
+}
+
+// charIn returns true if char is between first and last
+func charIn(b *ir.Block, char ir.Value, first byte, last byte) ir.Value {
+	lower := ir.NewICmp(ir.IPredSGE, char, ir.NewInt(ir.I8, int64(first)))
+	b.AddInstruction(lower)
+	upper := ir.NewICmp(ir.IPredSLE, char, ir.NewInt(ir.I8, int64(last)))
+	b.AddInstruction(upper)
+	and := ir.NewAnd(lower, upper)
+	b.AddInstruction(and)
+	return and
+}
+
+func charOneOf(b *ir.Block, char ir.Value, chars string) ir.Value {
+	var result ir.Value = ir.False
+	for i := 0; i < len(chars); i++ {
+		equal := ir.NewICmp(ir.IPredEQ, char, ir.NewInt(ir.I8, int64(chars[i])))
+		b.AddInstruction(equal)
+		or := ir.NewOr(result, equal)
+		b.AddInstruction(or)
+		result = or
+	}
+	return result
+}
+
+func (p *Program) jsonFail(b *ir.Block, stream ir.Value, message string) {
+	b.AddInstruction(ir.NewCall(p.jsonFailFunction(), stream, p.AddString(message)))
+}
+
+func (p *Program) jsonWriteText(b *ir.Block, stream ir.Value, text string) {
+	b.AddInstruction(ir.NewCall(p.jsonWriteFunction(), stream, p.AddString(text), ir.NewInt(ir.I32, int64(len(text)))))
+}
+
+func (p *Program) jsonWriteChar(b *ir.Block, stream ir.Value, char byte) {
+	b.AddInstruction(ir.NewCall(p.jsonWriteCharFunction(), stream, ir.NewInt(ir.I8, int64(char))))
+}
+
+// jsonLengthFunction generates function which returns length of null terminated string
+func (p *Program) jsonLengthFunction() *ir.Func {
+	text := newParam("text", pointerType)
+	f, created := p.newStreamFunction(JSON+".length", ir.I32, text)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	char := charAt(loop, text, index)
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	loop.AddInstruction(increment)
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, loop))
+	end := ir.NewICmp(ir.IPredEQ, char, ir.NewInt(ir.I8, 0))
+	loop.AddInstruction(end)
+	loop.AddInstruction(ir.NewCondBr(end, exit, loop))
+
+	exit.AddInstruction(ir.NewRet(index))
+	return f
+}
+
+// jsonFailFunction generates function which fails stream with message, only the first error is kept
+func (p *Program) jsonFailFunction() *ir.Func {
+	stream := streamParam()
+	message := newParam("message", pointerType)
+	f, created := p.newStreamFunction(JSON+".fail", ir.Void, stream, message)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	set := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	failed := loadStreamMember(entry, stream, streamFailed)
+	entry.AddInstruction(ir.NewCondBr(failed, exit, set))
+
+	set.AddInstruction(ir.NewStore(ir.True, streamMember(set, stream, streamFailed)))
+	old := ir.NewLoad(pointerType, p.jsonError())
+	set.AddInstruction(old)
+	set.AddInstruction(ir.NewCall(free, old))
+	length := ir.NewCall(p.jsonLengthFunction(), message)
+	set.AddInstruction(length)
+	size := ir.NewAdd(length, ir.NewInt(ir.I32, 1))
+	set.AddInstruction(size)
+	copied := ir.NewCall(malloc, size)
+	set.AddInstruction(copied)
+	set.AddInstruction(ir.NewCall(memcpy, copied, message, size))
+	set.AddInstruction(ir.NewStore(copied, p.jsonError()))
+	set.AddInstruction(ir.NewBr(exit))
+
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonPrependFunction generates function which prepends path segment to error message if stream is failed
+func (p *Program) jsonPrependFunction() *ir.Func {
+	stream := streamParam()
+	segment := newParam("segment", pointerType)
+	f, created := p.newStreamFunction(JSON+".prepend", ir.Void, stream, segment)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	body := f.NewBlock(FunctionBody)
+	exit := f.NewBlock(FunctionExit)
+
+	failed := loadStreamMember(entry, stream, streamFailed)
+	old := ir.NewLoad(pointerType, p.jsonError())
+	entry.AddInstruction(old)
+	hasMessage := ir.NewICmp(ir.IPredNE, old, ir.NewNull(pointerType))
+	entry.AddInstruction(hasMessage)
+	prepend := ir.NewAnd(failed, hasMessage)
+	entry.AddInstruction(prepend)
+	entry.AddInstruction(ir.NewCondBr(prepend, body, exit))
+
+	segmentLength := ir.NewCall(p.jsonLengthFunction(), segment)
+	body.AddInstruction(segmentLength)
+	oldLength := ir.NewCall(p.jsonLengthFunction(), old)
+	body.AddInstruction(oldLength)
+	oldSize := ir.NewAdd(oldLength, ir.NewInt(ir.I32, 1))
+	body.AddInstruction(oldSize)
+	size := ir.NewAdd(segmentLength, oldSize)
+	body.AddInstruction(size)
+	message := ir.NewCall(malloc, size)
+	body.AddInstruction(message)
+	body.AddInstruction(ir.NewCall(memcpy, message, segment, segmentLength))
+	tail := ir.NewGetElementPtr(ir.I8, message, segmentLength)
+	body.AddInstruction(tail)
+	body.AddInstruction(ir.NewCall(memcpy, tail, old, oldSize))
+	body.AddInstruction(ir.NewCall(free, old))
+	body.AddInstruction(ir.NewStore(message, p.jsonError()))
+	body.AddInstruction(ir.NewBr(exit))
+
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonEnterFunction generates function which increases nesting depth, stream fails if it is too deep
+func (p *Program) jsonEnterFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".enter", ir.I1, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	deep := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	depth := loadStreamMember(entry, stream, streamCount)
+	increment := ir.NewAdd(depth, ir.NewInt(ir.I32, 1))
+	entry.AddInstruction(increment)
+	entry.AddInstruction(ir.NewStore(increment, streamMember(entry, stream, streamCount)))
+	tooDeep := ir.NewICmp(ir.IPredSGT, increment, ir.NewInt(ir.I32, JSONMaxDepth))
+	entry.AddInstruction(tooDeep)
+	entry.AddInstruction(ir.NewCondBr(tooDeep, deep, exit))
+
+	p.jsonFail(deep, stream, ": nesting is too deep")
+	deep.AddInstruction(ir.NewRet(ir.False))
+
+	exit.AddInstruction(ir.NewRet(ir.True))
+	return f
+}
+
+// jsonLeaveFunction generates function which decreases nesting depth
+func (p *Program) jsonLeaveFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".leave", ir.Void, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	depth := loadStreamMember(entry, stream, streamCount)
+	decrement := ir.NewSub(depth, ir.NewInt(ir.I32, 1))
+	entry.AddInstruction(decrement)
+	entry.AddInstruction(ir.NewStore(decrement, streamMember(entry, stream, streamCount)))
+	entry.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonWriteFunction generates function which writes bytes of text
+func (p *Program) jsonWriteFunction() *ir.Func {
+	stream := streamParam()
+	text := newParam("text", pointerType)
+	size := newParam("size", ir.I32)
+	f, created := p.newStreamFunction(JSON+".write", ir.Void, stream, text, size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	address := ir.NewCall(p.reserveFunction(), stream, size)
+	entry.AddInstruction(address)
+	entry.AddInstruction(ir.NewCall(memcpy, address, text, size))
+	entry.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonWriteCharFunction generates function which writes a char
+func (p *Program) jsonWriteCharFunction() *ir.Func {
+	stream := streamParam()
+	char := newParam("char", ir.I8)
+	f, created := p.newStreamFunction(JSON+".write_char", ir.Void, stream, char)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	address := ir.NewCall(p.reserveFunction(), stream, ir.NewInt(ir.I32, 1))
+	entry.AddInstruction(address)
+	entry.AddInstruction(ir.NewStore(char, address))
+	entry.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonWriteKeyFunction generates function which writes quoted key with colon, it is separated by comma if it is not the first key
+func (p *Program) jsonWriteKeyFunction() *ir.Func {
+	stream := streamParam()
+	first := newParam("first", ir.NewPointerType(ir.I1))
+	key := newParam("key", pointerType)
+	size := newParam("size", ir.I32)
+	f, created := p.newStreamFunction(JSON+".write_key", ir.Void, stream, first, key, size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	comma := f.NewBlock("")
+	write := f.NewBlock("")
+
+	isFirst := ir.NewLoad(ir.I1, first)
+	entry.AddInstruction(isFirst)
+	entry.AddInstruction(ir.NewCondBr(isFirst, write, comma))
+
+	p.jsonWriteChar(comma, stream, ',')
+	comma.AddInstruction(ir.NewBr(write))
+
+	write.AddInstruction(ir.NewStore(ir.False, first))
+	write.AddInstruction(ir.NewCall(p.jsonWriteFunction(), stream, key, size))
+	write.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonWriteStringFunction generates function which writes null terminated string as quoted string, null is written if string is null
+func (p *Program) jsonWriteStringFunction() *ir.Func {
+	stream := streamParam()
+	text := newParam("text", pointerType)
+	f, created := p.newStreamFunction(JSON+".write_string", ir.Void, stream, text)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	null := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	control := f.NewBlock("")
+	plain := f.NewBlock("")
+	next := f.NewBlock("")
+	end := f.NewBlock("")
+
+	isNull := ir.NewICmp(ir.IPredEQ, text, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, null, body))
+
+	p.jsonWriteText(null, stream, "null")
+	null.AddInstruction(ir.NewRet(nil))
+
+	p.jsonWriteChar(body, stream, '"')
+	body.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), body))
+	loop.AddInstruction(index)
+	char := charAt(loop, text, index)
+	cases := []*ir.Case{ir.NewCase(ir.NewInt(ir.I8, 0), end)}
+	for _, e := range jsonEscapes {
+		escape := f.NewBlock("")
+		p.jsonWriteText(escape, stream, e.escaped)
+		escape.AddInstruction(ir.NewBr(next))
+		cases = append(cases, ir.NewCase(ir.NewInt(ir.I8, int64(e.char)), escape))
+	}
+	loop.AddInstruction(ir.NewSwitch(char, check, cases...))
+
+	isControl := ir.NewICmp(ir.IPredULT, char, ir.NewInt(ir.I8, 0x20))
+	check.AddInstruction(isControl)
+	check.AddInstruction(ir.NewCondBr(isControl, control, plain))
+
+	// other control chars are escaped as \u00XX
+	p.jsonWriteText(control, stream, `\u00`)
+	digits := p.AddString("0123456789abcdef")
+	high := ir.NewLShr(char, ir.NewInt(ir.I8, 4))
+	control.AddInstruction(high)
+	low := ir.NewAnd(char, ir.NewInt(ir.I8, 15))
+	control.AddInstruction(low)
+	for _, digit := range []ir.Value{high, low} {
+		control.AddInstruction(ir.NewCall(p.jsonWriteCharFunction(), stream, charAt(control, digits, digit)))
+	}
+	control.AddInstruction(ir.NewBr(next))
+
+	plain.AddInstruction(ir.NewCall(p.jsonWriteCharFunction(), stream, char))
+	plain.AddInstruction(ir.NewBr(next))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	p.jsonWriteChar(end, stream, '"')
+	end.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonWriteIntFunction generates function which writes integer in decimal
+func (p *Program) jsonWriteIntFunction() *ir.Func {
+	stream := streamParam()
+	value := newParam("value", ir.I64)
+	unsigned := newParam("unsigned", ir.I1)
+	f, created := p.newStreamFunction(JSON+".write_int", ir.Void, stream, value, unsigned)
+	if !created {
+		return f
+	}
+	const size = 24
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	sign := f.NewBlock("")
+	minus := f.NewBlock("")
+	write := f.NewBlock("")
+
+	bufferType := ir.NewArrayType(size, ir.I8)
+	buffer := ir.NewAlloca(bufferType)
+	entry.AddInstruction(buffer)
+	negative := ir.NewICmp(ir.IPredSLT, value, ir.NewInt(ir.I64, 0))
+	entry.AddInstruction(negative)
+	signed := ir.NewXor(unsigned, ir.True)
+	entry.AddInstruction(signed)
+	isNegative := ir.NewAnd(negative, signed)
+	entry.AddInstruction(isNegative)
+	negated := ir.NewSub(ir.NewInt(ir.I64, 0), value)
+	entry.AddInstruction(negated)
+	magnitude := ir.NewSelect(isNegative, negated, value)
+	entry.AddInstruction(magnitude)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	// digits are written from the end of buffer
+	current := ir.NewPhi(ir.NewIncoming(magnitude, entry))
+	loop.AddInstruction(current)
+	position := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, size), entry))
+	loop.AddInstruction(position)
+	digit := ir.NewURem(current, ir.NewInt(ir.I64, 10))
+	loop.AddInstruction(digit)
+	quotient := ir.NewUDiv(current, ir.NewInt(ir.I64, 10))
+	loop.AddInstruction(quotient)
+	previous := ir.NewSub(position, ir.NewInt(ir.I32, 1))
+	loop.AddInstruction(previous)
+	char := ir.NewTrunc(digit, ir.I8)
+	loop.AddInstruction(char)
+	digitChar := ir.NewAdd(char, ir.NewInt(ir.I8, '0'))
+	loop.AddInstruction(digitChar)
+	digitPointer := ir.NewGetElementPtr(bufferType, buffer, ir.NewInt(ir.I32, 0), previous)
+	loop.AddInstruction(digitPointer)
+	loop.AddInstruction(ir.NewStore(digitChar, digitPointer))
+	current.Incs = append(current.Incs, ir.NewIncoming(quotient, loop))
+	position.Incs = append(position.Incs, ir.NewIncoming(previous, loop))
+	more := ir.NewICmp(ir.IPredNE, quotient, ir.NewInt(ir.I64, 0))
+	loop.AddInstruction(more)
+	loop.AddInstruction(ir.NewCondBr(more, loop, sign))
+
+	sign.AddInstruction(ir.NewCondBr(isNegative, minus, write))
+
+	signPosition := ir.NewSub(previous, ir.NewInt(ir.I32, 1))
+	minus.AddInstruction(signPosition)
+	signPointer := ir.NewGetElementPtr(bufferType, buffer, ir.NewInt(ir.I32, 0), signPosition)
+	minus.AddInstruction(signPointer)
+	minus.AddInstruction(ir.NewStore(ir.NewInt(ir.I8, '-'), signPointer))
+	minus.AddInstruction(ir.NewBr(write))
+
+	start := ir.NewPhi(ir.NewIncoming(previous, sign), ir.NewIncoming(signPosition, minus))
+	write.AddInstruction(start)
+	text := ir.NewGetElementPtr(bufferType, buffer, ir.NewInt(ir.I32, 0), start)
+	write.AddInstruction(text)
+	length := ir.NewSub(ir.NewInt(ir.I32, size), start)
+	write.AddInstruction(length)
+	write.AddInstruction(ir.NewCall(p.jsonWriteFunction(), stream, text, length))
+	write.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonWriteDoubleFunction generates function which writes number by format, stream fails if number is not finite
+func (p *Program) jsonWriteDoubleFunction() *ir.Func {
+	stream := streamParam()
+	value := newParam("value", ir.Float64)
+	format := newParam("format", pointerType)
+	f, created := p.newStreamFunction(JSON+".write_double", ir.Void, stream, value, format)
+	if !created {
+		return f
+	}
+	const size = 32
+	entry := f.NewBlock(FunctionEntry)
+	write := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	bufferType := ir.NewArrayType(size, ir.I8)
+	buffer := ir.NewAlloca(bufferType)
+	entry.AddInstruction(buffer)
+	// difference of infinity or nan is nan
+	difference := ir.NewFSub(value, value)
+	entry.AddInstruction(difference)
+	finite := ir.NewFCmp(ir.FPredOEQ, difference, ir.NewFloat(ir.Float64, 0))
+	entry.AddInstruction(finite)
+	entry.AddInstruction(ir.NewCondBr(finite, write, failed))
+
+	text := ir.NewGetElementPtr(bufferType, buffer, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 0))
+	write.AddInstruction(text)
+	length := ir.NewCall(p.snprintf(), text, ir.NewInt(ir.I64, size), format, value)
+	write.AddInstruction(length)
+	write.AddInstruction(ir.NewCall(p.jsonWriteFunction(), stream, text, length))
+	write.AddInstruction(ir.NewRet(nil))
+
+	p.jsonFail(failed, stream, ": number is not finite")
+	failed.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonWriteEnumFunction generates function which writes name of enum member by type info of enum
+func (p *Program) jsonWriteEnumFunction() *ir.Func {
+	stream := streamParam()
+	info := newParam("info", typeInfoPointer)
+	value := newParam("value", ir.I32)
+	f, created := p.newStreamFunction(JSON+".write_enum", ir.Void, stream, info, value)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	found := f.NewBlock("")
+	next := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	count, fields := loadTypeInfoFields(entry, info)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	inRange := ir.NewICmp(ir.IPredSLT, index, count)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, check, failed))
+
+	field := ir.NewGetElementPtr(reflectField, fields, index)
+	check.AddInstruction(field)
+	offset := loadFieldInfo(check, field, memberInfoOffset, ir.I32)
+	equal := ir.NewICmp(ir.IPredEQ, offset, value)
+	check.AddInstruction(equal)
+	check.AddInstruction(ir.NewCondBr(equal, found, next))
+
+	name := loadFieldInfo(found, field, memberInfoName, pointerType)
+	found.AddInstruction(ir.NewCall(p.jsonWriteStringFunction(), stream, name))
+	found.AddInstruction(ir.NewRet(nil))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	p.jsonFail(failed, stream, ": invalid value of enum")
+	failed.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+func loadTypeInfoFields(b *ir.Block, info ir.Value) (ir.Value, ir.Value) {
+	countPointer := ir.NewGetElementPtr(reflectType, info, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, typeInfoFieldCount))
+	b.AddInstruction(countPointer)
+	count := ir.NewLoad(ir.I32, countPointer)
+	b.AddInstruction(count)
+	fieldsPointer := ir.NewGetElementPtr(reflectType, info, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, typeInfoFields))
+	b.AddInstruction(fieldsPointer)
+	fields := ir.NewLoad(ir.NewPointerType(reflectField), fieldsPointer)
+	b.AddInstruction(fields)
+	return count, fields
+}
+
+func loadFieldInfo(b *ir.Block, field ir.Value, index int64, t ir.Type) ir.Value {
+	pointer := ir.NewGetElementPtr(reflectField, field, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, index))
+	b.AddInstruction(pointer)
+	load := ir.NewLoad(t, pointer)
+	b.AddInstruction(load)
+	return load
+}
+
+// jsonSpaceFunction generates function which skips white spaces and returns the next char, it is 0 at the end of text
+func (p *Program) jsonSpaceFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".space", ir.I8, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	skip := f.NewBlock("")
+	found := f.NewBlock("")
+	end := f.NewBlock("")
+	entry.AddInstruction(ir.NewBr(loop))
+
+	position := loadStreamMember(loop, stream, streamPosition)
+	size := loadStreamMember(loop, stream, streamSize)
+	inRange := ir.NewICmp(ir.IPredSLT, position, size)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, check, end))
+
+	char := charAt(check, loadStreamMember(check, stream, streamData), position)
+	var cases []*ir.Case
+	for _, space := range " \t\n\r" {
+		cases = append(cases, ir.NewCase(ir.NewInt(ir.I8, int64(space)), skip))
+	}
+	check.AddInstruction(ir.NewSwitch(char, found, cases...))
+
+	increment := ir.NewAdd(position, ir.NewInt(ir.I32, 1))
+	skip.AddInstruction(increment)
+	skip.AddInstruction(ir.NewStore(increment, streamMember(skip, stream, streamPosition)))
+	skip.AddInstruction(ir.NewBr(loop))
+
+	found.AddInstruction(ir.NewRet(char))
+
+	end.AddInstruction(ir.NewRet(ir.NewInt(ir.I8, 0)))
+	return f
+}
+
+// jsonExpectFunction generates function which skips the expected char after white spaces, stream fails with message if it is not found
+func (p *Program) jsonExpectFunction() *ir.Func {
+	stream := streamParam()
+	expected := newParam("expected", ir.I8)
+	message := newParam("message", pointerType)
+	f, created := p.newStreamFunction(JSON+".expect", ir.I1, stream, expected, message)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	take := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	char := ir.NewCall(p.jsonSpaceFunction(), stream)
+	entry.AddInstruction(char)
+	equal := ir.NewICmp(ir.IPredEQ, char, expected)
+	entry.AddInstruction(equal)
+	entry.AddInstruction(ir.NewCondBr(equal, take, failed))
+
+	skipChar(take, stream)
+	take.AddInstruction(ir.NewRet(ir.True))
+
+	failed.AddInstruction(ir.NewCall(p.jsonFailFunction(), stream, message))
+	failed.AddInstruction(ir.NewRet(ir.False))
+	return f
+}
+
+func (p *Program) jsonExpect(b *ir.Block, stream ir.Value, expected byte, message string) ir.Value {
+	call := ir.NewCall(p.jsonExpectFunction(), stream, ir.NewInt(ir.I8, int64(expected)), p.AddString(message))
+	b.AddInstruction(call)
+	return call
+}
+
+func skipChar(b *ir.Block, stream ir.Value) {
+	position := loadStreamMember(b, stream, streamPosition)
+	increment := ir.NewAdd(position, ir.NewInt(ir.I32, 1))
+	b.AddInstruction(increment)
+	b.AddInstruction(ir.NewStore(increment, streamMember(b, stream, streamPosition)))
+}
+
+// jsonMatchFunction generates function which skips word after white spaces if it is matched
+func (p *Program) jsonMatchFunction() *ir.Func {
+	stream := streamParam()
+	word := newParam("word", pointerType)
+	length := newParam("length", ir.I32)
+	f, created := p.newStreamFunction(JSON+".match", ir.I1, stream, word, length)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	compare := f.NewBlock("")
+	next := f.NewBlock("")
+	matched := f.NewBlock("")
+	unmatched := f.NewBlock("")
+
+	entry.AddInstruction(ir.NewCall(p.jsonSpaceFunction(), stream))
+	position := loadStreamMember(entry, stream, streamPosition)
+	size := loadStreamMember(entry, stream, streamSize)
+	data := loadStreamMember(entry, stream, streamData)
+	end := ir.NewAdd(position, length)
+	entry.AddInstruction(end)
+	fits := ir.NewICmp(ir.IPredSLE, end, size)
+	entry.AddInstruction(fits)
+	entry.AddInstruction(ir.NewCondBr(fits, loop, unmatched))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	done := ir.NewICmp(ir.IPredEQ, index, length)
+	loop.AddInstruction(done)
+	loop.AddInstruction(ir.NewCondBr(done, matched, compare))
+
+	at := ir.NewAdd(position, index)
+	compare.AddInstruction(at)
+	equal := ir.NewICmp(ir.IPredEQ, charAt(compare, data, at), charAt(compare, word, index))
+	compare.AddInstruction(equal)
+	compare.AddInstruction(ir.NewCondBr(equal, next, unmatched))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	matched.AddInstruction(ir.NewStore(end, streamMember(matched, stream, streamPosition)))
+	matched.AddInstruction(ir.NewRet(ir.True))
+
+	unmatched.AddInstruction(ir.NewRet(ir.False))
+	return f
+}
+
+func (p *Program) jsonMatch(b *ir.Block, stream ir.Value, word string) ir.Value {
+	call := ir.NewCall(p.jsonMatchFunction(), stream, p.AddString(word), ir.NewInt(ir.I32, int64(len(word))))
+	b.AddInstruction(call)
+	return call
+}
+
+// jsonReadHexFunction generates function which reads 4 hex digits at position, it returns -1 if they are invalid
+func (p *Program) jsonReadHexFunction() *ir.Func {
+	stream := streamParam()
+	at := newParam("at", ir.I32)
+	f, created := p.newStreamFunction(JSON+".read_hex", ir.I32, stream, at)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	notDigit := f.NewBlock("")
+	notLower := f.NewBlock("")
+	next := f.NewBlock("")
+	result := f.NewBlock("")
+	invalid := f.NewBlock("")
+
+	size := loadStreamMember(entry, stream, streamSize)
+	data := loadStreamMember(entry, stream, streamData)
+	end := ir.NewAdd(at, ir.NewInt(ir.I32, 4))
+	entry.AddInstruction(end)
+	fits := ir.NewICmp(ir.IPredSLE, end, size)
+	entry.AddInstruction(fits)
+	entry.AddInstruction(ir.NewCondBr(fits, loop, invalid))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	code := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(code)
+	done := ir.NewICmp(ir.IPredEQ, index, ir.NewInt(ir.I32, 4))
+	loop.AddInstruction(done)
+	loop.AddInstruction(ir.NewCondBr(done, result, check))
+
+	position := ir.NewAdd(at, index)
+	check.AddInstruction(position)
+	char := charAt(check, data, position)
+	digitValue := ir.NewSub(char, ir.NewInt(ir.I8, '0'))
+	check.AddInstruction(digitValue)
+	check.AddInstruction(ir.NewCondBr(charIn(check, char, '0', '9'), next, notDigit))
+
+	lowerValue := ir.NewSub(char, ir.NewInt(ir.I8, 'a'-10))
+	notDigit.AddInstruction(lowerValue)
+	notDigit.AddInstruction(ir.NewCondBr(charIn(notDigit, char, 'a', 'f'), next, notLower))
+
+	upperValue := ir.NewSub(char, ir.NewInt(ir.I8, 'A'-10))
+	notLower.AddInstruction(upperValue)
+	notLower.AddInstruction(ir.NewCondBr(charIn(notLower, char, 'A', 'F'), next, invalid))
+
+	value := ir.NewPhi(ir.NewIncoming(digitValue, check), ir.NewIncoming(lowerValue, notDigit), ir.NewIncoming(upperValue, notLower))
+	next.AddInstruction(value)
+	extended := ir.NewZExt(value, ir.I32)
+	next.AddInstruction(extended)
+	shifted := ir.NewShl(code, ir.NewInt(ir.I32, 4))
+	next.AddInstruction(shifted)
+	or := ir.NewOr(shifted, extended)
+	next.AddInstruction(or)
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+	code.Incs = append(code.Incs, ir.NewIncoming(or, next))
+
+	result.AddInstruction(ir.NewRet(code))
+
+	invalid.AddInstruction(ir.NewRet(ir.NewInt(ir.I32, -1)))
+	return f
+}
+
+// jsonPutUTF8Function generates function which encodes code point in utf-8 to buffer at index, it returns index after encoded bytes
+func (p *Program) jsonPutUTF8Function() *ir.Func {
+	buffer := newParam("buffer", pointerType)
+	index := newParam("index", ir.I32)
+	code := newParam("code", ir.I32)
+	f, created := p.newStreamFunction(JSON+".put_utf8", ir.I32, buffer, index, code)
+	if !created {
+		return f
+	}
+	current := f.NewBlock(FunctionEntry)
+	leads := []int64{0, 0xC0, 0xE0, 0xF0}
+	limits := []int64{0x80, 0x800, 0x10000}
+	for size := 1; size <= 4; size++ {
+		encode := current
+		if size < 4 {
+			encode = f.NewBlock("")
+			next := f.NewBlock("")
+			fits := ir.NewICmp(ir.IPredULT, code, ir.NewInt(ir.I32, limits[size-1]))
+			current.AddInstruction(fits)
+			current.AddInstruction(ir.NewCondBr(fits, encode, next))
+			current = next
+		}
+		// the first byte has the lead bits, the following bytes have 6 bits each
+		for i := 0; i < size; i++ {
+			value := ir.Value(code)
+			if shift := int64(6 * (size - 1 - i)); shift > 0 {
+				shifted := ir.NewLShr(code, ir.NewInt(ir.I32, shift))
+				encode.AddInstruction(shifted)
+				value = shifted
+			}
+			if i > 0 {
+				masked := ir.NewAnd(value, ir.NewInt(ir.I32, 0x3F))
+				encode.AddInstruction(masked)
+				marked := ir.NewOr(masked, ir.NewInt(ir.I32, 0x80))
+				encode.AddInstruction(marked)
+				value = marked
+			} else if size > 1 {
+				marked := ir.NewOr(value, ir.NewInt(ir.I32, leads[size-1]))
+				encode.AddInstruction(marked)
+				value = marked
+			}
+			char := ir.NewTrunc(value, ir.I8)
+			encode.AddInstruction(char)
+			at := ir.NewAdd(index, ir.NewInt(ir.I32, int64(i)))
+			encode.AddInstruction(at)
+			pointer := ir.NewGetElementPtr(ir.I8, buffer, at)
+			encode.AddInstruction(pointer)
+			encode.AddInstruction(ir.NewStore(char, pointer))
+		}
+		end := ir.NewAdd(index, ir.NewInt(ir.I32, int64(size)))
+		encode.AddInstruction(end)
+		encode.AddInstruction(ir.NewRet(end))
+	}
+	return f
+}
+
+// jsonReadStringFunction generates function which reads quoted string to a new null terminated string, it returns null if string is invalid
+func (p *Program) jsonReadStringFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".read_string", pointerType, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	start := f.NewBlock("")
+	loop := f.NewBlock("")
+	char := f.NewBlock("")
+	normal := f.NewBlock("")
+	copyChar := f.NewBlock("")
+	end := f.NewBlock("")
+	escape := f.NewBlock("")
+	escaped := f.NewBlock("")
+	storeEscaped := f.NewBlock("")
+	unicode := f.NewBlock("")
+	checkHigh := f.NewBlock("")
+	checkLow := f.NewBlock("")
+	single := f.NewBlock("")
+	pair := f.NewBlock("")
+	combine := f.NewBlock("")
+	unterminated := f.NewBlock("")
+	invalidChar := f.NewBlock("")
+	invalidEscape := f.NewBlock("")
+	notQuoted := f.NewBlock("")
+	null := ir.NewNull(pointerType)
+
+	in := ir.NewAlloca(ir.I32)
+	entry.AddInstruction(in)
+	out := ir.NewAlloca(ir.I32)
+	entry.AddInstruction(out)
+	quoted := p.jsonExpect(entry, stream, '"', ": expected string")
+	entry.AddInstruction(ir.NewCondBr(quoted, start, notQuoted))
+
+	notQuoted.AddInstruction(ir.NewRet(null))
+
+	// unescaped string is not longer than the rest of text
+	position := loadStreamMember(start, stream, streamPosition)
+	size := loadStreamMember(start, stream, streamSize)
+	data := loadStreamMember(start, stream, streamData)
+	rest := ir.NewSub(size, position)
+	start.AddInstruction(rest)
+	capacity := ir.NewAdd(rest, ir.NewInt(ir.I32, 1))
+	start.AddInstruction(capacity)
+	buffer := ir.NewCall(malloc, capacity)
+	start.AddInstruction(buffer)
+	start.AddInstruction(ir.NewStore(position, in))
+	start.AddInstruction(ir.NewStore(ir.NewInt(ir.I32, 0), out))
+	start.AddInstruction(ir.NewBr(loop))
+
+	load := func(b *ir.Block, v ir.Value) ir.Value {
+		l := ir.NewLoad(ir.I32, v)
+		b.AddInstruction(l)
+		return l
+	}
+	advance := func(b *ir.Block, v ir.Value, from ir.Value, offset int64) {
+		add := ir.NewAdd(from, ir.NewInt(ir.I32, offset))
+		b.AddInstruction(add)
+		b.AddInstruction(ir.NewStore(add, v))
+	}
+	put := func(b *ir.Block, c ir.Value) {
+		index := load(b, out)
+		pointer := ir.NewGetElementPtr(ir.I8, buffer, index)
+		b.AddInstruction(pointer)
+		b.AddInstruction(ir.NewStore(c, pointer))
+		advance(b, out, index, 1)
+	}
+
+	index := load(loop, in)
+	inRange := ir.NewICmp(ir.IPredSLT, index, size)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, char, unterminated))
+
+	c := charAt(char, data, index)
+	char.AddInstruction(ir.NewSwitch(c, normal, ir.NewCase(ir.NewInt(ir.I8, '"'), end), ir.NewCase(ir.NewInt(ir.I8, '\\'), escape)))
+
+	isControl := ir.NewICmp(ir.IPredULT, c, ir.NewInt(ir.I8, 0x20))
+	normal.AddInstruction(isControl)
+	normal.AddInstruction(ir.NewCondBr(isControl, invalidChar, copyChar))
+
+	put(copyChar, c)
+	advance(copyChar, in, index, 1)
+	copyChar.AddInstruction(ir.NewBr(loop))
+
+	terminator := ir.NewGetElementPtr(ir.I8, buffer, load(end, out))
+	end.AddInstruction(terminator)
+	end.AddInstruction(ir.NewStore(ir.NewInt(ir.I8, 0), terminator))
+	advance(end, streamMember(end, stream, streamPosition), index, 1)
+	end.AddInstruction(ir.NewRet(buffer))
+
+	escapeIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	escape.AddInstruction(escapeIndex)
+	hasEscape := ir.NewICmp(ir.IPredSLT, escapeIndex, size)
+	escape.AddInstruction(hasEscape)
+	escape.AddInstruction(ir.NewCondBr(hasEscape, escaped, unterminated))
+
+	e := charAt(escaped, data, escapeIndex)
+	cases := []*ir.Case{ir.NewCase(ir.NewInt(ir.I8, 'u'), unicode)}
+	var incomings []*ir.Incoming
+	for _, u := range jsonUnescapes {
+		simple := f.NewBlock("")
+		simple.AddInstruction(ir.NewBr(storeEscaped))
+		cases = append(cases, ir.NewCase(ir.NewInt(ir.I8, int64(u.escaped)), simple))
+		incomings = append(incomings, ir.NewIncoming(ir.NewInt(ir.I8, int64(u.char)), simple))
+	}
+	escaped.AddInstruction(ir.NewSwitch(e, invalidEscape, cases...))
+
+	unescaped := ir.NewPhi(incomings...)
+	storeEscaped.AddInstruction(unescaped)
+	put(storeEscaped, unescaped)
+	advance(storeEscaped, in, index, 2)
+	storeEscaped.AddInstruction(ir.NewBr(loop))
+
+	hexIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 2))
+	unicode.AddInstruction(hexIndex)
+	code := ir.NewCall(p.jsonReadHexFunction(), stream, hexIndex)
+	unicode.AddInstruction(code)
+	invalidCode := ir.NewICmp(ir.IPredSLT, code, ir.NewInt(ir.I32, 0))
+	unicode.AddInstruction(invalidCode)
+	unicode.AddInstruction(ir.NewCondBr(invalidCode, invalidEscape, checkHigh))
+
+	codeIn := func(b *ir.Block, v ir.Value, first int64, last int64) ir.Value {
+		lower := ir.NewICmp(ir.IPredUGE, v, ir.NewInt(ir.I32, first))
+		b.AddInstruction(lower)
+		upper := ir.NewICmp(ir.IPredULE, v, ir.NewInt(ir.I32, last))
+		b.AddInstruction(upper)
+		and := ir.NewAnd(lower, upper)
+		b.AddInstruction(and)
+		return and
+	}
+	checkHigh.AddInstruction(ir.NewCondBr(codeIn(checkHigh, code, 0xD800, 0xDBFF), pair, checkLow))
+
+	// low surrogate without high surrogate is invalid
+	checkLow.AddInstruction(ir.NewCondBr(codeIn(checkLow, code, 0xDC00, 0xDFFF), invalidEscape, single))
+
+	singleEnd := ir.NewCall(p.jsonPutUTF8Function(), buffer, load(single, out), code)
+	single.AddInstruction(singleEnd)
+	single.AddInstruction(ir.NewStore(singleEnd, out))
+	advance(single, in, index, 6)
+	single.AddInstruction(ir.NewBr(loop))
+
+	// high surrogate must be followed by \u and low surrogate, chars before low surrogate are in text if it is valid
+	lowIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 8))
+	pair.AddInstruction(lowIndex)
+	low := ir.NewCall(p.jsonReadHexFunction(), stream, lowIndex)
+	pair.AddInstruction(low)
+	validLow := codeIn(pair, low, 0xDC00, 0xDFFF)
+	slashIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 6))
+	pair.AddInstruction(slashIndex)
+	uIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 7))
+	pair.AddInstruction(uIndex)
+	slash := ir.NewICmp(ir.IPredEQ, charAt(pair, data, slashIndex), ir.NewInt(ir.I8, '\\'))
+	pair.AddInstruction(slash)
+	u := ir.NewICmp(ir.IPredEQ, charAt(pair, data, uIndex), ir.NewInt(ir.I8, 'u'))
+	pair.AddInstruction(u)
+	validEscape := ir.NewAnd(slash, u)
+	pair.AddInstruction(validEscape)
+	validPair := ir.NewAnd(validLow, validEscape)
+	pair.AddInstruction(validPair)
+	pair.AddInstruction(ir.NewCondBr(validPair, combine, invalidEscape))
+
+	high := ir.NewSub(code, ir.NewInt(ir.I32, 0xD800))
+	combine.AddInstruction(high)
+	highBits := ir.NewShl(high, ir.NewInt(ir.I32, 10))
+	combine.AddInstruction(highBits)
+	lowBits := ir.NewSub(low, ir.NewInt(ir.I32, 0xDC00))
+	combine.AddInstruction(lowBits)
+	bits := ir.NewOr(highBits, lowBits)
+	combine.AddInstruction(bits)
+	combined := ir.NewAdd(bits, ir.NewInt(ir.I32, 0x10000))
+	combine.AddInstruction(combined)
+	pairEnd := ir.NewCall(p.jsonPutUTF8Function(), buffer, load(combine, out), combined)
+	combine.AddInstruction(pairEnd)
+	combine.AddInstruction(ir.NewStore(pairEnd, out))
+	advance(combine, in, index, 12)
+	combine.AddInstruction(ir.NewBr(loop))
+
+	for _, failed := range []struct {
+		block   *ir.Block
+		message string
+	}{
+		{unterminated, ": unterminated string"},
+		{invalidChar, ": invalid character in string"},
+		{invalidEscape, ": invalid escape in string"},
+	} {
+		failed.block.AddInstruction(ir.NewCall(free, buffer))
+		p.jsonFail(failed.block, stream, failed.message)
+		failed.block.AddInstruction(ir.NewRet(null))
+	}
+	return f
+}
+
+// jsonReadTextFunction generates function which reads quoted string to a new string, it returns null if string is invalid
+func (p *Program) jsonReadTextFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".read_text", CreateStringType(), stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	body := f.NewBlock(FunctionBody)
+	failed := f.NewBlock("")
+
+	bytes := ir.NewCall(p.jsonReadStringFunction(), stream)
+	entry.AddInstruction(bytes)
+	isNull := ir.NewICmp(ir.IPredEQ, bytes, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, failed, body))
+
+	// bytes are copied to string, so they are freed
+	length := ir.NewCall(p.jsonLengthFunction(), bytes)
+	body.AddInstruction(length)
+	s := ir.NewCall(p.stringCreateFunction(), bytes, length)
+	body.AddInstruction(s)
+	body.AddInstruction(ir.NewCall(free, bytes))
+	body.AddInstruction(ir.NewRet(s))
+
+	failed.AddInstruction(ir.NewRet(ir.NewNull(CreateStringType())))
+	return f
+}
+
+// jsonReadIntFunction generates function which reads integer between min and max, max is unsigned if integer is unsigned
+func (p *Program) jsonReadIntFunction() *ir.Func {
+	stream := streamParam()
+	unsigned := newParam("unsigned", ir.I1)
+	min := newParam("min", ir.I64)
+	max := newParam("max", ir.I64)
+	f, created := p.newStreamFunction(JSON+".read_int", ir.I64, stream, unsigned, min, max)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	digit := f.NewBlock("")
+	done := f.NewBlock("")
+	fraction := f.NewBlock("")
+	checkFraction := f.NewBlock("")
+	checkRange := f.NewBlock("")
+	sign := f.NewBlock("")
+	signed := f.NewBlock("")
+	unsignedRange := f.NewBlock("")
+	negative := f.NewBlock("")
+	positive := f.NewBlock("")
+	result := f.NewBlock("")
+	negativeResult := f.NewBlock("")
+	noNumber := f.NewBlock("")
+	notInteger := f.NewBlock("")
+	outOfRange := f.NewBlock("")
+
+	magnitude := ir.NewAlloca(ir.I64)
+	entry.AddInstruction(magnitude)
+	overflow := ir.NewAlloca(ir.I1)
+	entry.AddInstruction(overflow)
+	first := ir.NewCall(p.jsonSpaceFunction(), stream)
+	entry.AddInstruction(first)
+	isNegative := ir.NewICmp(ir.IPredEQ, first, ir.NewInt(ir.I8, '-'))
+	entry.AddInstruction(isNegative)
+	position := loadStreamMember(entry, stream, streamPosition)
+	afterSign := ir.NewAdd(position, ir.NewInt(ir.I32, 1))
+	entry.AddInstruction(afterSign)
+	start := ir.NewSelect(isNegative, afterSign, position)
+	entry.AddInstruction(start)
+	entry.AddInstruction(ir.NewStore(start, streamMember(entry, stream, streamPosition)))
+	entry.AddInstruction(ir.NewStore(ir.NewInt(ir.I64, 0), magnitude))
+	entry.AddInstruction(ir.NewStore(ir.False, overflow))
+	size := loadStreamMember(entry, stream, streamSize)
+	data := loadStreamMember(entry, stream, streamData)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	current := loadStreamMember(loop, stream, streamPosition)
+	inRange := ir.NewICmp(ir.IPredSLT, current, size)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, check, done))
+
+	char := charAt(check, data, current)
+	check.AddInstruction(ir.NewCondBr(charIn(check, char, '0', '9'), digit, done))
+
+	value := ir.NewSub(char, ir.NewInt(ir.I8, '0'))
+	digit.AddInstruction(value)
+	extended := ir.NewZExt(value, ir.I64)
+	digit.AddInstruction(extended)
+	m := ir.NewLoad(ir.I64, magnitude)
+	digit.AddInstruction(m)
+	// magnitude * 10 + digit overflows if magnitude > (max uint64 - digit) / 10
+	rest := ir.NewSub(ir.NewInt(ir.I64, -1), extended)
+	digit.AddInstruction(rest)
+	limit := ir.NewUDiv(rest, ir.NewInt(ir.I64, 10))
+	digit.AddInstruction(limit)
+	overflowed := ir.NewICmp(ir.IPredUGT, m, limit)
+	digit.AddInstruction(overflowed)
+	o := ir.NewLoad(ir.I1, overflow)
+	digit.AddInstruction(o)
+	anyOverflow := ir.NewOr(o, overflowed)
+	digit.AddInstruction(anyOverflow)
+	digit.AddInstruction(ir.NewStore(anyOverflow, overflow))
+	multiplied := ir.NewMul(m, ir.NewInt(ir.I64, 10))
+	digit.AddInstruction(multiplied)
+	added := ir.NewAdd(multiplied, extended)
+	digit.AddInstruction(added)
+	digit.AddInstruction(ir.NewStore(added, magnitude))
+	skipChar(digit, stream)
+	digit.AddInstruction(ir.NewBr(loop))
+
+	end := loadStreamMember(done, stream, streamPosition)
+	empty := ir.NewICmp(ir.IPredEQ, end, start)
+	done.AddInstruction(empty)
+	done.AddInstruction(ir.NewCondBr(empty, noNumber, fraction))
+
+	hasMore := ir.NewICmp(ir.IPredSLT, end, size)
+	fraction.AddInstruction(hasMore)
+	fraction.AddInstruction(ir.NewCondBr(hasMore, checkFraction, checkRange))
+
+	isFraction := charOneOf(checkFraction, charAt(checkFraction, data, end), ".eE")
+	checkFraction.AddInstruction(ir.NewCondBr(isFraction, notInteger, checkRange))
+
+	finalMagnitude := ir.NewLoad(ir.I64, magnitude)
+	checkRange.AddInstruction(finalMagnitude)
+	finalOverflow := ir.NewLoad(ir.I1, overflow)
+	checkRange.AddInstruction(finalOverflow)
+	checkRange.AddInstruction(ir.NewCondBr(finalOverflow, outOfRange, sign))
+
+	sign.AddInstruction(ir.NewCondBr(unsigned, unsignedRange, signed))
+	signed.AddInstruction(ir.NewCondBr(isNegative, negative, positive))
+
+	// -0 is valid for unsigned integer
+	nonZero := ir.NewICmp(ir.IPredNE, finalMagnitude, ir.NewInt(ir.I64, 0))
+	unsignedRange.AddInstruction(nonZero)
+	negativeValue := ir.NewAnd(isNegative, nonZero)
+	unsignedRange.AddInstruction(negativeValue)
+	tooLarge := ir.NewICmp(ir.IPredUGT, finalMagnitude, max)
+	unsignedRange.AddInstruction(tooLarge)
+	invalidUnsigned := ir.NewOr(negativeValue, tooLarge)
+	unsignedRange.AddInstruction(invalidUnsigned)
+	unsignedRange.AddInstruction(ir.NewCondBr(invalidUnsigned, outOfRange, result))
+
+	tooSmall := ir.NewICmp(ir.IPredUGT, finalMagnitude, ir.NewInt(ir.I64, math.MinInt64))
+	negative.AddInstruction(tooSmall)
+	negated := ir.NewSub(ir.NewInt(ir.I64, 0), finalMagnitude)
+	negative.AddInstruction(negated)
+	lessThanMin := ir.NewICmp(ir.IPredSLT, negated, min)
+	negative.AddInstruction(lessThanMin)
+	invalidNegative := ir.NewOr(tooSmall, lessThanMin)
+	negative.AddInstruction(invalidNegative)
+	negative.AddInstruction(ir.NewCondBr(invalidNegative, outOfRange, negativeResult))
+
+	negativeResult.AddInstruction(ir.NewRet(negated))
+
+	greaterThanMax := ir.NewICmp(ir.IPredUGT, finalMagnitude, max)
+	positive.AddInstruction(greaterThanMax)
+	positive.AddInstruction(ir.NewCondBr(greaterThanMax, outOfRange, result))
+
+	result.AddInstruction(ir.NewRet(finalMagnitude))
+
+	for _, failed := range []struct {
+		block   *ir.Block
+		message string
+	}{
+		{noNumber, ": expected number"},
+		{notInteger, ": expected integer"},
+		{outOfRange, ": number is out of range"},
+	} {
+		p.jsonFail(failed.block, stream, failed.message)
+		failed.block.AddInstruction(ir.NewRet(ir.NewInt(ir.I64, 0)))
+	}
+	return f
+}
+
+// jsonReadDoubleFunction generates function which reads number, it only accepts digits, sign, decimal point and exponent
+func (p *Program) jsonReadDoubleFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".read_double", ir.Float64, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	next := f.NewBlock("")
+	finish := f.NewBlock("")
+	noNumber := f.NewBlock("")
+
+	endPointer := ir.NewAlloca(pointerType)
+	entry.AddInstruction(endPointer)
+	entry.AddInstruction(ir.NewCall(p.jsonSpaceFunction(), stream))
+	position := loadStreamMember(entry, stream, streamPosition)
+	data := loadStreamMember(entry, stream, streamData)
+	start := ir.NewGetElementPtr(ir.I8, data, position)
+	entry.AddInstruction(start)
+	value := ir.NewCall(p.strtod(), start, endPointer)
+	entry.AddInstruction(value)
+	end := ir.NewLoad(pointerType, endPointer)
+	entry.AddInstruction(end)
+	startAddress := ir.NewPtrToInt(start, ir.I64)
+	entry.AddInstruction(startAddress)
+	endAddress := ir.NewPtrToInt(end, ir.I64)
+	entry.AddInstruction(endAddress)
+	difference := ir.NewSub(endAddress, startAddress)
+	entry.AddInstruction(difference)
+	consumed := ir.NewTrunc(difference, ir.I32)
+	entry.AddInstruction(consumed)
+	empty := ir.NewICmp(ir.IPredEQ, consumed, ir.NewInt(ir.I32, 0))
+	entry.AddInstruction(empty)
+	entry.AddInstruction(ir.NewCondBr(empty, noNumber, loop))
+
+	// strtod also accepts hex, inf and nan which are not json numbers
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	done := ir.NewICmp(ir.IPredEQ, index, consumed)
+	loop.AddInstruction(done)
+	loop.AddInstruction(ir.NewCondBr(done, finish, check))
+
+	char := charAt(check, start, index)
+	isDigit := charIn(check, char, '0', '9')
+	isSymbol := charOneOf(check, char, "+-.eE")
+	valid := ir.NewOr(isDigit, isSymbol)
+	check.AddInstruction(valid)
+	check.AddInstruction(ir.NewCondBr(valid, next, noNumber))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	newPosition := ir.NewAdd(position, consumed)
+	finish.AddInstruction(newPosition)
+	finish.AddInstruction(ir.NewStore(newPosition, streamMember(finish, stream, streamPosition)))
+	finish.AddInstruction(ir.NewRet(value))
+
+	p.jsonFail(noNumber, stream, ": expected number")
+	noNumber.AddInstruction(ir.NewRet(ir.NewFloat(ir.Float64, 0)))
+	return f
+}
+
+// jsonReadBoolFunction generates function which reads true or false
+func (p *Program) jsonReadBoolFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".read_bool", ir.I1, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	isTrue := f.NewBlock("")
+	notTrue := f.NewBlock("")
+	isFalse := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	entry.AddInstruction(ir.NewCondBr(p.jsonMatch(entry, stream, "true"), isTrue, notTrue))
+	isTrue.AddInstruction(ir.NewRet(ir.True))
+	notTrue.AddInstruction(ir.NewCondBr(p.jsonMatch(notTrue, stream, "false"), isFalse, failed))
+	isFalse.AddInstruction(ir.NewRet(ir.False))
+	p.jsonFail(failed, stream, ": expected bool")
+	failed.AddInstruction(ir.NewRet(ir.False))
+	return f
+}
+
+// jsonReadEnumFunction generates function which reads name of enum member and returns its value by type info of enum
+func (p *Program) jsonReadEnumFunction() *ir.Func {
+	stream := streamParam()
+	info := newParam("info", typeInfoPointer)
+	f, created := p.newStreamFunction(JSON+".read_enum", ir.I32, stream, info)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	body := f.NewBlock(FunctionBody)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	found := f.NewBlock("")
+	next := f.NewBlock("")
+	unknown := f.NewBlock("")
+	invalid := f.NewBlock("")
+
+	name := ir.NewCall(p.jsonReadStringFunction(), stream)
+	entry.AddInstruction(name)
+	isNull := ir.NewICmp(ir.IPredEQ, name, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, invalid, body))
+
+	count, fields := loadTypeInfoFields(body, info)
+	body.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), body))
+	loop.AddInstruction(index)
+	inRange := ir.NewICmp(ir.IPredSLT, index, count)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, check, unknown))
+
+	field := ir.NewGetElementPtr(reflectField, fields, index)
+	check.AddInstruction(field)
+	equal := ir.NewCall(p.stringEqualFunction(), name, loadFieldInfo(check, field, memberInfoName, pointerType))
+	check.AddInstruction(equal)
+	check.AddInstruction(ir.NewCondBr(equal, found, next))
+
+	found.AddInstruction(ir.NewCall(free, name))
+	found.AddInstruction(ir.NewRet(loadFieldInfo(found, field, memberInfoOffset, ir.I32)))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	unknown.AddInstruction(ir.NewCall(free, name))
+	p.jsonFail(unknown, stream, ": unknown member of enum")
+	unknown.AddInstruction(ir.NewRet(ir.NewInt(ir.I32, 0)))
+
+	invalid.AddInstruction(ir.NewRet(ir.NewInt(ir.I32, 0)))
+	return f
+}
+
+// jsonSkipFunction generates function which skips any value, it is used for unknown keys of object
+func (p *Program) jsonSkipFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(JSON+".skip", ir.Void, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	str := f.NewBlock("")
+	literal := f.NewBlock("")
+	notTrue := f.NewBlock("")
+	notFalse := f.NewBlock("")
+	number := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	char := ir.NewCall(p.jsonSpaceFunction(), stream)
+	entry.AddInstruction(char)
+	object := p.jsonSkipContainer(f, stream, '}', ": expected , or }", true, exit)
+	array := p.jsonSkipContainer(f, stream, ']', ": expected , or ]", false, exit)
+	entry.AddInstruction(ir.NewSwitch(char, literal,
+		ir.NewCase(ir.NewInt(ir.I8, '{'), object),
+		ir.NewCase(ir.NewInt(ir.I8, '['), array),
+		ir.NewCase(ir.NewInt(ir.I8, '"'), str)))
+
+	text := ir.NewCall(p.jsonReadStringFunction(), stream)
+	str.AddInstruction(text)
+	str.AddInstruction(ir.NewCall(free, text))
+	str.AddInstruction(ir.NewBr(exit))
+
+	literal.AddInstruction(ir.NewCondBr(p.jsonMatch(literal, stream, "true"), exit, notTrue))
+	notTrue.AddInstruction(ir.NewCondBr(p.jsonMatch(notTrue, stream, "false"), exit, notFalse))
+	notFalse.AddInstruction(ir.NewCondBr(p.jsonMatch(notFalse, stream, "null"), exit, number))
+	number.AddInstruction(ir.NewCall(p.jsonReadDoubleFunction(), stream))
+	number.AddInstruction(ir.NewBr(exit))
+
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// jsonSkipContainer generates blocks of skip function which skip object or array, it returns the first block
+func (p *Program) jsonSkipContainer(f *ir.Func, stream ir.Value, close byte, message string, isObject bool, exit *ir.Block) *ir.Block {
+	start := f.NewBlock("")
+	open := f.NewBlock("")
+	item := f.NewBlock("")
+	value := f.NewBlock("")
+	separator := f.NewBlock("")
+	next := f.NewBlock("")
+	closed := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	entered := ir.NewCall(p.jsonEnterFunction(), stream)
+	start.AddInstruction(entered)
+	start.AddInstruction(ir.NewCondBr(entered, open, exit))
+
+	skipChar(open, stream)
+	char := ir.NewCall(p.jsonSpaceFunction(), stream)
+	open.AddInstruction(char)
+	isClosed := ir.NewICmp(ir.IPredEQ, char, ir.NewInt(ir.I8, int64(close)))
+	open.AddInstruction(isClosed)
+	open.AddInstruction(ir.NewCondBr(isClosed, closed, item))
+
+	if isObject {
+		key := ir.NewCall(p.jsonReadStringFunction(), stream)
+		item.AddInstruction(key)
+		item.AddInstruction(ir.NewCall(free, key))
+		item.AddInstruction(ir.NewCondBr(p.jsonExpect(item, stream, ':', ": expected :"), value, exit))
+	} else {
+		item.AddInstruction(ir.NewBr(value))
+	}
+
+	value.AddInstruction(ir.NewCall(f, stream))
+	isFailed := loadStreamMember(value, stream, streamFailed)
+	value.AddInstruction(ir.NewCondBr(isFailed, exit, separator))
+
+	separatorChar := ir.NewCall(p.jsonSpaceFunction(), stream)
+	separator.AddInstruction(separatorChar)
+	separator.AddInstruction(ir.NewSwitch(separatorChar, failed,
+		ir.NewCase(ir.NewInt(ir.I8, ','), next),
+		ir.NewCase(ir.NewInt(ir.I8, int64(close)), closed)))
+
+	skipChar(next, stream)
+	next.AddInstruction(ir.NewBr(item))
+
+	skipChar(closed, stream)
+	closed.AddInstruction(ir.NewCall(p.jsonLeaveFunction(), stream))
+	closed.AddInstruction(ir.NewBr(exit))
+
+	p.jsonFail(failed, stream, message)
+	failed.AddInstruction(ir.NewBr(exit))
+	return start
+}
@@ -1,15 +1,25 @@
 package ast
 
-import "github.com/panda-foundation/go-compiler/token"
+import (
+	"crypto/md5"
+	"fmt"
+
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
 
 type Import struct {
 	NodeBase
 	Alias     string
 	Namespace string
+
+	// invalid import is reported and ignored
+	invalid bool
 }
 
 type Module struct {
-	File *token.File
+	NodeBase
+	File *token.File `json:"-"`
 
 	Namespace string
 	Imports   []*Import
@@ -18,6 +28,137 @@ type Module struct {
 	Variables  []*Variable
 	Functions  []*Function
 	Enums      []*Enum
+	Structs    []*Struct
 	Interfaces []*Interface
 	Classes    []*Class
+
+	Initializers  []*Variable `json:"-"`
+	IRInitializer *ir.Func
+	IRFinalizer   *ir.Func
+}
+
+// GenerateIRInitializer generates functions which initialize global variables with non-constant values
+// and release the class instances they hold when program exit
+func (m *Module) GenerateIRInitializer(p *Program) {
+	if len(m.Initializers) == 0 {
+		return
+	}
+	hash := fmt.Sprintf("%x", md5.Sum([]byte(m.File.Name)))
+
+	initializer := m.createFunction(ModuleInitializer + "." + hash)
+	finalizer := m.createFunction(ModuleFinalizer + "." + hash)
+	for _, v := range m.sortedInitializers() {
+		initializer.Body.Statements = append(initializer.Body.Statements, &variableInitializer{Variable: v})
+		if _, ok := p.FindQualified(GetUserData(v.IRVariable)).(*Class); ok || IsString(v.IRVariable.ContentType) || IsClosure(v.IRVariable.ContentType) {
+			// release in reverse order
+			finalizer.Body.Statements = append([]Statement{&variableFinalizer{Variable: v}}, finalizer.Body.Statements...)
+		}
+	}
+
+	m.IRInitializer = initializer.GenerateIRDeclaration(p)
+	initializer.GenerateIR(p)
+	if len(finalizer.Body.Statements) > 0 {
+		m.IRFinalizer = finalizer.GenerateIRDeclaration(p)
+		finalizer.GenerateIR(p)
+	}
+}
+
+// sortedInitializers returns variables to initialize in source order, except that variables referred by initial value are initialized before it
+// cycles are reported by checker, variables of other modules are initialized by their own modules
+func (m *Module) sortedInitializers() []*Variable {
+	initializers := make(map[*Variable]bool)
+	for _, v := range m.Initializers {
+		initializers[v] = true
+	}
+	var sorted []*Variable
+	visited := make(map[*Variable]bool)
+	var visit func(v *Variable)
+	visit = func(v *Variable) {
+		if visited[v] {
+			return
+		}
+		visited[v] = true
+		for _, d := range v.dependencies {
+			if initializers[d] {
+				visit(d)
+			}
+		}
+		sorted = append(sorted, v)
+	}
+	for _, v := range m.Initializers {
+		visit(v)
+	}
+	return sorted
+}
+
+func (m *Module) createFunction(name string) *Function {
+	f := &Function{}
+	f.Name = &Identifier{
+		Name: name,
+	}
+	f.Body = &Block{}
+	return f
+}
+
+type variableInitializer struct {
+	StatementBase
+	Variable *Variable
+}
+
+func (i *variableInitializer) GenerateIR(c *Context) {
+	v := i.Variable
+	n, isNew := v.Value.(*New)
+	if isNew {
+		n.HasOwner = true
+	}
+	lambda, isLambda := v.Value.(*Lambda)
+	if isLambda {
+		lambda.HasOwner = true
+	}
+	t := v.IRVariable.ContentType
+	value := v.Value.GenerateIR(c, t)
+	if value == nil {
+		return
+	}
+	value, err := ImplicitCast(c, c.AutoLoad(value), t)
+	if err != nil {
+		c.Program.Error(v.Value.GetPosition(), err.Error())
+		return
+	}
+	c.Block.AddInstruction(ir.NewStore(value, v.IRVariable))
+
+	// instance returned by call is owned by caller, string returned is a temporary
+	_, isInvocation := v.Value.(*Invocation)
+	if isCounted(c.Program, t) && !isNew && !(isInvocation && !IsString(t)) {
+		// global variable shares the instance
+		retainInstance(c.Program, c.Block, value)
+	} else if IsClosure(t) && !isLambda {
+		RetainClosure(c.Program, c.Block, value)
+	}
+}
+
+type variableFinalizer struct {
+	StatementBase
+	Variable *Variable
+}
+
+func (f *variableFinalizer) GenerateIR(c *Context) {
+	v := f.Variable
+	qualified := GetUserData(v.IRVariable)
+	if IsClosure(v.IRVariable.ContentType) {
+		load := ir.NewLoad(v.IRVariable.ContentType, v.IRVariable)
+		c.Block.AddInstruction(load)
+		ReleaseClosure(c.Program, c.Block, load)
+		c.Block.AddInstruction(ir.NewStore(ir.NewNull(v.IRVariable.ContentType.(*ir.PointerType)), v.IRVariable))
+		return
+	}
+	load := ir.NewLoad(pointerType, v.IRVariable)
+	c.Block.AddInstruction(load)
+	if IsBuiltinClass(qualified) {
+		class := c.Program.FindQualified(qualified).(*Class)
+		class.DestroyInstance(c.Block, load)
+	} else {
+		c.Block.AddInstruction(ir.NewCall(releaseShared, load))
+	}
+	c.Block.AddInstruction(ir.NewStore(ir.NewNull(pointerType), v.IRVariable))
 }
@@ -0,0 +1,257 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/panda-foundation/go-compiler/ir"
+)
+
+// MangleType returns name of type used in signature of overloaded function
+func MangleType(t ir.Type) string {
+	if qualified := GetTypeUserData(t); qualified != "" {
+		return qualified
+	}
+	switch t := t.(type) {
+	case *ir.IntType:
+		if t.BitSize == 1 {
+			return "bool"
+		}
+		if t.Unsigned {
+			return fmt.Sprintf("u%d", t.BitSize)
+		}
+		return fmt.Sprintf("i%d", t.BitSize)
+
+	case *ir.FloatType:
+		switch t.Kind {
+		case ir.FloatKindFloat:
+			return "f32"
+		case ir.FloatKindDouble:
+			return "f64"
+		}
+
+	case *ir.PointerType:
+		if f, ok := t.ElemType.(*ir.FuncType); ok {
+			name := "function(" + MangleTypes(f.Params) + ")"
+			if !ir.IsVoid(f.RetType) {
+				name += MangleType(f.RetType)
+			}
+			return name
+		}
+		if t.Equal(pointerType) {
+			return "pointer"
+		}
+		return MangleType(t.ElemType) + "*"
+	}
+	return t.String()
+}
+
+// MangleTypes returns names of types separated by "$"
+func MangleTypes(types []ir.Type) string {
+	var names []string
+	for _, t := range types {
+		names = append(names, MangleType(t))
+	}
+	return strings.Join(names, "$")
+}
+
+// ResolveOverload selects the candidate which matches argument types best
+// exact match is better than implicit conversion, call is ambiguous if no candidate is better than all others
+func ResolveOverload(c *Context, name string, candidates []*Function, args *Arguments) (*Function, error) {
+	var types []ir.Type
+	if args != nil {
+		for _, arg := range args.Arguments {
+			t := arg.Type(c, nil)
+			if t != nil && ir.IsFunc(t) {
+				// function is passed as function value
+				t = ir.NewPointerType(t)
+			}
+			types = append(types, t)
+		}
+	}
+	return resolveOverload(c.Program, name, candidates, types)
+}
+
+// resolveOverload selects the candidate by types of arguments, nil type is unknown which matches any parameter
+func resolveOverload(p *Program, name string, candidates []*Function, types []ir.Type) (*Function, error) {
+	var viable []*Function
+	var ranks [][]int
+	for _, candidate := range candidates {
+		params := candidate.ParameterTypes()
+		if len(params) != len(types) {
+			continue
+		}
+		rank := make([]int, len(types))
+		for i, t := range types {
+			rank[i] = conversionRank(p, t, params[i])
+			if rank[i] < 0 {
+				rank = nil
+				break
+			}
+		}
+		if rank != nil {
+			viable = append(viable, candidate)
+			ranks = append(ranks, rank)
+		}
+	}
+
+	for i, candidate := range viable {
+		best := true
+		for j := range viable {
+			if i != j && !betterRank(ranks[i], ranks[j]) {
+				best = false
+				break
+			}
+		}
+		if best {
+			return candidate, nil
+		}
+	}
+
+	var signatures []string
+	for _, candidate := range candidates {
+		signatures = append(signatures, describeFunction(candidate.Name.Name, candidate.ParameterTypes()))
+	}
+	if len(viable) == 0 {
+		return nil, fmt.Errorf("no overload matches %s, candidates: %s", describeFunction(name, types), strings.Join(signatures, "; "))
+	}
+	return nil, fmt.Errorf("ambiguous call to %s, candidates: %s", describeFunction(name, types), strings.Join(signatures, "; "))
+}
+
+// SelectOverload returns overload of f which has the expected function type, nil is returned if it cannot be decided
+func SelectOverload(f *Function, expected ir.Type) *Function {
+	if len(f.Overloads) == 0 {
+		return f
+	}
+	if IsClosure(expected) {
+		sig := expected.(*ir.PointerType).ElemType
+		for _, overload := range f.Overloads {
+			if overload.IRFunction != nil && overload.IRFunction.Sig.Equal(sig) {
+				return overload
+			}
+		}
+	}
+	return nil
+}
+
+// conversionRank returns 0 if argument type is exactly the parameter type, 1 if it is converted implicitly, -1 if it is not convertible
+func conversionRank(p *Program, from ir.Type, to ir.Type) int {
+	if from == nil {
+		// type is unknown, error is reported when argument is generated
+		return 1
+	}
+	if MangleType(from) == MangleType(to) {
+		return 0
+	}
+	if IsReference(p, to) {
+		// variable of struct is passed by reference
+		if from.Equal(to.(*ir.PointerType).ElemType) {
+			return 0
+		}
+		return -1
+	}
+	if ir.IsNumber(from) && ir.IsNumber(to) && GetTypeUserData(from) == "" && GetTypeUserData(to) == "" {
+		if promoted, err := PromoteNumberType(to, from); err == nil && promoted.Equal(to) {
+			return 1
+		}
+		return -1
+	}
+	fromClass, _ := p.FindQualified(GetTypeUserData(from)).(*Class)
+	toClass, _ := p.FindQualified(GetTypeUserData(to)).(*Class)
+	if fromClass != nil && toClass != nil {
+		if fromClass.IsSubclassOf(toClass) {
+			return 1
+		}
+		return -1
+	}
+	if from.Equal(to) {
+		return 1
+	}
+	return -1
+}
+
+// betterRank reports whether conversions of r1 are not worse than r2 for every argument, and better for at least one
+func betterRank(r1 []int, r2 []int) bool {
+	better := false
+	for i := range r1 {
+		if r1[i] > r2[i] {
+			return false
+		}
+		if r1[i] < r2[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// sameParameters reports whether parameters are declared with the same types, and passed by reference the same way
+// types written differently are compared after they are resolved, when function is declared
+func sameParameters(p1 *Parameters, p2 *Parameters) bool {
+	var t1, t2 []Type
+	var r1, r2 []bool
+	if p1 != nil {
+		for _, param := range p1.Parameters {
+			t1 = append(t1, param.Type)
+			r1 = append(r1, param.Reference)
+		}
+	}
+	if p2 != nil {
+		for _, param := range p2.Parameters {
+			t2 = append(t2, param.Type)
+			r2 = append(r2, param.Reference)
+		}
+	}
+	if !sameTypes(t1, t2) {
+		return false
+	}
+	for i := range r1 {
+		if r1[i] != r2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameTypes(types1 []Type, types2 []Type) bool {
+	if len(types1) != len(types2) {
+		return false
+	}
+	for i := range types1 {
+		if !sameType(types1[i], types2[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameType(t1 Type, t2 Type) bool {
+	if t1 == nil || t2 == nil {
+		return t1 == nil && t2 == nil
+	}
+	switch t1 := t1.(type) {
+	case *BuitinType:
+		t2, ok := t2.(*BuitinType)
+		return ok && t1.Token == t2.Token
+
+	case *TypeName:
+		t2, ok := t2.(*TypeName)
+		return ok && t1.Selector == t2.Selector && t1.Name == t2.Name
+
+	case *TypeFunction:
+		t2, ok := t2.(*TypeFunction)
+		return ok && sameType(t1.ReturnType, t2.ReturnType) && sameTypes(t1.Parameters, t2.Parameters)
+	}
+	return false
+}
+
+func describeFunction(name string, types []ir.Type) string {
+	var names []string
+	for _, t := range types {
+		if t == nil {
+			names = append(names, "?")
+		} else {
+			names = append(names, MangleType(t))
+		}
+	}
+	return name + "(" + strings.Join(names, ", ") + ")"
+}
@@ -3,6 +3,7 @@ package ast
 import (
 	"crypto/md5"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/panda-foundation/go-compiler/ir"
@@ -19,10 +20,15 @@ type Program struct {
 	Module   *Module
 	IRModule *ir.Module
 
-	Declarations map[string]Declaration
-	Strings      map[string]ir.Constant
+	Declarations   map[string]Declaration
+	Strings        map[string]ir.Constant
+	StringLiterals map[string]*ir.Global
+	Intrinsics     map[string]*ir.Func
+	Closures       map[string]ir.Constant
+	Lambdas        int
 
-	Errors []*Error
+	Errors   []*Error
+	Warnings []*Error
 }
 
 func NewProgram() *Program {
@@ -37,8 +43,13 @@ func (p *Program) Reset() {
 
 	p.Declarations = make(map[string]Declaration)
 	p.Strings = make(map[string]ir.Constant)
+	p.StringLiterals = make(map[string]*ir.Global)
+	p.Intrinsics = make(map[string]*ir.Func)
+	p.Closures = make(map[string]ir.Constant)
+	p.Lambdas = 0
 
 	p.Errors = p.Errors[:0]
+	p.Warnings = p.Warnings[:0]
 }
 
 func (p *Program) FindSelector(selector, member string) (string, Declaration) {
@@ -57,7 +68,7 @@ func (p *Program) FindSelector(selector, member string) (string, Declaration) {
 	} else {
 		// search imports
 		for _, i := range p.Module.Imports {
-			if i.Alias == selector {
+			if i.Alias == selector && !i.invalid {
 				qualified := i.Namespace + "." + member
 				return qualified, p.Declarations[qualified]
 			}
@@ -88,6 +99,15 @@ func (p *Program) AddString(value string) ir.Constant {
 	return v
 }
 
+// DeclareIntrinsic adds declaration of llvm intrinsic function to module when it is first used
+func (p *Program) DeclareIntrinsic(f *ir.Func) *ir.Func {
+	if _, ok := p.Intrinsics[f.Name()]; !ok {
+		p.Intrinsics[f.Name()] = f
+		p.IRModule.Funcs = append(p.IRModule.Funcs, f)
+	}
+	return f
+}
+
 func (p *Program) Error(offset int, message string) {
 	p.Errors = append(p.Errors, &Error{
 		Position: p.Module.File.Position(offset),
@@ -95,10 +115,82 @@ func (p *Program) Error(offset int, message string) {
 	})
 }
 
+// Warning reports problem which does not stop compiling
+func (p *Program) Warning(offset int, message string) {
+	p.Warnings = append(p.Warnings, &Error{
+		Position: p.Module.File.Position(offset),
+		Message:  message,
+	})
+}
+
+// SortedModules returns modules ordered by imports, modules of imported namespace come first
+// modules of the same namespace are ordered by file names, this is also the order of module initialization
+func (p *Program) SortedModules() []*Module {
+	var files []string
+	for file := range p.Modules {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	namespaces := make(map[string][]*Module)
+	for _, file := range files {
+		m := p.Modules[file]
+		namespaces[m.Namespace] = append(namespaces[m.Namespace], m)
+	}
+
+	var modules []*Module
+	for _, namespace := range p.Dependencies().Order() {
+		modules = append(modules, namespaces[namespace]...)
+	}
+	return modules
+}
+
 func (p *Program) GenerateIR() string {
+	p.ValidateImports()
+	modules := p.SortedModules()
+
+	// invalid attributes could break generating declarations (e.g. extern member function)
+	errors := len(p.Errors)
+	p.CheckAttributes()
+	if len(p.Errors) > errors {
+		return ""
+	}
+
+	// enum pass (fold values of enum members, they are constants of other declarations)
+	for _, m := range modules {
+		p.Module = m
+		for _, e := range m.Enums {
+			e.GenerateIRValues(p)
+		}
+	}
+
+	// structs are declared before other declarations, so they could be used as types of variables and parameters
+	for _, m := range modules {
+		p.Module = m
+		for _, s := range m.Structs {
+			s.DeclareIR(p)
+		}
+	}
+
+	// struct pass (generate layouts and default values of structs, which are used by default values of other declarations)
+	for _, m := range modules {
+		p.Module = m
+		for _, s := range m.Structs {
+			s.GenerateIRDeclaration(p)
+		}
+	}
+	for _, m := range modules {
+		p.Module = m
+		for _, s := range m.Structs {
+			s.CheckLayout(p)
+		}
+	}
+	p.generateStructValues(modules)
+
+	p.declareReflectTypes()
+
 	// zero pass (generate declarations)
-	for _, m := range p.Modules {
-		// TO-DO check if import is valid // must be valid, cannot import self, cannot duplicated
+	for _, m := range modules {
 		p.Module = m
 
 		for _, f := range m.Functions {
@@ -121,8 +213,10 @@ func (p *Program) GenerateIR() string {
 		}
 	}
 
+	p.ResolveInheritance()
+
 	// first pass (resolve oop)
-	for _, m := range p.Modules {
+	for _, m := range modules {
 		p.Module = m
 
 		for _, c := range m.Classes {
@@ -131,23 +225,69 @@ func (p *Program) GenerateIR() string {
 		}
 	}
 
+	// check pass (resolve types of expressions)
+	NewChecker(p).Check()
+	if len(p.Errors) > 0 {
+		return ""
+	}
+
+	// type info is generated after types of member variables are inferred by checker
+	for _, m := range modules {
+		p.Module = m
+
+		for _, c := range m.Classes {
+			c.GenerateIRTypeInfo(p)
+		}
+	}
+
 	// second pass (generate functions)
-	for _, m := range p.Modules {
+	for _, m := range modules {
 		p.Module = m
 
 		for _, v := range m.Variables {
 			v.GenerateIR(p)
 		}
 
+		for _, c := range m.Classes {
+			c.GenerateIRStatics(p)
+		}
+
 		for _, f := range m.Functions {
 			f.GenerateIR(p)
 		}
 
+		for _, s := range m.Structs {
+			s.GenerateIR(p)
+		}
+
 		for _, c := range m.Classes {
 			c.GenerateIR(p)
 		}
 	}
 
+	// attribute pass (transform generated declarations)
+	p.ProcessAttributes()
+
+	// third pass (generate module initializers)
+	var initializers []ir.Constant
+	var finalizers []ir.Constant
+	for i, m := range modules {
+		p.Module = m
+
+		m.GenerateIRInitializer(p)
+		priority := ir.NewInt(ir.I32, int64(InitializerPriority+i))
+		if m.IRInitializer != nil {
+			initializers = append(initializers, ir.NewStruct(initializerType, priority, m.IRInitializer, ir.NewNull(pointerType)))
+		}
+		if m.IRFinalizer != nil {
+			// destructors with same priority run in reverse order
+			finalizers = append(finalizers, ir.NewStruct(initializerType, priority, m.IRFinalizer, ir.NewNull(pointerType)))
+		}
+	}
+	p.registerInitializers("llvm.global_ctors", initializers)
+	p.registerInitializers("llvm.global_dtors", finalizers)
+	p.generateStringLiterals()
+
 	buf := &strings.Builder{}
 	_, err := p.IRModule.WriteTo(buf)
 	if err != nil {
@@ -155,3 +295,40 @@ func (p *Program) GenerateIR() string {
 	}
 	return buf.String()
 }
+
+// generateStructValues generates default values of structs, default values of structs which are types of their variables are generated first
+func (p *Program) generateStructValues(modules []*Module) {
+	owners := make(map[*Struct]*Module)
+	for _, m := range modules {
+		for _, s := range m.Structs {
+			owners[s] = m
+		}
+	}
+	generated := make(map[*Struct]bool)
+	var generate func(s *Struct)
+	generate = func(s *Struct) {
+		generated[s] = true
+		for _, t := range s.IRStruct.Fields {
+			if nested := StructOf(p, t); nested != nil && !generated[nested] {
+				generate(nested)
+			}
+		}
+		p.Module = owners[s]
+		s.GenerateIRValues(p)
+	}
+	for _, m := range modules {
+		for _, s := range m.Structs {
+			if !generated[s] {
+				generate(s)
+			}
+		}
+	}
+}
+
+func (p *Program) registerInitializers(name string, initializers []ir.Constant) {
+	if len(initializers) > 0 {
+		t := ir.NewArrayType(uint64(len(initializers)), initializerType)
+		g := p.IRModule.NewGlobalDef(name, ir.NewArray(t, initializers...))
+		g.Linkage = ir.LinkageAppending
+	}
+}
@@ -0,0 +1,506 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+// kinds of type info
+const (
+	TypeInfoClass = iota
+	TypeInfoEnum
+)
+
+// indexes of reflect.type members
+const (
+	typeInfoName = iota
+	typeInfoKind
+	typeInfoParent
+	typeInfoSize
+	typeInfoFieldCount
+	typeInfoFields
+	typeInfoFunctionCount
+	typeInfoFunctions
+	typeInfoAttributeCount
+	typeInfoAttributes
+)
+
+// indexes of reflect.field, reflect.function and reflect.attribute members
+const (
+	memberInfoName   = 0
+	memberInfoType   = 1
+	memberInfoOffset = 2
+	memberInfoSlot   = 2
+	memberInfoText   = 1
+)
+
+var (
+	reflectType = namedStruct(ReflectType, pointerType, ir.I32, ir.NewPointerType(CreateStruct(ReflectType)), ir.I32,
+		ir.I32, ir.NewPointerType(reflectField),
+		ir.I32, ir.NewPointerType(reflectFunction),
+		ir.I32, ir.NewPointerType(reflectAttribute))
+	reflectField     = namedStruct(ReflectField, pointerType, pointerType, ir.I32)
+	reflectFunction  = namedStruct(ReflectFunction, pointerType, pointerType, ir.I32)
+	reflectAttribute = namedStruct(ReflectAttribute, pointerType, pointerType)
+
+	typeInfoPointer = ir.NewPointerType(reflectType)
+)
+
+func namedStruct(name string, fields ...ir.Type) *ir.StructType {
+	t := ir.NewStructType(fields...)
+	t.TypeName = name
+	return t
+}
+
+func init() {
+	RegisterReflectFunction("type_of", reflectTypeOf, []reflectParameter{reflectObject}, pointerType)
+	RegisterReflectFunction("type_info", reflectTypeInfo, []reflectParameter{reflectTypeName}, pointerType)
+	RegisterReflectFunction("type_name", reflectMember(typeInfoName), []reflectParameter{reflectPointer}, pointerType)
+	RegisterReflectFunction("type_parent", reflectMember(typeInfoParent), []reflectParameter{reflectPointer}, pointerType)
+	RegisterReflectFunction("type_size", reflectMember(typeInfoSize), []reflectParameter{reflectPointer}, ir.I32)
+	RegisterReflectFunction("field_count", reflectMember(typeInfoFieldCount), []reflectParameter{reflectPointer}, ir.I32)
+	RegisterReflectFunction("field_name", reflectElement(typeInfoFields, reflectField, memberInfoName), []reflectParameter{reflectPointer, reflectIndex}, pointerType)
+	RegisterReflectFunction("field_type", reflectElement(typeInfoFields, reflectField, memberInfoType), []reflectParameter{reflectPointer, reflectIndex}, pointerType)
+	RegisterReflectFunction("field_offset", reflectElement(typeInfoFields, reflectField, memberInfoOffset), []reflectParameter{reflectPointer, reflectIndex}, ir.I32)
+	RegisterReflectFunction("function_count", reflectMember(typeInfoFunctionCount), []reflectParameter{reflectPointer}, ir.I32)
+	RegisterReflectFunction("function_name", reflectElement(typeInfoFunctions, reflectFunction, memberInfoName), []reflectParameter{reflectPointer, reflectIndex}, pointerType)
+	RegisterReflectFunction("function_type", reflectElement(typeInfoFunctions, reflectFunction, memberInfoType), []reflectParameter{reflectPointer, reflectIndex}, pointerType)
+	RegisterReflectFunction("function_slot", reflectElement(typeInfoFunctions, reflectFunction, memberInfoSlot), []reflectParameter{reflectPointer, reflectIndex}, ir.I32)
+	RegisterReflectFunction("attribute_count", reflectMember(typeInfoAttributeCount), []reflectParameter{reflectPointer}, ir.I32)
+	RegisterReflectFunction("attribute_name", reflectElement(typeInfoAttributes, reflectAttribute, memberInfoName), []reflectParameter{reflectPointer, reflectIndex}, pointerType)
+	RegisterReflectFunction("attribute_text", reflectElement(typeInfoAttributes, reflectAttribute, memberInfoText), []reflectParameter{reflectPointer, reflectIndex}, pointerType)
+	RegisterReflectFunction("get_field", reflectGetField, []reflectParameter{reflectObject, reflectPointer, reflectValue}, nil)
+	RegisterReflectFunction("set_field", reflectSetField, []reflectParameter{reflectObject, reflectPointer, reflectValue}, ir.I1)
+}
+
+// reflectParameter validates type of argument of reflect function
+type reflectParameter = func(p *Program, arg Expression) error
+
+// RegisterReflectFunction registers compiler function in reflect namespace, result type nil means type of the last argument
+func RegisterReflectFunction(name string, f CompilerFunction, params []reflectParameter, result ir.Type) {
+	RegisterComplierFunction(Reflect, name, f)
+	RegisterComplierFunctionType(Reflect, name, func(p *Program, i *Invocation) (ir.Type, error) {
+		var args []Expression
+		if i.Arguments != nil {
+			args = i.Arguments.Arguments
+		}
+		if len(args) != len(params) {
+			return nil, fmt.Errorf("%s.%s expects %d arguments, but found %d", Reflect, name, len(params), len(args))
+		}
+		for j, param := range params {
+			if err := param(p, args[j]); err != nil {
+				return nil, fmt.Errorf("argument %d of %s.%s %s", j+1, Reflect, name, err.Error())
+			}
+		}
+		if result == nil {
+			return args[len(args)-1].ResolvedType(), nil
+		}
+		return result, nil
+	})
+}
+
+func reflectObject(p *Program, arg Expression) error {
+	qualified := GetTypeUserData(arg.ResolvedType())
+	if _, ok := p.FindQualified(qualified).(*Class); !ok || IsBuiltinClass(qualified) {
+		return fmt.Errorf("must be class instance, but found %s", MangleType(arg.ResolvedType()))
+	}
+	return nil
+}
+
+func reflectTypeName(p *Program, arg Expression) error {
+	l, ok := arg.(*Literal)
+	if !ok || l.Typ != token.STRING {
+		return fmt.Errorf("must be string literal")
+	}
+	if findTypeInfo(p, l) == nil {
+		return fmt.Errorf("%s is not class or enum", l.Value)
+	}
+	return nil
+}
+
+func reflectPointer(p *Program, arg Expression) error {
+	t := arg.ResolvedType()
+	if !ir.IsPointer(t) || GetTypeUserData(t) != "" || IsClosure(t) {
+		return fmt.Errorf("must be pointer, but found %s", MangleType(t))
+	}
+	return nil
+}
+
+func reflectIndex(p *Program, arg Expression) error {
+	if t := arg.ResolvedType(); !ir.IsInt(t) || GetTypeUserData(t) != "" || t.Equal(ir.I1) {
+		return fmt.Errorf("must be integer, but found %s", MangleType(t))
+	}
+	return nil
+}
+
+// reflectValue accepts number, bool, enum and pointer, class instances and function values need reference counting
+func reflectValue(p *Program, arg Expression) error {
+	t := arg.ResolvedType()
+	if IsClosure(t) || isClassPointer(p, t) {
+		return fmt.Errorf("must be number, bool, enum or pointer, but found %s", MangleType(t))
+	}
+	return nil
+}
+
+// declareReflectTypes declares types of type info
+func (p *Program) declareReflectTypes() {
+	for _, t := range []*ir.StructType{reflectType, reflectField, reflectFunction, reflectAttribute} {
+		p.IRModule.NewTypeDef(t.TypeName, t)
+	}
+}
+
+// GenerateIRTypeInfo generates type info of class after its struct and vtable are generated
+// fields include variables of parent classes, functions are ordered by vtable slots
+func (c *Class) GenerateIRTypeInfo(p *Program) {
+	qualified := c.Qualified(p.Module.Namespace)
+	structPointer := ir.NewPointerType(c.IRStruct)
+
+	names := make([]string, len(c.IRStruct.Fields))
+	for name, index := range c.VariableIndexes {
+		names[index] = name
+	}
+	var fields []ir.Constant
+	for index := 1; index < len(names); index++ {
+		address := ir.NewExprGetElementPtr(c.IRStruct, ir.NewNull(structPointer), ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+		fields = append(fields, ir.NewStruct(reflectField, p.AddString(names[index]),
+			p.AddString(MangleType(c.IRStruct.Fields[index])), ir.NewExprPtrToInt(address, ir.I32)))
+	}
+
+	var functions []ir.Constant
+	for slot, f := range c.vtable {
+		functions = append(functions, ir.NewStruct(reflectFunction, p.AddString(f.Name.Name),
+			p.AddString(MangleType(functionType(f))), ir.NewInt(ir.I32, int64(slot))))
+	}
+
+	var parent ir.Constant
+	if c.Parent != nil {
+		parent = c.Parent.IRTypeInfo
+	}
+	size := ir.NewExprPtrToInt(ir.NewExprGetElementPtr(c.IRStruct, ir.NewNull(structPointer), ir.NewInt(ir.I32, 1)), ir.I32)
+	c.IRTypeInfo.Init = p.typeInfo(qualified, TypeInfoClass, parent, size, fields, functions, c.Attributes)
+}
+
+func (p *Program) typeInfo(qualified string, kind int64, parent ir.Constant, size ir.Constant, fields []ir.Constant, functions []ir.Constant, attributes []*Attribute) ir.Constant {
+	if parent == nil {
+		parent = ir.NewNull(typeInfoPointer)
+	}
+	var attributeInfos []ir.Constant
+	for _, a := range attributes {
+		attributeInfos = append(attributeInfos, ir.NewStruct(reflectAttribute, p.AddString(a.Name), p.AddString(a.GetText())))
+	}
+	name := qualified + "." + TypeInfo
+	return ir.NewStruct(reflectType, p.AddString(qualified), ir.NewInt(ir.I32, kind), parent, size,
+		ir.NewInt(ir.I32, int64(len(fields))), p.typeInfoArray(name+".fields", reflectField, fields),
+		ir.NewInt(ir.I32, int64(len(functions))), p.typeInfoArray(name+".functions", reflectFunction, functions),
+		ir.NewInt(ir.I32, int64(len(attributeInfos))), p.typeInfoArray(name+".attributes", reflectAttribute, attributeInfos))
+}
+
+// typeInfoArray returns pointer to the first element of constant array, it is null if array is empty
+func (p *Program) typeInfoArray(name string, elemType *ir.StructType, elems []ir.Constant) ir.Constant {
+	if len(elems) == 0 {
+		return ir.NewNull(ir.NewPointerType(elemType))
+	}
+	t := ir.NewArrayType(uint64(len(elems)), elemType)
+	g := p.IRModule.NewGlobalDef(name, ir.NewArray(t, elems...))
+	g.Immutable = true
+	return ir.NewExprGetElementPtr(t, g, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, 0))
+}
+
+// TypeInfoOf returns type info of class instance, it is loaded from the slot before vtable
+// instance must not be null
+func TypeInfoOf(c *Context, instance ir.Value) ir.Value {
+	counterClass := c.Program.FindQualified(Counter).(*Class)
+	object, _ := counterClass.GetMember(c, instance, "object", false)
+	object = c.AutoLoad(object)
+	return loadTypeInfo(c.Block, object)
+}
+
+func loadTypeInfo(b *ir.Block, object ir.Value) ir.Value {
+	vtablePointer := ir.NewBitCast(object, ir.NewPointerType(ir.NewPointerType(typeInfoPointer)))
+	b.AddInstruction(vtablePointer)
+	vtable := ir.NewLoad(ir.NewPointerType(typeInfoPointer), vtablePointer)
+	b.AddInstruction(vtable)
+	slot := ir.NewGetElementPtr(typeInfoPointer, vtable, ir.NewInt(ir.I32, -1))
+	b.AddInstruction(slot)
+	info := ir.NewLoad(typeInfoPointer, slot)
+	b.AddInstruction(info)
+	return info
+}
+
+func reflectArgument(c *Context, i *Invocation, index int) ir.Value {
+	arg := i.Arguments.Arguments[index]
+	if arg.IsConstant(c.Program) {
+		return arg.GenerateConstIR(c.Program, arg.ResolvedType())
+	}
+	return c.AutoLoad(arg.GenerateIR(c, arg.ResolvedType()))
+}
+
+// reflectTypeOf returns type info of class instance, it is null if instance is null
+func reflectTypeOf(c *Context, i *Invocation) ir.Value {
+	instance := reflectArgument(c, i, 0)
+	entry := c.Block
+	infoBlock := c.Function.IRFunction.NewBlock("")
+	nextBlock := c.Function.IRFunction.NewBlock("")
+	isNull := ir.NewICmp(ir.IPredEQ, instance, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, nextBlock, infoBlock))
+
+	c.Block = infoBlock
+	info := ir.NewBitCast(TypeInfoOf(c, instance), pointerType)
+	c.Block.AddInstruction(info)
+	c.Block.AddInstruction(ir.NewBr(nextBlock))
+
+	phi := ir.NewPhi(ir.NewIncoming(ir.NewNull(pointerType), entry), ir.NewIncoming(info, c.Block))
+	c.Block = nextBlock
+	c.Block.AddInstruction(phi)
+	return phi
+}
+
+// reflectTypeInfo returns type info of class or enum named by string literal
+func reflectTypeInfo(c *Context, i *Invocation) ir.Value {
+	return ir.NewExprBitCast(findTypeInfo(c.Program, i.Arguments.Arguments[0].(*Literal)), pointerType)
+}
+
+// findTypeInfo returns type info of class or enum, name is found like type name or it is qualified name
+func findTypeInfo(p *Program, l *Literal) *ir.Global {
+	name, _ := strconv.Unquote(l.Value)
+	var d Declaration
+	if index := strings.LastIndex(name, "."); index > -1 {
+		_, d = p.FindSelector(name[:index], name[index+1:])
+	} else {
+		_, d = p.FindSelector("", name)
+	}
+	if d == nil {
+		d = p.FindQualified(name)
+	}
+	switch t := d.(type) {
+	case *Class:
+		return t.IRTypeInfo
+
+	case *Enum:
+		return t.IRTypeInfo
+	}
+	return nil
+}
+
+// reflectMember loads member of type info
+func reflectMember(index int) CompilerFunction {
+	return func(c *Context, i *Invocation) ir.Value {
+		info := ir.NewBitCast(reflectArgument(c, i, 0), typeInfoPointer)
+		c.Block.AddInstruction(info)
+		member := ir.NewGetElementPtr(reflectType, info, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+		c.Block.AddInstruction(member)
+		var value ir.Value = c.AutoLoad(member)
+		if index == typeInfoParent {
+			cast := ir.NewBitCast(value, pointerType)
+			c.Block.AddInstruction(cast)
+			value = cast
+		}
+		return value
+	}
+}
+
+// reflectElement loads member of element in array of type info, index is not checked
+func reflectElement(array int, elemType *ir.StructType, index int) CompilerFunction {
+	return func(c *Context, i *Invocation) ir.Value {
+		elems := reflectMember(array)(c, i)
+		elem := ir.NewGetElementPtr(elemType, elems, reflectArgument(c, i, 1), ir.NewInt(ir.I32, int64(index)))
+		c.Block.AddInstruction(elem)
+		return c.AutoLoad(elem)
+	}
+}
+
+// reflectGetField returns value of field by name, default value is returned if instance is null,
+// it has no such field or type of field is not the same as default value
+func reflectGetField(c *Context, i *Invocation) ir.Value {
+	address, value := reflectFieldAddress(c, i)
+	entry := c.Block
+	loadBlock := c.Function.IRFunction.NewBlock("")
+	nextBlock := c.Function.IRFunction.NewBlock("")
+	isNull := ir.NewICmp(ir.IPredEQ, address, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, nextBlock, loadBlock))
+
+	field := ir.NewBitCast(address, ir.NewPointerType(value.Type()))
+	loadBlock.AddInstruction(field)
+	load := ir.NewLoad(value.Type(), field)
+	loadBlock.AddInstruction(load)
+	loadBlock.AddInstruction(ir.NewBr(nextBlock))
+
+	c.Block = nextBlock
+	phi := ir.NewPhi(ir.NewIncoming(value, entry), ir.NewIncoming(load, loadBlock))
+	c.Block.AddInstruction(phi)
+	return phi
+}
+
+// reflectSetField sets value of field by name, it returns false if value is not set for the same reasons as get_field
+func reflectSetField(c *Context, i *Invocation) ir.Value {
+	address, value := reflectFieldAddress(c, i)
+	entry := c.Block
+	storeBlock := c.Function.IRFunction.NewBlock("")
+	nextBlock := c.Function.IRFunction.NewBlock("")
+	isNull := ir.NewICmp(ir.IPredEQ, address, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, nextBlock, storeBlock))
+
+	field := ir.NewBitCast(address, ir.NewPointerType(value.Type()))
+	storeBlock.AddInstruction(field)
+	storeBlock.AddInstruction(ir.NewStore(value, field))
+	storeBlock.AddInstruction(ir.NewBr(nextBlock))
+
+	c.Block = nextBlock
+	phi := ir.NewPhi(ir.NewIncoming(ir.False, entry), ir.NewIncoming(ir.True, storeBlock))
+	c.Block.AddInstruction(phi)
+	return phi
+}
+
+func reflectFieldAddress(c *Context, i *Invocation) (address ir.Value, value ir.Value) {
+	instance := reflectArgument(c, i, 0)
+	name := reflectArgument(c, i, 1)
+	value = reflectArgument(c, i, 2)
+	call := ir.NewCall(c.Program.fieldAddressFunction(), instance, name, c.Program.AddString(MangleType(value.Type())))
+	c.Block.AddInstruction(call)
+	return call, value
+}
+
+// fieldAddressFunction generates function which finds address of field by name and type in type info of instance
+// it returns null if instance is null or field is not found
+func (p *Program) fieldAddressFunction() *ir.Func {
+	const name = Reflect + ".field_address"
+	if f, ok := p.Intrinsics[name]; ok {
+		return f
+	}
+	instance := ir.NewParam(pointerType)
+	instance.LocalName = "instance"
+	fieldName := ir.NewParam(pointerType)
+	fieldName.LocalName = "name"
+	fieldType := ir.NewParam(pointerType)
+	fieldType.LocalName = "type"
+	f := p.IRModule.NewFunc(name, pointerType, instance, fieldName, fieldType)
+	p.Intrinsics[name] = f
+
+	entry := f.NewBlock(FunctionEntry)
+	body := f.NewBlock(FunctionBody)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	checkType := f.NewBlock("")
+	found := f.NewBlock("")
+	next := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	isNull := ir.NewICmp(ir.IPredEQ, instance, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, exit, body))
+
+	counterClass := p.FindQualified(Counter).(*Class)
+	counter := ir.NewBitCast(instance, ir.NewPointerType(counterClass.IRStruct))
+	body.AddInstruction(counter)
+	objectPointer := ir.NewGetElementPtr(counterClass.IRStruct, counter, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(counterClass.VariableIndexes["object"])))
+	body.AddInstruction(objectPointer)
+	object := ir.NewLoad(pointerType, objectPointer)
+	body.AddInstruction(object)
+	info := loadTypeInfo(body, object)
+	countPointer := ir.NewGetElementPtr(reflectType, info, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, typeInfoFieldCount))
+	body.AddInstruction(countPointer)
+	count := ir.NewLoad(ir.I32, countPointer)
+	body.AddInstruction(count)
+	fieldsPointer := ir.NewGetElementPtr(reflectType, info, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, typeInfoFields))
+	body.AddInstruction(fieldsPointer)
+	fields := ir.NewLoad(ir.NewPointerType(reflectField), fieldsPointer)
+	body.AddInstruction(fields)
+	body.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), body))
+	loop.AddInstruction(index)
+	inRange := ir.NewICmp(ir.IPredSLT, index, count)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, check, exit))
+
+	field := func(b *ir.Block, member int64) ir.Value {
+		pointer := ir.NewGetElementPtr(reflectField, fields, index, ir.NewInt(ir.I32, member))
+		b.AddInstruction(pointer)
+		return pointer
+	}
+	nameValue := ir.NewLoad(pointerType, field(check, memberInfoName))
+	check.AddInstruction(nameValue)
+	nameEqual := ir.NewCall(p.stringEqualFunction(), nameValue, fieldName)
+	check.AddInstruction(nameEqual)
+	check.AddInstruction(ir.NewCondBr(nameEqual, checkType, next))
+
+	typeValue := ir.NewLoad(pointerType, field(checkType, memberInfoType))
+	checkType.AddInstruction(typeValue)
+	typeEqual := ir.NewCall(p.stringEqualFunction(), typeValue, fieldType)
+	checkType.AddInstruction(typeEqual)
+	checkType.AddInstruction(ir.NewCondBr(typeEqual, found, exit))
+
+	offset := ir.NewLoad(ir.I32, field(found, memberInfoOffset))
+	found.AddInstruction(offset)
+	address := ir.NewGetElementPtr(ir.I8, object, offset)
+	found.AddInstruction(address)
+	found.AddInstruction(ir.NewBr(exit))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	null := ir.NewNull(pointerType)
+	result := ir.NewPhi(ir.NewIncoming(null, entry), ir.NewIncoming(null, loop), ir.NewIncoming(null, checkType), ir.NewIncoming(address, found))
+	exit.AddInstruction(result)
+	exit.AddInstruction(ir.NewRet(result))
+	return f
+}
+
+// stringEqualFunction generates function which compares null terminated strings
+func (p *Program) stringEqualFunction() *ir.Func {
+	const name = Reflect + ".string_equal"
+	if f, ok := p.Intrinsics[name]; ok {
+		return f
+	}
+	a := ir.NewParam(pointerType)
+	a.LocalName = "a"
+	b := ir.NewParam(pointerType)
+	b.LocalName = "b"
+	f := p.IRModule.NewFunc(name, ir.I1, a, b)
+	p.Intrinsics[name] = f
+
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	same := f.NewBlock("")
+	next := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	char := func(s ir.Value) ir.Value {
+		pointer := ir.NewGetElementPtr(ir.I8, s, index)
+		loop.AddInstruction(pointer)
+		load := ir.NewLoad(ir.I8, pointer)
+		loop.AddInstruction(load)
+		return load
+	}
+	charA := char(a)
+	charB := char(b)
+	notEqual := ir.NewICmp(ir.IPredNE, charA, charB)
+	loop.AddInstruction(notEqual)
+	loop.AddInstruction(ir.NewCondBr(notEqual, exit, same))
+
+	end := ir.NewICmp(ir.IPredEQ, charA, ir.NewInt(ir.I8, 0))
+	same.AddInstruction(end)
+	same.AddInstruction(ir.NewCondBr(end, exit, next))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	result := ir.NewPhi(ir.NewIncoming(ir.False, loop), ir.NewIncoming(ir.True, same))
+	exit.AddInstruction(result)
+	exit.AddInstruction(ir.NewRet(result))
+	return f
+}
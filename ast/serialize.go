@@ -0,0 +1,957 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/panda-foundation/go-compiler/ir"
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+// binary format of serialized class instance, integers are little-endian
+// header: magic "PDB", version (u8), length of buffer (u32)
+// reference: tag (u32), 0 is null, 0xFFFFFFFF is new instance, otherwise it is index of serialized instance starting from 1
+// new instance: length of qualified class name (u32), qualified class name terminated by 0, count of fields (u32), fields
+// fields are in struct order: integers and enums in their sizes, bool in 1 byte, floats in their bits, class instances as references
+// string: size in bytes (u32), utf-8 bytes without terminator, null string is the same as empty string
+const (
+	BinaryVersion = 1
+
+	binaryMagic      = 'P' | 'D'<<8 | 'B'<<16 | BinaryVersion<<24
+	binaryHeaderSize = 8
+	binaryNull       = 0
+	binaryNew        = -1
+)
+
+func init() {
+	RegisterAttributeHandler(Serializable, TargetClass, serializableAttribute)
+
+	RegisterComplierFunction(Serialization, "serialize", binarySerialize)
+	RegisterComplierFunctionType(Serialization, "serialize", func(p *Program, i *Invocation) (ir.Type, error) {
+		if err := intrinsicArguments(Serialization, i, 1); err != nil {
+			return nil, err
+		}
+		t := i.Arguments.Arguments[0].ResolvedType()
+		if attributedInstance(p, t, Serializable) == nil {
+			return nil, fmt.Errorf("argument 1 of %s.serialize must be instance of serializable class, but found %s", Serialization, MangleType(t))
+		}
+		return pointerType, nil
+	})
+
+	RegisterComplierFunction(Serialization, "deserialize", binaryDeserialize)
+	RegisterComplierFunctionType(Serialization, "deserialize", func(p *Program, i *Invocation) (ir.Type, error) {
+		if err := intrinsicArguments(Serialization, i, 2); err != nil {
+			return nil, err
+		}
+		if err := reflectPointer(p, i.Arguments.Arguments[0]); err != nil {
+			return nil, fmt.Errorf("argument 1 of %s.deserialize %s", Serialization, err.Error())
+		}
+		class := attributedClass(p, i.Arguments.Arguments[1], Serializable)
+		if class == nil {
+			return nil, fmt.Errorf("argument 2 of %s.deserialize must be name of serializable class", Serialization)
+		}
+		return CreateClassPointer(class.IRStruct.TypeName), nil
+	})
+
+	RegisterComplierFunction(Serialization, "length", binaryLength)
+	RegisterComplierFunctionType(Serialization, "length", func(p *Program, i *Invocation) (ir.Type, error) {
+		if err := intrinsicArguments(Serialization, i, 1); err != nil {
+			return nil, err
+		}
+		if err := reflectPointer(p, i.Arguments.Arguments[0]); err != nil {
+			return nil, fmt.Errorf("argument 1 of %s.length %s", Serialization, err.Error())
+		}
+		return ir.I32, nil
+	})
+}
+
+// intrinsicArguments checks count of arguments of compiler function
+func intrinsicArguments(namespace string, i *Invocation, count int) error {
+	if i.Arguments == nil || len(i.Arguments.Arguments) != count {
+		found := 0
+		if i.Arguments != nil {
+			found = len(i.Arguments.Arguments)
+		}
+		return fmt.Errorf("%s.%s expects %d arguments, but found %d", namespace, i.Function.(*MemberAccess).Member.Name, count, found)
+	}
+	return nil
+}
+
+// attributedClass finds class named by string literal like type name, class must have attribute
+func attributedClass(p *Program, e Expression, attribute string) *Class {
+	l, ok := e.(*Literal)
+	if !ok || l.Typ != token.STRING {
+		return nil
+	}
+	info := findTypeInfo(p, l)
+	for _, d := range p.Declarations {
+		if class, ok := d.(*Class); ok && info != nil && class.IRTypeInfo == info && class.HasAttribute(attribute) {
+			return class
+		}
+	}
+	return nil
+}
+
+// @serializable generates binary serialization of class, all variables must be numbers, bools, enums, strings or instances of serializable classes
+func serializableAttribute(p *Program, d Declaration, a *Attribute) {
+	noArguments(p, d, a)
+	c := d.(*Class)
+	for current := c; current != nil; current = current.Parent {
+		for i, v := range current.Variables {
+			if v.IsStatic() {
+				continue
+			}
+			t := current.IRVariables[i]
+			if binaryFieldSize(t) == 0 && !IsString(t) && attributedInstance(p, t, Serializable) == nil {
+				p.Error(v.Position, fmt.Sprintf("variable %s of serializable class %s cannot be serialized, its type is %s", v.Name.Name, c.Name.Name, MangleType(t)))
+			}
+		}
+	}
+}
+
+// attributedInstance returns class of instance type if class has attribute
+func attributedInstance(p *Program, t ir.Type, attribute string) *Class {
+	if class, ok := p.FindQualified(GetTypeUserData(t)).(*Class); ok && ir.IsPointer(t) && class.HasAttribute(attribute) {
+		return class
+	}
+	return nil
+}
+
+// binaryFieldSize returns size of number, bool or enum in bytes, it is 0 if type is not one of them
+func binaryFieldSize(t ir.Type) int64 {
+	switch t := t.(type) {
+	case *ir.IntType:
+		if t.BitSize == 1 {
+			return 1
+		}
+		return int64(t.BitSize / 8)
+
+	case *ir.FloatType:
+		switch t.Kind {
+		case ir.FloatKindFloat:
+			return 4
+		case ir.FloatKindDouble:
+			return 8
+		}
+	}
+	return 0
+}
+
+// binarySerialize returns buffer of serialized instance which should be freed, it is null if any instance in it is not serializable
+func binarySerialize(c *Context, i *Invocation) ir.Value {
+	instance := reflectArgument(c, i, 0)
+	class := c.Program.FindQualified(GetUserData(instance)).(*Class)
+	call := ir.NewCall(c.Program.serializeFunction(class), instance)
+	c.Block.AddInstruction(call)
+	return call
+}
+
+// binaryDeserialize returns instance of class from buffer, it is null if buffer is invalid
+func binaryDeserialize(c *Context, i *Invocation) ir.Value {
+	buffer := reflectArgument(c, i, 0)
+	class := attributedClass(c.Program, i.Arguments.Arguments[1], Serializable)
+	call := ir.NewCall(c.Program.deserializeFunction(class), buffer)
+	call.Typ = CreateClassPointer(class.IRStruct.TypeName)
+	c.Block.AddInstruction(call)
+	return call
+}
+
+// binaryLength returns length of serialized buffer, it is 0 if buffer is null
+func binaryLength(c *Context, i *Invocation) ir.Value {
+	call := ir.NewCall(c.Program.bufferLengthFunction(), reflectArgument(c, i, 0))
+	c.Block.AddInstruction(call)
+	return call
+}
+
+func loadCounterObject(p *Program, b *ir.Block, counter ir.Value) ir.Value {
+	object := ir.NewLoad(pointerType, counterMember(p, b, counter, "object"))
+	b.AddInstruction(object)
+	return object
+}
+
+// counterMember returns address of variable of counter
+func counterMember(p *Program, b *ir.Block, counter ir.Value, name string) ir.Value {
+	counterClass := p.FindQualified(Counter).(*Class)
+	cast := ir.NewBitCast(counter, ir.NewPointerType(counterClass.IRStruct))
+	b.AddInstruction(cast)
+	member := ir.NewGetElementPtr(counterClass.IRStruct, cast, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(counterClass.VariableIndexes[name])))
+	b.AddInstruction(member)
+	return member
+}
+
+// serializableDescendants returns class and classes inherit from it which are serializable
+func serializableDescendants(p *Program, c *Class) []*Class {
+	var classes []*Class
+	for _, class := range c.Descendants(p) {
+		if class.HasAttribute(Serializable) {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
+
+func newParam(name string, t ir.Type) *ir.Param {
+	param := ir.NewParam(t)
+	param.LocalName = name
+	return param
+}
+
+// serializeFunction generates function which writes header and instance to a new buffer
+func (p *Program) serializeFunction(c *Class) *ir.Func {
+	instance := newParam("instance", pointerType)
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".binary.serialize", pointerType, instance)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	failed := f.NewBlock("")
+	succeeded := f.NewBlock("")
+
+	stream := newStream(entry)
+	entry.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, ir.NewInt(ir.I64, binaryMagic), ir.NewInt(ir.I32, 4)))
+	entry.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, ir.NewInt(ir.I64, 0), ir.NewInt(ir.I32, 4)))
+	entry.AddInstruction(ir.NewCall(p.writeReferenceFunction(c), stream, instance))
+	entry.AddInstruction(ir.NewCall(free, loadObjects(entry, stream)))
+	isFailed := loadStreamMember(entry, stream, streamFailed)
+	entry.AddInstruction(ir.NewCondBr(isFailed, failed, succeeded))
+
+	failed.AddInstruction(ir.NewCall(free, loadStreamMember(failed, stream, streamData)))
+	failed.AddInstruction(ir.NewRet(ir.NewNull(pointerType)))
+
+	// length is written after header is written
+	length := loadStreamMember(succeeded, stream, streamPosition)
+	succeeded.AddInstruction(ir.NewStore(ir.NewInt(ir.I32, 4), streamMember(succeeded, stream, streamPosition)))
+	extended := ir.NewZExt(length, ir.I64)
+	succeeded.AddInstruction(extended)
+	succeeded.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, extended, ir.NewInt(ir.I32, 4)))
+	succeeded.AddInstruction(ir.NewRet(loadStreamMember(succeeded, stream, streamData)))
+	return f
+}
+
+func loadObjects(b *ir.Block, stream ir.Value) ir.Value {
+	objects := loadStreamMember(b, stream, streamObjects)
+	cast := ir.NewBitCast(objects, pointerType)
+	b.AddInstruction(cast)
+	return cast
+}
+
+// deserializeFunction generates function which checks header and reads instance from buffer
+// instances which are read are released if buffer is invalid
+func (p *Program) deserializeFunction(c *Class) *ir.Func {
+	buffer := newParam("buffer", pointerType)
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".binary.deserialize", pointerType, buffer)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	header := f.NewBlock("")
+	body := f.NewBlock("")
+	release := f.NewBlock("")
+	loop := f.NewBlock("")
+	next := f.NewBlock("")
+	failed := f.NewBlock("")
+	succeeded := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	stream := newStream(entry)
+	isNull := ir.NewICmp(ir.IPredEQ, buffer, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, exit, header))
+
+	header.AddInstruction(ir.NewStore(buffer, streamMember(header, stream, streamData)))
+	header.AddInstruction(ir.NewStore(ir.NewInt(ir.I32, binaryHeaderSize), streamMember(header, stream, streamSize)))
+	magic := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, 4))
+	header.AddInstruction(magic)
+	length := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, 4))
+	header.AddInstruction(length)
+	validMagic := ir.NewICmp(ir.IPredEQ, magic, ir.NewInt(ir.I64, binaryMagic))
+	header.AddInstruction(validMagic)
+	validLength := ir.NewICmp(ir.IPredSGE, length, ir.NewInt(ir.I64, binaryHeaderSize))
+	header.AddInstruction(validLength)
+	valid := ir.NewAnd(validMagic, validLength)
+	header.AddInstruction(valid)
+	header.AddInstruction(ir.NewCondBr(valid, body, exit))
+
+	size := ir.NewTrunc(length, ir.I32)
+	body.AddInstruction(size)
+	body.AddInstruction(ir.NewStore(size, streamMember(body, stream, streamSize)))
+	instance := ir.NewCall(p.readReferenceFunction(c), stream)
+	body.AddInstruction(instance)
+	isFailed := loadStreamMember(body, stream, streamFailed)
+	position := loadStreamMember(body, stream, streamPosition)
+	complete := ir.NewICmp(ir.IPredEQ, position, size)
+	body.AddInstruction(complete)
+	notFailed := ir.NewXor(isFailed, ir.True)
+	body.AddInstruction(notFailed)
+	ok := ir.NewAnd(notFailed, complete)
+	body.AddInstruction(ok)
+	body.AddInstruction(ir.NewCondBr(ok, succeeded, release))
+
+	count := loadStreamMember(release, stream, streamCount)
+	objects := loadStreamMember(release, stream, streamObjects)
+	release.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), release))
+	loop.AddInstruction(index)
+	inRange := ir.NewICmp(ir.IPredSLT, index, count)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, next, failed))
+
+	objectPointer := ir.NewGetElementPtr(pointerType, objects, index)
+	next.AddInstruction(objectPointer)
+	object := ir.NewLoad(pointerType, objectPointer)
+	next.AddInstruction(object)
+	next.AddInstruction(ir.NewCall(releaseShared, object))
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	failed.AddInstruction(ir.NewCall(free, loadObjects(failed, stream)))
+	failed.AddInstruction(ir.NewBr(exit))
+
+	succeeded.AddInstruction(ir.NewCall(free, loadObjects(succeeded, stream)))
+	succeeded.AddInstruction(ir.NewBr(exit))
+
+	null := ir.NewNull(pointerType)
+	result := ir.NewPhi(ir.NewIncoming(null, entry), ir.NewIncoming(null, header), ir.NewIncoming(null, failed), ir.NewIncoming(instance, succeeded))
+	exit.AddInstruction(result)
+	exit.AddInstruction(ir.NewRet(result))
+	return f
+}
+
+// bufferLengthFunction generates function which reads length from header of buffer
+func (p *Program) bufferLengthFunction() *ir.Func {
+	buffer := newParam("buffer", pointerType)
+	f, created := p.newStreamFunction(Serialization+".length", ir.I32, buffer)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	body := f.NewBlock(FunctionBody)
+	exit := f.NewBlock(FunctionExit)
+
+	stream := newStream(entry)
+	isNull := ir.NewICmp(ir.IPredEQ, buffer, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, exit, body))
+
+	body.AddInstruction(ir.NewStore(buffer, streamMember(body, stream, streamData)))
+	body.AddInstruction(ir.NewStore(ir.NewInt(ir.I32, binaryHeaderSize), streamMember(body, stream, streamSize)))
+	body.AddInstruction(ir.NewStore(ir.NewInt(ir.I32, 4), streamMember(body, stream, streamPosition)))
+	length := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, 4))
+	body.AddInstruction(length)
+	trunc := ir.NewTrunc(length, ir.I32)
+	body.AddInstruction(trunc)
+	body.AddInstruction(ir.NewBr(exit))
+
+	result := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry), ir.NewIncoming(trunc, body))
+	exit.AddInstruction(result)
+	exit.AddInstruction(ir.NewRet(result))
+	return f
+}
+
+// writeReferenceFunction generates function which writes null, reference of serialized instance or new instance
+// instance whose class is not serializable fails the stream
+func (p *Program) writeReferenceFunction(c *Class) *ir.Func {
+	stream := streamParam()
+	instance := newParam("instance", pointerType)
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".binary.write", ir.Void, stream, instance)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	null := f.NewBlock("")
+	lookup := f.NewBlock("")
+	reference := f.NewBlock("")
+	write := f.NewBlock("")
+
+	isNull := ir.NewICmp(ir.IPredEQ, instance, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, null, lookup))
+
+	null.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, ir.NewInt(ir.I64, binaryNull), ir.NewInt(ir.I32, 4)))
+	null.AddInstruction(ir.NewRet(nil))
+
+	index := ir.NewCall(p.findObjectFunction(), stream, instance)
+	lookup.AddInstruction(index)
+	found := ir.NewICmp(ir.IPredNE, index, ir.NewInt(ir.I32, 0))
+	lookup.AddInstruction(found)
+	lookup.AddInstruction(ir.NewCondBr(found, reference, write))
+
+	extended := ir.NewZExt(index, ir.I64)
+	reference.AddInstruction(extended)
+	reference.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, extended, ir.NewInt(ir.I32, 4)))
+	reference.AddInstruction(ir.NewRet(nil))
+
+	write.AddInstruction(ir.NewCall(p.addObjectFunction(), stream, instance))
+	write.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, ir.NewInt(ir.I64, 0xFFFFFFFF), ir.NewInt(ir.I32, 4)))
+	object := loadCounterObject(p, write, instance)
+	info := loadTypeInfo(write, object)
+	current := write
+	for _, class := range serializableDescendants(p, c) {
+		matched := f.NewBlock("")
+		next := f.NewBlock("")
+		equal := ir.NewICmp(ir.IPredEQ, info, class.IRTypeInfo)
+		current.AddInstruction(equal)
+		current.AddInstruction(ir.NewCondBr(equal, matched, next))
+		matched.AddInstruction(ir.NewCall(p.writeFieldsFunction(class), stream, object))
+		matched.AddInstruction(ir.NewRet(nil))
+		current = next
+	}
+	current.AddInstruction(ir.NewStore(ir.True, streamMember(current, stream, streamFailed)))
+	current.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// writeFieldsFunction generates function which writes class name and variables of instance
+func (p *Program) writeFieldsFunction(c *Class) *ir.Func {
+	stream := streamParam()
+	object := newParam("object", pointerType)
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".binary.write_fields", ir.Void, stream, object)
+	if !created {
+		return f
+	}
+	b := f.NewBlock(FunctionEntry)
+	writeInt := func(value ir.Value, size int64) {
+		b.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, value, ir.NewInt(ir.I32, size)))
+	}
+
+	name := c.IRStruct.TypeName
+	writeInt(ir.NewInt(ir.I64, int64(len(name)+1)), 4)
+	address := ir.NewCall(p.reserveFunction(), stream, ir.NewInt(ir.I32, int64(len(name)+1)))
+	b.AddInstruction(address)
+	b.AddInstruction(ir.NewCall(memcpy, address, p.AddString(name), ir.NewInt(ir.I32, int64(len(name)+1))))
+	writeInt(ir.NewInt(ir.I64, int64(len(c.IRStruct.Fields)-1)), 4)
+
+	instance := ir.NewBitCast(object, ir.NewPointerType(c.IRStruct))
+	b.AddInstruction(instance)
+	for index := 1; index < len(c.IRStruct.Fields); index++ {
+		t := c.IRStruct.Fields[index]
+		field := ir.NewGetElementPtr(c.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+		b.AddInstruction(field)
+		value := ir.NewLoad(t, field)
+		b.AddInstruction(value)
+		if size := binaryFieldSize(t); size > 0 {
+			var v ir.Value = value
+			if ir.IsFloat(t) {
+				cast := ir.NewBitCast(v, &ir.IntType{BitSize: uint64(size * 8)})
+				b.AddInstruction(cast)
+				v = cast
+			}
+			if size < 8 {
+				// sign is restored when value is truncated
+				extended := ir.NewZExt(v, ir.I64)
+				b.AddInstruction(extended)
+				v = extended
+			}
+			writeInt(v, size)
+		} else if IsString(t) {
+			b.AddInstruction(ir.NewCall(p.writeStringFunction(), stream, value))
+		} else if class := attributedInstance(p, t, Serializable); class != nil {
+			b.AddInstruction(ir.NewCall(p.writeReferenceFunction(class), stream, value))
+		}
+	}
+	b.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// readReferenceFunction generates function which reads null, reference of deserialized instance or new instance
+// referenced instance is shared, and its class must be class or its subclass
+func (p *Program) readReferenceFunction(c *Class) *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".binary.read", pointerType, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	tagged := f.NewBlock("")
+	reference := f.NewBlock("")
+	shared := f.NewBlock("")
+	retain := f.NewBlock("")
+	instance := f.NewBlock("")
+	named := f.NewBlock("")
+	read := f.NewBlock("")
+	failed := f.NewBlock("")
+	null := ir.NewNull(pointerType)
+
+	value := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, 4))
+	entry.AddInstruction(value)
+	tag := ir.NewTrunc(value, ir.I32)
+	entry.AddInstruction(tag)
+	isNull := ir.NewICmp(ir.IPredEQ, tag, ir.NewInt(ir.I32, binaryNull))
+	entry.AddInstruction(isNull)
+	isFailed := loadStreamMember(entry, stream, streamFailed)
+	stop := ir.NewOr(isNull, isFailed)
+	entry.AddInstruction(stop)
+	returnNull := f.NewBlock("")
+	returnNull.AddInstruction(ir.NewRet(null))
+	entry.AddInstruction(ir.NewCondBr(stop, returnNull, tagged))
+
+	isNew := ir.NewICmp(ir.IPredEQ, tag, ir.NewInt(ir.I32, binaryNew))
+	tagged.AddInstruction(isNew)
+	tagged.AddInstruction(ir.NewCondBr(isNew, instance, reference))
+
+	// reference of deserialized instance
+	count := loadStreamMember(reference, stream, streamCount)
+	inRange := ir.NewICmp(ir.IPredULE, tag, count)
+	reference.AddInstruction(inRange)
+	reference.AddInstruction(ir.NewCondBr(inRange, shared, failed))
+
+	objects := loadStreamMember(shared, stream, streamObjects)
+	index := ir.NewSub(tag, ir.NewInt(ir.I32, 1))
+	shared.AddInstruction(index)
+	counterPointer := ir.NewGetElementPtr(pointerType, objects, index)
+	shared.AddInstruction(counterPointer)
+	counter := ir.NewLoad(pointerType, counterPointer)
+	shared.AddInstruction(counter)
+	info := loadTypeInfo(shared, loadCounterObject(p, shared, counter))
+	var matched ir.Value = ir.False
+	for _, class := range c.Descendants(p) {
+		equal := ir.NewICmp(ir.IPredEQ, info, class.IRTypeInfo)
+		shared.AddInstruction(equal)
+		or := ir.NewOr(matched, equal)
+		shared.AddInstruction(or)
+		matched = or
+	}
+	shared.AddInstruction(ir.NewCondBr(matched, retain, failed))
+
+	retain.AddInstruction(ir.NewCall(retainShared, counter))
+	retain.AddInstruction(ir.NewRet(counter))
+
+	// new instance, class is found by its name
+	length := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, 4))
+	instance.AddInstruction(length)
+	size := ir.NewTrunc(length, ir.I32)
+	instance.AddInstruction(size)
+	name := ir.NewCall(p.takeFunction(), stream, size)
+	instance.AddInstruction(name)
+	nameIsNull := ir.NewICmp(ir.IPredEQ, name, null)
+	instance.AddInstruction(nameIsNull)
+	empty := ir.NewICmp(ir.IPredSLT, size, ir.NewInt(ir.I32, 1))
+	instance.AddInstruction(empty)
+	invalid := ir.NewOr(nameIsNull, empty)
+	instance.AddInstruction(invalid)
+	instance.AddInstruction(ir.NewCondBr(invalid, failed, named))
+
+	last := ir.NewSub(size, ir.NewInt(ir.I32, 1))
+	named.AddInstruction(last)
+	lastPointer := ir.NewGetElementPtr(ir.I8, name, last)
+	named.AddInstruction(lastPointer)
+	lastChar := ir.NewLoad(ir.I8, lastPointer)
+	named.AddInstruction(lastChar)
+	terminated := ir.NewICmp(ir.IPredEQ, lastChar, ir.NewInt(ir.I8, 0))
+	named.AddInstruction(terminated)
+	named.AddInstruction(ir.NewCondBr(terminated, read, failed))
+
+	current := read
+	for _, class := range serializableDescendants(p, c) {
+		matched := f.NewBlock("")
+		next := f.NewBlock("")
+		equal := ir.NewCall(p.stringEqualFunction(), name, p.AddString(class.IRStruct.TypeName))
+		current.AddInstruction(equal)
+		current.AddInstruction(ir.NewCondBr(equal, matched, next))
+		result := ir.NewCall(p.readFieldsFunction(class), stream)
+		matched.AddInstruction(result)
+		matched.AddInstruction(ir.NewRet(result))
+		current = next
+	}
+	current.AddInstruction(ir.NewBr(failed))
+
+	failed.AddInstruction(ir.NewStore(ir.True, streamMember(failed, stream, streamFailed)))
+	failed.AddInstruction(ir.NewRet(null))
+	return f
+}
+
+// readFieldsFunction generates function which creates instance and reads its variables, instance is shared once
+func (p *Program) readFieldsFunction(c *Class) *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(c.IRStruct.TypeName+".binary.read_fields", pointerType, stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	b := f.NewBlock(FunctionBody)
+	failed := f.NewBlock("")
+
+	ctx := NewContext(p)
+	ctx.Block = entry
+	counter := c.CreateShared(ctx, c.IRStruct.TypeName, c.Allocate(p, entry))
+	entry.AddInstruction(ir.NewCall(p.addObjectFunction(), stream, counter))
+	count := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, 4))
+	entry.AddInstruction(count)
+	matched := ir.NewICmp(ir.IPredEQ, count, ir.NewInt(ir.I64, int64(len(c.IRStruct.Fields)-1)))
+	entry.AddInstruction(matched)
+	entry.AddInstruction(ir.NewCondBr(matched, b, failed))
+
+	failed.AddInstruction(ir.NewStore(ir.True, streamMember(failed, stream, streamFailed)))
+	failed.AddInstruction(ir.NewRet(counter))
+
+	object := loadCounterObject(p, b, counter)
+	instance := ir.NewBitCast(object, ir.NewPointerType(c.IRStruct))
+	b.AddInstruction(instance)
+	for index := 1; index < len(c.IRStruct.Fields); index++ {
+		t := c.IRStruct.Fields[index]
+		field := ir.NewGetElementPtr(c.IRStruct, instance, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, int64(index)))
+		b.AddInstruction(field)
+		var value ir.Value
+		if size := binaryFieldSize(t); size > 0 {
+			read := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, size))
+			b.AddInstruction(read)
+			value = read
+			bits := &ir.IntType{BitSize: uint64(size * 8)}
+			if intType, ok := t.(*ir.IntType); ok {
+				bits = &ir.IntType{BitSize: intType.BitSize}
+			}
+			if bits.BitSize < 64 {
+				trunc := ir.NewTrunc(value, bits)
+				b.AddInstruction(trunc)
+				value = trunc
+			}
+			if !value.Type().Equal(t) {
+				cast := ir.NewBitCast(value, t)
+				b.AddInstruction(cast)
+				value = cast
+			}
+		} else if IsString(t) {
+			// string is owned by instance, default value is released
+			read := ir.NewCall(p.readStringFunction(), stream)
+			b.AddInstruction(read)
+			old := ir.NewLoad(t, field)
+			b.AddInstruction(old)
+			b.AddInstruction(ir.NewStore(read, field))
+			b.AddInstruction(ir.NewCall(releaseShared, old))
+			continue
+		} else if class := attributedInstance(p, t, Serializable); class != nil {
+			read := ir.NewCall(p.readReferenceFunction(class), stream)
+			b.AddInstruction(read)
+			value = read
+		} else {
+			continue
+		}
+		b.AddInstruction(ir.NewStore(value, field))
+	}
+	b.AddInstruction(ir.NewRet(counter))
+	return f
+}
+
+// reserveFunction generates function which reserves bytes at position of writer and returns their address, buffer grows if it is full
+func (p *Program) reserveFunction() *ir.Func {
+	stream := streamParam()
+	size := newParam("size", ir.I32)
+	f, created := p.newStreamFunction(Serialization+".reserve", pointerType, stream, size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	grow := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	position := loadStreamMember(entry, stream, streamPosition)
+	capacity := loadStreamMember(entry, stream, streamSize)
+	end := ir.NewAdd(position, size)
+	entry.AddInstruction(end)
+	fits := ir.NewICmp(ir.IPredSLE, end, capacity)
+	entry.AddInstruction(fits)
+	entry.AddInstruction(ir.NewCondBr(fits, exit, grow))
+
+	doubled := ir.NewMul(capacity, ir.NewInt(ir.I32, 2))
+	grow.AddInstruction(doubled)
+	enough := ir.NewICmp(ir.IPredSGT, doubled, end)
+	grow.AddInstruction(enough)
+	newCapacity := ir.NewSelect(enough, doubled, end)
+	grow.AddInstruction(newCapacity)
+	data := loadStreamMember(grow, stream, streamData)
+	newData := ir.NewCall(malloc, newCapacity)
+	grow.AddInstruction(newData)
+	grow.AddInstruction(ir.NewCall(memcpy, newData, data, position))
+	grow.AddInstruction(ir.NewCall(free, data))
+	grow.AddInstruction(ir.NewStore(newData, streamMember(grow, stream, streamData)))
+	grow.AddInstruction(ir.NewStore(newCapacity, streamMember(grow, stream, streamSize)))
+	grow.AddInstruction(ir.NewBr(exit))
+
+	buffer := loadStreamMember(exit, stream, streamData)
+	address := ir.NewGetElementPtr(ir.I8, buffer, position)
+	exit.AddInstruction(address)
+	exit.AddInstruction(ir.NewStore(end, streamMember(exit, stream, streamPosition)))
+	exit.AddInstruction(ir.NewRet(address))
+	return f
+}
+
+// writeIntFunction generates function which writes lower bytes of integer in little-endian
+func (p *Program) writeIntFunction() *ir.Func {
+	stream := streamParam()
+	value := newParam("value", ir.I64)
+	size := newParam("size", ir.I32)
+	f, created := p.newStreamFunction(Serialization+".write_int", ir.Void, stream, value, size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	address := ir.NewCall(p.reserveFunction(), stream, size)
+	entry.AddInstruction(address)
+	count := ir.NewSExt(size, ir.I64)
+	entry.AddInstruction(count)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I64, 0), entry))
+	loop.AddInstruction(index)
+	bits := ir.NewMul(index, ir.NewInt(ir.I64, 8))
+	loop.AddInstruction(bits)
+	shifted := ir.NewLShr(value, bits)
+	loop.AddInstruction(shifted)
+	byteValue := ir.NewTrunc(shifted, ir.I8)
+	loop.AddInstruction(byteValue)
+	bytePointer := ir.NewGetElementPtr(ir.I8, address, index)
+	loop.AddInstruction(bytePointer)
+	loop.AddInstruction(ir.NewStore(byteValue, bytePointer))
+	increment := ir.NewAdd(index, ir.NewInt(ir.I64, 1))
+	loop.AddInstruction(increment)
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, loop))
+	more := ir.NewICmp(ir.IPredSLT, increment, count)
+	loop.AddInstruction(more)
+	loop.AddInstruction(ir.NewCondBr(more, loop, exit))
+
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// takeFunction generates function which returns address of bytes at position of reader and skips them
+// it returns null and fails the stream if there are not enough bytes
+func (p *Program) takeFunction() *ir.Func {
+	stream := streamParam()
+	size := newParam("size", ir.I32)
+	f, created := p.newStreamFunction(Serialization+".take", pointerType, stream, size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	take := f.NewBlock("")
+	failed := f.NewBlock("")
+
+	position := loadStreamMember(entry, stream, streamPosition)
+	length := loadStreamMember(entry, stream, streamSize)
+	end := ir.NewAdd(position, size)
+	entry.AddInstruction(end)
+	fits := ir.NewICmp(ir.IPredSLE, end, length)
+	entry.AddInstruction(fits)
+	notNegative := ir.NewICmp(ir.IPredSGE, size, ir.NewInt(ir.I32, 0))
+	entry.AddInstruction(notNegative)
+	valid := ir.NewAnd(fits, notNegative)
+	entry.AddInstruction(valid)
+	entry.AddInstruction(ir.NewCondBr(valid, take, failed))
+
+	data := loadStreamMember(take, stream, streamData)
+	address := ir.NewGetElementPtr(ir.I8, data, position)
+	take.AddInstruction(address)
+	take.AddInstruction(ir.NewStore(end, streamMember(take, stream, streamPosition)))
+	take.AddInstruction(ir.NewRet(address))
+
+	failed.AddInstruction(ir.NewStore(ir.True, streamMember(failed, stream, streamFailed)))
+	failed.AddInstruction(ir.NewRet(ir.NewNull(pointerType)))
+	return f
+}
+
+// readIntFunction generates function which reads integer in little-endian, it returns 0 if there are not enough bytes
+func (p *Program) readIntFunction() *ir.Func {
+	stream := streamParam()
+	size := newParam("size", ir.I32)
+	f, created := p.newStreamFunction(Serialization+".read_int", ir.I64, stream, size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	address := ir.NewCall(p.takeFunction(), stream, size)
+	entry.AddInstruction(address)
+	count := ir.NewSExt(size, ir.I64)
+	entry.AddInstruction(count)
+	isNull := ir.NewICmp(ir.IPredEQ, address, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, exit, loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I64, 0), entry))
+	loop.AddInstruction(index)
+	value := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I64, 0), entry))
+	loop.AddInstruction(value)
+	bytePointer := ir.NewGetElementPtr(ir.I8, address, index)
+	loop.AddInstruction(bytePointer)
+	byteValue := ir.NewLoad(ir.I8, bytePointer)
+	loop.AddInstruction(byteValue)
+	extended := ir.NewZExt(byteValue, ir.I64)
+	loop.AddInstruction(extended)
+	bits := ir.NewMul(index, ir.NewInt(ir.I64, 8))
+	loop.AddInstruction(bits)
+	shifted := ir.NewShl(extended, bits)
+	loop.AddInstruction(shifted)
+	or := ir.NewOr(value, shifted)
+	loop.AddInstruction(or)
+	increment := ir.NewAdd(index, ir.NewInt(ir.I64, 1))
+	loop.AddInstruction(increment)
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, loop))
+	value.Incs = append(value.Incs, ir.NewIncoming(or, loop))
+	more := ir.NewICmp(ir.IPredSLT, increment, count)
+	loop.AddInstruction(more)
+	loop.AddInstruction(ir.NewCondBr(more, loop, exit))
+
+	result := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I64, 0), entry), ir.NewIncoming(or, loop))
+	exit.AddInstruction(result)
+	exit.AddInstruction(ir.NewRet(result))
+	return f
+}
+
+// writeStringFunction generates function which writes size and bytes of string
+func (p *Program) writeStringFunction() *ir.Func {
+	stream := streamParam()
+	s := stringParam("s")
+	f, created := p.newStreamFunction(Serialization+".write_string", ir.Void, stream, s)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	extended := ir.NewZExt(size, ir.I64)
+	entry.AddInstruction(extended)
+	entry.AddInstruction(ir.NewCall(p.writeIntFunction(), stream, extended, ir.NewInt(ir.I32, 4)))
+	address := ir.NewCall(p.reserveFunction(), stream, size)
+	entry.AddInstruction(address)
+	data := ir.NewCall(p.stringDataFunction(), s)
+	entry.AddInstruction(data)
+	entry.AddInstruction(ir.NewCall(memcpy, address, data, size))
+	entry.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// readStringFunction generates function which reads size and bytes to a new string, it returns null if string is empty or there are not enough bytes
+func (p *Program) readStringFunction() *ir.Func {
+	stream := streamParam()
+	f, created := p.newStreamFunction(Serialization+".read_string", CreateStringType(), stream)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	take := f.NewBlock("")
+	create := f.NewBlock("")
+	null := f.NewBlock("")
+
+	length := ir.NewCall(p.readIntFunction(), stream, ir.NewInt(ir.I32, 4))
+	entry.AddInstruction(length)
+	size := ir.NewTrunc(length, ir.I32)
+	entry.AddInstruction(size)
+	empty := ir.NewICmp(ir.IPredEQ, size, ir.NewInt(ir.I32, 0))
+	entry.AddInstruction(empty)
+	entry.AddInstruction(ir.NewCondBr(empty, null, take))
+
+	bytes := ir.NewCall(p.takeFunction(), stream, size)
+	take.AddInstruction(bytes)
+	isNull := ir.NewICmp(ir.IPredEQ, bytes, ir.NewNull(pointerType))
+	take.AddInstruction(isNull)
+	take.AddInstruction(ir.NewCondBr(isNull, null, create))
+
+	s := ir.NewCall(p.stringCreateFunction(), bytes, size)
+	create.AddInstruction(s)
+	create.AddInstruction(ir.NewRet(s))
+
+	null.AddInstruction(ir.NewRet(ir.NewNull(CreateStringType())))
+	return f
+}
+
+// findObjectFunction generates function which returns index of instance in objects of stream starting from 1, it is 0 if not found
+func (p *Program) findObjectFunction() *ir.Func {
+	stream := streamParam()
+	object := newParam("object", pointerType)
+	f, created := p.newStreamFunction(Serialization+".find_object", ir.I32, stream, object)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	found := f.NewBlock("")
+	next := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	count := loadStreamMember(entry, stream, streamCount)
+	objects := loadStreamMember(entry, stream, streamObjects)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	inRange := ir.NewICmp(ir.IPredSLT, index, count)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, check, exit))
+
+	objectPointer := ir.NewGetElementPtr(pointerType, objects, index)
+	check.AddInstruction(objectPointer)
+	current := ir.NewLoad(pointerType, objectPointer)
+	check.AddInstruction(current)
+	equal := ir.NewICmp(ir.IPredEQ, current, object)
+	check.AddInstruction(equal)
+	check.AddInstruction(ir.NewCondBr(equal, found, next))
+
+	result := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	found.AddInstruction(result)
+	found.AddInstruction(ir.NewRet(result))
+
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(increment)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, next))
+
+	exit.AddInstruction(ir.NewRet(ir.NewInt(ir.I32, 0)))
+	return f
+}
+
+// addObjectFunction generates function which appends instance to objects of stream, objects grow if they are full
+func (p *Program) addObjectFunction() *ir.Func {
+	stream := streamParam()
+	object := newParam("object", pointerType)
+	f, created := p.newStreamFunction(Serialization+".add_object", ir.Void, stream, object)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	grow := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	count := loadStreamMember(entry, stream, streamCount)
+	capacity := loadStreamMember(entry, stream, streamCapacity)
+	full := ir.NewICmp(ir.IPredEQ, count, capacity)
+	entry.AddInstruction(full)
+	entry.AddInstruction(ir.NewCondBr(full, grow, exit))
+
+	doubled := ir.NewMul(capacity, ir.NewInt(ir.I32, 2))
+	grow.AddInstruction(doubled)
+	newCapacity := ir.NewAdd(doubled, ir.NewInt(ir.I32, 16))
+	grow.AddInstruction(newCapacity)
+	pointerSize := ir.NewExprPtrToInt(ir.NewExprGetElementPtr(pointerType, ir.NewNull(ir.NewPointerType(pointerType)), ir.NewInt(ir.I32, 1)), ir.I32)
+	newSize := ir.NewMul(newCapacity, pointerSize)
+	grow.AddInstruction(newSize)
+	size := ir.NewMul(count, pointerSize)
+	grow.AddInstruction(size)
+	newObjects := ir.NewCall(malloc, newSize)
+	grow.AddInstruction(newObjects)
+	objects := loadObjects(grow, stream)
+	grow.AddInstruction(ir.NewCall(memcpy, newObjects, objects, size))
+	grow.AddInstruction(ir.NewCall(free, objects))
+	cast := ir.NewBitCast(newObjects, ir.NewPointerType(pointerType))
+	grow.AddInstruction(cast)
+	grow.AddInstruction(ir.NewStore(cast, streamMember(grow, stream, streamObjects)))
+	grow.AddInstruction(ir.NewStore(newCapacity, streamMember(grow, stream, streamCapacity)))
+	grow.AddInstruction(ir.NewBr(exit))
+
+	current := loadStreamMember(exit, stream, streamObjects)
+	objectPointer := ir.NewGetElementPtr(pointerType, current, count)
+	exit.AddInstruction(objectPointer)
+	exit.AddInstruction(ir.NewStore(object, objectPointer))
+	increment := ir.NewAdd(count, ir.NewInt(ir.I32, 1))
+	exit.AddInstruction(increment)
+	exit.AddInstruction(ir.NewStore(increment, streamMember(exit, stream, streamCount)))
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
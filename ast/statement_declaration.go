@@ -7,62 +7,147 @@ type DeclarationStatement struct {
 	Name  *Identifier
 	Type  Type
 	Value Expression
+	// variable is captured by reference, it is stored in box so that closures could outlive function
+	Boxed bool `json:"-"`
 }
 
 func (d *DeclarationStatement) GenerateIR(c *Context) {
-	var alloca *ir.InstAlloca
-	switch t := d.Type.(type) {
-	case *BuitinType:
-		alloca = ir.NewAlloca(d.Type.Type(c.Program))
+	n, isNew := d.Value.(*New)
+	if isNew {
+		n.HasOwner = true
+	}
+	lambda, isLambda := d.Value.(*Lambda)
+	if isLambda {
+		lambda.HasOwner = true
+	}
 
-	case *TypeName:
-		qualified, declaration := c.Program.FindDeclaration(t)
-		switch declaration.(type) {
-		case *Class:
-			alloca = ir.NewAlloca(pointerType)
-			SetUserData(alloca, qualified)
-			if IsBuiltinClass(qualified) {
-				c.Function.BuiltinReleasePool = append(c.Function.BuiltinReleasePool, alloca)
-			} else {
-				c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, alloca)
+	var t ir.Type
+	var value ir.Value
+	if d.Type == nil {
+		// infer type from value
+		if d.Value == nil {
+			c.Program.Error(d.Position, "missing type of declaration")
+			return
+		}
+		value = d.generateValue(c, nil)
+		if value == nil {
+			return
+		}
+		t = value.Type()
+	} else {
+		t = d.Type.Type(c.Program)
+		if d.Value != nil {
+			value = d.generateValue(c, t)
+			if value == nil {
+				return
 			}
-
-		case *Enum:
-			alloca = ir.NewAlloca(ir.I32)
-
-		case *Interface:
-			// TO-DO interface
-			//TO-DO need to be some convert
-			SetUserData(alloca, qualified)
 		}
-
-	case *TypeFunction:
-		alloca = ir.NewAlloca(d.Type.Type(c.Program))
 	}
-
-	if alloca == nil {
+	if t == nil || ir.IsVoid(t) {
 		c.Program.Error(d.Position, "invalid declaration")
+		return
+	}
+
+	var storage ir.Value
+	if d.Boxed {
+		storage = d.box(c, t)
 	} else {
-		c.Function.IREntry.InsertAlloca(alloca)
-		var store *ir.InstStore
-		if d.Value == nil {
-			switch d.Type.(type) {
-			case *BuitinType:
-				store = ir.NewStore(ir.NewZeroInitializer(d.Type.Type(c.Program)), alloca)
-			case *TypeName:
-				store = ir.NewStore(ir.NewZeroInitializer(pointerType), alloca)
+		storage = d.allocate(c, t)
+	}
+	if IsString(t) {
+		if value != nil {
+			if _, err := ImplicitCast(c, value, t); err != nil {
+				c.Program.Error(d.Value.GetPosition(), err.Error())
+				return
 			}
 		} else {
-			if n, ok := d.Value.(*New); ok {
-				n.HasOwner = true
-			}
-			instance := d.Value.GenerateIR(c, d.Type.Type(c.Program))
-			store = ir.NewStore(instance, alloca)
+			value = ir.NewNull(pointerType)
 		}
-		c.Block.AddInstruction(store)
-		err := c.AddObject(d.Name.Name, alloca)
-		if err != nil {
+		assignString(c.Program, c.Block, value, storage)
+		if err := c.AddObject(d.Name.Name, storage); err != nil {
 			c.Program.Error(d.Position, err.Error())
 		}
+		return
+	}
+
+	if value == nil {
+		if s := StructOf(c.Program, t); s != nil {
+			value = s.Default(c.Program)
+		} else {
+			value = ir.NewZeroInitializer(t)
+		}
+	} else {
+		var err error
+		value, err = ImplicitCast(c, value, t)
+		if err != nil {
+			c.Program.Error(d.Value.GetPosition(), err.Error())
+			return
+		}
+		// instance returned by call is owned by caller
+		if _, isInvocation := d.Value.(*Invocation); isCounted(c.Program, t) && !isNew && !isInvocation {
+			// variable shares the instance
+			retainInstance(c.Program, c.Block, value)
+		} else if IsClosure(t) && !isLambda {
+			// variable shares the closure
+			RetainClosure(c.Program, c.Block, value)
+		}
+	}
+	c.Block.AddInstruction(ir.NewStore(value, storage))
+	err := c.AddObject(d.Name.Name, storage)
+	if err != nil {
+		c.Program.Error(d.Position, err.Error())
+	}
+}
+
+// allocate allocates variable in stack of function, it is released when function exits
+func (d *DeclarationStatement) allocate(c *Context, t ir.Type) ir.Value {
+	alloca := ir.NewAlloca(t)
+	qualified := GetTypeUserData(t)
+	SetUserData(alloca, qualified)
+	if _, ok := c.Program.FindQualified(qualified).(*Class); ok {
+		if IsBuiltinClass(qualified) {
+			c.Function.BuiltinReleasePool = append(c.Function.BuiltinReleasePool, alloca)
+		} else {
+			c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, alloca)
+		}
+	} else if IsClosure(t) {
+		c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, alloca)
+	}
+	c.Function.IREntry.InsertAlloca(alloca)
+	if IsString(t) {
+		// string declared in loop releases the previous one
+		c.Function.IREntry.InsertInstruction(ir.NewStore(ir.NewNull(pointerType), alloca))
+		c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, alloca)
+	}
+	return alloca
+}
+
+// box allocates variable in box, function shares the box until it exits, value is released with the box
+func (d *DeclarationStatement) box(c *Context, t ir.Type) ir.Value {
+	slot := ir.NewAlloca(pointerType)
+	c.Function.IREntry.InsertAlloca(slot)
+	c.Function.IREntry.InsertInstruction(ir.NewStore(ir.NewNull(pointerType), slot))
+	c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, slot)
+
+	// box declared in loop releases the previous one
+	old := ir.NewLoad(pointerType, slot)
+	c.Block.AddInstruction(old)
+	c.Block.AddInstruction(ir.NewCall(releaseShared, old))
+	storage, counter := CreateBox(c, t)
+	c.Block.AddInstruction(ir.NewCall(retainShared, counter))
+	c.Block.AddInstruction(ir.NewStore(counter, slot))
+	SetUserData(storage, GetTypeUserData(t))
+	c.Function.addBox(storage, slot)
+	return storage
+}
+
+func (d *DeclarationStatement) generateValue(c *Context, expected ir.Type) ir.Value {
+	if d.Value.IsConstant(c.Program) {
+		return d.Value.GenerateConstIR(c.Program, expected)
+	}
+	value := d.Value.GenerateIR(c, expected)
+	if value == nil {
+		return nil
 	}
+	return c.AutoLoad(value)
 }
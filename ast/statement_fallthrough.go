@@ -0,0 +1,18 @@
+package ast
+
+import "github.com/panda-foundation/go-compiler/ir"
+
+// Fallthrough transfers control to the next case of switch, it must be the last statement of case
+type Fallthrough struct {
+	StatementBase
+}
+
+func (f *Fallthrough) GenerateIR(c *Context) {
+	if c.FallthroughBlock == nil {
+		c.Program.Error(f.Position, "invalid fallthrough")
+		return
+	}
+	c.Block.AddInstruction(ir.NewBr(c.FallthroughBlock))
+	// the next case is counted by switch
+	c.Returned = true
+}
@@ -1,5 +1,8 @@
 package ast
 
+import "github.com/panda-foundation/go-compiler/ir"
+
+// Foreach iterates values of generator, key is index of value if it is declared
 type Foreach struct {
 	StatementBase
 	Key      Statement
@@ -8,6 +11,86 @@ type Foreach struct {
 	Body     Statement
 }
 
-func (*Foreach) GenerateIR(*Context) {
-	//TO-DO
+func (f *Foreach) GenerateIR(c *Context) {
+	ctx := c.NewContext()
+	ctx.Block = c.Block
+	iterator := ctx.AutoLoad(f.Iterator.GenerateIR(ctx, nil))
+	element := GeneratorElement(iterator.Type())
+	if element == nil {
+		c.Program.Error(f.Iterator.GetPosition(), "invalid iterator")
+		return
+	}
+
+	// loop shares generator until it is finished, generator is released by function if loop is not finished
+	generator := f.variable(ctx, iterator.Type())
+	c.Function.IREntry.InsertInstruction(ir.NewStore(ir.NewNull(iterator.Type().(*ir.PointerType)), generator))
+	c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, generator)
+	RetainClosure(c.Program, ctx.Block, iterator)
+	ctx.Block.AddInstruction(ir.NewStore(iterator, generator))
+
+	var key ir.Value
+	if f.Key != nil {
+		key = f.declare(ctx, f.Key, ir.I32)
+		t := ctx.ContentType(key).(*ir.IntType)
+		ctx.Block.AddInstruction(ir.NewStore(ir.NewInt(t, 0), key))
+	}
+	item := f.declare(ctx, f.Item, element)
+
+	nextBlock := c.Function.IRFunction.NewBlock("")
+	ctx.LeaveBlock = nextBlock
+
+	conditionBlock := c.Function.IRFunction.NewBlock("")
+	conditionContext := ctx.NewContext()
+	conditionContext.Block = conditionBlock
+
+	postBlock := c.Function.IRFunction.NewBlock("")
+	ctx.LoopBlock = postBlock
+	if key != nil {
+		t := ctx.ContentType(key).(*ir.IntType)
+		load := ir.NewLoad(t, key)
+		postBlock.AddInstruction(load)
+		add := ir.NewAdd(load, ir.NewInt(t, 1))
+		postBlock.AddInstruction(add)
+		postBlock.AddInstruction(ir.NewStore(add, key))
+	}
+	postBlock.AddInstruction(ir.NewBr(conditionBlock))
+
+	bodyBlock := c.Function.IRFunction.NewBlock("")
+	bodyContext := ctx.NewContext()
+	bodyContext.Block = bodyBlock
+	f.Body.GenerateIR(bodyContext)
+	if !bodyContext.Block.Terminated {
+		bodyContext.Block.AddInstruction(ir.NewBr(postBlock))
+	}
+
+	next := callClosure(conditionContext, conditionContext.AutoLoad(generator), []ir.Value{item})
+	conditionContext.Block.AddInstruction(ir.NewCondBr(next, bodyBlock, nextBlock))
+	ctx.Block.AddInstruction(ir.NewBr(conditionBlock))
+
+	// generator is released when loop is finished
+	load := ir.NewLoad(iterator.Type(), generator)
+	nextBlock.AddInstruction(load)
+	ReleaseClosure(c.Program, nextBlock, load)
+	nextBlock.AddInstruction(ir.NewStore(ir.NewNull(iterator.Type().(*ir.PointerType)), generator))
+	c.Block = nextBlock
+}
+
+// declare declares key or item of foreach, t is its type if type is not declared
+func (f *Foreach) declare(c *Context, s Statement, t ir.Type) ir.Value {
+	d := s.(*DeclarationStatement)
+	if d.Type != nil {
+		t = d.Type.Type(c.Program)
+	}
+	alloca := f.variable(c, t)
+	if err := c.AddObject(d.Name.Name, alloca); err != nil {
+		c.Program.Error(d.Position, err.Error())
+	}
+	return alloca
+}
+
+func (*Foreach) variable(c *Context, t ir.Type) *ir.InstAlloca {
+	alloca := ir.NewAlloca(t)
+	SetUserData(alloca, GetTypeUserData(t))
+	c.Function.IREntry.InsertAlloca(alloca)
+	return alloca
 }
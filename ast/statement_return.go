@@ -11,23 +11,39 @@ type Return struct {
 
 func (r *Return) GenerateIR(c *Context) {
 	if r.Expression != nil {
+		if n, ok := r.Expression.(*New); ok {
+			// caller owns the returned instance
+			n.HasOwner = true
+		}
+		lambda, isLambda := r.Expression.(*Lambda)
+		if isLambda {
+			lambda.HasOwner = true
+		}
 		var value ir.Value
 		if r.Expression.IsConstant(c.Program) {
 			value = r.Expression.GenerateConstIR(c.Program, c.Function.ReturnType.Type(c.Program))
 		} else {
-			value = r.Expression.GenerateIR(c, nil)
+			value = c.AutoLoad(r.Expression.GenerateIR(c, nil))
 		}
 		var t ir.Type = ir.Void
 		if c.Function.ReturnType != nil {
 			t = c.Function.ReturnType.Type(c.Program)
 		}
+		if ir.IsNumber(value.Type()) && ir.IsNumber(t) {
+			// value is promoted to return type
+			if cast, err := ImplicitCast(c, value, t); err == nil {
+				value = cast
+			}
+		}
 		if value.Type().Equal(t) {
+			if IsClosure(t) && !isLambda {
+				// caller shares the closure
+				RetainClosure(c.Program, c.Block, value)
+			} else if IsString(t) {
+				// caller owns the returned string
+				retainString(c.Program, c.Block, value)
+			}
 			c.Block.AddInstruction(ir.NewStore(value, c.Function.IRReturn))
-		} else if p, ok := value.Type().(*ir.PointerType); ok && p.ElemType.Equal(t) {
-			// TO-DO Check user data of pointer type
-			load := ir.NewLoad(p.ElemType, value)
-			c.Block.AddInstruction(load)
-			c.Block.AddInstruction(ir.NewStore(load, c.Function.IRReturn))
 		} else {
 			c.Program.Error(r.Position, "return type mismatch with function define")
 		}
@@ -27,15 +27,16 @@ func (s *Switch) GenerateIR(c *Context) {
 	if s.Initialization != nil {
 		s.Initialization.GenerateIR(ctx)
 	}
+	// operand could refer to variable declared by initialization
 	var operand ir.Value
 	if s.Operand.IsConstant(c.Program) {
-		operand = s.Operand.GenerateConstIR(c.Program, ir.I1)
+		operand = s.Operand.GenerateConstIR(c.Program, s.Operand.ResolvedType())
 	} else {
-		operand = c.AutoLoad(s.Operand.GenerateIR(c, nil))
+		operand = ctx.AutoLoad(s.Operand.GenerateIR(ctx, s.Operand.ResolvedType()))
 	}
 	t := operand.Type()
-	if !ir.IsInt(t) {
-		c.Program.Error(s.Operand.GetPosition(), "switch operand must be integer type")
+	if !ir.IsInt(t) && !IsString(t) {
+		c.Program.Error(s.Operand.GetPosition(), "switch operand must be integer, enum or string")
 		return
 	}
 
@@ -55,34 +56,101 @@ func (s *Switch) GenerateIR(c *Context) {
 		ctx.Returned = false
 	}
 
-	var caseBlocks []*ir.Case
-	for _, cc := range s.Cases {
+	// blocks of cases are created first, so case could fall through to the next one
+	var blocks []*ir.Block
+	for range s.Cases {
+		blocks = append(blocks, c.Function.IRFunction.NewBlock(""))
+	}
+	for i, cc := range s.Cases {
 		caseContext := ctx.NewContext()
-		caseBlock := c.Function.IRFunction.NewBlock("")
-		caseContext.Block = caseBlock
+		caseContext.Block = blocks[i]
+		if i < len(blocks)-1 {
+			caseContext.FallthroughBlock = blocks[i+1]
+		} else if s.Default != nil {
+			caseContext.FallthroughBlock = defaultBlock
+		}
 		cc.Body.GenerateIR(caseContext)
 		if !caseContext.Returned {
 			ctx.Returned = false
 		}
-		if !cc.Case.IsConstant(c.Program) {
-			c.Program.Error(cc.Position, "expect constant int expression")
+		if !caseContext.Block.Terminated {
+			caseContext.Block.AddInstruction(ir.NewBr(nextBlock))
 		}
-		caseBlocks = append(caseBlocks, ir.NewCase(cc.Case.GenerateConstIR(c.Program, t.(*ir.IntType)), caseBlock))
 	}
 
-	for i, cc := range caseBlocks {
-		b := cc.Target.(*ir.Block)
-		if !b.Terminated {
-			if i == len(caseBlocks)-1 {
-				// last one
-				b.AddInstruction(ir.NewBr(defaultBlock))
-			} else {
-				b.AddInstruction(ir.NewBr(caseBlocks[i+1].Target))
+	if IsString(t) {
+		s.generateStringCases(c, ctx.Block, operand, blocks, defaultBlock)
+	} else {
+		var caseBlocks []*ir.Case
+		for i, cc := range s.Cases {
+			if !cc.Case.IsConstant(c.Program) {
+				c.Program.Error(cc.Position, "expect constant int expression")
 			}
+			caseBlocks = append(caseBlocks, ir.NewCase(cc.Case.GenerateConstIR(c.Program, t.(*ir.IntType)), blocks[i]))
 		}
+		ctx.Block.AddInstruction(ir.NewSwitch(operand, defaultBlock, caseBlocks...))
 	}
-
-	ctx.Block.AddInstruction(ir.NewSwitch(operand, defaultBlock, caseBlocks...))
 	c.Block = nextBlock
 	c.Returned = ctx.Returned
 }
+
+// generateStringCases switches on hash of operand, then operand is compared with cases of the same hash in order
+func (s *Switch) generateStringCases(c *Context, b *ir.Block, operand ir.Value, blocks []*ir.Block, defaultBlock *ir.Block) {
+	var hashes []uint32
+	var texts []string
+	cases := make(map[uint32][]int)
+	for i, cc := range s.Cases {
+		text, _ := caseText(cc.Case)
+		texts = append(texts, text)
+		hash := stringHash(text)
+		if _, ok := cases[hash]; !ok {
+			hashes = append(hashes, hash)
+		}
+		cases[hash] = append(cases[hash], i)
+	}
+
+	var caseBlocks []*ir.Case
+	for _, hash := range hashes {
+		compare := c.Function.IRFunction.NewBlock("")
+		caseBlocks = append(caseBlocks, ir.NewCase(ir.NewInt(ir.I32, int64(int32(hash))), compare))
+		indexes := cases[hash]
+		for i, index := range indexes {
+			compared := ir.NewCall(c.Program.stringCompareFunction(), operand, c.Program.StringLiteral(texts[index]))
+			compare.AddInstruction(compared)
+			equal := ir.NewICmp(ir.IPredEQ, compared, ir.NewInt(ir.I32, 0))
+			compare.AddInstruction(equal)
+			next := defaultBlock
+			if i < len(indexes)-1 {
+				next = c.Function.IRFunction.NewBlock("")
+			}
+			compare.AddInstruction(ir.NewCondBr(equal, blocks[index], next))
+			compare = next
+		}
+	}
+	hash := ir.NewCall(c.Program.stringHashFunction(), operand)
+	b.AddInstruction(hash)
+	b.AddInstruction(ir.NewSwitch(hash, defaultBlock, caseBlocks...))
+}
+
+// caseText returns text of constant string case, it is literal or constant variable of literal, null is empty text
+func caseText(e Expression) (string, bool) {
+	switch e := e.(type) {
+	case *Literal:
+		switch e.Typ {
+		case token.STRING:
+			return e.text(), true
+
+		case token.NULL:
+			return "", true
+		}
+
+	case *Parentheses:
+		return caseText(e.Expression)
+
+	case *Identifier, *MemberAccess:
+		if v, ok := e.Reference().(*Variable); ok && v.Const && v.Value != nil {
+			return caseText(v.Value)
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,32 @@
+package ast
+
+import "github.com/panda-foundation/go-compiler/ir"
+
+// Yield suspends generator function, value of expression is the next value of generator
+type Yield struct {
+	StatementBase
+	Expression Expression
+}
+
+func (y *Yield) GenerateIR(c *Context) {
+	g := c.Function.generator
+	var value ir.Value
+	if y.Expression.IsConstant(c.Program) {
+		value = y.Expression.GenerateConstIR(c.Program, g.element)
+	} else {
+		value = c.AutoLoad(y.Expression.GenerateIR(c, g.element))
+	}
+	value, err := ImplicitCast(c, value, g.element)
+	if err != nil {
+		c.Program.Error(y.Position, err.Error())
+		return
+	}
+	c.Block.AddInstruction(ir.NewStore(value, g.out))
+
+	// generator is resumed from the next block
+	resume := c.Function.IRFunction.NewBlock("")
+	g.resumes = append(g.resumes, resume)
+	c.Block.AddInstruction(ir.NewStore(ir.NewInt(ir.I32, int64(len(g.resumes))), g.field(c.Block, g.env, generatorState)))
+	c.Block.AddInstruction(ir.NewBr(g.suspend))
+	c.Block = resume
+}
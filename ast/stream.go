@@ -0,0 +1,65 @@
+package ast
+
+import "github.com/panda-foundation/go-compiler/ir"
+
+// indexes of runtime.stream members
+const (
+	streamData = iota
+	streamSize
+	streamPosition
+	streamObjects
+	streamCount
+	streamCapacity
+	streamFailed
+)
+
+var (
+	// stream is buffer of binary serialization and json, it is used by both writer and reader, size is capacity of writer or length of reader
+	// serialized instances are stored in objects, so shared instances are serialized once, count is nesting depth of json
+	runtimeStream = namedStruct(Stream, pointerType, ir.I32, ir.I32, ir.NewPointerType(pointerType), ir.I32, ir.I32, ir.I1)
+)
+
+func (p *Program) declareStream() {
+	for _, t := range p.IRModule.TypeDefs {
+		if t.Name() == Stream {
+			return
+		}
+	}
+	p.IRModule.NewTypeDef(Stream, runtimeStream)
+}
+
+// newStreamFunction declares function which uses runtime.stream, it is false if function is generated already
+func (p *Program) newStreamFunction(name string, retType ir.Type, params ...*ir.Param) (*ir.Func, bool) {
+	if f, ok := p.Intrinsics[name]; ok {
+		return f, false
+	}
+	p.declareStream()
+	f := p.IRModule.NewFunc(name, retType, params...)
+	p.Intrinsics[name] = f
+	return f, true
+}
+
+func streamParam() *ir.Param {
+	return newParam("stream", ir.NewPointerType(runtimeStream))
+}
+
+func streamMember(b *ir.Block, stream ir.Value, index int64) ir.Value {
+	member := ir.NewGetElementPtr(runtimeStream, stream, ir.NewInt(ir.I32, 0), ir.NewInt(ir.I32, index))
+	b.AddInstruction(member)
+	return member
+}
+
+func loadStreamMember(b *ir.Block, stream ir.Value, index int64) ir.Value {
+	member := streamMember(b, stream, index)
+	load := ir.NewLoad(member.Type().(*ir.PointerType).ElemType, member)
+	b.AddInstruction(load)
+	return load
+}
+
+// newStream allocates stream in entry block of function
+func newStream(b *ir.Block) ir.Value {
+	stream := ir.NewAlloca(runtimeStream)
+	b.AddInstruction(stream)
+	b.AddInstruction(ir.NewStore(ir.NewZeroInitializer(runtimeStream), stream))
+	return stream
+}
@@ -0,0 +1,879 @@
+package ast
+
+import (
+	"crypto/md5"
+	"fmt"
+	"hash/fnv"
+
+	"github.com/panda-foundation/go-compiler/ir"
+)
+
+// string is shared by counter like class instance, object of counter is data of string
+// data starts with size in bytes (i32), it is followed by utf-8 bytes which are terminated by 0, null string is empty
+// literals are interned as global counters which are never released
+const (
+	stringHeader   = 4
+	stringInterned = 0x3fffffff
+
+	// parameters of 32 bits fnv-1a hash
+	fnvOffset = 2166136261
+	fnvPrime  = 16777619
+)
+
+// stringFunction is member function of string, it is generated with string as the first parameter
+type stringFunction struct {
+	sig      *ir.FuncType
+	generate func(p *Program) *ir.Func
+}
+
+var (
+	stringFunctions = map[string]stringFunction{
+		"length":     {ir.NewFuncType(ir.I32), (*Program).stringLengthFunction},
+		"size":       {ir.NewFuncType(ir.I32), (*Program).stringSizeFunction},
+		"data":       {ir.NewFuncType(pointerType), (*Program).stringDataFunction},
+		"compare":    {ir.NewFuncType(ir.I32, CreateStringType()), (*Program).stringCompareFunction},
+		"char_at":    {ir.NewFuncType(CreateCharType(), ir.I32), (*Program).stringCharAtFunction},
+		"byte_at":    {ir.NewFuncType(ir.UI8, ir.I32), (*Program).stringByteAtFunction},
+		"slice":      {ir.NewFuncType(CreateStringType(), ir.I32, ir.I32), (*Program).stringSliceFunction},
+		"byte_slice": {ir.NewFuncType(CreateStringType(), ir.I32, ir.I32), (*Program).stringByteSliceFunction},
+	}
+)
+
+// StringMemberType returns type of member function of string without string itself, nil is returned if it is not found
+func StringMemberType(name string) ir.Type {
+	if f, ok := stringFunctions[name]; ok {
+		return ir.NewPointerType(f.sig)
+	}
+	return nil
+}
+
+// StringMember returns call of member function of string, arguments are added by invocation
+func StringMember(c *Context, s ir.Value, name string) ir.Value {
+	if f, ok := stringFunctions[name]; ok {
+		return ir.NewCall(f.generate(c.Program), s)
+	}
+	return nil
+}
+
+// StringLiteral returns interned string of literal, its counter is generated when all classes are generated
+func (p *Program) StringLiteral(value string) ir.Constant {
+	hash := fmt.Sprintf("%x", md5.Sum([]byte(value)))
+	g, ok := p.StringLiterals[hash]
+	if !ok {
+		bytes := append([]byte(value), 0)
+		data := p.IRModule.NewGlobalDef(String+"."+hash+".data", ir.NewStruct(ir.NewStructType(ir.I32, ir.NewArrayType(uint64(len(bytes)), ir.I8)),
+			ir.NewInt(ir.I32, int64(len(value))), ir.NewCharArray(bytes)))
+		data.Immutable = true
+		g = p.IRModule.NewGlobalDef(String+"."+hash, ir.NewExprBitCast(data, pointerType))
+		p.StringLiterals[hash] = g
+	}
+	return ir.NewExprBitCast(g, CreateStringType())
+}
+
+// generateStringLiterals replaces data of interned strings with their counters
+func (p *Program) generateStringLiterals() {
+	counterClass, ok := p.FindQualified(Counter).(*Class)
+	if !ok || len(p.StringLiterals) == 0 {
+		return
+	}
+	destructor := p.FunctionValue(p.stringDestroyFunction())
+	for _, g := range p.StringLiterals {
+		var fields []ir.Constant
+		for _, t := range counterClass.IRStruct.Fields {
+			fields = append(fields, ir.NewZeroInitializer(t))
+		}
+		fields[counterClass.VariableIndexes["shared"]] = ir.NewInt(ir.I32, stringInterned)
+		fields[counterClass.VariableIndexes["object"]] = g.Init
+		fields[counterClass.VariableIndexes["destructor"]] = destructor
+		g.Init = ir.NewStruct(counterClass.IRStruct, fields...)
+		g.ContentType = counterClass.IRStruct
+		g.Typ = nil
+	}
+}
+
+// temporary keeps string or closure created by expression until function exits, the one created by the same expression before is released
+func (c *Context) temporary(value ir.Value) ir.Value {
+	slot := ir.NewAlloca(value.Type())
+	c.Function.IREntry.InsertAlloca(slot)
+	if t, ok := value.Type().(*ir.PointerType); ok && IsClosure(t) {
+		c.Function.IREntry.InsertInstruction(ir.NewStore(ir.NewNull(t), slot))
+		old := ir.NewLoad(t, slot)
+		c.Block.AddInstruction(old)
+		ReleaseClosure(c.Program, c.Block, old)
+	} else {
+		SetUserData(slot, String)
+		c.Function.IREntry.InsertInstruction(ir.NewStore(ir.NewNull(pointerType), slot))
+		old := ir.NewLoad(pointerType, slot)
+		c.Block.AddInstruction(old)
+		c.Block.AddInstruction(ir.NewCall(releaseShared, old))
+	}
+	c.Block.AddInstruction(ir.NewStore(value, slot))
+	c.Function.AutoReleasePool = append(c.Function.AutoReleasePool, slot)
+	return value
+}
+
+// assignString shares string with variable, the old one of variable is released after
+func assignString(p *Program, b *ir.Block, value ir.Value, address ir.Value) {
+	retainString(p, b, value)
+	old := ir.NewLoad(pointerType, address)
+	b.AddInstruction(old)
+	b.AddInstruction(ir.NewCall(releaseShared, old))
+	b.AddInstruction(ir.NewStore(value, address))
+}
+
+// ConvertToString converts number, bool, char, enum or null terminated raw pointer to a new string, enum is converted to name of member
+func ConvertToString(c *Context, value ir.Value) (ir.Value, error) {
+	t := value.Type()
+	e := EnumOf(c.Program, t)
+	var call *ir.InstCall
+	switch {
+	case IsString(t):
+		return value, nil
+
+	case e != nil:
+		call = ir.NewCall(e.nameFunction(c.Program), value)
+
+	case ir.IsBool(t):
+		s := ir.NewSelect(value, c.Program.StringLiteral("true"), c.Program.StringLiteral("false"))
+		c.Block.AddInstruction(s)
+		return s, nil
+
+	case IsChar(t):
+		call = ir.NewCall(c.Program.stringFromCharFunction(), value)
+
+	case ir.IsInt(t):
+		unsigned := t.(*ir.IntType).Unsigned
+		if t.(*ir.IntType).BitSize < 64 {
+			value = CastNumber(c, value, ir.I64)
+		}
+		call = ir.NewCall(c.Program.stringFromIntFunction(unsigned), value)
+
+	case ir.IsFloat(t):
+		if t.(*ir.FloatType).Kind != ir.FloatKindDouble {
+			value = CastNumber(c, value, ir.Float64)
+		}
+		call = ir.NewCall(c.Program.stringFromFloatFunction(), value)
+
+	case ir.IsPointer(t) && GetTypeUserData(t) == "" && !IsClosure(t):
+		call = ir.NewCall(c.Program.stringFromPointerFunction(), value)
+
+	default:
+		return nil, fmt.Errorf("cannot convert %s to string", MangleType(t))
+	}
+	c.Block.AddInstruction(call)
+	return c.temporary(call), nil
+}
+
+// ConcatString returns a new string which joins a and b
+func ConcatString(c *Context, a ir.Value, b ir.Value) ir.Value {
+	call := ir.NewCall(c.Program.stringConcatFunction(), a, b)
+	c.Block.AddInstruction(call)
+	return c.temporary(call)
+}
+
+// CompareString compares strings by their bytes, the result is compared with 0 by operator
+func CompareString(c *Context, a ir.Value, b ir.Value, pred ir.IPred) ir.Value {
+	call := ir.NewCall(c.Program.stringCompareFunction(), a, b)
+	c.Block.AddInstruction(call)
+	result := ir.NewICmp(pred, call, ir.NewInt(ir.I32, 0))
+	c.Block.AddInstruction(result)
+	return result
+}
+
+// retainString increases shared count of string which is not null
+func retainString(p *Program, b *ir.Block, value ir.Value) {
+	if _, ok := value.(*ir.Null); ok {
+		return
+	}
+	b.AddInstruction(ir.NewCall(p.stringRetainFunction(), value))
+}
+
+func (p *Program) newStringFunction(name string, retType ir.Type, params ...*ir.Param) (*ir.Func, bool) {
+	name = String + "." + name
+	if f, ok := p.Intrinsics[name]; ok {
+		return f, false
+	}
+	f := p.IRModule.NewFunc(name, retType, params...)
+	p.Intrinsics[name] = f
+	return f, true
+}
+
+func stringParam(name string) *ir.Param {
+	return newParam(name, CreateStringType())
+}
+
+// stringDestroyFunction generates destructor of string data, data has nothing to release and it is freed by counter
+func (p *Program) stringDestroyFunction() *ir.Func {
+	f, created := p.newStringFunction(Destructor, ir.Void, newParam("data", pointerType))
+	if !created {
+		return f
+	}
+	f.NewBlock(FunctionEntry).AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// stringRetainFunction generates function which retains string, null string is not counted
+func (p *Program) stringRetainFunction() *ir.Func {
+	s := stringParam("s")
+	f, created := p.newStringFunction("retain", ir.Void, s)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	body := f.NewBlock(FunctionBody)
+	exit := f.NewBlock(FunctionExit)
+
+	isNull := ir.NewICmp(ir.IPredEQ, s, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, exit, body))
+	body.AddInstruction(ir.NewCall(retainShared, s))
+	body.AddInstruction(ir.NewBr(exit))
+	exit.AddInstruction(ir.NewRet(nil))
+	return f
+}
+
+// stringAllocateFunction generates function which creates string of size, its bytes are not initialized except the terminator
+func (p *Program) stringAllocateFunction() *ir.Func {
+	size := newParam("size", ir.I32)
+	f, created := p.newStringFunction("allocate", CreateStringType(), size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	counterClass := p.FindQualified(Counter).(*Class)
+	counter := ir.NewCall(counterClass.IRFunctions[0])
+	entry.AddInstruction(counter)
+	entry.AddInstruction(ir.NewCall(retainShared, counter))
+
+	total := ir.NewAdd(size, ir.NewInt(ir.I32, stringHeader+1))
+	entry.AddInstruction(total)
+	data := ir.NewCall(malloc, total)
+	entry.AddInstruction(data)
+	header := ir.NewBitCast(data, ir.NewPointerType(ir.I32))
+	entry.AddInstruction(header)
+	entry.AddInstruction(ir.NewStore(size, header))
+	bytes := ir.NewGetElementPtr(ir.I8, data, ir.NewInt(ir.I32, stringHeader))
+	entry.AddInstruction(bytes)
+	end := ir.NewGetElementPtr(ir.I8, bytes, size)
+	entry.AddInstruction(end)
+	entry.AddInstruction(ir.NewStore(ir.NewInt(ir.I8, 0), end))
+
+	entry.AddInstruction(ir.NewStore(data, counterMember(p, entry, counter, "object")))
+	entry.AddInstruction(ir.NewStore(p.FunctionValue(p.stringDestroyFunction()), counterMember(p, entry, counter, "destructor")))
+	entry.AddInstruction(ir.NewRet(counter))
+	return f
+}
+
+// stringCreateFunction generates function which creates string by copying bytes
+func (p *Program) stringCreateFunction() *ir.Func {
+	bytes := newParam("bytes", pointerType)
+	size := newParam("size", ir.I32)
+	f, created := p.newStringFunction("create", CreateStringType(), bytes, size)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	s := ir.NewCall(p.stringAllocateFunction(), size)
+	entry.AddInstruction(s)
+	data := ir.NewCall(p.stringDataFunction(), s)
+	entry.AddInstruction(data)
+	entry.AddInstruction(ir.NewCall(memcpy, data, bytes, size))
+	entry.AddInstruction(ir.NewRet(s))
+	return f
+}
+
+// stringDataFunction generates function which returns null terminated bytes of string
+func (p *Program) stringDataFunction() *ir.Func {
+	s := stringParam("s")
+	f, created := p.newStringFunction("data", pointerType, s)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	empty := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+
+	isNull := ir.NewICmp(ir.IPredEQ, s, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, empty, body))
+	empty.AddInstruction(ir.NewRet(p.AddString("")))
+
+	bytes := ir.NewGetElementPtr(ir.I8, loadCounterObject(p, body, s), ir.NewInt(ir.I32, stringHeader))
+	body.AddInstruction(bytes)
+	body.AddInstruction(ir.NewRet(bytes))
+	return f
+}
+
+// stringSizeFunction generates function which returns count of bytes
+func (p *Program) stringSizeFunction() *ir.Func {
+	s := stringParam("s")
+	f, created := p.newStringFunction("size", ir.I32, s)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	empty := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+
+	isNull := ir.NewICmp(ir.IPredEQ, s, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, empty, body))
+	empty.AddInstruction(ir.NewRet(ir.NewInt(ir.I32, 0)))
+
+	header := ir.NewBitCast(loadCounterObject(p, body, s), ir.NewPointerType(ir.I32))
+	body.AddInstruction(header)
+	size := ir.NewLoad(ir.I32, header)
+	body.AddInstruction(size)
+	body.AddInstruction(ir.NewRet(size))
+	return f
+}
+
+// stringLengthFunction generates function which returns count of code points, continuation bytes are not counted
+func (p *Program) stringLengthFunction() *ir.Func {
+	s := stringParam("s")
+	f, created := p.newStringFunction("length", ir.I32, s)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	next := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	data := ir.NewCall(p.stringDataFunction(), s)
+	entry.AddInstruction(data)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	count := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(count)
+	end := ir.NewICmp(ir.IPredSGE, index, size)
+	loop.AddInstruction(end)
+	loop.AddInstruction(ir.NewCondBr(end, exit, next))
+
+	start := isCodePointStart(next, charAt(next, data, index))
+	increment := ir.NewZExt(start, ir.I32)
+	next.AddInstruction(increment)
+	counted := ir.NewAdd(count, increment)
+	next.AddInstruction(counted)
+	nextIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(nextIndex)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(nextIndex, next))
+	count.Incs = append(count.Incs, ir.NewIncoming(counted, next))
+
+	exit.AddInstruction(ir.NewRet(count))
+	return f
+}
+
+// stringOffsetFunction generates function which returns byte offset of code point at index, size is returned if index is out of range
+func (p *Program) stringOffsetFunction() *ir.Func {
+	s := stringParam("s")
+	target := newParam("index", ir.I32)
+	f, created := p.newStringFunction("offset", ir.I32, s, target)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	check := f.NewBlock("")
+	found := f.NewBlock("")
+	next := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	data := ir.NewCall(p.stringDataFunction(), s)
+	entry.AddInstruction(data)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	count := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(count)
+	end := ir.NewICmp(ir.IPredSGE, index, size)
+	loop.AddInstruction(end)
+	loop.AddInstruction(ir.NewCondBr(end, exit, check))
+
+	start := isCodePointStart(check, charAt(check, data, index))
+	matched := ir.NewICmp(ir.IPredEQ, count, target)
+	check.AddInstruction(matched)
+	isFound := ir.NewAnd(start, matched)
+	check.AddInstruction(isFound)
+	check.AddInstruction(ir.NewCondBr(isFound, found, next))
+	found.AddInstruction(ir.NewRet(index))
+
+	increment := ir.NewZExt(start, ir.I32)
+	next.AddInstruction(increment)
+	counted := ir.NewAdd(count, increment)
+	next.AddInstruction(counted)
+	nextIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(nextIndex)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(nextIndex, next))
+	count.Incs = append(count.Incs, ir.NewIncoming(counted, next))
+
+	exit.AddInstruction(ir.NewRet(size))
+	return f
+}
+
+// isCodePointStart returns true if byte is not continuation byte of utf-8 (10xxxxxx)
+func isCodePointStart(b *ir.Block, char ir.Value) ir.Value {
+	high := ir.NewAnd(char, ir.NewInt(ir.I8, 0xC0))
+	b.AddInstruction(high)
+	start := ir.NewICmp(ir.IPredNE, high, ir.NewInt(ir.I8, 0x80))
+	b.AddInstruction(start)
+	return start
+}
+
+// stringDecodeFunction generates function which decodes utf-8 code point at bytes, decoding stops at terminator
+func (p *Program) stringDecodeFunction() *ir.Func {
+	bytes := newParam("bytes", pointerType)
+	f, created := p.newStringFunction("decode", CreateCharType(), bytes)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	ascii := f.NewBlock("")
+	multiple := f.NewBlock("")
+	loop := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+	next := f.NewBlock("")
+	exit := f.NewBlock(FunctionExit)
+
+	first := ir.NewZExt(charAt(entry, bytes, ir.NewInt(ir.I32, 0)), ir.I32)
+	entry.AddInstruction(first)
+	isASCII := ir.NewICmp(ir.IPredULT, first, ir.NewInt(ir.I32, 0x80))
+	entry.AddInstruction(isASCII)
+	entry.AddInstruction(ir.NewCondBr(isASCII, ascii, multiple))
+	ascii.AddInstruction(ir.NewRet(first))
+
+	// count of continuation bytes is 1 (110xxxxx), 2 (1110xxxx) or 3 (11110xxx)
+	three := ir.NewICmp(ir.IPredUGE, first, ir.NewInt(ir.I32, 0xF0))
+	multiple.AddInstruction(three)
+	two := ir.NewICmp(ir.IPredUGE, first, ir.NewInt(ir.I32, 0xE0))
+	multiple.AddInstruction(two)
+	twoOrOne := ir.NewSelect(two, ir.NewInt(ir.I32, 2), ir.NewInt(ir.I32, 1))
+	multiple.AddInstruction(twoOrOne)
+	count := ir.NewSelect(three, ir.NewInt(ir.I32, 3), twoOrOne)
+	multiple.AddInstruction(count)
+	mask := ir.NewLShr(ir.NewInt(ir.I32, 0x3F), count)
+	multiple.AddInstruction(mask)
+	bits := ir.NewAnd(first, mask)
+	multiple.AddInstruction(bits)
+	multiple.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 1), multiple))
+	loop.AddInstruction(index)
+	char := ir.NewPhi(ir.NewIncoming(bits, multiple))
+	loop.AddInstruction(char)
+	more := ir.NewICmp(ir.IPredSLE, index, count)
+	loop.AddInstruction(more)
+	loop.AddInstruction(ir.NewCondBr(more, body, exit))
+
+	continuation := ir.NewZExt(charAt(body, bytes, index), ir.I32)
+	body.AddInstruction(continuation)
+	terminated := ir.NewICmp(ir.IPredEQ, continuation, ir.NewInt(ir.I32, 0))
+	body.AddInstruction(terminated)
+	body.AddInstruction(ir.NewCondBr(terminated, exit, next))
+
+	shifted := ir.NewShl(char, ir.NewInt(ir.I32, 6))
+	next.AddInstruction(shifted)
+	low := ir.NewAnd(continuation, ir.NewInt(ir.I32, 0x3F))
+	next.AddInstruction(low)
+	decoded := ir.NewOr(shifted, low)
+	next.AddInstruction(decoded)
+	nextIndex := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	next.AddInstruction(nextIndex)
+	next.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(nextIndex, next))
+	char.Incs = append(char.Incs, ir.NewIncoming(decoded, next))
+
+	exit.AddInstruction(ir.NewRet(char))
+	return f
+}
+
+// stringCharAtFunction generates function which returns code point at index, it traps if index is out of range
+func (p *Program) stringCharAtFunction() *ir.Func {
+	s := stringParam("s")
+	index := newParam("index", ir.I32)
+	f, created := p.newStringFunction("char_at", CreateCharType(), s, index)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	failed := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	offset := ir.NewCall(p.stringOffsetFunction(), s, index)
+	entry.AddInstruction(offset)
+	entry.AddInstruction(ir.NewCondBr(inRange(entry, index, offset, size), body, failed))
+	trapBlock(p, failed)
+
+	data := ir.NewCall(p.stringDataFunction(), s)
+	body.AddInstruction(data)
+	bytes := ir.NewGetElementPtr(ir.I8, data, offset)
+	body.AddInstruction(bytes)
+	char := ir.NewCall(p.stringDecodeFunction(), bytes)
+	body.AddInstruction(char)
+	body.AddInstruction(ir.NewRet(char))
+	return f
+}
+
+// stringByteAtFunction generates function which returns byte at index, it traps if index is out of range
+func (p *Program) stringByteAtFunction() *ir.Func {
+	s := stringParam("s")
+	index := newParam("index", ir.I32)
+	f, created := p.newStringFunction("byte_at", ir.UI8, s, index)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	failed := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	entry.AddInstruction(ir.NewCondBr(inRange(entry, index, index, size), body, failed))
+	trapBlock(p, failed)
+
+	data := ir.NewCall(p.stringDataFunction(), s)
+	body.AddInstruction(data)
+	body.AddInstruction(ir.NewRet(charAt(body, data, index)))
+	return f
+}
+
+// inRange returns true if index is not negative and offset is less than size
+func inRange(b *ir.Block, index ir.Value, offset ir.Value, size ir.Value) ir.Value {
+	positive := ir.NewICmp(ir.IPredSGE, index, ir.NewInt(ir.I32, 0))
+	b.AddInstruction(positive)
+	less := ir.NewICmp(ir.IPredSLT, offset, size)
+	b.AddInstruction(less)
+	and := ir.NewAnd(positive, less)
+	b.AddInstruction(and)
+	return and
+}
+
+func trapBlock(p *Program, b *ir.Block) {
+	b.AddInstruction(ir.NewCall(p.DeclareIntrinsic(trap)))
+	b.AddInstruction(ir.NewUnreachable())
+}
+
+// clamp returns value which is limited between min and max
+func clamp(b *ir.Block, value ir.Value, min ir.Value, max ir.Value) ir.Value {
+	less := ir.NewICmp(ir.IPredSLT, value, min)
+	b.AddInstruction(less)
+	lower := ir.NewSelect(less, min, value)
+	b.AddInstruction(lower)
+	greater := ir.NewICmp(ir.IPredSGT, lower, max)
+	b.AddInstruction(greater)
+	upper := ir.NewSelect(greater, max, lower)
+	b.AddInstruction(upper)
+	return upper
+}
+
+// stringSliceFunction generates function which copies code points from begin to end, indexes are limited in range of string
+func (p *Program) stringSliceFunction() *ir.Func {
+	s := stringParam("s")
+	begin := newParam("begin", ir.I32)
+	end := newParam("end", ir.I32)
+	f, created := p.newStringFunction("slice", CreateStringType(), s, begin, end)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	first := clamp(entry, begin, ir.NewInt(ir.I32, 0), size)
+	last := clamp(entry, end, first, size)
+	from := ir.NewCall(p.stringOffsetFunction(), s, first)
+	entry.AddInstruction(from)
+	to := ir.NewCall(p.stringOffsetFunction(), s, last)
+	entry.AddInstruction(to)
+	entry.AddInstruction(ir.NewRet(p.copyBytes(entry, s, from, to)))
+	return f
+}
+
+// stringByteSliceFunction generates function which copies bytes from begin to end, indexes are limited in range of string
+func (p *Program) stringByteSliceFunction() *ir.Func {
+	s := stringParam("s")
+	begin := newParam("begin", ir.I32)
+	end := newParam("end", ir.I32)
+	f, created := p.newStringFunction("byte_slice", CreateStringType(), s, begin, end)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	from := clamp(entry, begin, ir.NewInt(ir.I32, 0), size)
+	to := clamp(entry, end, from, size)
+	entry.AddInstruction(ir.NewRet(p.copyBytes(entry, s, from, to)))
+	return f
+}
+
+// copyBytes creates string of bytes of s from offset "from" to offset "to"
+func (p *Program) copyBytes(b *ir.Block, s ir.Value, from ir.Value, to ir.Value) ir.Value {
+	data := ir.NewCall(p.stringDataFunction(), s)
+	b.AddInstruction(data)
+	bytes := ir.NewGetElementPtr(ir.I8, data, from)
+	b.AddInstruction(bytes)
+	size := ir.NewSub(to, from)
+	b.AddInstruction(size)
+	copied := ir.NewCall(p.stringCreateFunction(), bytes, size)
+	b.AddInstruction(copied)
+	return copied
+}
+
+// stringConcatFunction generates function which creates string of bytes of a followed by bytes of b
+func (p *Program) stringConcatFunction() *ir.Func {
+	a := stringParam("a")
+	b := stringParam("b")
+	f, created := p.newStringFunction("concat", CreateStringType(), a, b)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	sizeA := ir.NewCall(p.stringSizeFunction(), a)
+	entry.AddInstruction(sizeA)
+	sizeB := ir.NewCall(p.stringSizeFunction(), b)
+	entry.AddInstruction(sizeB)
+	size := ir.NewAdd(sizeA, sizeB)
+	entry.AddInstruction(size)
+	s := ir.NewCall(p.stringAllocateFunction(), size)
+	entry.AddInstruction(s)
+	data := ir.NewCall(p.stringDataFunction(), s)
+	entry.AddInstruction(data)
+	dataA := ir.NewCall(p.stringDataFunction(), a)
+	entry.AddInstruction(dataA)
+	entry.AddInstruction(ir.NewCall(memcpy, data, dataA, sizeA))
+	tail := ir.NewGetElementPtr(ir.I8, data, sizeA)
+	entry.AddInstruction(tail)
+	dataB := ir.NewCall(p.stringDataFunction(), b)
+	entry.AddInstruction(dataB)
+	entry.AddInstruction(ir.NewCall(memcpy, tail, dataB, sizeB))
+	entry.AddInstruction(ir.NewRet(s))
+	return f
+}
+
+// stringCompareFunction generates function which returns -1, 0 or 1 if a is less than, equal to or greater than b
+// bytes are compared first, shorter string is less if bytes are the same, so code points are compared in order
+func (p *Program) stringCompareFunction() *ir.Func {
+	a := stringParam("a")
+	b := stringParam("b")
+	f, created := p.newStringFunction("compare", ir.I32, a, b)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	sizeA := ir.NewCall(p.stringSizeFunction(), a)
+	entry.AddInstruction(sizeA)
+	sizeB := ir.NewCall(p.stringSizeFunction(), b)
+	entry.AddInstruction(sizeB)
+	shorter := ir.NewICmp(ir.IPredSLT, sizeA, sizeB)
+	entry.AddInstruction(shorter)
+	size := ir.NewSelect(shorter, sizeA, sizeB)
+	entry.AddInstruction(size)
+	length := ir.NewZExt(size, ir.I64)
+	entry.AddInstruction(length)
+	dataA := ir.NewCall(p.stringDataFunction(), a)
+	entry.AddInstruction(dataA)
+	dataB := ir.NewCall(p.stringDataFunction(), b)
+	entry.AddInstruction(dataB)
+	compared := ir.NewCall(p.externFunction("memcmp", ir.NewFuncType(ir.I32, pointerType, pointerType, ir.I64)), dataA, dataB, length)
+	entry.AddInstruction(compared)
+	difference := ir.NewSub(sizeA, sizeB)
+	entry.AddInstruction(difference)
+	same := ir.NewICmp(ir.IPredEQ, compared, ir.NewInt(ir.I32, 0))
+	entry.AddInstruction(same)
+	result := ir.NewSelect(same, difference, compared)
+	entry.AddInstruction(result)
+	entry.AddInstruction(ir.NewRet(sign(entry, result)))
+	return f
+}
+
+// stringHashFunction generates function which returns fnv-1a hash of bytes, it is the same as stringHash
+func (p *Program) stringHashFunction() *ir.Func {
+	s := stringParam("s")
+	f, created := p.newStringFunction("hash", ir.I32, s)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	loop := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+	exit := f.NewBlock("")
+
+	size := ir.NewCall(p.stringSizeFunction(), s)
+	entry.AddInstruction(size)
+	data := ir.NewCall(p.stringDataFunction(), s)
+	entry.AddInstruction(data)
+	entry.AddInstruction(ir.NewBr(loop))
+
+	index := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, 0), entry))
+	loop.AddInstruction(index)
+	offset := uint32(fnvOffset)
+	hash := ir.NewPhi(ir.NewIncoming(ir.NewInt(ir.I32, int64(int32(offset))), entry))
+	loop.AddInstruction(hash)
+	inRange := ir.NewICmp(ir.IPredSLT, index, size)
+	loop.AddInstruction(inRange)
+	loop.AddInstruction(ir.NewCondBr(inRange, body, exit))
+
+	pointer := ir.NewGetElementPtr(ir.I8, data, index)
+	body.AddInstruction(pointer)
+	char := ir.NewLoad(ir.I8, pointer)
+	body.AddInstruction(char)
+	extended := ir.NewZExt(char, ir.I32)
+	body.AddInstruction(extended)
+	mixed := ir.NewXor(hash, extended)
+	body.AddInstruction(mixed)
+	next := ir.NewMul(mixed, ir.NewInt(ir.I32, fnvPrime))
+	body.AddInstruction(next)
+	increment := ir.NewAdd(index, ir.NewInt(ir.I32, 1))
+	body.AddInstruction(increment)
+	body.AddInstruction(ir.NewBr(loop))
+	index.Incs = append(index.Incs, ir.NewIncoming(increment, body))
+	hash.Incs = append(hash.Incs, ir.NewIncoming(next, body))
+
+	exit.AddInstruction(ir.NewRet(hash))
+	return f
+}
+
+// stringHash returns fnv-1a hash of text, cases of switch on string are hashed by it
+func stringHash(text string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(text))
+	return h.Sum32()
+}
+
+// sign returns -1, 0 or 1 by sign of value
+func sign(b *ir.Block, value ir.Value) ir.Value {
+	positive := ir.NewICmp(ir.IPredSGT, value, ir.NewInt(ir.I32, 0))
+	b.AddInstruction(positive)
+	negative := ir.NewICmp(ir.IPredSLT, value, ir.NewInt(ir.I32, 0))
+	b.AddInstruction(negative)
+	one := ir.NewZExt(positive, ir.I32)
+	b.AddInstruction(one)
+	minus := ir.NewZExt(negative, ir.I32)
+	b.AddInstruction(minus)
+	result := ir.NewSub(one, minus)
+	b.AddInstruction(result)
+	return result
+}
+
+// stringFromIntFunction generates function which formats integer in decimal
+func (p *Program) stringFromIntFunction(unsigned bool) *ir.Func {
+	name, format := "from_int", "%lld"
+	if unsigned {
+		name, format = "from_uint", "%llu"
+	}
+	return p.stringFormatFunction(name, format, ir.I64)
+}
+
+// stringFromFloatFunction generates function which formats float in the shortest form of %g
+func (p *Program) stringFromFloatFunction() *ir.Func {
+	return p.stringFormatFunction("from_float", "%g", ir.Float64)
+}
+
+func (p *Program) stringFormatFunction(name string, format string, t ir.Type) *ir.Func {
+	const capacity = 32
+	value := newParam("value", t)
+	f, created := p.newStringFunction(name, CreateStringType(), value)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	buffer := ir.NewAlloca(ir.NewArrayType(capacity, ir.I8))
+	entry.AddInstruction(buffer)
+	bytes := ir.NewBitCast(buffer, pointerType)
+	entry.AddInstruction(bytes)
+	size := ir.NewCall(p.snprintf(), bytes, ir.NewInt(ir.I64, capacity), p.AddString(format), value)
+	entry.AddInstruction(size)
+	s := ir.NewCall(p.stringCreateFunction(), bytes, size)
+	entry.AddInstruction(s)
+	entry.AddInstruction(ir.NewRet(s))
+	return f
+}
+
+// stringFromCharFunction generates function which encodes code point in utf-8
+func (p *Program) stringFromCharFunction() *ir.Func {
+	char := newParam("char", CreateCharType())
+	f, created := p.newStringFunction("from_char", CreateStringType(), char)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	buffer := ir.NewAlloca(ir.NewArrayType(4, ir.I8))
+	entry.AddInstruction(buffer)
+	bytes := ir.NewBitCast(buffer, pointerType)
+	entry.AddInstruction(bytes)
+
+	// code point is encoded in 1 to 4 bytes, the first byte has prefix of count and the others are 10xxxxxx
+	limits := []int64{0x80, 0x800, 0x10000}
+	prefixes := []int64{0, 0xC0, 0xE0, 0xF0}
+	masks := []int64{0x7F, 0x1F, 0x0F, 0x07}
+	current := entry
+	for count := 1; count <= 4; count++ {
+		encode := current
+		if count < 4 {
+			encode = f.NewBlock("")
+			next := f.NewBlock("")
+			fits := ir.NewICmp(ir.IPredULT, char, ir.NewInt(ir.I32, limits[count-1]))
+			current.AddInstruction(fits)
+			current.AddInstruction(ir.NewCondBr(fits, encode, next))
+			current = next
+		}
+		for i := 0; i < count; i++ {
+			prefix, mask := int64(0x80), int64(0x3F)
+			if i == 0 {
+				prefix, mask = prefixes[count-1], masks[count-1]
+			}
+			shifted := ir.NewLShr(char, ir.NewInt(ir.I32, int64(6*(count-1-i))))
+			encode.AddInstruction(shifted)
+			bits := ir.NewAnd(shifted, ir.NewInt(ir.I32, mask))
+			encode.AddInstruction(bits)
+			prefixed := ir.NewOr(bits, ir.NewInt(ir.I32, prefix))
+			encode.AddInstruction(prefixed)
+			byteValue := ir.NewTrunc(prefixed, ir.I8)
+			encode.AddInstruction(byteValue)
+			pointer := ir.NewGetElementPtr(ir.I8, bytes, ir.NewInt(ir.I32, int64(i)))
+			encode.AddInstruction(pointer)
+			encode.AddInstruction(ir.NewStore(byteValue, pointer))
+		}
+		s := ir.NewCall(p.stringCreateFunction(), bytes, ir.NewInt(ir.I32, int64(count)))
+		encode.AddInstruction(s)
+		encode.AddInstruction(ir.NewRet(s))
+	}
+	return f
+}
+
+// stringFromPointerFunction generates function which copies null terminated bytes, null pointer is converted to null string
+func (p *Program) stringFromPointerFunction() *ir.Func {
+	text := newParam("text", pointerType)
+	f, created := p.newStringFunction("from_pointer", CreateStringType(), text)
+	if !created {
+		return f
+	}
+	entry := f.NewBlock(FunctionEntry)
+	empty := f.NewBlock("")
+	body := f.NewBlock(FunctionBody)
+
+	isNull := ir.NewICmp(ir.IPredEQ, text, ir.NewNull(pointerType))
+	entry.AddInstruction(isNull)
+	entry.AddInstruction(ir.NewCondBr(isNull, empty, body))
+	empty.AddInstruction(ir.NewRet(ir.NewNull(CreateStringType())))
+
+	length := ir.NewCall(p.externFunction("strlen", ir.NewFuncType(ir.I64, pointerType)), text)
+	body.AddInstruction(length)
+	size := ir.NewTrunc(length, ir.I32)
+	body.AddInstruction(size)
+	s := ir.NewCall(p.stringCreateFunction(), text, size)
+	body.AddInstruction(s)
+	body.AddInstruction(ir.NewRet(s))
+	return f
+}
@@ -5,7 +5,7 @@ import (
 )
 
 var (
-	builtinClasses = []string{"global.counter", "golbal.allocator", "global.string"}
+	builtinClasses = []string{"global.counter", "golbal.allocator"}
 )
 
 type Type interface {
@@ -29,6 +29,80 @@ func CreateStructPointer(qualified string) *ir.PointerType {
 	return ir.NewPointerType(t)
 }
 
+// CreateClassPointer creates pointer type of class instance, qualified name of class is stored as user data
+func CreateClassPointer(qualified string) *ir.PointerType {
+	t := ir.NewPointerType(ir.I8)
+	t.UserData = qualified
+	return t
+}
+
+// CreateEnumType creates integer type of enum value by its underlying type, qualified name of enum is stored as user data
+func CreateEnumType(qualified string, underlying *ir.IntType) *ir.IntType {
+	return &ir.IntType{
+		BitSize:  underlying.BitSize,
+		Unsigned: underlying.Unsigned,
+		UserData: qualified,
+	}
+}
+
+// CreateStringType creates type of string, string is counter of its data like class instance
+func CreateStringType() *ir.PointerType {
+	return CreateClassPointer(String)
+}
+
+// IsString reports whether t is type of string value, address of string variable is not string
+func IsString(t ir.Type) bool {
+	p, ok := t.(*ir.PointerType)
+	return ok && p.UserData == String && !ir.IsPointer(p.ElemType)
+}
+
+// CreateCharType creates type of unicode code point, it is i32 which is distinguished from integers by user data
+func CreateCharType() *ir.IntType {
+	return &ir.IntType{
+		BitSize:  32,
+		UserData: Char,
+	}
+}
+
+// IsChar reports whether t is type of unicode code point
+func IsChar(t ir.Type) bool {
+	i, ok := t.(*ir.IntType)
+	return ok && i.UserData == Char
+}
+
+// isCounted reports whether value of type t is managed by counter, they are instances of classes except builtin classes and strings
+func isCounted(p *Program, t ir.Type) bool {
+	qualified := GetTypeUserData(t)
+	if _, ok := p.FindQualified(qualified).(*Class); ok && !IsBuiltinClass(qualified) {
+		return true
+	}
+	return IsString(t)
+}
+
+// retainInstance increases shared count of class instance which is not null
+func retainInstance(p *Program, b *ir.Block, value ir.Value) {
+	if _, ok := value.(*ir.Null); ok {
+		return
+	}
+	f, ok := p.Intrinsics[InstanceRetain]
+	if !ok {
+		instance := newParam("instance", pointerType)
+		f = p.IRModule.NewFunc(InstanceRetain, ir.Void, instance)
+		p.Intrinsics[InstanceRetain] = f
+
+		entry := f.NewBlock(FunctionEntry)
+		body := f.NewBlock(FunctionBody)
+		exit := f.NewBlock(FunctionExit)
+		isNull := ir.NewICmp(ir.IPredEQ, instance, ir.NewNull(pointerType))
+		entry.AddInstruction(isNull)
+		entry.AddInstruction(ir.NewCondBr(isNull, exit, body))
+		body.AddInstruction(ir.NewCall(retainShared, instance))
+		body.AddInstruction(ir.NewBr(exit))
+		exit.AddInstruction(ir.NewRet(nil))
+	}
+	b.AddInstruction(ir.NewCall(f, value))
+}
+
 func IsBuiltinClass(qualified string) bool {
 	for _, str := range builtinClasses {
 		if str == qualified {
@@ -41,15 +115,26 @@ func IsBuiltinClass(qualified string) bool {
 func CopyUserData(source, dest ir.Value) {
 	t1 := source.Type()
 	t2 := dest.Type()
-	if ir.IsPointer(t1) && ir.IsPointer((t2)) {
+	if ir.IsPointer(t1) && ir.IsPointer((t2)) && t1.(*ir.PointerType).UserData != "" {
 		t2.(*ir.PointerType).UserData = t1.(*ir.PointerType).UserData
 	}
 }
 
 func GetUserData(value ir.Value) string {
-	t := value.Type()
-	if ir.IsPointer(t) {
-		return t.(*ir.PointerType).UserData
+	return GetTypeUserData(value.Type())
+}
+
+func GetTypeUserData(t ir.Type) string {
+	switch t := t.(type) {
+	case *ir.PointerType:
+		return t.UserData
+
+	case *ir.IntType:
+		return t.UserData
+
+	// value of struct
+	case *ir.StructType:
+		return t.TypeName
 	}
 	return ""
 }
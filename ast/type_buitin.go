@@ -16,7 +16,7 @@ func (b *BuitinType) Type(*Program) ir.Type {
 		return ir.I1
 
 	case token.Char:
-		return ir.I32
+		return CreateCharType()
 
 	case token.Int8, token.SByte:
 		return ir.I8
@@ -54,6 +54,9 @@ func (b *BuitinType) Type(*Program) ir.Type {
 	case token.Pointer:
 		return pointerType
 
+	case token.String:
+		return CreateStringType()
+
 	case token.Any:
 		return ir.NewVectorType(9, ir.I8)
 
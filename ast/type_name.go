@@ -13,9 +13,16 @@ type TypeName struct {
 
 func (n *TypeName) Type(p *Program) ir.Type {
 	qualified, d := p.FindDeclaration(n)
-	if d == nil {
-		p.Error(n.GetPosition(), "undefined: "+n.Name)
-		return ir.Void
+	switch t := d.(type) {
+	case *Class, *Interface:
+		return CreateClassPointer(qualified)
+
+	case *Enum:
+		return CreateEnumType(qualified, t.UnderlyingType(p))
+
+	case *Struct:
+		return t.IRStruct
 	}
-	return ir.NewPointerType(&ir.StructType{TypeName: qualified})
+	p.Error(n.GetPosition(), "undefined: "+n.Name)
+	return ir.Void
 }
@@ -0,0 +1,343 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Visitor is called for every node when it is walked, Enter is called before children of node and Leave after them
+// if Enter returns false, children of node are skipped and Leave is not called
+type Visitor interface {
+	Enter(node Node) bool
+	Leave(node Node)
+}
+
+// Walk traverses node and all its children in depth-first order
+func Walk(v Visitor, node Node) {
+	Rewrite(node, v.Enter, func(n Node) Node {
+		v.Leave(n)
+		return n
+	})
+}
+
+// Inspect traverses node like Walk with functions as pre and post hooks, nil hook is ignored
+func Inspect(node Node, pre func(Node) bool, post func(Node)) {
+	Walk(&inspector{pre: pre, post: post}, node)
+}
+
+// Rewrite traverses node like Walk, every node is replaced by the result of post hook, then the (new) root is returned
+// returning nil from post hook removes node, replacement must be assignable to the field which holds the original node
+func Rewrite(node Node, pre func(Node) bool, post func(Node) Node) Node {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return node
+	}
+	r := &rewriter{
+		pre:  pre,
+		post: post,
+	}
+	return r.node(node)
+}
+
+// Walk traverses all modules of program, modules are ordered by imports
+func (p *Program) Walk(v Visitor) {
+	for _, m := range p.SortedModules() {
+		Walk(v, m)
+	}
+}
+
+type inspector struct {
+	pre  func(Node) bool
+	post func(Node)
+}
+
+func (i *inspector) Enter(node Node) bool {
+	return i.pre == nil || i.pre(node)
+}
+
+func (i *inspector) Leave(node Node) {
+	if i.post != nil {
+		i.post(node)
+	}
+}
+
+type rewriter struct {
+	pre  func(Node) bool
+	post func(Node) Node
+}
+
+func (r *rewriter) node(n Node) Node {
+	if r.pre != nil && !r.pre(n) {
+		return n
+	}
+
+	switch n := n.(type) {
+	// declarations
+	case *Module:
+		r.attributes(n.Attributes)
+		r.list(&n.Imports)
+		r.list(&n.Variables)
+		r.list(&n.Functions)
+		r.list(&n.Enums)
+		r.list(&n.Structs)
+		r.list(&n.Interfaces)
+		r.list(&n.Classes)
+
+	case *Variable:
+		r.declaration(&n.DeclarationBase)
+		r.field(&n.Type)
+		r.field(&n.Value)
+
+	case *Function:
+		// function of lambda is generated from lambda itself, so it is not walked
+		r.declaration(&n.DeclarationBase)
+		r.field(&n.TypeParameters)
+		r.field(&n.Parameters)
+		r.field(&n.ReturnType)
+		r.field(&n.Body)
+
+	case *Enum:
+		r.declaration(&n.DeclarationBase)
+		r.field(&n.Underlying)
+		r.list(&n.Members)
+
+	case *Struct:
+		r.declaration(&n.DeclarationBase)
+		r.list(&n.Variables)
+		r.list(&n.Functions)
+
+	case *Interface:
+		r.declaration(&n.DeclarationBase)
+		r.field(&n.TypeParameters)
+		r.list(&n.Parents)
+		r.list(&n.Functions)
+
+	case *Class:
+		r.declaration(&n.DeclarationBase)
+		r.field(&n.TypeParameters)
+		r.list(&n.Parents)
+		r.list(&n.Variables)
+		r.list(&n.Functions)
+
+	case *Parameters:
+		r.list(&n.Parameters)
+
+	case *Parameter:
+		r.field(&n.Type)
+
+	case *Arguments:
+		r.list(&n.Arguments)
+
+	case *TypeParameters:
+		r.list(&n.Parameters)
+
+	case *TypeParameter:
+		r.field(&n.Type)
+
+	case *TypeArguments:
+		r.list(&n.Arguments)
+
+	// types
+	case *TypeName:
+		r.field(&n.TypeArguments)
+
+	case *TypeFunction:
+		r.list(&n.Parameters)
+		r.field(&n.ReturnType)
+
+	// statements
+	case *Block:
+		r.list(&n.Statements)
+
+	case *DeclarationStatement:
+		r.field(&n.Name)
+		r.field(&n.Type)
+		r.field(&n.Value)
+
+	case *ExpressionStatement:
+		r.field(&n.Expression)
+
+	case *For:
+		r.field(&n.Initialization)
+		r.field(&n.Condition)
+		r.field(&n.Post)
+		r.field(&n.Body)
+
+	case *Foreach:
+		r.field(&n.Key)
+		r.field(&n.Item)
+		r.field(&n.Iterator)
+		r.field(&n.Body)
+
+	case *If:
+		r.field(&n.Initialization)
+		r.field(&n.Condition)
+		r.field(&n.Body)
+		r.field(&n.Else)
+
+	case *Return:
+		r.field(&n.Expression)
+
+	case *Switch:
+		r.field(&n.Initialization)
+		r.field(&n.Operand)
+		r.list(&n.Cases)
+		r.field(&n.Default)
+
+	case *Case:
+		r.field(&n.Case)
+		r.field(&n.Body)
+
+	case *Throw:
+		r.field(&n.Expression)
+
+	case *Yield:
+		r.field(&n.Expression)
+
+	case *Try:
+		r.field(&n.Try)
+		r.field(&n.Operand)
+		r.field(&n.Catch)
+		r.field(&n.Finally)
+
+	// expressions
+	case *Binary:
+		r.field(&n.Left)
+		r.field(&n.Right)
+
+	case *Conversion:
+		r.field(&n.Expression)
+		r.field(&n.Typ)
+
+	case *Decrement:
+		r.field(&n.Expression)
+
+	case *Increment:
+		r.field(&n.Expression)
+
+	case *Invocation:
+		r.field(&n.Function)
+		r.field(&n.TypeArguments)
+		r.field(&n.Arguments)
+
+	case *Lambda:
+		r.list(&n.Captures)
+		r.field(&n.Parameters)
+		r.field(&n.ReturnType)
+		r.field(&n.Body)
+
+	case *MemberAccess:
+		r.field(&n.Parent)
+		r.field(&n.Member)
+
+	case *New:
+		r.field(&n.Typ)
+		r.field(&n.Arguments)
+		r.list(&n.Initializers)
+
+	case *Initializer:
+		r.field(&n.Name)
+		r.field(&n.Value)
+
+	case *Interpolation:
+		r.list(&n.Parts)
+
+	case *Parentheses:
+		r.field(&n.Expression)
+
+	case *Subscripting:
+		r.field(&n.Parent)
+		r.field(&n.Element)
+
+	case *Unary:
+		r.field(&n.Expression)
+	}
+
+	if r.post != nil {
+		return r.post(n)
+	}
+	return n
+}
+
+func (r *rewriter) declaration(d *DeclarationBase) {
+	r.attributes(d.Attributes)
+	r.field(&d.Name)
+}
+
+// attributes walks literal values of attributes ordered by their names
+func (r *rewriter) attributes(attributes []*Attribute) {
+	for _, a := range attributes {
+		var names []string
+		for name := range a.Values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if a.Values[name] == nil {
+				continue
+			}
+			n := r.node(a.Values[name])
+			if n == nil {
+				delete(a.Values, name)
+			} else if literal, ok := n.(*Literal); ok {
+				a.Values[name] = literal
+			} else {
+				panic(fmt.Sprintf("attribute value cannot be replaced by %T", n))
+			}
+		}
+	}
+}
+
+// field walks node stored in field, field is pointer to struct field
+func (r *rewriter) field(field interface{}) {
+	v := reflect.ValueOf(field).Elem()
+	if v.IsNil() {
+		return
+	}
+	original := v.Interface().(Node)
+	n := r.node(original)
+	if n != original {
+		r.set(v, n)
+	}
+}
+
+// list walks nodes stored in slice, list is pointer to slice
+func (r *rewriter) list(list interface{}) {
+	v := reflect.ValueOf(list).Elem()
+	changed := false
+	result := reflect.MakeSlice(v.Type(), 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		element := v.Index(i)
+		if element.IsNil() {
+			result = reflect.Append(result, element)
+			continue
+		}
+		original := element.Interface().(Node)
+		n := r.node(original)
+		if n != original {
+			changed = true
+		}
+		if n != nil {
+			result = reflect.Append(result, r.value(element.Type(), n))
+		}
+	}
+	if changed {
+		v.Set(result)
+	}
+}
+
+func (r *rewriter) set(v reflect.Value, n Node) {
+	if n == nil {
+		v.Set(reflect.Zero(v.Type()))
+	} else {
+		v.Set(r.value(v.Type(), n))
+	}
+}
+
+func (r *rewriter) value(t reflect.Type, n Node) reflect.Value {
+	value := reflect.ValueOf(n)
+	if !value.Type().AssignableTo(t) {
+		panic(fmt.Sprintf("%s cannot be replaced by %T", t, n))
+	}
+	return value
+}
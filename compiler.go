@@ -1,8 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"os/exec"
 
 	"github.com/panda-foundation/go-compiler/ast"
@@ -14,6 +17,8 @@ type Compiler struct {
 	parser  *parser.Parser
 	fileset *token.FileSet
 	program *ast.Program
+	// errors and warnings of program are written to it
+	log io.Writer
 }
 
 func NewCompiler(flags []string) *Compiler {
@@ -22,6 +27,7 @@ func NewCompiler(flags []string) *Compiler {
 		parser:  parser.NewParser(flags, p),
 		fileset: &token.FileSet{},
 		program: p,
+		log:     os.Stderr,
 	}
 }
 
@@ -36,34 +42,75 @@ func (c *Compiler) ParseFile(file string) {
 	c.parser.ParseFile(f, b)
 }
 
-func (c *Compiler) Compile(file string) {
+// Compile builds executable file of program, nothing is written if program has errors
+func (c *Compiler) Compile(file string) error {
 	content := c.program.GenerateIR()
-	errors := c.program.Errors
-	if len(errors) > 0 {
-		for _, e := range errors {
-			fmt.Println(e.Position.String())
-			fmt.Println(e.Message)
-		}
-		panic("compile failed.")
+	if err := c.report(); err != nil {
+		return err
 	}
 	if err := ioutil.WriteFile(file+".ll", []byte(content), 0644); err != nil {
-		panic(err)
+		return err
 	}
 
 	cmd := exec.Command("opt-10", "-o", file+".opt.ll", "-S", "--O2", file+".ll")
 	if err := cmd.Run(); err != nil {
-		panic(err)
+		return err
 	}
 
 	cmd = exec.Command("llc-10", "-filetype=obj", "-o", file+".o", file+".opt.ll")
 	if err := cmd.Run(); err != nil {
-		panic(err)
+		return err
 	}
 
 	cmd = exec.Command("clang", "-o", file, file+".o")
-	if err := cmd.Run(); err != nil {
-		panic(err)
+	return cmd.Run()
+}
+
+// report writes errors and warnings of program to log, error is returned if program has errors
+func (c *Compiler) report() error {
+	for _, w := range c.program.Warnings {
+		fmt.Fprintln(c.log, w.Position.String(), "warning:", w.Message)
+	}
+	for _, e := range c.program.Errors {
+		fmt.Fprintln(c.log, e.Position.String(), e.Message)
+	}
+	if len(c.program.Errors) > 0 {
+		return fmt.Errorf("compile failed with %d errors", len(c.program.Errors))
+	}
+	return nil
+}
+
+// Emit writes parsed ast and resolved symbols of program as JSON, symbols are resolved by generating ir
+// JSON is written even if program has errors, they are included in symbols and reported to log
+func (c *Compiler) Emit(w io.Writer, dumpAST bool, dumpSymbols bool) error {
+	result := make(map[string]interface{})
+	if dumpSymbols {
+		// ast is dumped with types resolved by checker
+		c.program.GenerateIR()
+		for k, v := range c.program.DumpSymbols() {
+			result[k] = v
+		}
+	}
+	if dumpAST {
+		for k, v := range c.program.DumpAST() {
+			result[k] = v
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		return err
+	}
+	return c.report()
+}
+
+// Graph writes namespace dependency graph of program in DOT language, invalid imports are reported and excluded
+func (c *Compiler) Graph(w io.Writer) error {
+	c.program.ValidateImports()
+	if err := c.program.Dependencies().WriteDOT(w); err != nil {
+		return err
 	}
+	return c.report()
 }
 
 /*
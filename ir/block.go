@@ -39,6 +39,11 @@ func (block *Block) AddInstruction(inst Instruction) {
 }
 
 func (block *Block) InsertAlloca(inst *InstAlloca) {
+	block.InsertInstruction(inst)
+}
+
+// InsertInstruction adds instruction before the terminator if block is already terminated
+func (block *Block) InsertInstruction(inst Instruction) {
 	if block.Terminated {
 		block.Insts = append(block.Insts, block.Insts[len(block.Insts)-1])
 		block.Insts[len(block.Insts)-2] = inst
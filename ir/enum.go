@@ -1,5 +1,25 @@
 package ir
 
+// Linkage specifies the linkage type of a global variable.
+type Linkage string
+
+// Linkage types.
+const (
+	LinkageNone      Linkage = ""          // none
+	LinkageAppending Linkage = "appending" // appending
+	LinkageInternal  Linkage = "internal"  // internal
+	LinkagePrivate   Linkage = "private"   // private
+)
+
+// FuncAttribute is a function attribute.
+type FuncAttribute string
+
+// Function attributes.
+const (
+	FuncAttrAlwaysInline FuncAttribute = "alwaysinline" // alwaysinline
+	FuncAttrNoInline     FuncAttribute = "noinline"     // noinline
+)
+
 // ClauseType specifies the clause type of a landingpad clause.
 type ClauseType uint8
 
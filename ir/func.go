@@ -18,6 +18,10 @@ type Func struct {
 	Params []*Param
 	// Basic blocks.
 	Blocks []*Block // nil if declaration.
+	// Function attributes.
+	FuncAttrs []FuncAttribute
+	// Section name; empty if not present.
+	Section string
 	// Pointer type to function, including an optional address space. If Typ is
 	// nil, the first invocation of Type stores a pointer type with Sig as
 	// element.
@@ -146,6 +150,12 @@ func headerString(f *Func) string {
 		buf.WriteString("...")
 	}
 	buf.WriteString(")")
+	for _, attr := range f.FuncAttrs {
+		fmt.Fprintf(buf, " %s", attr)
+	}
+	if len(f.Section) > 0 {
+		fmt.Fprintf(buf, " section %s", Quote([]byte(f.Section)))
+	}
 	return buf.String()
 }
 
@@ -11,12 +11,16 @@ import (
 type Global struct {
 	// Global variable name (without '@' prefix).
 	GlobalIdent
+	// Linkage type.
+	Linkage Linkage
 	// Immutability of global variable (constant or global).
 	Immutable bool
 	// Content type.
 	ContentType Type
 	// Initial value; or nil if declaration.
 	Init Constant
+	// Section name; empty if not present.
+	Section string
 	// Pointer type to global variable, including an optional address space. If
 	// Typ is nil, the first invocation of Type stores a pointer type with
 	// ContentType as element.
@@ -65,6 +69,9 @@ func (g *Global) Type() Type {
 func (g *Global) LLString() string {
 	buf := &strings.Builder{}
 	fmt.Fprintf(buf, "%s =", g.Ident())
+	if g.Linkage != LinkageNone {
+		fmt.Fprintf(buf, " %s", g.Linkage)
+	}
 	if g.Immutable {
 		buf.WriteString(" constant")
 	} else {
@@ -75,5 +82,8 @@ func (g *Global) LLString() string {
 		// Global definition.
 		fmt.Fprintf(buf, " %s", g.Init.Ident())
 	}
+	if len(g.Section) > 0 {
+		fmt.Fprintf(buf, ", section %s", Quote([]byte(g.Section)))
+	}
 	return buf.String()
 }
@@ -276,6 +276,8 @@ type IntType struct {
 	BitSize uint64
 	// If int is unsigned
 	Unsigned bool
+	// Store original type or other custom data
+	UserData string
 }
 
 // NewIntType returns a new integer type based on the given integer bit size.
@@ -1 +1,90 @@
 package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const usage = `usage:
+  panda build [-D flag[=value]] -o output files...
+  panda emit [-D flag[=value]] [--ast=json] [--symbols=json] files...
+    symbols are emitted if neither --ast nor --symbols is given
+  panda graph [-D flag[=value]] files...`
+
+// defines are preprocessor flags passed by "-D"
+type defines []string
+
+func (d *defines) String() string {
+	return strings.Join(*d, ",")
+}
+
+func (d *defines) Set(value string) error {
+	*d = append(*d, value)
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var flags defines
+	set := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	set.Var(&flags, "D", "define preprocessor flag")
+
+	switch os.Args[1] {
+	case "build":
+		output := set.String("o", "a.out", "output file")
+		set.Parse(os.Args[2:])
+		c := parseFiles(flags, set.Args())
+		if err := c.Compile(*output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case "emit":
+		dumpAST := set.String("ast", "", "emit ast, format: json")
+		dumpSymbols := set.String("symbols", "", "emit symbols, format: json")
+		set.Parse(os.Args[2:])
+		for _, format := range []string{*dumpAST, *dumpSymbols} {
+			if format != "" && format != "json" {
+				fmt.Fprintf(os.Stderr, "unsupported format: %s\n", format)
+				os.Exit(2)
+			}
+		}
+		if *dumpAST == "" && *dumpSymbols == "" {
+			*dumpSymbols = "json"
+		}
+		c := parseFiles(flags, set.Args())
+		if err := c.Emit(os.Stdout, *dumpAST != "", *dumpSymbols != ""); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	case "graph":
+		set.Parse(os.Args[2:])
+		c := parseFiles(flags, set.Args())
+		if err := c.Graph(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func parseFiles(flags []string, files []string) *Compiler {
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	c := NewCompiler(flags)
+	for _, file := range files {
+		c.ParseFile(file)
+	}
+	return c
+}
@@ -1,7 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -11,7 +18,9 @@ func TestVector(t *testing.T) {
 	c.ParseFile("../panda/core/console.pd")
 	c.ParseFile("../panda/collection/vector.pd")
 	c.ParseFile("./sample/vector.pd")
-	c.Compile("./sample/vector.cpp")
+	if err := c.Compile("./sample/vector.cpp"); err != nil {
+		t.Fatal(err)
+	}
 	cmd := exec.Command("g++", "-o", "./sample/vector", "./sample/vector.cpp")
 	err := cmd.Run()
 	if err != nil {
@@ -23,7 +32,9 @@ func TestSample(t *testing.T) {
 	c := NewCompiler([]string{"cpp"})
 
 	c.ParseFile("./sample/foobar.pd")
-	c.Compile("./sample/foobar.cpp")
+	if err := c.Compile("./sample/foobar.cpp"); err != nil {
+		t.Fatal(err)
+	}
 	cmd := exec.Command("g++", "-o", "./sample/foobar", "./sample/foobar.cpp")
 	err := cmd.Run()
 	if err != nil {
@@ -39,7 +50,146 @@ func TestBasic(t *testing.T) {
 	//c.ParseFile("../panda/core/string.pd")
 	c.ParseFile("../panda/core/counter.pd")
 	c.ParseFile("./sample/basic.pd")
-	c.Compile("./sample/basic")
+	if err := c.Compile("./sample/basic"); err != nil {
+		t.Fatal(err)
+	}
 
 	//TO-DO vector[any] for generic function call
 }
+
+const emitSource = "namespace; public class counter { var shared int; var weaks int; var object pointer; var destructor function(pointer); } " +
+	"class a { public function f() int { return 1; } } class b : a { public function f() int { return 2; } } " +
+	"function main() int { var o a = new b(); return o.f(); }"
+
+func writeSource(t *testing.T, source string) string {
+	file := filepath.Join(t.TempDir(), "main.pd")
+	if err := ioutil.WriteFile(file, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func emit(t *testing.T, source string, dumpAST bool, dumpSymbols bool) (map[string]interface{}, string, error) {
+	c := NewCompiler(nil)
+	log := &bytes.Buffer{}
+	c.log = log
+	c.ParseFile(writeSource(t, source))
+	output := &bytes.Buffer{}
+	err := c.Emit(output, dumpAST, dumpSymbols)
+	result := make(map[string]interface{})
+	if e := json.Unmarshal(output.Bytes(), &result); e != nil {
+		t.Fatal(e)
+	}
+	return result, log.String(), err
+}
+
+func TestEmit(t *testing.T) {
+	result, log, err := emit(t, emitSource, true, true)
+	if err != nil || log != "" {
+		t.Fatalf("unexpected error %v: %s", err, log)
+	}
+
+	// every node has kind and position, expressions have resolved type
+	var kinds []string
+	var resolved []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if kind, ok := v["kind"].(string); ok {
+				if kind != "Module" && !strings.Contains(fmt.Sprint(v["position"]), "main.pd:1:") {
+					t.Errorf("%s has no position", kind)
+				}
+				kinds = append(kinds, kind)
+				if kind == "Invocation" {
+					resolved = append(resolved, fmt.Sprint(v["resolved_type"]))
+				}
+			}
+			for _, value := range v {
+				walk(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	walk(result["modules"])
+	for _, kind := range []string{"Module", "Class", "Function", "Return", "Invocation"} {
+		if !strings.Contains(fmt.Sprint(kinds), kind) {
+			t.Errorf("%s not found in ast", kind)
+		}
+	}
+	if fmt.Sprint(resolved) != "[i32]" {
+		t.Errorf("expected resolved type of invocation i32, but got %v", resolved)
+	}
+
+	// vtable of class b overrides function f of class a
+	var vtable interface{}
+	for _, s := range result["symbols"].([]interface{}) {
+		symbol := s.(map[string]interface{})
+		if symbol["qualified"] == "global.b" {
+			vtable = symbol["vtable"]
+			if symbol["kind"] != "class" || symbol["parent"] != "global.a" || !strings.HasSuffix(symbol["position"].(string), "main.pd:1:178") {
+				t.Errorf("unexpected symbol of class b %v", symbol)
+			}
+		}
+	}
+	if fmt.Sprint(vtable) != "[map[function:global.b.create index:0 name:create$] map[function:global.b.destroy index:1 name:destroy$] map[function:global.b.f index:2 name:f$]]" {
+		t.Errorf("unexpected vtable of class b %v", vtable)
+	}
+	if fmt.Sprint(result["errors"]) != "[]" {
+		t.Errorf("unexpected errors %v", result["errors"])
+	}
+}
+
+func TestEmitFail(t *testing.T) {
+	result, log, err := emit(t, "namespace; function main() int { return x; }", false, true)
+	if err == nil {
+		t.Errorf("error of program is not returned")
+	}
+	if !strings.HasSuffix(log, "main.pd:1:41 undefined x\n") {
+		t.Errorf("unexpected log %s", log)
+	}
+	errors := result["errors"].([]interface{})
+	if len(errors) != 1 || errors[0].(map[string]interface{})["message"] != "undefined x" {
+		t.Errorf("unexpected errors %v", errors)
+	}
+}
+
+// TestCommand runs panda in a child process, so its output and exit status are checked
+func TestCommand(t *testing.T) {
+	if args := os.Getenv("PANDA_ARGS"); args != "" {
+		os.Args = append([]string{"panda"}, strings.Split(args, " ")...)
+		main()
+		os.Exit(0)
+	}
+	run := func(args ...string) (string, string, int) {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestCommand$")
+		cmd.Env = append(os.Environ(), "PANDA_ARGS="+strings.Join(args, " "))
+		stdout := &bytes.Buffer{}
+		stderr := &bytes.Buffer{}
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
+		cmd.Run()
+		return stdout.String(), stderr.String(), cmd.ProcessState.ExitCode()
+	}
+
+	// symbols are emitted by default
+	stdout, stderr, status := run("emit", writeSource(t, emitSource))
+	if status != 0 || stderr != "" || !strings.Contains(stdout, "\"symbols\": [") || strings.Contains(stdout, "\"modules\"") {
+		t.Errorf("unexpected result of emit %d %s %s", status, stderr, stdout)
+	}
+
+	for _, command := range []string{"emit", "build"} {
+		_, stderr, status = run(command, writeSource(t, "namespace; function main() int { return x; }"))
+		if status != 1 || !strings.Contains(stderr, "main.pd:1:41 undefined x\ncompile failed with 1 errors\n") {
+			t.Errorf("unexpected result of %s %d %s", command, status, stderr)
+		}
+	}
+
+	_, stderr, status = run("graph", writeSource(t, "namespace; import missing;"))
+	if status != 1 || !strings.Contains(stderr, "missing") {
+		t.Errorf("unexpected result of graph %d %s", status, stderr)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 func (p *Parser) parseVariable(modifier *ast.Modifier, attributes []*ast.Attribute, objectName string) *ast.Variable {
 	d := &ast.Variable{}
+	d.Position = p.position
 	d.ObjectName = objectName
 	d.Modifier = modifier
 	d.Attributes = attributes
@@ -16,7 +17,9 @@ func (p *Parser) parseVariable(modifier *ast.Modifier, attributes []*ast.Attribu
 	}
 	p.next()
 	d.Name = p.parseIdentifier()
-	d.Type = p.parseType()
+	if p.token != token.Assign {
+		d.Type = p.parseType()
+	}
 
 	if p.token == token.Assign {
 		p.next()
@@ -31,6 +34,7 @@ func (p *Parser) parseVariable(modifier *ast.Modifier, attributes []*ast.Attribu
 
 func (p *Parser) parseFunction(modifier *ast.Modifier, attributes []*ast.Attribute, objectName string) *ast.Function {
 	d := &ast.Function{}
+	d.Position = p.position
 	d.ObjectName = objectName
 	d.Modifier = modifier
 	d.Attributes = attributes
@@ -53,13 +57,19 @@ func (p *Parser) parseFunction(modifier *ast.Modifier, attributes []*ast.Attribu
 
 func (p *Parser) parseEnum(modifier *ast.Modifier, attributes []*ast.Attribute) *ast.Enum {
 	e := &ast.Enum{}
+	e.Position = p.position
 	e.Modifier = modifier
 	e.Attributes = attributes
 	p.next()
 	e.Name = p.parseIdentifier()
+	if p.token == token.Colon {
+		p.next()
+		e.Underlying = p.parseType()
+	}
 	p.expect(token.LeftBrace)
 	for p.token != token.RightBrace {
 		v := &ast.Variable{}
+		v.Position = p.position
 		v.Const = true
 		v.Name = p.parseIdentifier()
 		v.ObjectName = e.Name.Name
@@ -80,8 +90,43 @@ func (p *Parser) parseEnum(modifier *ast.Modifier, attributes []*ast.Attribute)
 	return e
 }
 
+func (p *Parser) parseStruct(modifier *ast.Modifier, attributes []*ast.Attribute) *ast.Struct {
+	s := &ast.Struct{}
+	s.Position = p.position
+	s.Modifier = modifier
+	s.Attributes = attributes
+	p.next()
+	s.Name = p.parseIdentifier()
+	p.expect(token.LeftBrace)
+	for p.token != token.RightBrace {
+		attr := p.parseAttributes()
+		modifier := p.parseDeclarationModifier()
+		switch p.token {
+		case token.Const, token.Var:
+			v := p.parseVariable(modifier, attr, s.Name.Name)
+			err := s.AddVariable(v)
+			if err != nil {
+				p.error(v.Position, err.Error())
+			}
+
+		case token.Function:
+			f := p.parseFunction(modifier, attr, s.Name.Name)
+			err := s.AddFunction(f)
+			if err != nil {
+				p.error(f.Position, err.Error())
+			}
+
+		default:
+			p.expectedError(p.position, "member declaration")
+		}
+	}
+	p.expect(token.RightBrace)
+	return s
+}
+
 func (p *Parser) parseInterface(modifier *ast.Modifier, attributes []*ast.Attribute) *ast.Interface {
 	i := &ast.Interface{}
+	i.Position = p.position
 	i.Modifier = modifier
 	i.Attributes = attributes
 	p.next()
@@ -95,7 +140,7 @@ func (p *Parser) parseInterface(modifier *ast.Modifier, attributes []*ast.Attrib
 	p.expect(token.LeftBrace)
 	for p.token != token.RightBrace {
 		attr := p.parseAttributes()
-		modifier := p.parseModifier()
+		modifier := p.parseDeclarationModifier()
 		switch p.token {
 		case token.Function:
 			f := p.parseFunction(modifier, attr, i.Name.Name)
@@ -113,6 +158,7 @@ func (p *Parser) parseInterface(modifier *ast.Modifier, attributes []*ast.Attrib
 
 func (p *Parser) parseClass(modifier *ast.Modifier, attributes []*ast.Attribute) *ast.Class {
 	c := &ast.Class{}
+	c.Position = p.position
 	c.Modifier = modifier
 	c.Attributes = attributes
 	p.next()
@@ -160,6 +206,28 @@ func (p *Parser) parseModifier() *ast.Modifier {
 		m.Weak = true
 		p.next()
 	}
+	if p.token == token.Static {
+		m.Static = true
+		p.next()
+	}
+	if p.token == token.Abstract {
+		position := p.position
+		m.Abstract = true
+		p.next()
+		if p.token != token.Class {
+			p.error(position, "abstract is only allowed for classes")
+		}
+	}
+	return m
+}
+
+// parseDeclarationModifier parses modifier of declaration which is not class member, static is invalid
+func (p *Parser) parseDeclarationModifier() *ast.Modifier {
+	position := p.position
+	m := p.parseModifier()
+	if m.Static {
+		p.error(position, "static is only allowed for members of class")
+	}
 	return m
 }
 
@@ -1,10 +1,17 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/panda-foundation/go-compiler/ast"
 	"github.com/panda-foundation/go-compiler/token"
 )
 
+var (
+	// braces are doubled in text of interpolated string
+	braces = strings.NewReplacer("{{", "{", "}}", "}")
+)
+
 func (p *Parser) parseExpression() ast.Expression {
 	return p.parseBinaryExpression(0)
 }
@@ -34,6 +41,9 @@ func (p *Parser) parseOperand() ast.Expression {
 		p.next()
 		return e
 
+	case token.INTERPOLATED, token.INTERPOLATED_END:
+		return p.parseInterpolation()
+
 	case token.This:
 		e := &ast.This{}
 		e.Position = p.position
@@ -54,12 +64,94 @@ func (p *Parser) parseOperand() ast.Expression {
 		p.expect(token.RightParen)
 		return e
 
+	case token.Function:
+		return p.parseLambda()
+
 	default:
 		p.error(p.position, "unexpected "+p.token.String())
 		return nil
 	}
 }
 
+func (p *Parser) parseLambda() *ast.Lambda {
+	e := &ast.Lambda{}
+	e.Position = p.position
+	p.next()
+	if p.token == token.LeftBracket {
+		p.next()
+		for p.token != token.RightBracket {
+			c := &ast.Capture{}
+			c.Position = p.position
+			if p.token == token.BitAnd {
+				c.Reference = true
+				p.next()
+			}
+			c.Name = p.parseIdentifier().Name
+			e.Captures = append(e.Captures, c)
+			if p.token != token.Comma {
+				break
+			}
+			p.next()
+		}
+		p.expect(token.RightBracket)
+	}
+	e.Parameters = p.parseParameters()
+	if p.token != token.LeftBrace {
+		e.ReturnType = p.parseType()
+	}
+	e.Body = p.parseBlockStatement()
+	return e
+}
+
+// parseInterpolation parses $"text{expression}text", each text is parsed as string literal, empty text is skipped
+func (p *Parser) parseInterpolation() *ast.Interpolation {
+	e := &ast.Interpolation{}
+	e.Position = p.position
+	for {
+		end := p.token == token.INTERPOLATED_END
+		if p.token != token.INTERPOLATED && !end {
+			p.expectedError(p.position, "'}'")
+		}
+		// text is between $" or } and { or "
+		prefix := 1
+		if strings.HasPrefix(p.literal, "$") {
+			prefix = 2
+		}
+		if text := p.literal[prefix : len(p.literal)-1]; text != "" {
+			l := &ast.Literal{}
+			l.Position = p.position + prefix
+			l.Typ = token.STRING
+			l.Value = `"` + braces.Replace(text) + `"`
+			e.Parts = append(e.Parts, l)
+		}
+		p.next()
+		if end {
+			return e
+		}
+		e.Parts = append(e.Parts, p.parseExpression())
+	}
+}
+
+// parseInitializers parses "{a = 1, b = 2}" after new, trailing comma is allowed
+func (p *Parser) parseInitializers() []*ast.Initializer {
+	var initializers []*ast.Initializer
+	p.next()
+	for p.token != token.RightBrace {
+		i := &ast.Initializer{}
+		i.Position = p.position
+		i.Name = p.parseIdentifier()
+		p.expect(token.Assign)
+		i.Value = p.parseExpression()
+		initializers = append(initializers, i)
+		if p.token != token.Comma {
+			break
+		}
+		p.next()
+	}
+	p.expect(token.RightBrace)
+	return initializers
+}
+
 func (p *Parser) parsePrimaryExpression() ast.Expression {
 	x := p.parseOperand()
 	for {
@@ -75,6 +167,7 @@ func (p *Parser) parsePrimaryExpression() ast.Expression {
 		case token.LeftBracket:
 			e := &ast.Subscripting{}
 			e.Position = p.position
+			e.Parent = x
 			p.next()
 			e.Element = p.parseExpression()
 			p.expect(token.RightBracket)
@@ -144,6 +237,9 @@ func (p *Parser) parseUnaryExpression() ast.Expression {
 		p.next()
 		e.Typ = p.parseTypeName()
 		e.Arguments = p.parseArguments()
+		if p.token == token.LeftBrace {
+			e.Initializers = p.parseInitializers()
+		}
 		return e
 
 	default:
@@ -151,8 +247,26 @@ func (p *Parser) parseUnaryExpression() ast.Expression {
 	}
 }
 
-func (p *Parser) parseBinaryExpression(precedence int) ast.Expression {
+func (p *Parser) parseConversionExpression() ast.Expression {
 	x := p.parseUnaryExpression()
+	for p.token == token.As {
+		e := &ast.Conversion{}
+		e.Position = p.position
+		e.Expression = x
+		p.next()
+		if p.token == token.Not {
+			// "as!" traps if value cannot be converted
+			e.Checked = true
+			p.next()
+		}
+		e.Typ = p.parseType()
+		x = e
+	}
+	return x
+}
+
+func (p *Parser) parseBinaryExpression(precedence int) ast.Expression {
+	x := p.parseConversionExpression()
 	for {
 		if p.token == token.Semi {
 			return x
@@ -162,13 +276,16 @@ func (p *Parser) parseBinaryExpression(precedence int) ast.Expression {
 		if opPrec <= precedence {
 			return x
 		}
+		position := p.position
 		p.next()
 		y := p.parseBinaryExpression(opPrec)
-		x = &ast.Binary{
+		b := &ast.Binary{
 			Left:     x,
 			Operator: op,
 			Right:    y,
 		}
+		b.Position = position
+		x = b
 		if n, ok := y.(*ast.New); ok {
 			n.HasOwner = op.IsAssign()
 		}
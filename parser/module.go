@@ -18,7 +18,7 @@ func (p *Parser) parseSourceFile(file *token.File) {
 
 	for p.token != token.EOF {
 		attr := p.parseAttributes()
-		modifier := p.parseModifier()
+		modifier := p.parseDeclarationModifier()
 		switch p.token {
 		case token.Const, token.Var:
 			v := p.parseVariable(modifier, attr, "")
@@ -32,11 +32,18 @@ func (p *Parser) parseSourceFile(file *token.File) {
 		case token.Function:
 			f := p.parseFunction(modifier, attr, "")
 			qualified := m.Namespace + "." + f.Name.Name
-			if p.program.Declarations[qualified] != nil {
-				p.error(f.Name.Position, fmt.Sprintf("function %s redeclared", f.Name.Name))
+			if d := p.program.Declarations[qualified]; d != nil {
+				// functions with the same name are overloads
+				function, ok := d.(*ast.Function)
+				if !ok || (m.Namespace == ast.Global && f.Name.Name == ast.ProgramEntry) {
+					p.error(f.Name.Position, fmt.Sprintf("function %s redeclared", f.Name.Name))
+				} else if err := function.AddOverload(f); err != nil {
+					p.error(f.Name.Position, err.Error())
+				}
+			} else {
+				p.program.Declarations[qualified] = f
 			}
 			m.Functions = append(m.Functions, f)
-			p.program.Declarations[qualified] = f
 
 		case token.Enum:
 			e := p.parseEnum(modifier, attr)
@@ -47,6 +54,15 @@ func (p *Parser) parseSourceFile(file *token.File) {
 			m.Enums = append(m.Enums, e)
 			p.program.Declarations[qualified] = e
 
+		case token.Struct:
+			s := p.parseStruct(modifier, attr)
+			qualified := m.Namespace + "." + s.Name.Name
+			if p.program.Declarations[qualified] != nil {
+				p.error(s.Name.Position, fmt.Sprintf("struct %s redeclared", s.Name.Name))
+			}
+			m.Structs = append(m.Structs, s)
+			p.program.Declarations[qualified] = s
+
 		case token.Interface:
 			i := p.parseInterface(modifier, attr)
 			qualified := m.Namespace + "." + i.Name.Name
@@ -87,8 +103,9 @@ func (p *Parser) parseNamespace() string {
 func (p *Parser) parseImports() []*ast.Import {
 	imports := []*ast.Import{}
 	for p.token == token.Import {
-		p.expect(token.Import)
 		u := &ast.Import{}
+		u.Position = p.position
+		p.expect(token.Import)
 		name := p.parseIdentifier()
 		if p.token == token.Assign {
 			u.Alias = name.Name
@@ -2,10 +2,16 @@ package parser
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/panda-foundation/go-compiler/ast"
+	"github.com/panda-foundation/go-compiler/token"
 )
 
 func isNil(i interface{}) bool {
@@ -21,6 +27,38 @@ func assertEqual(t *testing.T, a interface{}, b interface{}) {
 	}
 }
 
+// errorMessages returns messages of errors reported by program in order
+func errorMessages(p *Parser) []string {
+	var messages []string
+	for _, e := range p.program.Errors {
+		messages = append(messages, e.Message)
+	}
+	return messages
+}
+
+// beforeComma removes details after the first comma of messages
+func beforeComma(messages []string) []string {
+	for i, m := range messages {
+		messages[i] = strings.Split(m, ",")[0]
+	}
+	return messages
+}
+
+// assertMessages compares messages with expected ones in order, each different message is reported
+func assertMessages(t *testing.T, messages []string, expected ...string) {
+	t.Helper()
+	for i := 0; i < len(messages) || i < len(expected); i++ {
+		switch {
+		case i >= len(expected):
+			t.Errorf("unexpected message %d: %s", i, messages[i])
+		case i >= len(messages):
+			t.Errorf("missing message %d: %s", i, expected[i])
+		case messages[i] != expected[i]:
+			t.Errorf("message %d: expected %s, but got %s", i, expected[i], messages[i])
+		}
+	}
+}
+
 func TestStatement(t *testing.T) {
 	p := NewParser([]string{}, ast.NewProgram())
 	p.ParseStatements([]byte("{i++;}"))
@@ -59,6 +97,117 @@ func TestDeclaration(t *testing.T) {
 	p.ParseBytes([]byte("namespace; public class a {} public class b<type> : a, x<type> { public var e int = 100; public function print<t>() void {} function ~b(){}}"))
 }
 
+func TestInference(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; var j = 1; class c { var k = 2.0; function f() { var l = k; } }"))
+
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterRuntime + "enum kind : u8 { a, b } " +
+		"class foo { public function create() { puts(\"create\"); } public function destroy() { puts(\"destroy\"); } public function get() int { return 1; } } " +
+		"function main() int { var o = new foo(); var x = o; var k = kind.b; var n = k.name(); return x.get() + n.length() + k as int; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+
+	// inferred types keep qualified name, so member functions resolve and instances are shared
+	var types []string
+	p.program.Walk(&counter{enter: func(n ast.Node) bool {
+		if d, ok := n.(*ast.DeclarationStatement); ok {
+			types = append(types, d.Name.Name+":"+ast.GetTypeUserData(d.Value.ResolvedType()))
+		}
+		return true
+	}})
+	assertEqual(t, fmt.Sprint(types), "[o:global.foo x:global.foo k:global.kind n:global.string]")
+	for _, s := range []string{"call void @global.instance.retain(i8* %", "call i8* @global.kind.name(i8 %", "call i32 @global.string.length(i8* %"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+
+	// x shares instance of o, it is destroyed once both are released
+	output, err := execute(t, content)
+	assertEqual(t, output, "create\ndestroy\n")
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 3 {
+		t.Errorf("expected exit status 3, but got %v", err)
+	}
+}
+
+func TestGlobalInference(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterRuntime + "class bar { public var x int = 7; } " +
+		"class foo { public var v int = 3; public var w = new bar(); public var b bar = new bar(); public var name string = label(); } " +
+		"function label() string { var s string = \"na\"; s += \"me\"; return s; } function make() foo { return new foo(); } " +
+		"var g = new foo(); var h = g; var k = make(); var n = label(); " +
+		"function main() int { puts(n.data()); puts(k.name.data()); return g.v * 2 + h.w.x + k.b.x + n.size(); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+
+	// types of globals and member variables are inferred from values resolved by checker
+	var types []string
+	p.program.Walk(&counter{enter: func(n ast.Node) bool {
+		if v, ok := n.(*ast.Variable); ok && v.Type == nil {
+			types = append(types, v.Name.Name+":"+ast.GetTypeUserData(v.Value.ResolvedType()))
+		}
+		return true
+	}})
+	sort.Strings(types)
+	assertEqual(t, fmt.Sprint(types), "[g:global.foo h:global.foo k:global.foo n:global.string w:global.bar]")
+	for _, s := range []string{"define void @global.initialize.", "%global.foo = type { %global.foo.vtable.type*, i32, i8*, i8*, i8* }"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+
+	// member variables with non-constant values are assigned by constructor
+	output, err := execute(t, content)
+	assertEqual(t, output, "name\nname\n")
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 24 {
+		t.Errorf("expected exit status 24, but got %v", err)
+	}
+}
+
+func TestInferenceFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; function f() {} function g() int { return 1; } var a = f(); var b = b; const c = g(); " +
+		"class k { public var w = f(); public var u = k.missing; } function main() {}"))
+	p.program.GenerateIR()
+	assertMessages(t, errorMessages(p),
+		"expression has no value",
+		"missing undefined",
+		"expression has no value",
+		"initial value of b refers to itself",
+		"only constant expression is allowed to initialize const value")
+}
+
+func TestInitializationOrder(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterRuntime + "class foo { public var v int; } " +
+		"class holder { public static var s foo = t; public static var t = new foo(){v = 4}; } " +
+		"var a foo = b; var c = a.v + b.v; var b foo = new foo(){v = 3}; var f = function() int { return d.v; }; var d = new foo(){v = 5}; " +
+		"function main() int { return a.v + c + holder.s.v + f(); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+
+	// variables are initialized after variables which their values refer to, value of lambda is not referred until it is invoked
+	_, err := execute(t, content)
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 18 {
+		t.Errorf("expected exit status 18, but got %v", err)
+	}
+}
+
+func TestInitializationFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	first := []byte("namespace; class foo { public var v int; } var a foo = a; var b = c; var c = b; var x = y.v;")
+	p.ParseFile(token.NewFile("a.pd", len(first)), first)
+	second := []byte("namespace; var y = new foo(); function main() {}")
+	p.ParseFile(token.NewFile("b.pd", len(second)), second)
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"initial value of a refers to itself",
+		"initialization cycle of b",
+		"y is used before it is initialized")
+}
+
 func TestDeclarationFail1(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -109,6 +258,23 @@ func TestDeclarationFail5(t *testing.T) {
 	p.ParseBytes([]byte("namespace; class a { var b int; var b int; }"))
 }
 
+func TestDeclarationFail6(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("function redeclare did not panic")
+		}
+	}()
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; function f(a int) {} function f(b int) {}"))
+}
+
+func TestOverload(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; function f(a int) {} function f(a float) {} function f(a int, b int) {} class a { function b() {} function b(c a) {} }"))
+	f := p.program.Declarations["global.f"].(*ast.Function)
+	assertEqual(t, len(f.Overloads), 3)
+}
+
 func TestNamespace(t *testing.T) {
 	p := NewParser([]string{}, ast.NewProgram())
 	p.ParseBytes([]byte("@doc \"package document here\" \nnamespace first.second.third;"))
@@ -117,4 +283,842 @@ func TestNamespace(t *testing.T) {
 func TestExpression(t *testing.T) {
 	p := NewParser([]string{}, ast.NewProgram())
 	p.ParseStatements([]byte("{ this.call_back(); var a = new vector<int>(); }"))
+	p.ParseStatements([]byte("{ var b = a as i8 + 1; var c = -b as! u8; var d = shape as circle; }"))
+	p.ParseStatements([]byte("{ var f = function(x int) int { return x + 1; }; var g = function [a, &b]() { b = a; }; apply(function() {}); var h function() int = f; }"))
+}
+
+func TestCast(t *testing.T) {
+	classes := "class animal {} class dog : animal { public function fetch() int { return 1; } } class puppy : dog {} class cat : animal {} "
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterRuntime + classes + "function main() int { var a animal = new puppy(); var r = 0; " +
+		"var d = a as dog; if (d != null) { r += d.fetch(); } var c = a as cat; if (c == null) { r += 2; } var p = a as! puppy; if (p != null) { r += 4; } return r; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"icmp eq i8* %", "bitcast (%global.puppy.vtable.type* getelementptr ({ %reflect.type*, %global.puppy.vtable.type }, { %reflect.type*, %global.puppy.vtable.type }* @global.puppy.vtable.data, i32 0, i32 1) to i8*)", "phi i8* [ %", "call void @llvm.trap()"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+	// down cast to dog succeeds, cast to cat results in null and checked cast to puppy does not trap
+	output, err := execute(t, content)
+	assertEqual(t, output, "")
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 7 {
+		t.Errorf("expected exit status 7, but got %v", err)
+	}
+
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterRuntime + classes + "function main() int { var a animal = new cat(); var d = a as! dog; puts(\"unreachable\"); return 0; }"))
+	content = p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	// checked down cast traps when instance is not dog
+	output, err = execute(t, content)
+	assertEqual(t, output, "")
+	if e, ok := err.(*exec.ExitError); !ok || e.Exited() {
+		t.Errorf("expected program to be aborted, but got %v", err)
+	}
+}
+
+func TestLambda(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterRuntime + "class foo { public var value int; public function destroy() { puts(\"destroy\"); } } " +
+		"function make() function() int { var n = 1; var o = new foo(); o.value = 10; var f = function [&n]() int { n++; return n + o.value; }; n = 5; return f; } " +
+		"function main() int { var f = make(); puts(\"call\"); var a = f(); return a + f(); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"define void @global.box.destroy(i8* %box)", "define void @global.lambda.0.destroy(i8* %closure.env)"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+	// n is shared with closure after make returns, o is released with environment
+	output, err := execute(t, content)
+	assertEqual(t, output, "call\ndestroy\n")
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 33 {
+		t.Errorf("expected exit status 33, but got %v", err)
+	}
+}
+
+func TestLambdaFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a { var v int; function f() { var g = function [&v]() { v++; }; } } " +
+		"function h(x int) { var g = function [&x]() { x++; }; var k = 1; var l = function [k, k]() {}; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p), "cannot capture x by reference", "k captured more than once", "cannot capture v by reference")
+}
+
+func TestWalk(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; var i int = 1; function print(a int) int { if (a > 0) { return a + i; } return i * 2; } class c { var x = 1; function get() int { return x; } }"))
+
+	literals := 0
+	skipped := 0
+	p.program.Walk(&counter{enter: func(n ast.Node) bool {
+		if _, ok := n.(*ast.Literal); ok {
+			literals++
+		}
+		// statements of functions are skipped
+		if _, ok := n.(*ast.Block); ok {
+			skipped++
+			return false
+		}
+		return true
+	}})
+	assertEqual(t, literals, 2)
+	assertEqual(t, skipped, 2)
+
+	var order []string
+	var module *ast.Module
+	for _, m := range p.program.Modules {
+		module = m
+	}
+	ast.Inspect(module.Functions[0].Body, nil, func(n ast.Node) {
+		if i, ok := n.(*ast.Identifier); ok {
+			order = append(order, i.Name)
+		}
+	})
+	assertEqual(t, fmt.Sprint(order), "[a a i i]")
+
+	// replace identifier "i" with literal and remove if statement
+	ast.Rewrite(module.Functions[0], nil, func(n ast.Node) ast.Node {
+		if i, ok := n.(*ast.Identifier); ok && i.Name == "i" {
+			return &ast.Literal{Value: "1"}
+		}
+		if _, ok := n.(*ast.If); ok {
+			return nil
+		}
+		return n
+	})
+	body := module.Functions[0].Body
+	assertEqual(t, len(body.Statements), 1)
+	_, ok := body.Statements[0].(*ast.Return).Expression.(*ast.Binary).Left.(*ast.Literal)
+	assertEqual(t, ok, true)
+}
+
+func TestRewriteFail(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("invalid replacement did not panic")
+		}
+	}()
+	e := &ast.Binary{Left: &ast.Identifier{Name: "a"}, Right: &ast.Identifier{Name: "b"}}
+	ast.Rewrite(e, nil, func(n ast.Node) ast.Node {
+		if _, ok := n.(*ast.Identifier); ok {
+			return &ast.Block{}
+		}
+		return n
+	})
+}
+
+func TestCheck(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; var r double = 2; function half(x double) double { return x / 2; } function main() int { var i = 3; var d = half(i) * r; if (d > 1) { return i; } return 0; }"))
+	p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+
+	types := make(map[string]string)
+	references := make(map[string]ast.Node)
+	p.program.Walk(&counter{enter: func(n ast.Node) bool {
+		if i, ok := n.(*ast.Identifier); ok && i.ResolvedType() != nil {
+			types[i.Name] = ast.MangleType(i.ResolvedType())
+			references[i.Name] = i.Reference()
+		}
+		return true
+	}})
+	assertEqual(t, types["i"], "i32")
+	assertEqual(t, types["d"], "f64")
+	assertEqual(t, types["half"], "function(f64)f64")
+	_, ok := references["r"].(*ast.Variable)
+	assertEqual(t, ok, true)
+	_, ok = references["x"].(*ast.Parameter)
+	assertEqual(t, ok, true)
+}
+
+func TestCheckFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; const c int = 1; function f(a int) {} function main() int { var d float = 1.5; var i int = d; if (i) {} f(); c = 2; break; return; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"cannot implicit convert f32 to i32 [down grade]",
+		"condition must be bool, but found i32",
+		"too few arguments",
+		"cannot assign to constant c",
+		"invalid break",
+		"missing return value")
+}
+
+func TestVisibilityFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace lib; public var a int = 1; var b int = 2; function f() {} class c {} public class d { var e int; public function create() {} function g() { e = 1; } } public class h : d { public function i() { g(); e = 2; } }"))
+	p.ParseBytes([]byte("namespace; import lib; function main() int { var x = lib.a + lib.b; lib.f(); var y lib.c; var z = new lib.d(); z.e = 1; z.g(); return 0; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, beforeComma(errorMessages(p)), "lib.b is not public", "lib.f is not public", "lib.c is not public", "lib.d.e is not public", "lib.d.g is not public")
+}
+
+func TestImportFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace a; import b; import a; import b; import b = c; import x = b.d; public function f() {}"))
+	p.ParseBytes([]byte("namespace b; import a; function g() { a.f(); }"))
+	p.ParseBytes([]byte("namespace c;"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"namespace a cannot import itself",
+		"b imported more than once",
+		"alias b of c conflicts with import of b",
+		"unknown namespace b.d",
+		"import cycle not allowed: a -> b -> a",
+		"b imported and not used")
+}
+
+func TestDependencies(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace app; import util; import core;"))
+	p.ParseBytes([]byte("namespace util; import core;"))
+	p.ParseBytes([]byte("namespace core;"))
+	p.ParseBytes([]byte("namespace;"))
+	p.program.ValidateImports()
+	assertEqual(t, len(p.program.Errors), 0)
+
+	g := p.program.Dependencies()
+	assertEqual(t, fmt.Sprint(g.Order()), "[global core util app]")
+	assertEqual(t, len(g.Cycles()), 0)
+
+	b := &strings.Builder{}
+	g.WriteDOT(b)
+	assertEqual(t, b.String(), "digraph namespaces {\n\t\"app\";\n\t\"core\";\n\t\"global\";\n\t\"util\";\n\t\"app\" -> \"core\";\n\t\"app\" -> \"util\";\n\t\"util\" -> \"core\";\n}\n")
+}
+
+func TestInheritanceFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; class a : b {} class b : a {} interface i : i {} class c { function f() int {} function g(x int) {} } class d : c { function f() float {} @override function g(x float) {} @override function h() {} }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"inheritance cycle: global.a -> global.b -> global.a",
+		"inheritance cycle: global.i -> global.i",
+		"member function f does not match its parent class c: expected function()i32, but found function()f32",
+		"member function g is marked as override, but it does not match any function of parent classes: expected function(i32), but found function(f32)",
+		"member function h is marked as override, but parent classes have no function h")
+}
+
+func TestAttribute(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; @packed class a { var x i8; var y i32; } @section \".data.custom\" var v int = 1; @inline function f() int { return v; } " +
+		"@deprecated \"use f\" function g() int { return f(); } function main() int { return g(); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"%global.a = type <{", "@global.v = global i32 1, section \".data.custom\"", "define i32 @global.f() alwaysinline {"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+	assertEqual(t, len(p.program.Warnings), 1)
+	assertEqual(t, p.program.Warnings[0].Message, "global.g is deprecated: use f")
+
+	restore := ast.RegisterAttributeHandler("test_marker", ast.TargetClass, func(p *ast.Program, d ast.Declaration, a *ast.Attribute) {
+		p.Error(a.Position, "handled "+d.Identifier())
+	})
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; @test_marker class a {}"))
+	p.program.GenerateIR()
+	restore()
+	assertEqual(t, len(p.program.Errors), 1)
+	assertEqual(t, p.program.Errors[0].Message, "handled a")
+	assertEqual(t, ast.IsAttributeRegistered("test_marker"), false)
+
+	restore = ast.RegisterAttributeHandler(ast.Deprecated, ast.TargetClass, nil)
+	restore()
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; @deprecated function g() {} function main() { g(); }"))
+	p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	assertEqual(t, len(p.program.Warnings), 1)
+}
+
+func TestAttributeFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; @unknown var a int = 1; @packed function f() {} class c { @section \"s\" var x int; @doc \"x\" @doc \"y\" function g() {} }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"unknown attribute @unknown",
+		"attribute @packed cannot be applied to function",
+		"attribute @section cannot be applied to member variable",
+		"duplicated attribute @doc")
+
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; @extern(variadic = 1, size = 2) function f(); @inline function g() {} @section function h() {} @deprecated(reason = \"x\") class c {}"))
+	p.program.GenerateIR()
+
+	messages := errorMessages(p)
+	sort.Strings(messages)
+	assertMessages(t, messages,
+		"argument variadic of attribute @extern must be bool",
+		"attribute @deprecated accepts text only",
+		"attribute @section requires section name only",
+		"unknown argument size of attribute @extern")
+}
+
+// counterClass is layout of builtin counter class, which is required by "new"
+const counterClass = "public class counter { var shared int; var weaks int; var object pointer; var destructor function(pointer); } "
+
+// counterRuntime is implementation of builtin counter class and libc functions, which are required to run programs
+const counterRuntime = "@extern function puts(text pointer) int; @extern function malloc(size int) pointer; @extern function free(address pointer); " +
+	"@extern function memcpy(dest pointer, source pointer, size int); @extern function memset(source pointer, value int, size int); " +
+	"public class counter { var shared int; var weaks int; var object pointer; var destructor function(pointer); " +
+	"function retain_shared() { this.shared++; } function retain_weak() { this.weaks++; } function release_weak() { this.weaks--; } " +
+	"function release_shared() { if (this == null) { return; } this.shared--; if (this.shared == 0) { this.destructor(this.object); free(this.object); this.object = null; " +
+	"if (this.weaks == 0) { free(this); } } } } "
+
+// execute runs ir of program with lli, it returns output and exit status of program
+func execute(t *testing.T, content string) (string, error) {
+	lli, err := exec.LookPath("lli")
+	if err != nil {
+		t.Skip("lli is not installed")
+	}
+	file := filepath.Join(t.TempDir(), "main.ll")
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output, err := exec.Command(lli, file).Output()
+	return string(output), err
+}
+
+func TestReflect(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import reflect; " + counterClass + "enum e { x, y = 3 } class a { var v int; } class b : a { var w float; } " +
+		"function main() int { var o a = new b(); var t = reflect.type_of(o); reflect.set_field(o, \"w\", 1.5); return reflect.field_count(t) + reflect.get_field(o, \"v\", 0); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"@global.a.type_info = constant %reflect.type", "@global.b.vtable.data = global { %reflect.type*, %global.b.vtable.type } { %reflect.type* @global.b.type_info",
+		"@global.e.type_info = constant %reflect.type", "define i8* @reflect.field_address(i8* %instance, i8* %name, i8* %type)"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestReflectFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import reflect; " + counterClass + "class a {} function main() { var i = 1; reflect.type_of(i); reflect.type_name(new a()); reflect.type_info(\"b\"); " +
+		"reflect.field_name(reflect.type_info(\"a\")); reflect.set_field(new a(), \"x\", new a()); }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"argument 1 of reflect.type_of must be class instance, but found i32",
+		"argument 1 of reflect.type_name must be pointer, but found global.a",
+		"argument 1 of reflect.type_info \"b\" is not class or enum",
+		"reflect.field_name expects 2 arguments, but found 1",
+		"argument 3 of reflect.set_field must be number, bool, enum or pointer, but found global.a")
+}
+
+func TestSerialize(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import binary; " + counterClass + "enum e { x, y } @serializable class a { var v int; var n a; } @serializable class b : a { var f e; } " +
+		"function main() int { var buffer = binary.serialize(new a()); var o = binary.deserialize(buffer, \"a\"); return binary.length(buffer); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"%runtime.stream = type { i8*, i32, i32, i8**, i32, i32, i1 }", "define i8* @global.a.binary.serialize(i8* %instance)",
+		"define i8* @global.a.binary.deserialize(i8* %buffer)", "define void @global.b.binary.write_fields(%runtime.stream* %stream, i8* %object)",
+		"define i8* @global.b.binary.read_fields(%runtime.stream* %stream)", "define i32 @binary.length(i8* %buffer)"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+
+	// string is written as size and bytes, empty string is read as null string
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import binary; " + counterRuntime + "@serializable class c { public var s string = \"default\"; public var e string; public var n int; " +
+		"public function destroy() { puts(\"destroy\"); } } function main() int { var o = new c(); o.s += \"!\"; o.n = 2; var buffer = binary.serialize(o); " +
+		"var d = binary.deserialize(buffer, \"c\"); free(buffer); puts(d.s.data()); return d.s.size() + d.e.size() + d.n; }"))
+	content = p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"define void @binary.write_string(%runtime.stream* %stream, i8* %s)", "define i8* @binary.read_string(%runtime.stream* %stream)"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+	// strings of member variables are released by destructor
+	destructor := content[strings.Index(content, "define void @global.c.destroy("):]
+	if destructor = destructor[:strings.Index(destructor, "\n}")]; strings.Count(destructor, "@global.counter.release_shared") != 2 {
+		t.Errorf("strings are not released by destructor:\n%s", destructor)
+	}
+	output, err := execute(t, content)
+	assertEqual(t, output, "default!\ndestroy\ndestroy\n")
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 10 {
+		t.Errorf("expected exit status 10, but got %v", err)
+	}
+}
+
+func TestSerializeFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a {} @serializable class b { var p pointer; var o a; } function main() {}"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"variable p of serializable class b cannot be serialized, its type is pointer",
+		"variable o of serializable class b cannot be serialized, its type is global.a")
+
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import binary; " + counterClass + "class a {} function main() { binary.serialize(new a()); binary.deserialize(null, \"a\"); binary.length(); }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"argument 1 of binary.serialize must be instance of serializable class, but found global.a",
+		"argument 2 of binary.deserialize must be name of serializable class",
+		"binary.length expects 1 arguments, but found 0")
+}
+
+func TestJSON(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import json; " + counterClass + "enum e { x, y } @json class a { @json(name = \"value\") var v int; @json(skip = true) var s pointer; @json(omit_empty = true) var n a; var f e; } " +
+		"function main() { var text = json.encode(new a()); var o = json.decode(text, \"a\"); var error = json.error(); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"define i8* @global.a.json.encode(i8* %instance)", "define i8* @global.a.json.decode(i8* %text)",
+		"define void @global.a.json.write(%runtime.stream* %stream, i8* %instance)", "define i8* @global.a.json.read(%runtime.stream* %stream)",
+		"@json.error = global i8* null", `c"\22value\22:\00"`} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+	if strings.Contains(content, `c"\22s\22:\00"`) {
+		t.Errorf("skipped variable s found in ir")
+	}
+
+	// decoded string is owned by instance and released by its destructor
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import json; " + counterRuntime + "@json class a { public var s string; @json(omit_empty = true) public var e string; public var n int; } " +
+		`function main() int { var o = new a(); o.s = "say \"hi\""; var text = json.encode(o); puts(text); free(text); ` +
+		`var d = json.decode("{\"s\": \"caf\\u00e9\", \"e\": null, \"n\": 2}", "a"); puts(d.s.data()); return d.s.size() + d.n; }`))
+	content = p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"define i8* @json.read_text(%runtime.stream* %stream)", "define void @global.a.destroy(i8* %this)"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+	output, err := execute(t, content)
+	assertEqual(t, output, "{\"s\":\"say \\\"hi\\\"\",\"n\":0}\ncafé\n")
+	if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != 7 {
+		t.Errorf("expected exit status 7, but got %v", err)
+	}
+}
+
+func TestJSONFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a {} @json class b { var o a; @json(name = \"v\") var x int; var v int; @json(size = 1, skip = 1) var y int; } function main() {}"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"variable o of json class b cannot be encoded, its type is global.a",
+		"duplicated json name v of class b",
+		"unknown argument size of attribute @json",
+		"argument skip of attribute @json must be bool")
+
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; import json; " + counterClass + "class a {} function main() { json.encode(new a()); json.decode(null, \"a\"); json.error(1); }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"argument 1 of json.encode must be instance of json class, but found global.a",
+		"argument 2 of json.decode must be name of json class",
+		"json.error expects 0 arguments, but found 1")
+}
+
+func TestGenerator(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a {} function items(o a, n int) a { for (var i = 0; i < n; i++) { yield o; } } " +
+		"function range(n int) int { var i = 0; for (i < n) { yield i; i++; } } function main() int { var sum = 0; for (var i; var x : range(3)) { sum += i * x; } var g = items(new a(), 2); for (var o a : g) {} return sum; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"define i1 (i32*)* @global.range(i32 %n)", "define i1 @global.range.resume(i8* %closure.env, i32* %value)",
+		"define void @global.items.destroy(i8* %closure.env)", "%global.items.frame = type { i8*, i8*, i32, i8*, i32, i32 }"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestGeneratorFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; class a { function f() int { yield 1; } } function g() { yield 1; } function h() int { var x = 1; var l = function [&x]() { yield x; }; yield 1; return 2; } " +
+		"function main() { for (var x : 1) {} for (var x float : h()) {} for (var k bool; var x : h()) {} for (var x = 1 : h()) {} }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"generator function g must declare type of yielded values",
+		"cannot capture x by reference in generator function",
+		"lambda cannot yield",
+		"generator function cannot return value",
+		"cannot iterate i32, iterator must be generator",
+		"item of foreach is declared as f32, but iterator yields i32",
+		"key of foreach must be integer type, but found bool",
+		"key and item of foreach must be variable declarations without value",
+		"member function f cannot yield")
+}
+
+func TestNewInitializer(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a { public var x int; public var f function() int; } class b : a { public var y float; var z int; public function g() b { return new b(){z = 1}; } } " +
+		"function main() int { var k = 2; var o = new b(){x = k, y = 1, f = function() int { return 1; },}; var e = new a(){}; return o.x; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	if !strings.Contains(content, "store float 1.0, float* %") {
+		t.Errorf("initializer of y not found in ir")
+	}
+
+	var initializers []string
+	p.program.Walk(&counter{enter: func(n ast.Node) bool {
+		if i, ok := n.(*ast.Initializer); ok {
+			initializers = append(initializers, i.Name.Name)
+		}
+		return true
+	}})
+	assertEqual(t, fmt.Sprint(initializers), "[x y f z]")
+}
+
+func TestNewInitializerFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a { public var x int; var hidden int; public function f() {} } " +
+		"function main() { var o = new a(){x = 1, x = 2, y = 3, f = 4, hidden = 5, x = 1.5}; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, beforeComma(errorMessages(p)),
+		"x initialized more than once",
+		"y is not variable of class a",
+		"f is not variable of class a",
+		"global.a.hidden is not public",
+		"x initialized more than once",
+		"cannot implicit convert f32 to i32 [down grade]")
+}
+
+func TestGlobalInitializer(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterRuntime + "class foo { public function create() { puts(\"create\"); } public function destroy() { puts(\"destroy\"); } } " +
+		"var g foo = make(); var h foo = g; function make() foo { return new foo(); } " +
+		"function main() int { puts(\"main\"); return 0; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	// g owns result of make, only h shares it
+	if !strings.Contains(content, "store i8* %0, i8** @global.g\n\t%1 = load i8*, i8** @global.g\n\tstore i8* %1, i8** @global.h\n\tcall void @global.instance.retain(i8* %1)\n\tbr") {
+		t.Errorf("global g is not owned by initializer")
+	}
+	// destructor runs once both globals are released
+	output, err := execute(t, content)
+	assertEqual(t, err, nil)
+	assertEqual(t, output, "create\nmain\ndestroy\n")
+}
+
+type counter struct {
+	enter func(ast.Node) bool
+}
+
+func (c *counter) Enter(n ast.Node) bool {
+	return c.enter(n)
+}
+
+func (c *counter) Leave(ast.Node) {}
+
+func TestStruct(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "struct point { public var x int = 1; public var y int; public function move(d int) { x += d; } } " +
+		"struct line { public var start point; public var end = new point(){y = 2}; } function reset(&p point) { p.x = 0; } " +
+		"function main() int { var a point; var b = a; b.move(1); reset(a); var l line; l.start = b; return l.end.y; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"%global.point = type { i32, i32 }", "%global.line = type { %global.point, %global.point }", "define void @global.reset(%global.point* %p)",
+		"store %global.line { %global.point { i32 1, i32 zeroinitializer }, %global.point { i32 1, i32 2 } }"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestStructFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a {} struct s { var o a; var n s; } struct point { public var x int; } " +
+		"function f(&x int) {} function g(&p point) {} function main() { var p point; g(new point()); g(p.x); p.y = 1; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"variable o of struct cannot be class instance, string or function",
+		"struct s contains itself",
+		"x cannot be passed by reference, only struct could be passed by reference",
+		"only variable could be passed by reference",
+		"cannot implicit convert i32 to global.point",
+		"y undefined")
+}
+
+func TestString(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "var name string = \"panda\"; " +
+		"function greet(s string) string { s += \"!\"; return \"hi \" + s; } " +
+		"function main() int { var s string = greet(name); var c = s[1]; var n = 42 as string; " +
+		"if (s == \"hi panda!\" && n < s) { return s.length() + s.slice(0, 2).size(); } return 'a' as int; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"define i8* @global.string.concat(i8* %a, i8* %b)", "define i32 @global.string.compare(i8* %a, i8* %b)",
+		"define i32 @global.string.char_at(i8* %s, i32 %index)", "define i8* @global.string.from_int(i64 %value)",
+		"@global.string.ce61649168c4550c2f7acab92354dc6e.data = constant { i32, [6 x i8] } { i32 5, [6 x i8] c\"panda\\00\" }",
+		"call void @global.string.retain(i8* %s)", "i32 97"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestStringFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a {} function f(p pointer) {} " +
+		"function main() { var s string = \"x\"; var p pointer = s; var q string = p; f(s); var n = s - s; var b = s == 1; " +
+		"var x = s.size; s.upper(); var i = s as int; var o = new a() as string; var l = s[1.5]; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"cannot implicit convert global.string to pointer",
+		"cannot implicit convert pointer to global.string",
+		"cannot implicit convert global.string to pointer",
+		"invalid type for binary expression",
+		"invalid type for binary expression",
+		"member function size of string must be invoked",
+		"upper undefined",
+		"cannot convert string to i32, use its member functions instead",
+		"cannot convert global.a to string",
+		"index of string must be integer")
+}
+
+func TestInterpolation(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	e := p.ParseExpression([]byte(`$"a={a}, {{b}}={b.c + 1}"`)).(*ast.Interpolation)
+	assertEqual(t, len(e.Parts), 4)
+	assertEqual(t, e.Parts[0].(*ast.Literal).Value, `"a="`)
+	assertEqual(t, e.Parts[1].(*ast.Identifier).Position, 5)
+	assertEqual(t, e.Parts[2].(*ast.Literal).Value, `", {b}="`)
+	assertEqual(t, e.Parts[2].GetPosition(), 7)
+	assertEqual(t, e.Parts[3].(*ast.Binary).Position, 20)
+
+	p = NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a { public var name string = \"x\"; } " +
+		"function main() int { var x = 1; var o = new a(); var s string = $\"x={x}, name={o.name}, f={1.5 * x}, b={x > 0}\"; var e string = $\"\"; return s.size(); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	for _, s := range []string{"call i8* @global.string.from_int(i64", "call i8* @global.string.from_float(double", "call i8* @global.string.concat(i8*",
+		"constant { i32, [3 x i8] } { i32 2, [3 x i8] c\"x=\\00\" }", "constant { i32, [1 x i8] } { i32 0, [1 x i8] c\"\\00\" }"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestInterpolationFail1(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("unclosed expression did not panic")
+		}
+	}()
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseExpression([]byte(`$"a={a b}"`))
+}
+
+func TestInterpolationFail2(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class a {} function f() {} " +
+		"function main() { var o = new a(); var s string = $\"{o} {f} {f()} {y}\"; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"cannot interpolate global.a in string",
+		"cannot interpolate function() in string",
+		"expression has no value",
+		"undefined y")
+}
+
+func TestEnum(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "const start = 10; enum color : u8 { red = start, green, blue = green * 2, alias = red } " +
+		"@flags enum mode { none = 0, read, write, all = read | write } " +
+		"function main() int { var c = color.green; var m = mode.read | mode.write; m |= mode.all; var s string = $\"{c}\"; " +
+		"switch (c) { case color.red: return 1; } return color.from_name(c.name(), color.red) as int; }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	assertEqual(t, len(p.program.Warnings), 1)
+	assertEqual(t, p.program.Warnings[0].Message, "switch on global.color does not handle green, blue")
+
+	color := p.program.FindQualified("global.color").(*ast.Enum)
+	assertEqual(t, color.IRType.String(), "i8")
+	assertEqual(t, color.IRValues[2].X.Int64(), int64(22))
+	assertEqual(t, color.IRValues[3].X.Int64(), int64(10))
+	mode := p.program.FindQualified("global.mode").(*ast.Enum)
+	assertEqual(t, mode.IRValues[2].X.Int64(), int64(2))
+	assertEqual(t, mode.IRValues[3].X.Int64(), int64(3))
+	for _, s := range []string{"define i8* @global.color.name(i8 %value)", "define i8 @global.color.from_name(i8* %name, i8 %fallback)",
+		"switch i8 %value, label", "store i8 11"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestEnumFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "enum f : float { a } enum small : u8 { a = 255, b } enum bad { a = x, b = 1 / 0, c = \"s\" } " +
+		"enum color { red, green } @flags enum mode { x, y } enum loop { a = loop.b, b } " +
+		"function main() { var c = color.red | color.green; var m = mode.x | color.red; var n = color.red.name; var k = ~color.red; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"underlying type of enum must be integer",
+		"value 256 of b overflows u8",
+		"x undefined",
+		"division by zero",
+		"enum value must be constant integer expression",
+		"value of b refers to itself",
+		"enum global.color is not @flags",
+		"cannot combine global.mode and global.color",
+		"function name of enum must be invoked",
+		"enum global.color is not @flags")
+}
+
+func TestSwitch(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "const fruit = \"apple\"; enum color { red, green } " +
+		"function kind(s string) int { switch (s) { case fruit: return 1; case \"k32728\": return 2; case \"k261234\": return 3; default: return 0; } } " +
+		"function main() int { var n = 0; switch (var c = color.red; c) { case color.red: { n = 1; fallthrough; } case color.green: n += 2; } " +
+		"switch (n) { case 1: fallthrough; default: n = 0; } return n + kind(\"apple\"); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+	assertEqual(t, len(p.program.Warnings), 0)
+	for _, s := range []string{"define i32 @global.string.hash(i8* %s)", "call i32 @global.string.hash(i8*", "call i32 @global.string.compare(i8*"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestSwitchFail(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "enum color { red, green, alias = red } " +
+		"function main(s string, v int, x float) { var t = \"a\"; switch (s) { case \"a\": fallthrough; case t: v = 1; case \"a\": v = 2; } " +
+		"switch (v) { case 1: { fallthrough; v = 3; } case 1: fallthrough; } switch (v) { case 2: v = 1; default: fallthrough; } " +
+		"switch (color.red) { case color.red: v = 1; case color.alias: v = 2; default: v = 0; } switch (x) { case 1.0: v = 1; } fallthrough; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"expect constant string expression",
+		"duplicate case \"a\" in switch",
+		"fallthrough statement out of place",
+		"duplicate case 1 in switch",
+		"cannot fallthrough final case in switch",
+		"cannot fallthrough final case in switch",
+		"duplicate case 0 in switch",
+		"switch operand must be integer, enum or string",
+		"fallthrough statement out of place")
+}
+
+func TestStatic(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "@json class shape { public static var count int = 0; public static const sides = 4; public var id int; " +
+		"public function create() { count += 1; } public static function add(a int, b int) int { return a + b; } public static function add(a float, b float) float { return a + b; } " +
+		"public function area() int { id = count; return add(id, sides); } } class square : shape { public static var total int = shape.sides * 2; } " +
+		"var started int = shape.count; function main() int { var s = new square(); shape.count += square.total; switch (4) { case shape.sides: return 1; } " +
+		"var f = square.add(1.0, 2.0); return shape.add(s.area(), global.shape.count); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+
+	shape := p.program.FindQualified("global.shape").(*ast.Class)
+	assertEqual(t, shape.IRStruct.LLString(), "{ %global.shape.vtable.type*, i32 }")
+	assertEqual(t, shape.IRVTable.LLString(), "{ i8* ()*, void (i8*)*, i32 (i8*)* }")
+	for _, s := range []string{"@global.shape.count = global i32 0", "@global.square.total = global i32", "define i32 @global.shape.add$i32$i32(i32 %a, i32 %b)",
+		"define float @global.shape.add$f32$f32(float %a, float %b)", "call i32 @global.shape.add$i32$i32(i32", "store i32 %"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+}
+
+func TestStaticFail1(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("static global variable did not panic")
+		}
+	}()
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; static var x int;"))
+}
+
+func TestStaticFail2(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("static constructor did not panic")
+		}
+	}()
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; class a { static function create() {} }"))
+}
+
+func TestStaticFail3(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "class shape { public static var count int; static var hidden int; public var id int; " +
+		"public static function make() int { return this.id + id; } public function f() int { return this.count; } } " +
+		"function main() { var s = new shape(); var a = s.count + shape.hidden + shape.missing; }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, beforeComma(errorMessages(p)),
+		"static member count must be accessed through class shape",
+		"global.shape.hidden is not public",
+		"missing undefined",
+		"'this' undefined",
+		"undefined id",
+		"static member count must be accessed through class shape")
+}
+
+func TestAbstract(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "public abstract class shape { public function area() int; public function describe() int { return area() * 10 + this.area(); } } " +
+		"abstract class rect : shape { public var w int = 2; } class square : rect { public function area() int { return w * w; } } " +
+		"function main() int { var s shape = new square(); return s.describe(); }"))
+	content := p.program.GenerateIR()
+	assertEqual(t, len(p.program.Errors), 0)
+
+	shape := p.program.FindQualified("global.shape").(*ast.Class)
+	assertEqual(t, shape.IsAbstract(), true)
+	for _, s := range []string{"i32 (i8*)* null, i32 (i8*)* @global.shape.describe", "i32 (i8*)* @global.square.area, i32 (i8*)* @global.shape.describe"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("%s not found in ir", s)
+		}
+	}
+	if strings.Contains(content, "@global.shape.area") {
+		t.Errorf("abstract function is declared in ir")
+	}
+}
+
+func TestAbstractFail1(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("abstract function did not panic")
+		}
+	}()
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; abstract function f() {}"))
+}
+
+func TestAbstractFail2(t *testing.T) {
+	p := NewParser([]string{}, ast.NewProgram())
+	p.ParseBytes([]byte("namespace; " + counterClass + "abstract class shape { public function area() int; public function create(); public static function make(); } " +
+		"class circle : shape { public function radius() int; } abstract class oval : shape {} class rect : oval { public function area() int { return base.area(); } } " +
+		"function main() { var s = new shape(); var o = new oval(); }"))
+	p.program.GenerateIR()
+
+	assertMessages(t, errorMessages(p),
+		"function create must have body",
+		"function make must have body",
+		"class circle does not implement abstract function shape.area",
+		"member function radius has no body, class circle must be abstract",
+		"cannot instantiate abstract class shape",
+		"cannot instantiate abstract class oval",
+		"abstract function area of class shape cannot be invoked by base")
 }
@@ -21,6 +21,13 @@ func (p *Parser) parseStatement() ast.Statement {
 		p.expect(token.Semi)
 		return s
 
+	case token.Fallthrough:
+		s := &ast.Fallthrough{}
+		s.Position = p.position
+		p.next()
+		p.expect(token.Semi)
+		return s
+
 	case token.Return:
 		s := &ast.Return{}
 		s.Position = p.position
@@ -39,6 +46,14 @@ func (p *Parser) parseStatement() ast.Statement {
 		p.expect(token.Semi)
 		return s
 
+	case token.Yield:
+		s := &ast.Yield{}
+		s.Position = p.position
+		p.next()
+		s.Expression = p.parseExpression()
+		p.expect(token.Semi)
+		return s
+
 	case token.LeftBrace:
 		return p.parseBlockStatement()
 
@@ -106,6 +106,10 @@ func (p *Parser) parseParameters() *ast.Parameters {
 func (p *Parser) parseParameter() *ast.Parameter {
 	t := &ast.Parameter{}
 	t.Position = p.position
+	if p.token == token.BitAnd {
+		t.Reference = true
+		p.next()
+	}
 	t.Name = p.parseIdentifier().Name
 	t.Type = p.parseType()
 	return t
@@ -132,17 +136,15 @@ func (p *Parser) parseFunctionType() *ast.TypeFunction {
 	t := &ast.TypeFunction{}
 	t.Position = p.position
 	p.expect(token.LeftParen)
-	if p.token == token.RightParen {
-		p.next()
-		return t
-	}
-	t.Parameters = append(t.Parameters, p.parseType())
-	for p.token == token.Comma {
-		p.next()
+	if p.token != token.RightParen {
 		t.Parameters = append(t.Parameters, p.parseType())
+		for p.token == token.Comma {
+			p.next()
+			t.Parameters = append(t.Parameters, p.parseType())
+		}
 	}
 	p.expect(token.RightParen)
-	if p.token != token.Semi && p.token != token.Assign {
+	if p.token.IsScalar() || p.token == token.IDENT || p.token == token.Function {
 		t.ReturnType = p.parseType()
 	}
 	return t
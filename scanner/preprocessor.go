@@ -0,0 +1,279 @@
+package scanner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+const (
+	//#if #else #elif #end
+	preprocessorIf     = "if"
+	preprocessorElse   = "else"
+	preprocessorElseIf = "elif"
+	preprocessorEnd    = "end"
+)
+
+// PreprocessorError is error of condition expression, offset is relative to start of expression
+type PreprocessorError struct {
+	Offset  int
+	Message string
+}
+
+func (e *PreprocessorError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Offset, e.Message)
+}
+
+type conditionalBlock struct {
+	directive string
+	satisfied bool
+}
+
+// Preprocessor evaluates conditions of #if #elif and tracks nested conditional blocks
+// flags are defined as "name" or "name=value", condition could be:
+// name, name == "value", name != "value", !condition, condition && condition, condition || condition, (condition)
+type Preprocessor struct {
+	defines map[string]string
+	blocks  []*conditionalBlock
+}
+
+func NewPreprocessor(flags []string) *Preprocessor {
+	p := &Preprocessor{
+		defines: make(map[string]string),
+	}
+	for _, flag := range flags {
+		name, value := flag, ""
+		if i := strings.Index(flag, "="); i > -1 {
+			name, value = flag[:i], flag[i+1:]
+		}
+		p.defines[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return p
+}
+
+// Reset clears conditional blocks, it is called when a new file is scanned
+func (p *Preprocessor) Reset() {
+	p.blocks = p.blocks[:0]
+}
+
+// Defined reports whether flag is defined
+func (p *Preprocessor) Defined(name string) bool {
+	_, ok := p.defines[name]
+	return ok
+}
+
+// Value returns value of flag, it is empty if flag is defined without value
+func (p *Preprocessor) Value(name string) (string, bool) {
+	value, ok := p.defines[name]
+	return value, ok
+}
+
+// Level returns count of nested conditional blocks
+func (p *Preprocessor) Level() int {
+	return len(p.blocks)
+}
+
+// If begins a conditional block
+func (p *Preprocessor) If() {
+	p.blocks = append(p.blocks, &conditionalBlock{
+		directive: preprocessorIf,
+	})
+}
+
+// Branch moves current conditional block to #elif or #else
+func (p *Preprocessor) Branch(directive string) error {
+	if len(p.blocks) == 0 || p.blocks[len(p.blocks)-1].directive == preprocessorElse {
+		return fmt.Errorf("unexpected #%s", directive)
+	}
+	p.blocks[len(p.blocks)-1].directive = directive
+	return nil
+}
+
+// End ends current conditional block
+func (p *Preprocessor) End() error {
+	if len(p.blocks) == 0 {
+		return fmt.Errorf("unexpected #%s", preprocessorEnd)
+	}
+	p.blocks = p.blocks[:len(p.blocks)-1]
+	return nil
+}
+
+// Satisfied reports whether a branch of current conditional block is taken
+func (p *Preprocessor) Satisfied() bool {
+	return len(p.blocks) > 0 && p.blocks[len(p.blocks)-1].satisfied
+}
+
+// Satisfy marks current branch is taken, the rest branches are skipped
+func (p *Preprocessor) Satisfy() {
+	if len(p.blocks) > 0 {
+		p.blocks[len(p.blocks)-1].satisfied = true
+	}
+}
+
+// Evaluate returns result of condition expression, operators are ordered by precedence: ! (== !=) && ||
+func (p *Preprocessor) Evaluate(expression string) (result bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(*PreprocessorError); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	c := &condition{
+		preprocessor: p,
+		source:       expression,
+	}
+	c.next()
+	result = c.parseOr()
+	if c.token != token.EOF {
+		c.unexpected()
+	}
+	return result, nil
+}
+
+// condition is recursive descent parser of condition expression
+type condition struct {
+	preprocessor *Preprocessor
+	source       string
+
+	offset     int
+	readOffset int
+	token      token.Token
+	literal    string
+}
+
+func (c *condition) next() {
+	for c.readOffset < len(c.source) && strings.ContainsRune(" \t\r", rune(c.source[c.readOffset])) {
+		c.readOffset++
+	}
+	c.offset = c.readOffset
+	c.literal = ""
+	if c.readOffset == len(c.source) || strings.HasPrefix(c.source[c.readOffset:], "//") {
+		c.token = token.EOF
+		return
+	}
+
+	char := rune(c.source[c.readOffset])
+	switch {
+	case char == '_' || 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z':
+		for c.readOffset < len(c.source) && isFlagChar(rune(c.source[c.readOffset])) {
+			c.readOffset++
+		}
+		c.token = token.IDENT
+
+	case char == '"':
+		c.readOffset++
+		for c.readOffset < len(c.source) && c.source[c.readOffset] != '"' {
+			if c.source[c.readOffset] == '\\' {
+				c.readOffset++
+			}
+			c.readOffset++
+		}
+		if c.readOffset >= len(c.source) {
+			c.error(c.offset, "string literal not terminated")
+		}
+		c.readOffset++
+		c.token = token.STRING
+
+	default:
+		t, length := token.ReadOperator([]byte(c.source[c.readOffset:]))
+		switch t {
+		case token.LeftParen, token.RightParen, token.Not, token.And, token.Or, token.Equal, token.NotEqual:
+			c.readOffset += length
+			c.token = t
+		default:
+			c.error(c.offset, "unexpected: "+string(char))
+		}
+	}
+	c.literal = c.source[c.offset:c.readOffset]
+}
+
+func (c *condition) parseOr() bool {
+	result := c.parseAnd()
+	for c.token == token.Or {
+		c.next()
+		// both sides are parsed, so errors are always reported
+		right := c.parseAnd()
+		result = result || right
+	}
+	return result
+}
+
+func (c *condition) parseAnd() bool {
+	result := c.parseUnary()
+	for c.token == token.And {
+		c.next()
+		right := c.parseUnary()
+		result = result && right
+	}
+	return result
+}
+
+func (c *condition) parseUnary() bool {
+	if c.token == token.Not {
+		c.next()
+		return !c.parseUnary()
+	}
+	return c.parsePrimary()
+}
+
+func (c *condition) parsePrimary() bool {
+	switch c.token {
+	case token.LeftParen:
+		c.next()
+		result := c.parseOr()
+		if c.token != token.RightParen {
+			c.unexpected()
+		}
+		c.next()
+		return result
+
+	case token.IDENT:
+		name := c.literal
+		c.next()
+		if c.token != token.Equal && c.token != token.NotEqual {
+			return c.preprocessor.Defined(name)
+		}
+		operator := c.token
+		c.next()
+		if c.token != token.STRING {
+			c.unexpected()
+		}
+		expected, err := strconv.Unquote(c.literal)
+		if err != nil {
+			c.error(c.offset, "invalid string literal")
+		}
+		c.next()
+		value, ok := c.preprocessor.Value(name)
+		equal := ok && value == expected
+		if operator == token.Equal {
+			return equal
+		}
+		return !equal
+	}
+	c.unexpected()
+	return false
+}
+
+func (c *condition) unexpected() {
+	if c.token == token.EOF {
+		c.error(c.offset, "unexpected end of condition")
+	}
+	c.error(c.offset, "unexpected: "+c.literal)
+}
+
+func (c *condition) error(offset int, message string) {
+	panic(&PreprocessorError{
+		Offset:  offset,
+		Message: message,
+	})
+}
+
+func isFlagChar(char rune) bool {
+	return char == '_' || 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z' || '0' <= char && char <= '9'
+}
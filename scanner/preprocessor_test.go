@@ -0,0 +1,90 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/panda-foundation/go-compiler/token"
+)
+
+func evaluate(p *Preprocessor, expression string) bool {
+	result, err := p.Evaluate(expression)
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+func TestEvaluate(t *testing.T) {
+	p := NewPreprocessor([]string{"debug", "opengl", "os=linux", "arch = x64"})
+	assertEqual(t, evaluate(p, "debug"), true)
+	assertEqual(t, evaluate(p, "release"), false)
+	assertEqual(t, evaluate(p, "!release"), true)
+	assertEqual(t, evaluate(p, "!!debug"), true)
+	assertEqual(t, evaluate(p, "debug && opengl"), true)
+	assertEqual(t, evaluate(p, "debug && vulkan"), false)
+	assertEqual(t, evaluate(p, "vulkan || opengl"), true)
+	assertEqual(t, evaluate(p, "os == \"linux\""), true)
+	assertEqual(t, evaluate(p, "os != \"linux\""), false)
+	assertEqual(t, evaluate(p, "os == \"windows\" || arch == \"x64\""), true)
+	assertEqual(t, evaluate(p, "cpu == \"\""), false)
+	assertEqual(t, evaluate(p, "cpu != \"arm\""), true)
+	// && binds tighter than ||
+	assertEqual(t, evaluate(p, "debug || vulkan && release"), true)
+	assertEqual(t, evaluate(p, "(debug || vulkan) && release"), false)
+	assertEqual(t, evaluate(p, "!(os == \"linux\" && debug)"), false)
+	assertEqual(t, evaluate(p, " debug // comment"), true)
+}
+
+func TestEvaluateFail(t *testing.T) {
+	p := NewPreprocessor(nil)
+	for _, expression := range []string{"", "a b", "(a", "a &&", "a == b", "a == \"b", "100", "a & b", "!"} {
+		_, err := p.Evaluate(expression)
+		if err == nil {
+			t.Errorf("%q did not fail", expression)
+		}
+	}
+	_, err := p.Evaluate("a && (b c)")
+	assertEqual(t, err.(*PreprocessorError).Offset, 8)
+}
+
+func TestConditionalBlock(t *testing.T) {
+	p := NewPreprocessor(nil)
+	p.If()
+	assertEqual(t, p.Level(), 1)
+	assertEqual(t, p.Satisfied(), false)
+	p.Satisfy()
+	assertEqual(t, p.Satisfied(), true)
+	assertEqual(t, p.Branch(preprocessorElseIf), nil)
+	assertEqual(t, p.Branch(preprocessorElse), nil)
+	if p.Branch(preprocessorElse) == nil {
+		t.Errorf("#else after #else did not fail")
+	}
+	assertEqual(t, p.End(), nil)
+	if p.End() == nil {
+		t.Errorf("unexpected #end did not fail")
+	}
+}
+
+func TestPreprocessorExpression(t *testing.T) {
+	fs := &token.FileSet{}
+	f := fs.AddFile("file.pd", 100)
+	s := NewScanner([]string{"debug", "os=linux"})
+
+	src := `
+	#if os == "windows" && debug
+	windows
+	#elif os == "linux" && !release
+	linux
+	#else
+	none
+	#end
+	end
+	`
+	s.SetFile(f, []byte(src))
+	_, tok, literal := s.Scan()
+	assertEqual(t, tok, token.IDENT)
+	assertEqual(t, literal, "linux")
+	_, tok, literal = s.Scan()
+	assertEqual(t, tok, token.IDENT)
+	assertEqual(t, literal, "end")
+}
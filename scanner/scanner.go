@@ -11,47 +11,31 @@ import (
 const (
 	bom = 0xFEFF // byte order mark, only permitted as very first character
 	eof = -1
-
-	//#if #else #elif #end
-	preprocessorIf     = "if"
-	preprocessorElse   = "else"
-	preprocessorElseIf = "elif"
-	preprocessorEnd    = "end"
-
-	// TO-DO add logical operator support for preprocessor
-	// TO-DO separate preprocessor
-	// () == != ! && ||
 )
 
-type preprocessor struct {
-	currentBlock string
-	satisfied    bool
-}
-
 type Scanner struct {
 	file   *token.File
 	source []byte
 
-	flags             map[string]bool // flags for condition compiler
-	preprocessorLevel int             // for nested flag
-	preprocessorStack []*preprocessor
+	preprocessor *Preprocessor // for condition compiler
 
 	char       rune
 	offset     int
 	readOffset int
 
-	prevChar       rune
-	prevOffset     int
-	prevReadOffset int
-	prevLines      int
+	// depths of braces in expressions of interpolated strings being scanned
+	interpolations []int
+
+	prevChar           rune
+	prevOffset         int
+	prevReadOffset     int
+	prevLines          int
+	prevInterpolations []int
 }
 
 func NewScanner(flags []string) *Scanner {
 	s := &Scanner{}
-	s.flags = make(map[string]bool)
-	for _, flag := range flags {
-		s.flags[flag] = true
-	}
+	s.preprocessor = NewPreprocessor(flags)
 	return s
 }
 
@@ -59,11 +43,11 @@ func (s *Scanner) SetFile(file *token.File, source []byte) {
 	s.file = file
 	s.source = source
 
-	s.preprocessorLevel = 0
-	s.preprocessorStack = s.preprocessorStack[:0]
+	s.preprocessor.Reset()
 	s.char = ' '
 	s.offset = 0
 	s.readOffset = 0
+	s.interpolations = s.interpolations[:0]
 
 	s.next()
 	if s.char == bom {
@@ -314,6 +298,38 @@ func (s *Scanner) scanRawString() string {
 	return string(s.source[offset:s.offset])
 }
 
+// scanInterpolated scans text of interpolated string after $" or } which ends an expression
+// text ends with { which starts an expression or " which ends the string, {{ and }} are braces in text
+func (s *Scanner) scanInterpolated(offset int) (token.Token, string) {
+	for {
+		char := s.char
+		if char == '\n' || char < 0 {
+			s.error(s.offset, "interpolated string literal not terminated")
+		}
+		s.next()
+		switch char {
+		case '"':
+			s.interpolations = s.interpolations[:len(s.interpolations)-1]
+			return token.INTERPOLATED_END, string(s.source[offset:s.offset])
+
+		case '{':
+			if s.char != '{' {
+				return token.INTERPOLATED, string(s.source[offset:s.offset])
+			}
+			s.next()
+
+		case '}':
+			if s.char != '}' {
+				s.error(s.offset-1, "single '}' in interpolated string, use '}}'")
+			}
+			s.next()
+
+		case '\\':
+			s.scanEscape('"')
+		}
+	}
+}
+
 func (s *Scanner) scanOperators() (t token.Token, literal string) {
 	offset := s.offset - 1
 	t, length := token.ReadOperator(s.source[offset:])
@@ -333,44 +349,34 @@ func (s *Scanner) scanPreprossesor() (int, token.Token, string) {
 	}
 	literal := s.scanIdentifier()
 	if literal == preprocessorIf {
-		s.preprocessorLevel++
-		s.preprocessorStack = append(s.preprocessorStack, &preprocessor{
-			currentBlock: preprocessorIf,
-			satisfied:    false,
-		})
-
-		result := s.scanPreprossesorExpression()
-		if result {
-			s.preprocessorStack[s.preprocessorLevel-1].satisfied = true
+		s.preprocessor.If()
+		if s.scanPreprossesorExpression() {
+			s.preprocessor.Satisfy()
 		} else {
 			s.skipPreprossesor()
 		}
 	} else if literal == preprocessorElseIf {
-		if s.preprocessorLevel == 0 || s.preprocessorStack[s.preprocessorLevel-1].currentBlock == preprocessorElse {
-			s.error(s.offset, "unexpected #elif")
-		} else if s.preprocessorStack[s.preprocessorLevel-1].satisfied {
+		if err := s.preprocessor.Branch(preprocessorElseIf); err != nil {
+			s.error(s.offset, err.Error())
+		}
+		if s.preprocessor.Satisfied() {
 			s.skipPreprossesor()
+		} else if s.scanPreprossesorExpression() {
+			s.preprocessor.Satisfy()
 		} else {
-			if s.scanPreprossesorExpression() {
-				s.preprocessorStack[s.preprocessorLevel-1].satisfied = true
-			} else {
-				s.skipPreprossesor()
-			}
+			s.skipPreprossesor()
 		}
-		s.preprocessorStack[s.preprocessorLevel-1].currentBlock = preprocessorElseIf
 	} else if literal == preprocessorElse {
-		if s.preprocessorLevel == 0 || s.preprocessorStack[s.preprocessorLevel-1].currentBlock == preprocessorElse {
-			s.error(s.offset, "unexpected #else")
-		} else if s.preprocessorStack[s.preprocessorLevel-1].satisfied {
+		if err := s.preprocessor.Branch(preprocessorElse); err != nil {
+			s.error(s.offset, err.Error())
+		}
+		if s.preprocessor.Satisfied() {
 			s.skipPreprossesor()
 		}
-		s.preprocessorStack[s.preprocessorLevel-1].currentBlock = preprocessorElse
 	} else if literal == preprocessorEnd {
-		if s.preprocessorLevel == 0 {
-			s.error(s.offset, "unexpected #end")
+		if err := s.preprocessor.End(); err != nil {
+			s.error(s.offset, err.Error())
 		}
-		s.preprocessorLevel--
-		s.preprocessorStack = s.preprocessorStack[:s.preprocessorLevel]
 	} else {
 		s.error(s.offset, "unexpected preprocessor: "+literal)
 	}
@@ -378,34 +384,22 @@ func (s *Scanner) scanPreprossesor() (int, token.Token, string) {
 	return s.Scan()
 }
 
+// scanPreprossesorExpression evaluates the rest of line as condition
 func (s *Scanner) scanPreprossesorExpression() bool {
-	for s.char == ' ' || s.char == '\t' {
+	offset := s.offset
+	for s.char != '\n' && s.char != eof {
 		s.next()
 	}
-
-	if s.isLetter(s.char) {
-		flag := s.scanIdentifier()
-
-		for s.char == ' ' || s.char == '\t' || s.char == '\r' {
-			s.next()
-		}
-		if s.char != '\n' {
-			s.error(s.offset, "unexpected: "+string(s.char))
-		}
-
-		result := false
-		if _, ok := s.flags[flag]; ok {
-			result = true
-		}
-		return result
+	result, err := s.preprocessor.Evaluate(string(s.source[offset:s.offset]))
+	if err != nil {
+		e := err.(*PreprocessorError)
+		s.error(offset+e.Offset, e.Message)
 	}
-
-	s.error(s.offset, "unexpected: "+string(s.char))
-	return false
+	return result
 }
 
 func (s *Scanner) skipPreprossesor() {
-	level := s.preprocessorLevel
+	level := s.preprocessor.Level()
 	for {
 		for s.char != eof && s.char != '#' {
 			s.next()
@@ -420,42 +414,24 @@ func (s *Scanner) skipPreprossesor() {
 			literal := s.scanIdentifier()
 
 			if literal == preprocessorIf {
-				s.preprocessorLevel++
-				s.preprocessorStack = append(s.preprocessorStack, &preprocessor{
-					currentBlock: preprocessorIf,
-					satisfied:    false,
-				})
-			} else if literal == preprocessorElseIf {
-				if s.preprocessorLevel == level {
-					s.offset = offset
-					s.readOffset = readOffset
-					s.char = '#'
-					break
-				}
-				if s.preprocessorStack[s.preprocessorLevel-1].currentBlock == preprocessorElse {
-					s.error(s.offset, "unexpected #elif")
-				}
-				s.preprocessorStack[s.preprocessorLevel-1].currentBlock = preprocessorElseIf
-			} else if literal == preprocessorElse {
-				if s.preprocessorLevel == level {
+				s.preprocessor.If()
+			} else if literal == preprocessorElseIf || literal == preprocessorElse || literal == preprocessorEnd {
+				if s.preprocessor.Level() == level {
+					// branch of current block is handled by scanPreprossesor
 					s.offset = offset
 					s.readOffset = readOffset
 					s.char = '#'
 					break
 				}
-				if s.preprocessorStack[s.preprocessorLevel-1].currentBlock == preprocessorElse {
-					s.error(s.offset, "unexpected #else")
+				var err error
+				if literal == preprocessorEnd {
+					err = s.preprocessor.End()
+				} else {
+					err = s.preprocessor.Branch(literal)
 				}
-				s.preprocessorStack[s.preprocessorLevel-1].currentBlock = preprocessorElse
-			} else if literal == preprocessorEnd {
-				if s.preprocessorLevel == level {
-					s.offset = offset
-					s.readOffset = readOffset
-					s.char = '#'
-					break
+				if err != nil {
+					s.error(s.offset, err.Error())
 				}
-				s.preprocessorLevel--
-				s.preprocessorStack = s.preprocessorStack[:s.preprocessorLevel]
 			} else {
 				s.error(s.offset, "unexpected preprocessor: "+literal)
 			}
@@ -506,9 +482,12 @@ func (s *Scanner) Scan() (position int, t token.Token, literal string) {
 		switch char {
 		case eof:
 			t = token.EOF
-			if s.preprocessorLevel > 0 {
+			if s.preprocessor.Level() > 0 {
 				s.error(s.offset, "preprocessor not terminated, expecting #end")
 			}
+			if len(s.interpolations) > 0 {
+				s.error(s.offset, "interpolated string literal not terminated")
+			}
 		case '"':
 			t = token.STRING
 			literal = s.scanString()
@@ -518,6 +497,27 @@ func (s *Scanner) Scan() (position int, t token.Token, literal string) {
 		case '\'':
 			t = token.CHAR
 			literal = s.scanChar()
+		case '$':
+			if s.char != '"' {
+				s.error(position, "invalid token")
+			}
+			s.next()
+			s.interpolations = append(s.interpolations, 0)
+			t, literal = s.scanInterpolated(position)
+		case '{', '}':
+			if n := len(s.interpolations); n > 0 {
+				// braces in expression of interpolated string are counted to find its end
+				if char == '{' {
+					s.interpolations[n-1]++
+				} else if s.interpolations[n-1] == 0 {
+					// expression ends, text of string continues
+					t, literal = s.scanInterpolated(position)
+					return
+				} else {
+					s.interpolations[n-1]--
+				}
+			}
+			t, literal = s.scanOperators()
 		case '/':
 			if s.char == '/' || s.char == '*' {
 				s.scanComment()
@@ -549,12 +549,14 @@ func (s *Scanner) Backup() {
 	s.prevOffset = s.offset
 	s.prevReadOffset = s.readOffset
 	s.prevLines = s.file.Lines()
+	s.prevInterpolations = append(s.prevInterpolations[:0], s.interpolations...)
 }
 
 func (s *Scanner) Restore() {
 	s.char = s.prevChar
 	s.offset = s.prevOffset
 	s.readOffset = s.prevReadOffset
+	s.interpolations = append(s.interpolations[:0], s.prevInterpolations...)
 	if s.prevLines != s.file.Lines() {
 		s.file.Truncate(s.prevLines)
 	}
@@ -172,6 +172,45 @@ func TestStringEscape(t *testing.T) {
 	assertEqual(t, literal, `"hello\n\r\x1b\123\u1234\U0001FFFF"`)
 }
 
+func TestInterpolation(t *testing.T) {
+	fs := &token.FileSet{}
+	f := fs.AddFile("file.pd", 100)
+	s := NewScanner(nil)
+
+	s.SetFile(f, []byte(`$"a={a}, {{b}}={ f(function() { return $"{x}"; }) }\n" $"end"`))
+	expected := []struct {
+		position int
+		tok      token.Token
+		literal  string
+	}{
+		{0, token.INTERPOLATED, `$"a={`},
+		{5, token.IDENT, "a"},
+		{6, token.INTERPOLATED, `}, {{b}}={`},
+		{17, token.IDENT, "f"},
+		{18, token.LeftParen, "("},
+		{19, token.Function, "function"},
+		{27, token.LeftParen, "("},
+		{28, token.RightParen, ")"},
+		{30, token.LeftBrace, "{"},
+		{32, token.Return, "return"},
+		{39, token.INTERPOLATED, `$"{`},
+		{42, token.IDENT, "x"},
+		{43, token.INTERPOLATED_END, `}"`},
+		{45, token.Semi, ";"},
+		{47, token.RightBrace, "}"},
+		{48, token.RightParen, ")"},
+		{50, token.INTERPOLATED_END, `}\n"`},
+		{55, token.INTERPOLATED_END, `$"end"`},
+		{61, token.EOF, ""},
+	}
+	for _, e := range expected {
+		position, tok, literal := s.Scan()
+		assertEqual(t, position, e.position)
+		assertEqual(t, tok, e.tok)
+		assertEqual(t, literal, e.literal)
+	}
+}
+
 func TestCharEscape(t *testing.T) {
 	fs := &token.FileSet{}
 	f := fs.AddFile("file.pd", 100)
@@ -411,6 +450,40 @@ func TestUnterminatedString(t *testing.T) {
 	s.Scan()
 }
 
+func TestUnterminatedInterpolation(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("unterminated interpolated string did not panic")
+		}
+	}()
+
+	fs := &token.FileSet{}
+	f := fs.AddFile("file.pd", 100)
+	s := NewScanner(nil)
+
+	s.SetFile(f, []byte(`$"a={a`))
+	for {
+		if _, tok, _ := s.Scan(); tok == token.EOF {
+			break
+		}
+	}
+}
+
+func TestSingleBraceInterpolation(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("single brace in interpolated string did not panic")
+		}
+	}()
+
+	fs := &token.FileSet{}
+	f := fs.AddFile("file.pd", 100)
+	s := NewScanner(nil)
+
+	s.SetFile(f, []byte(`$"a}"`))
+	s.Scan()
+}
+
 func TestUnterminatedChar(t *testing.T) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -16,10 +16,14 @@ const (
 	CHAR
 	STRING
 	NULL
+	INTERPOLATED     // text of interpolated string before expression, $"text{ or }text{
+	INTERPOLATED_END // the last text of interpolated string, $"text" or }text"
 	literalEnd
 
 	// keywords
 	keywordBegin
+	Abstract
+	As
 	Base
 	Break
 	Case
@@ -30,6 +34,7 @@ const (
 	Default
 	Else
 	Enum
+	Fallthrough
 	Finally
 	For
 	Function
@@ -40,12 +45,15 @@ const (
 	Namespace
 	Public
 	Return
+	Static
+	Struct
 	Switch
 	This
 	Throw
 	Try
 	Var
 	Weak
+	Yield
 	keywordEnd
 
 	// scalars
@@ -75,6 +83,7 @@ const (
 	Double
 	Void
 	Pointer
+	String
 	scalarEnd
 
 	// operators
@@ -142,32 +151,41 @@ var (
 		STRING: "string_literal",
 		NULL:   "null",
 
-		Base:      "base",
-		Break:     "break",
-		Case:      "case",
-		Catch:     "catch",
-		Class:     "class",
-		Const:     "const",
-		Continue:  "continue",
-		Default:   "default",
-		Else:      "else",
-		Enum:      "enum",
-		Finally:   "finally",
-		For:       "for",
-		Function:  "function",
-		If:        "if",
-		Import:    "import",
-		Interface: "interface",
-		New:       "new",
-		Namespace: "namespace",
-		Public:    "public",
-		Return:    "return",
-		Switch:    "switch",
-		This:      "this",
-		Throw:     "throw",
-		Try:       "try",
-		Var:       "var",
-		Weak:      "weak",
+		INTERPOLATED:     "interpolated_literal",
+		INTERPOLATED_END: "interpolated_end_literal",
+
+		Abstract:    "abstract",
+		As:          "as",
+		Base:        "base",
+		Break:       "break",
+		Case:        "case",
+		Catch:       "catch",
+		Class:       "class",
+		Const:       "const",
+		Continue:    "continue",
+		Default:     "default",
+		Else:        "else",
+		Enum:        "enum",
+		Fallthrough: "fallthrough",
+		Finally:     "finally",
+		For:         "for",
+		Function:    "function",
+		If:          "if",
+		Import:      "import",
+		Interface:   "interface",
+		New:         "new",
+		Namespace:   "namespace",
+		Public:      "public",
+		Return:      "return",
+		Static:      "static",
+		Struct:      "struct",
+		Switch:      "switch",
+		This:        "this",
+		Throw:       "throw",
+		Try:         "try",
+		Var:         "var",
+		Weak:        "weak",
+		Yield:       "yield",
 
 		Any:     "any",
 		Bool:    "bool",
@@ -194,6 +212,7 @@ var (
 		Double:  "double",
 		Void:    "void",
 		Pointer: "pointer",
+		String:  "string",
 
 		LeftParen:        "(",
 		RightParen:       ")",
@@ -309,6 +328,14 @@ func (t Token) IsAssign() bool {
 	return assignBegin < t && t < assignEnd
 }
 
+func (t Token) IsComparison() bool {
+	switch t {
+	case Equal, NotEqual, Less, LessEqual, Greater, GreaterEqual:
+		return true
+	}
+	return false
+}
+
 func (t Token) Precedence() int {
 	switch t {
 	case Assign, MulAssign, DivAssign, RemAssign, PlusAssign, MinusAssign,
@@ -28,11 +28,17 @@ func TestTokens(t *testing.T) {
 	assertEqual(t, ReadToken("true"), BOOL)
 	assertEqual(t, ReadToken("false"), BOOL)
 	assertEqual(t, ReadToken("null"), NULL)
+	assertEqual(t, ReadToken("as"), As)
+	assertEqual(t, ReadToken("fallthrough"), Fallthrough)
+	assertEqual(t, ReadToken("static"), Static)
+	assertEqual(t, ReadToken("abstract"), Abstract)
+	assertEqual(t, ReadToken("string"), String)
 }
 
 func TestTypes(t *testing.T) {
 	assertEqual(t, Case.IsKeyword(), true)
 	assertEqual(t, IDENT.IsLiteral(), true)
+	assertEqual(t, INTERPOLATED_END.IsLiteral(), true)
 	assertEqual(t, Or.IsOperator(), true)
 	assertEqual(t, META.Precedence(), 0)
 }